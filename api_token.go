@@ -0,0 +1,326 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/faults"
+	"github.com/ochinchina/supervisord/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// apiToken a bearer token accepted in addition to the username and password
+type apiToken struct {
+	name string
+	// the token, or the hex SHA1 of it prefixed by {SHA} like the password
+	secret string
+	// the token is not accepted after it, zero if it never expires
+	expires time.Time
+	// "config" if it is set in the tokens of a server section, "rpc" if it is created by supervisor.createToken
+	source string
+	// the protocol of the server accepting the token like "tcp", "tcp:metrics", "unix" or "grpc", empty if the
+	// token is accepted by all the servers
+	server string
+	// the role of the client authenticated by the token, admin or readonly
+	role string
+}
+
+// check if the token matches the bearer token and is not expired
+func (t *apiToken) accept(token string, now time.Time) bool {
+	if t.expired(now) {
+		return false
+	}
+	if strings.HasPrefix(t.secret, "{SHA}") {
+		hash := sha1.New() //nolint:gosec
+		hash.Write([]byte(token))
+		token = "{SHA}" + hex.EncodeToString(hash.Sum(nil))
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(t.secret)) == 1
+}
+
+func (t *apiToken) expired(now time.Time) bool {
+	return !t.expires.IsZero() && now.After(t.expires)
+}
+
+// apiTokens the bearer tokens of all the servers, the tokens set in the configuration are replaced on reload
+// and the tokens created by RPC are kept until they are revoked or supervisord exits
+type apiTokens struct {
+	lock   sync.RWMutex
+	tokens []*apiToken
+}
+
+func newAPITokens() *apiTokens {
+	return &apiTokens{tokens: make([]*apiToken, 0)}
+}
+
+// parse the tokens option like "name:token[:expires][:role],...", the expires is RFC3339 time or date like
+// 2006-01-02 and the role is admin or readonly, admin by default
+func parseAPITokens(value string) ([]*apiToken, error) {
+	result := make([]*apiToken, 0)
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		fields := strings.SplitN(item, ":", 3)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("invalid token %s, it should be name:token[:expires][:role]", item)
+		}
+		token := &apiToken{name: fields[0], secret: fields[1], source: "config", role: roleAdmin}
+		if len(fields) == 3 {
+			// the RFC3339 expires has colons too, the role is the last field
+			for _, role := range []string{roleAdmin, roleReadOnly} {
+				if fields[2] == role || strings.HasSuffix(fields[2], ":"+role) {
+					token.role = role
+					fields[2] = strings.TrimSuffix(strings.TrimSuffix(fields[2], role), ":")
+					break
+				}
+			}
+		}
+		if len(fields) == 3 && fields[2] != "" {
+			expires, err := time.Parse(time.RFC3339, fields[2])
+			if err != nil {
+				if expires, err = time.ParseInLocation("2006-01-02", fields[2], time.Local); err != nil {
+					return nil, fmt.Errorf("invalid expires of token %s: %s", fields[0], fields[2])
+				}
+			}
+			token.expires = expires
+		}
+		result = append(result, token)
+	}
+	return result, nil
+}
+
+// replace the tokens set in the configuration with the tokens of every server by its protocol
+func (at *apiTokens) setConfigTokens(serverTokens map[string][]*apiToken) {
+	at.lock.Lock()
+	defer at.lock.Unlock()
+	tokens := make([]*apiToken, 0)
+	for _, token := range at.tokens {
+		if token.source != "config" {
+			tokens = append(tokens, token)
+		}
+	}
+	for server, serverTokens := range serverTokens {
+		for _, token := range serverTokens {
+			token.server = server
+			tokens = append(tokens, token)
+		}
+	}
+	at.tokens = tokens
+}
+
+// create a random token accepted by all the servers and valid for ttl, it never expires if ttl is 0
+func (at *apiTokens) create(name string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(b)
+	token := &apiToken{name: name, secret: secret, source: "rpc", role: roleAdmin}
+	if ttl > 0 {
+		token.expires = time.Now().Add(ttl)
+	}
+
+	at.lock.Lock()
+	defer at.lock.Unlock()
+	now := time.Now()
+	tokens := make([]*apiToken, 0)
+	for _, old := range at.tokens {
+		if old.source != "rpc" || old.name != name {
+			tokens = append(tokens, old)
+		} else if !old.expired(now) {
+			return "", fmt.Errorf("token %s already exists", name)
+		}
+	}
+	at.tokens = append(tokens, token)
+	return secret, nil
+}
+
+// revoke the tokens with the name on all the servers, return false if it is not found
+func (at *apiTokens) revoke(name string) bool {
+	at.lock.Lock()
+	defer at.lock.Unlock()
+	tokens := make([]*apiToken, 0)
+	for _, token := range at.tokens {
+		if token.name != name {
+			tokens = append(tokens, token)
+		}
+	}
+	found := len(tokens) < len(at.tokens)
+	at.tokens = tokens
+	return found
+}
+
+// list the tokens without the secrets sorted by name and server
+func (at *apiTokens) list() []types.APIToken {
+	at.lock.RLock()
+	defer at.lock.RUnlock()
+	result := make([]types.APIToken, 0)
+	for _, token := range at.tokens {
+		info := types.APIToken{Name: token.name, Source: token.source, Server: token.server, Role: token.role}
+		if !token.expires.IsZero() {
+			info.Expires = int(token.expires.Unix())
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Server < result[j].Server
+	})
+	return result
+}
+
+// get the tokens accepted by the server on the protocol like "tcp", "tcp:metrics", "unix" or "grpc"
+func (at *apiTokens) forServer(server string) *serverTokens {
+	return &serverTokens{tokens: at, server: server}
+}
+
+// serverTokens the bearer tokens accepted by a server, the tokens of its section and the tokens created by RPC
+type serverTokens struct {
+	tokens *apiTokens
+	server string
+}
+
+// check the "Authorization: Bearer <token>" header of the request and return the role of the token
+func (st *serverTokens) authenticate(r *http.Request) (string, bool) {
+	token, ok := st.getToken(r)
+	if !ok {
+		return "", false
+	}
+	log.WithFields(log.Fields{"token": token.name, "role": token.role}).Debug("auth with token")
+	return token.role, true
+}
+
+// check if the tokens option of the server section sets any token, the tokens created by RPC don't make an
+// open server require them
+func (st *serverTokens) configured() bool {
+	if st == nil {
+		return false
+	}
+	st.tokens.lock.RLock()
+	defer st.tokens.lock.RUnlock()
+	for _, t := range st.tokens.tokens {
+		if t.source == "config" && t.server == st.server {
+			return true
+		}
+	}
+	return false
+}
+
+// get the name of the token accepted by the "Authorization: Bearer <token>" header of the request
+func (st *serverTokens) getName(r *http.Request) (string, bool) {
+	token, ok := st.getToken(r)
+	if !ok {
+		return "", false
+	}
+	return token.name, true
+}
+
+// get the token accepted by the "Authorization: Bearer <token>" header of the request
+func (st *serverTokens) getToken(r *http.Request) (*apiToken, bool) {
+	token, ok := getBearerToken(r)
+	if !ok || st == nil {
+		return nil, false
+	}
+	st.tokens.lock.RLock()
+	defer st.tokens.lock.RUnlock()
+	now := time.Now()
+	for _, t := range st.tokens.tokens {
+		if (t.server == "" || t.server == st.server) && t.accept(token, now) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// get the token of the "Authorization: Bearer <token>" header
+func getBearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:]), true
+	}
+	return "", false
+}
+
+// set the tokens of the inet_http_server, unix_http_server and grpc_server sections, the tokens of a section are
+// only accepted by its server. The section whose tokens can't be parsed has no token
+func (s *Supervisor) setAPITokens() {
+	sections := make(map[string]*config.Entry)
+	for _, httpServerConfig := range s.config.GetInetHTTPServers() {
+		sections[inetHTTPServerProtocol(httpServerConfig)] = httpServerConfig
+	}
+	if httpServerConfig, ok := s.config.GetUnixHTTPServer(); ok {
+		sections["unix"] = httpServerConfig
+	}
+	if grpcServerConfig, ok := s.config.GetGRPCServer(); ok {
+		sections["grpc"] = grpcServerConfig
+	}
+	tokens := make(map[string][]*apiToken)
+	for server, section := range sections {
+		sectionTokens, err := parseAPITokens(section.GetString("tokens", ""))
+		if err != nil {
+			log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to parse the tokens of " + section.Name)
+			continue
+		}
+		tokens[server] = sectionTokens
+	}
+	s.tokens.setConfigTokens(tokens)
+}
+
+// only the clients authenticated by the username and password can manage the tokens
+func checkTokenAdmin(r *http.Request) error {
+	if r != nil {
+		if _, ok := getBearerToken(r); ok {
+			return faults.NewFault(faults.Failed, "FAILED: the tokens can only be managed with the username and password")
+		}
+	}
+	return nil
+}
+
+// CreateToken creates a random bearer token which expires after TTL seconds, or never if TTL is 0
+func (s *Supervisor) CreateToken(r *http.Request, args *struct {
+	Name string
+	TTL  int
+}, reply *struct{ Token string }) error {
+	if err := checkTokenAdmin(r); err != nil {
+		return err
+	}
+	if args.Name == "" || strings.ContainsAny(args.Name, ":, \t\n") || args.TTL < 0 {
+		return faults.NewFault(faults.BadArguments, fmt.Sprintf("BAD_ARGUMENTS: invalid token name %q or ttl %d", args.Name, args.TTL))
+	}
+	token, err := s.tokens.create(args.Name, time.Duration(args.TTL)*time.Second)
+	if err != nil {
+		return faults.NewFault(faults.AlreadyAdded, fmt.Sprintf("ALREADY_ADDED: %v", err))
+	}
+	log.WithFields(log.Fields{"token": args.Name, "ttl": args.TTL}).Info("create token")
+	reply.Token = token
+	return nil
+}
+
+// RevokeToken revokes the bearer token, the token set in the configuration is accepted again after reload
+// if it is not removed from the configuration
+func (s *Supervisor) RevokeToken(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
+	if err := checkTokenAdmin(r); err != nil {
+		return err
+	}
+	if !s.tokens.revoke(args.Name) {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: no token named %s", args.Name))
+	}
+	log.WithFields(log.Fields{"token": args.Name}).Info("revoke token")
+	reply.Success = true
+	return nil
+}
+
+// ListTokens lists the names, expiry and source of the bearer tokens
+func (s *Supervisor) ListTokens(r *http.Request, args *struct{}, reply *struct{ Tokens []types.APIToken }) error {
+	reply.Tokens = s.tokens.list()
+	return nil
+}
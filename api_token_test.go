@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAPITokens(t *testing.T) {
+	tokens, err := parseAPITokens("ci:secret, old:x:2020-01-01,hashed:{SHA}a9993e364706816aba3e25717850c26c9cd0d89d")
+	if err != nil || len(tokens) != 3 {
+		t.Fatalf("fail to parse tokens: %v", err)
+	}
+	if _, err := parseAPITokens("ci"); err == nil {
+		t.Error("the token without name should be rejected")
+	}
+
+	at := newAPITokens()
+	metricsTokens, _ := parseAPITokens("metrics:m")
+	at.setConfigTokens(map[string][]*apiToken{"tcp": tokens, "tcp:metrics": metricsTokens})
+	created, err := at.create("deploy", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(token string) *http.Request {
+		req := &http.Request{Header: http.Header{}}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+	for token, expected := range map[string]bool{"secret": true, "x": false, "abc": true, created: true, "bad": false, "m": false} {
+		if _, ok := at.forServer("tcp").authenticate(request(token)); ok != expected {
+			t.Errorf("the token %s should be accepted: %v", token, expected)
+		}
+	}
+	// the tokens of a server are not accepted by the other servers, the created tokens are accepted by all
+	for token, expected := range map[string]bool{"secret": false, "m": true, created: true} {
+		if _, ok := at.forServer("tcp:metrics").authenticate(request(token)); ok != expected {
+			t.Errorf("the token %s should be accepted by the metrics server: %v", token, expected)
+		}
+	}
+
+	// the tokens are admin unless the readonly role is given
+	roleTokens, err := parseAPITokens("a:a1,r:r1:readonly,e:e1:2099-01-01T00:00:00Z:readonly,d:d1:2099-01-01:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at.setConfigTokens(map[string][]*apiToken{"tcp": roleTokens})
+	for token, expected := range map[string]string{"a1": roleAdmin, "r1": roleReadOnly, "e1": roleReadOnly, "d1": roleAdmin} {
+		if role, ok := at.forServer("tcp").authenticate(request(token)); !ok || role != expected {
+			t.Errorf("the token %s should be %s, but get %s", token, expected, role)
+		}
+	}
+
+	// the created tokens are kept on reload
+	at.setConfigTokens(nil)
+	if len(at.list()) != 1 || !at.revoke("deploy") || at.revoke("deploy") {
+		t.Error("fail to revoke the created token")
+	}
+}
+
+func TestSetAPITokensPerServer(t *testing.T) {
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "supervisord.conf")
+	ioutil.WriteFile(confFile, []byte(`
+[inet_http_server]
+port=:9001
+tokens=admin:a1
+
+[inet_http_server:metrics]
+port=:9002
+tokens=metrics:m1
+
+[unix_http_server]
+tokens=broken
+`), 0644)
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.setAPITokens()
+	tokens := s.tokens.list()
+	if len(tokens) != 2 || tokens[0].Server != "tcp" || tokens[1].Server != "tcp:metrics" {
+		t.Errorf("the tokens of the valid sections should be set for their servers, but get %v", tokens)
+	}
+}
+
+func TestReadOnlyToken(t *testing.T) {
+	at := newAPITokens()
+	tokens, _ := parseAPITokens("viewer:v1:readonly")
+	at.setConfigTokens(map[string][]*apiToken{"tcp": tokens})
+	handler := newHTTPBasicAuth("admin", "secret", at.forServer("tcp"), nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for method, expected := range map[string]int{"GET": http.StatusOK, "POST": http.StatusForbidden, "PUT": http.StatusForbidden} {
+		r := httptest.NewRequest(method, "/program/stop/web", nil)
+		r.Header.Set("Authorization", "Bearer v1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("the readonly token should get %d on %s, but get %d", expected, method, w.Code)
+		}
+	}
+}
+
+func TestTokensOnlyServer(t *testing.T) {
+	at := newAPITokens()
+	tokens, _ := parseAPITokens("admin:a1")
+	at.setConfigTokens(map[string][]*apiToken{"tcp": tokens})
+	handler := newHTTPBasicAuth("", "", at.forServer("tcp"), nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for auth, expected := range map[string]int{"": http.StatusUnauthorized, "Bearer a2": http.StatusUnauthorized, "Bearer a1": http.StatusOK} {
+		r := httptest.NewRequest("GET", "/program/list", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("the server with only the tokens should get %d with %q, but get %d", expected, auth, w.Code)
+		}
+	}
+
+	// the server without the username, password and tokens of its section is open
+	open := newHTTPBasicAuth("", "", at.forServer("unix"), nil, nil, nil)
+	if role, ok := open.authenticate(httptest.NewRequest("GET", "/program/list", nil)); !ok || role != roleAdmin {
+		t.Errorf("the server without the credentials should be open, but get %s, %v", role, ok)
+	}
+}
@@ -1,5 +1,5 @@
-//go:build !release
-// +build !release
+//go:build dev
+// +build dev
 
 package main
 
@@ -7,5 +7,6 @@ import (
 	"net/http"
 )
 
-//HTTP auto generated
+// HTTP the files of the web GUI served from the webgui directory, so the pages can be edited without
+// building again
 var HTTP http.FileSystem = http.Dir("./webgui")
@@ -1,5 +1,5 @@
-//go:build release
-// +build release
+//go:build !dev
+// +build !dev
 
 package main
 
@@ -9,9 +9,12 @@ import (
 	"net/http"
 )
 
+// the web GUI is embedded in the binary, so it is served without the webgui directory
+//
 //go:embed webgui
 var content embed.FS
 
+// HTTP the files of the web GUI
 var HTTP http.FileSystem
 
 func init() {
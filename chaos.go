@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/ochinchina/supervisord/faults"
+	"github.com/ochinchina/supervisord/process"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChaosDelayArgs arguments for delaying the automatic restarts of program
+type ChaosDelayArgs struct {
+	Name    string // program name
+	Seconds int    // the delay in seconds, 0 to remove the delay
+}
+
+// ChaosSpawnErrorArgs arguments for simulating spawn errors of program
+type ChaosSpawnErrorArgs struct {
+	Name  string // program name
+	Count int    // the number of following start attempts to fail
+}
+
+// check if the chaos testing RPCs are enabled by --enable-chaos option
+func (s *Supervisor) checkChaosEnabled() error {
+	if !s.chaos {
+		return faults.NewFault(faults.Failed, "chaos testing is not enabled, start supervisord with --enable-chaos")
+	}
+	return nil
+}
+
+// find the programs for chaos testing
+func (s *Supervisor) findChaosProcesses(name string) ([]*process.Process, error) {
+	if err := s.checkChaosEnabled(); err != nil {
+		return nil, err
+	}
+	procs := s.procMgr.FindMatch(name)
+	if len(procs) <= 0 {
+		return nil, faults.NewFault(faults.BadName, fmt.Sprintf("fail to find process %s", name))
+	}
+	return procs, nil
+}
+
+// ChaosKillRandomProcess kills a random running process in the group with SIGKILL, the
+// process is handled as unexpected exit and restarted if autorestart is set
+func (s *Supervisor) ChaosKillRandomProcess(r *http.Request, args *struct{ Group string }, reply *struct{ Name string }) error {
+	if err := s.checkChaosEnabled(); err != nil {
+		return err
+	}
+	running := make([]*process.Process, 0)
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if proc.GetGroup() == args.Group && proc.GetState() == process.Running {
+			running = append(running, proc)
+		}
+	})
+	if len(running) == 0 {
+		return faults.NewFault(faults.NotRunning, fmt.Sprintf("no running process in group %s", args.Group))
+	}
+	proc := running[rand.Intn(len(running))]
+	log.WithFields(log.Fields{"program": proc.GetName()}).Warn("chaos: kill the program")
+	if err := proc.Signal(syscall.SIGKILL, false); err != nil {
+		return err
+	}
+	reply.Name = proc.GetName()
+	return nil
+}
+
+// ChaosDelayRestart delays the following automatic restarts of the programs
+func (s *Supervisor) ChaosDelayRestart(r *http.Request, args *ChaosDelayArgs, reply *struct{ Success bool }) error {
+	procs, err := s.findChaosProcesses(args.Name)
+	if err != nil {
+		return err
+	}
+	for _, proc := range procs {
+		log.WithFields(log.Fields{"program": proc.GetName(), "seconds": args.Seconds}).Warn("chaos: delay the restart of program")
+		proc.SetChaosRestartDelay(time.Duration(args.Seconds) * time.Second)
+	}
+	reply.Success = true
+	return nil
+}
+
+// ChaosSimulateSpawnError makes the following start attempts of the programs fail
+func (s *Supervisor) ChaosSimulateSpawnError(r *http.Request, args *ChaosSpawnErrorArgs, reply *struct{ Success bool }) error {
+	procs, err := s.findChaosProcesses(args.Name)
+	if err != nil {
+		return err
+	}
+	for _, proc := range procs {
+		log.WithFields(log.Fields{"program": proc.GetName(), "count": args.Count}).Warn("chaos: simulate spawn errors of program")
+		proc.SetChaosSpawnErrors(args.Count)
+	}
+	reply.Success = true
+	return nil
+}
+
+// ChaosClear removes all the failures injected to the programs
+func (s *Supervisor) ChaosClear(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
+	procs, err := s.findChaosProcesses(args.Name)
+	if err != nil {
+		return err
+	}
+	for _, proc := range procs {
+		proc.ClearChaos()
+	}
+	reply.Success = true
+	return nil
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"mime"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/ochinchina/supervisord/types"
 )
 
 type ConfApi struct {
@@ -18,10 +21,110 @@ func NewConfApi(supervisor *Supervisor) *ConfApi {
 
 // CreateHandler creates http handlers to process the program stdout and stderr through http interface
 func (ca *ConfApi) CreateHandler() http.Handler {
+	ca.router.HandleFunc("/conf/sections", ca.adminOnly(ca.listProgramSections)).Methods("GET")
+	ca.router.HandleFunc("/conf/section/{program}", ca.adminOnly(ca.getProgramSection)).Methods("GET")
+	ca.router.HandleFunc("/conf/section/{program}/validate", ca.adminOnly(ca.validateProgramSection)).Methods("POST")
+	ca.router.HandleFunc("/conf/section/{program}", ca.adminOnly(ca.saveProgramSection)).Methods("POST", "PUT")
 	ca.router.HandleFunc("/conf/{program}", ca.getProgramConfFile).Methods("GET")
 	return ca.router
 }
 
+// the program sections can be read and changed by the admin client only, the readonly client may not
+// even read them because they may have the secrets in the environment
+func (ca *ConfApi) adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if getRequestRole(request.Context()) != roleAdmin {
+			http.Error(writer, "only the admin client can edit the configuration", http.StatusForbidden)
+			return
+		}
+		handler(writer, request)
+	}
+}
+
+// list the [program:x] sections with the configuration files they are in
+func (ca *ConfApi) listProgramSections(writer http.ResponseWriter, request *http.Request) {
+	sections := make([]types.ProgramSection, 0)
+	for _, name := range ca.supervisor.config.GetProgramSectionNames() {
+		fileName, editable, err := ca.supervisor.config.ProgramSectionFile(name)
+		if err != nil {
+			continue
+		}
+		sections = append(sections, types.ProgramSection{Name: name, File: fileName, Editable: editable})
+	}
+	json.NewEncoder(writer).Encode(sections)
+}
+
+// get the [program:x] section with its text in the configuration file
+func (ca *ConfApi) getProgramSection(writer http.ResponseWriter, request *http.Request) {
+	name := mux.Vars(request)["program"]
+	if len(ca.supervisor.config.GetProgramSection(name)) == 0 {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	fileName, text, err := ca.supervisor.config.ReadProgramSection(name)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	_, editable, _ := ca.supervisor.config.ProgramSectionFile(name)
+	json.NewEncoder(writer).Encode(types.ProgramSection{Name: name, File: fileName, Editable: editable, Text: text})
+}
+
+// validate the text of the [program:x] section in the request body without saving it
+func (ca *ConfApi) validateProgramSection(writer http.ResponseWriter, request *http.Request) {
+	text, ok := readSectionText(writer, request)
+	if !ok {
+		return
+	}
+	result := types.ProgramSectionResult{Changes: make([]types.ProgramConfigChange, 0)}
+	result.Errors, result.Warnings = validateProgramSection(ca.supervisor.config, mux.Vars(request)["program"], text)
+	json.NewEncoder(writer).Encode(result)
+}
+
+// validate the text of the [program:x] section in the request body, write it to the configuration file and
+// reload the programs of the section. The section with errors is not saved and 400 is returned
+func (ca *ConfApi) saveProgramSection(writer http.ResponseWriter, request *http.Request) {
+	name := mux.Vars(request)["program"]
+	if len(ca.supervisor.config.GetProgramSection(name)) == 0 {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	text, ok := readSectionText(writer, request)
+	if !ok {
+		return
+	}
+	result := types.ProgramSectionResult{Changes: make([]types.ProgramConfigChange, 0)}
+	result.Errors, result.Warnings = validateProgramSection(ca.supervisor.config, name, text)
+	if len(result.Errors) == 0 {
+		changes, err := ca.supervisor.UpdateProgramSection(name, text)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.Changes = changes
+		}
+	}
+	if len(result.Errors) > 0 {
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(writer).Encode(result)
+}
+
+// read the text of the section from the {"text": "..."} JSON body. Only the application/json body is accepted,
+// which can't be sent by a cross-site form without a CORS preflight, so a page of another site can't change the
+// configuration with the credentials of the browser
+func readSectionText(writer http.ResponseWriter, request *http.Request) (string, bool) {
+	if mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(writer, "the section should be sent as application/json", http.StatusUnsupportedMediaType)
+		return "", false
+	}
+	section := types.ProgramSection{}
+	if err := json.NewDecoder(request.Body).Decode(&section); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return section.Text, true
+}
+
 func (ca *ConfApi) getProgramConfFile(writer http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	if vars == nil {
@@ -2,13 +2,16 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-envparse"
 	"github.com/ochinchina/go-ini"
@@ -21,6 +24,8 @@ type Entry struct {
 	Group     string
 	Name      string
 	keyValues map[string]string
+	// the name of the [program:x] or [eventlistener:x] section the entry is created from
+	program string
 }
 
 // IsProgram returns true if this is a program section
@@ -49,6 +54,59 @@ func (c *Entry) GetEventListenerName() string {
 	return ""
 }
 
+// IsEventWebhook returns true if this section is for event webhook
+func (c *Entry) IsEventWebhook() bool {
+	return strings.HasPrefix(c.Name, "eventwebhook:")
+}
+
+// GetEventWebhookName returns event webhook name
+func (c *Entry) GetEventWebhookName() string {
+	if strings.HasPrefix(c.Name, "eventwebhook:") {
+		return c.Name[len("eventwebhook:"):]
+	}
+	return ""
+}
+
+// IsEventSink returns true if this section is for event sink
+func (c *Entry) IsEventSink() bool {
+	return strings.HasPrefix(c.Name, "eventsink:")
+}
+
+// GetEventSinkName returns event sink name
+func (c *Entry) GetEventSinkName() string {
+	if strings.HasPrefix(c.Name, "eventsink:") {
+		return c.Name[len("eventsink:"):]
+	}
+	return ""
+}
+
+// IsNotify returns true if this section is for crash notification
+func (c *Entry) IsNotify() bool {
+	return strings.HasPrefix(c.Name, "notify:")
+}
+
+// GetNotifyName returns crash notification name
+func (c *Entry) GetNotifyName() string {
+	if strings.HasPrefix(c.Name, "notify:") {
+		return c.Name[len("notify:"):]
+	}
+	return ""
+}
+
+// IsInetHTTPServer returns true if this section is inet_http_server or a named inet_http_server like
+// [inet_http_server:metrics]
+func (c *Entry) IsInetHTTPServer() bool {
+	return c.Name == "inet_http_server" || strings.HasPrefix(c.Name, "inet_http_server:")
+}
+
+// GetInetHTTPServerName returns the name of the named inet_http_server, empty for [inet_http_server]
+func (c *Entry) GetInetHTTPServerName() string {
+	if strings.HasPrefix(c.Name, "inet_http_server:") {
+		return c.Name[len("inet_http_server:"):]
+	}
+	return ""
+}
+
 // IsGroup returns true if it is group section
 func (c *Entry) IsGroup() bool {
 	return strings.HasPrefix(c.Name, "group:")
@@ -74,6 +132,15 @@ func (c *Entry) GetPrograms() []string {
 	return make([]string, 0)
 }
 
+// KeyValues returns a copy of the keys and values of the section
+func (c *Entry) KeyValues() map[string]string {
+	keyValues := make(map[string]string, len(c.keyValues))
+	for k, v := range c.keyValues {
+		keyValues[k] = v
+	}
+	return keyValues
+}
+
 func (c *Entry) setGroup(group string) {
 	c.Group = group
 }
@@ -98,7 +165,7 @@ type Config struct {
 
 // NewEntry creates configuration entry
 func NewEntry(configDir string) *Entry {
-	return &Entry{configDir, "", "", make(map[string]string)}
+	return &Entry{ConfigDir: configDir, keyValues: make(map[string]string)}
 }
 
 // NewConfig creates Config object
@@ -120,19 +187,85 @@ func (c *Config) createEntry(name string, configDir string) *Entry {
 //
 // Load the configuration and return loaded programs
 func (c *Config) Load() ([]string, error) {
+	return c.load(nil)
+}
+
+// load the configuration files, the contents in overrides by file name are loaded instead of the files
+func (c *Config) load(overrides map[string]string) ([]string, error) {
+	// the ini loader ignores the file which can't be read, the loaded configuration is kept
+	if c.configFile != "" {
+		f, err := os.Open(c.configFile)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+	}
 	myini := ini.NewIni()
 	c.ProgramGroup = NewProcessGroup()
-	log.WithFields(log.Fields{"file": c.configFile}).Info("load configuration from file")
-	myini.LoadFile(c.configFile)
+	// the host name and IP addresses may be changed since last load
+	RefreshHostVariables()
+	loadFile := func(f string) {
+		log.WithFields(log.Fields{"file": f}).Info("load configuration from file")
+		if content, ok := overrides[f]; ok {
+			myini.LoadString(content)
+		} else {
+			myini.LoadFile(f)
+		}
+	}
+	loadFile(c.configFile)
 
 	includeFiles := c.getIncludeFiles(myini)
+	loadedFiles := make(map[string]bool)
 	for _, f := range includeFiles {
-		log.WithFields(log.Fields{"file": f}).Info("load configuration from file")
-		myini.LoadFile(f)
+		loadFile(f)
+		loadedFiles[f] = true
+	}
+	for _, f := range c.getProgramConfFiles(myini) {
+		if loadedFiles[f] {
+			continue
+		}
+		loadFile(f)
 	}
 	return c.parse(myini), nil
 }
 
+// Reread loads the configuration file again into a new Config without changing this one, so it can be
+// compared with the loaded configuration before it is reloaded
+func (c *Config) Reread() (*Config, error) {
+	reread := NewConfig(c.configFile)
+	if _, err := reread.Load(); err != nil {
+		return nil, err
+	}
+	return reread, nil
+}
+
+// get the directory where the programs added by AddProgramSection are persisted
+func (c *Config) getProgramConfDir(dir string) string {
+	if dir == "" || filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(c.GetConfigFileDir(), dir)
+}
+
+// get the .conf files in the program_conf_dir of the supervisord section
+func (c *Config) getProgramConfFiles(cfg *ini.Ini) []string {
+	result := make([]string, 0)
+	dir, err := cfg.GetValue("supervisord", "program_conf_dir")
+	if err != nil {
+		return result
+	}
+	dir, err = NewStringExpression("here", c.GetConfigFileDir()).Eval(dir)
+	if err != nil || dir == "" {
+		return result
+	}
+	files, err := filepath.Glob(filepath.Join(c.getProgramConfDir(dir), "*.conf"))
+	if err == nil {
+		sort.Strings(files)
+		result = append(result, files...)
+	}
+	return result
+}
+
 func (c *Config) getIncludeFiles(cfg *ini.Ini) []string {
 	result := make([]string, 0)
 	if includeSection, err := cfg.GetSection("include"); err == nil {
@@ -235,6 +368,23 @@ func (c *Config) GetInetHTTPServer() (*Entry, bool) {
 	return entry, ok
 }
 
+// GetInetHTTPServers returns the inet_http_server section and the named inet_http_server sections sorted by name
+func (c *Config) GetInetHTTPServers() []*Entry {
+	result := c.GetEntries(func(entry *Entry) bool {
+		return entry.IsInetHTTPServer()
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// GetGRPCServer returns grpc_server configuration section
+func (c *Config) GetGRPCServer() (*Entry, bool) {
+	entry, ok := c.entries["grpc_server"]
+	return entry, ok
+}
+
 // GetSupervisorctl returns "supervisorctl" configuration section
 func (c *Config) GetSupervisorctl() (*Entry, bool) {
 	entry, ok := c.entries["supervisorctl"]
@@ -277,6 +427,27 @@ func (c *Config) GetEventListeners() []*Entry {
 	return eventListeners
 }
 
+// GetEventWebhooks returns configuration entries of event webhooks
+func (c *Config) GetEventWebhooks() []*Entry {
+	return c.GetEntries(func(entry *Entry) bool {
+		return entry.IsEventWebhook()
+	})
+}
+
+// GetEventSinks returns configuration entries of event sinks
+func (c *Config) GetEventSinks() []*Entry {
+	return c.GetEntries(func(entry *Entry) bool {
+		return entry.IsEventSink()
+	})
+}
+
+// GetNotifies returns configuration entries of crash notifications
+func (c *Config) GetNotifies() []*Entry {
+	return c.GetEntries(func(entry *Entry) bool {
+		return entry.IsNotify()
+	})
+}
+
 // GetProgramNames returns slice with all program names
 func (c *Config) GetProgramNames() []string {
 	result := make([]string, 0)
@@ -474,15 +645,10 @@ func (c *Entry) GetStringExpression(key string, defValue string) string {
 		return ""
 	}
 
-	hostName, err := os.Hostname()
-	if err != nil {
-		hostName = "Unknown"
-	}
 	result, err := NewStringExpression("program_name", c.GetProgramName(),
 		"process_num", c.GetString("process_num", "0"),
 		"group_name", c.GetGroupName(),
-		"here", c.ConfigDir,
-		"host_node_name", hostName).Eval(s)
+		"here", c.ConfigDir).Eval(s)
 
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -496,6 +662,18 @@ func (c *Entry) GetStringExpression(key string, defValue string) string {
 	return result
 }
 
+// GetKeysWithPrefix returns the sorted keys starting with the prefix
+func (c *Entry) GetKeysWithPrefix(prefix string) []string {
+	keys := make([]string, 0)
+	for key := range c.keyValues {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // GetStringArray gets string value and split it with "sep" to slice
 func (c *Entry) GetStringArray(key string, sep string) []string {
 	s, ok := c.keyValues[key]
@@ -532,8 +710,30 @@ func (c *Entry) GetBytes(key string, defValue int) int {
 	return defValue
 }
 
+// GetDuration returns value of the key as time duration. The value can be a go duration
+// string or an integer in seconds:
+//
+//	interval=30
+//	interval=1m30s
+//	max_runtime=2h
+func (c *Entry) GetDuration(key string, defValue time.Duration) time.Duration {
+	v, ok := c.keyValues[key]
+
+	if ok {
+		if i, err := strconv.Atoi(v); err == nil {
+			return time.Duration(i) * time.Second
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return defValue
+}
+
 func (c *Entry) parse(section *ini.Section) {
 	c.Name = section.Name
+	// the keys removed from the section since the last load are not kept
+	c.keyValues = make(map[string]string)
 	for _, key := range section.Keys() {
 		c.keyValues[key.Name()] = strings.TrimSpace(key.ValueWithDefault(""))
 	}
@@ -585,13 +785,14 @@ func (c *Config) parseProgram(cfg *ini.Ini) []string {
 				numProcs = 1
 			}
 			procName, err := section.GetValue("process_name")
-			if numProcs > 1 {
-				if err != nil || strings.Index(procName, "%(process_num)") == -1 {
-					log.WithFields(log.Fields{
-						"numprocs":     numProcs,
-						"process_name": procName,
-					}).Error("no process_num in process name")
-				}
+			if checkErr := checkProcessName(section); checkErr != nil {
+				log.WithFields(log.Fields{
+					"numprocs":     numProcs,
+					"process_name": procName,
+				}).Error("no process_num in process name")
+			}
+			if prefix == "program:" {
+				warnUnknownProgramParams(section)
 			}
 			originalProcName := programName
 			if err == nil {
@@ -636,6 +837,7 @@ func (c *Config) parseProgram(cfg *ini.Ini) []string {
 				entry := c.createEntry(procName, c.GetConfigFileDir())
 				entry.parse(section)
 				entry.Name = prefix + procName
+				entry.program = programName
 				group := c.ProgramGroup.GetGroup(programName, programName)
 				entry.Group = group
 				loadedPrograms = append(loadedPrograms, procName)
@@ -660,3 +862,171 @@ func (c *Config) RemoveProgram(programName string) {
 	delete(c.entries, programName)
 	c.ProgramGroup.Remove(programName)
 }
+
+// GetProgramSection returns the entries of the programs created from the [program:name] section
+func (c *Config) GetProgramSection(name string) []*Entry {
+	entries := c.GetEntries(func(entry *Entry) bool {
+		return entry.IsProgram() && entry.program == name
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// GetProgramSectionNames returns the sorted names of the [program:x] sections
+func (c *Config) GetProgramSectionNames() []string {
+	names := make([]string, 0)
+	found := make(map[string]bool)
+	for _, entry := range c.GetPrograms() {
+		if !found[entry.program] {
+			found[entry.program] = true
+			names = append(names, entry.program)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddProgramSection adds the [program:name] section at runtime. The content is the lines of the section
+// with or without the section header, or a JSON object of its keys and values. The parameters in the
+// program-default section are applied. If program_conf_dir is set in the supervisord section, the
+// section is written to the "<name>.conf" file in it so it is loaded again by the reload.
+//
+// Return the added program names
+func (c *Config) AddProgramSection(name string, content string) ([]string, error) {
+	if name == "" || strings.ContainsAny(name, ":[]/\\ \t\r\n") {
+		return nil, fmt.Errorf("invalid program name %q", name)
+	}
+	if len(c.GetProgramSection(name)) > 0 {
+		return nil, fmt.Errorf("program %s already exists", name)
+	}
+	text, err := toProgramSection(name, content)
+	if err != nil {
+		return nil, err
+	}
+	myini := ini.NewIni()
+	myini.LoadString(text)
+	sections := myini.Sections()
+	if len(sections) != 1 || sections[0].Name != "program:"+name {
+		return nil, fmt.Errorf("only the [program:%s] section can be added", name)
+	}
+	if !sections[0].HasKey("command") {
+		return nil, fmt.Errorf("no command in program %s", name)
+	}
+	if programDefault, ok := c.entries["program-default"]; ok {
+		for key, value := range programDefault.keyValues {
+			if !sections[0].HasKey(key) {
+				sections[0].Add(key, value)
+			}
+		}
+	}
+
+	// parse to a new configuration so nothing is changed if the program names conflict
+	added := NewConfig(c.configFile)
+	added.ProgramGroup = c.ProgramGroup.Clone()
+	programs := added.parseProgram(myini)
+	if len(programs) == 0 {
+		return nil, fmt.Errorf("no program is created from [program:%s]", name)
+	}
+	for _, program := range programs {
+		if _, ok := c.entries[program]; ok {
+			return nil, fmt.Errorf("program %s already exists", program)
+		}
+	}
+	if fileName := c.getProgramConfFile(name); fileName != "" {
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(fileName, []byte(text), 0644); err != nil {
+			return nil, err
+		}
+	}
+	for program, entry := range added.entries {
+		c.entries[program] = entry
+	}
+	c.ProgramGroup = added.ProgramGroup
+	return programs, nil
+}
+
+// RemoveProgramSection removes the programs of the [program:name] section and its "<name>.conf" file
+// in program_conf_dir written by AddProgramSection. The programs defined in the other configuration
+// files are added again by the reload.
+//
+// Return the removed program names
+func (c *Config) RemoveProgramSection(name string) ([]string, error) {
+	entries := c.GetProgramSection(name)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no program %s", name)
+	}
+	if fileName := c.getProgramConfFile(name); fileName != "" {
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	programs := make([]string, 0)
+	for _, entry := range entries {
+		programs = append(programs, entry.GetProgramName())
+		c.RemoveProgram(entry.GetProgramName())
+	}
+	// keep the program in its [group:x] section
+	if c.ProgramGroup.GetGroup(name, name) == name {
+		c.ProgramGroup.Remove(name)
+	}
+	return programs, nil
+}
+
+// get the file in program_conf_dir where the [program:name] section is persisted
+func (c *Config) getProgramConfFile(name string) string {
+	supervisord, ok := c.GetSupervisord()
+	if !ok {
+		return ""
+	}
+	dir := c.getProgramConfDir(supervisord.GetString("program_conf_dir", ""))
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name+".conf")
+}
+
+// convert the content of AddProgramSection to the [program:name] section in ini format
+func toProgramSection(name string, content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "{") {
+		if !strings.HasPrefix(content, "[") {
+			content = fmt.Sprintf("[program:%s]\n%s", name, content)
+		}
+		return content + "\n", nil
+	}
+
+	keyValues := make(map[string]interface{})
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&keyValues); err != nil {
+		return "", err
+	}
+	keys := make([]string, 0)
+	for key := range keyValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf := bytes.NewBufferString(fmt.Sprintf("[program:%s]\n", name))
+	for _, key := range keys {
+		value := ""
+		switch v := keyValues[key].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return "", fmt.Errorf("the value of %s is not a string, number or boolean", key)
+		}
+		if strings.ContainsAny(key+value, "\r\n") {
+			return "", fmt.Errorf("the key or value of %s has line break", key)
+		}
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+	}
+	return buf.String(), nil
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"supervisord/util"
+	"github.com/ochinchina/supervisord/util"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -208,3 +208,59 @@ func TestDefaultParams(t *testing.T) {
 	}
 
 }
+
+func TestAddProgramSection(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tmp")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "file1"), []byte("[supervisord]\nprogram_conf_dir=%(here)s/conf.d\n[program-default]\nstartsecs=5\n[program:cat]\ncommand=cat\n"), os.ModePerm)
+	config := NewConfig(filepath.Join(dir, "file1"))
+	config.Load()
+
+	programs, err := config.AddProgramSection("ls", "command=ls\nnumprocs=2\nprocess_name=ls_%(process_num)d")
+	if err != nil || len(programs) != 2 {
+		t.Fatalf("fail to add program section: %v", err)
+	}
+	if entry := config.GetProgram("ls_2"); entry == nil || entry.GetInt("startsecs", 0) != 5 {
+		t.Error("fail to apply program-default to the added program")
+	}
+	if _, err := config.AddProgramSection("pwd", `{"command": "pwd", "autostart": false, "priority": 3}`); err != nil {
+		t.Fatalf("fail to add program section from JSON: %v", err)
+	}
+	if entry := config.GetProgram("pwd"); entry == nil || entry.GetBool("autostart", true) || entry.GetInt("priority", 0) != 3 {
+		t.Error("fail to get the values of the program added from JSON")
+	}
+	if _, err := config.AddProgramSection("cat", "command=cat"); err == nil {
+		t.Error("the existing program should not be added")
+	}
+	if _, err := config.AddProgramSection("echo", "autostart=true"); err == nil {
+		t.Error("the program without command should not be added")
+	}
+
+	// the added programs are persisted in program_conf_dir
+	reloaded := NewConfig(filepath.Join(dir, "file1"))
+	reloaded.Load()
+	if len(reloaded.GetProgramSection("ls")) != 2 || reloaded.GetProgram("pwd") == nil {
+		t.Error("fail to load the added programs from program_conf_dir")
+	}
+
+	programs, err = config.RemoveProgramSection("ls")
+	if err != nil || len(programs) != 2 || config.GetProgram("ls_1") != nil {
+		t.Errorf("fail to remove program section: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "conf.d", "ls.conf")); !os.IsNotExist(err) {
+		t.Error("the removed program should be removed from program_conf_dir")
+	}
+}
+
+func TestNamedInetHTTPServers(t *testing.T) {
+	s := "[inet_http_server]\nport=:9001\n[inet_http_server:metrics]\nport=:9100\nendpoints=metrics\n[program:test]\ncommand=/bin/ls"
+	config, _ := parse([]byte(s))
+	servers := config.GetInetHTTPServers()
+	if len(servers) != 2 || servers[0].GetInetHTTPServerName() != "" || servers[1].GetInetHTTPServerName() != "metrics" {
+		t.Fatalf("fail to get the inet_http_server sections: %v", servers)
+	}
+	if servers[1].GetString("port", "") != ":9100" {
+		t.Error("fail to get the port of inet_http_server:metrics")
+	}
+}
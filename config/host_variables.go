@@ -0,0 +1,128 @@
+package config
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the host derived variables used in the string expression:
+//
+//	host_node_name - the host name
+//	host_ip - the first non-loopback IP address of the host
+//	host_ip_<interface> - the IP address of the network interface, like host_ip_eth0
+//
+// They are resolved when the configuration is loaded or reloaded, and periodically
+// if the refresh interval is set
+var hostVariables = struct {
+	sync.RWMutex
+	values map[string]string
+	// closed to stop the periodical refresh
+	stopRefresh chan struct{}
+}{}
+
+// RefreshHostVariables resolves the host name and IP addresses again
+func RefreshHostVariables() {
+	values := make(map[string]string)
+	if hostname, err := os.Hostname(); err == nil {
+		values["host_node_name"] = hostname
+	}
+	for name, value := range resolveHostIPs() {
+		values[name] = value
+	}
+
+	hostVariables.Lock()
+	defer hostVariables.Unlock()
+	hostVariables.values = values
+}
+
+// SetHostRefreshInterval refreshes the host variables periodically in the interval, the
+// periodical refresh is disabled if interval is not positive
+func SetHostRefreshInterval(interval time.Duration) {
+	hostVariables.Lock()
+	defer hostVariables.Unlock()
+
+	if hostVariables.stopRefresh != nil {
+		close(hostVariables.stopRefresh)
+		hostVariables.stopRefresh = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	hostVariables.stopRefresh = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				RefreshHostVariables()
+			}
+		}
+	}()
+}
+
+// get the resolved host variables, they are resolved at the first call
+func getHostVariables() map[string]string {
+	hostVariables.RLock()
+	values := hostVariables.values
+	hostVariables.RUnlock()
+	if values == nil {
+		RefreshHostVariables()
+		hostVariables.RLock()
+		values = hostVariables.values
+		hostVariables.RUnlock()
+	}
+	return values
+}
+
+// get the IP address of each up interface as host_ip_<interface>, and the first
+// non-loopback one as host_ip. IPv4 address is preferred if the interface has both
+func resolveHostIPs() map[string]string {
+	result := make(map[string]string)
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		log.WithFields(log.Fields{log.ErrorKey: err}).Warn("fail to get the network interfaces")
+		return result
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		ip := ""
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				ip = ipNet.IP.String()
+				break
+			}
+			if ip == "" {
+				ip = ipNet.IP.String()
+			}
+		}
+		if ip == "" {
+			continue
+		}
+		result["host_ip_"+iface.Name] = ip
+		if _, ok := result["host_ip"]; !ok && iface.Flags&net.FlagLoopback == 0 {
+			result["host_ip"] = ip
+		}
+	}
+	if _, ok := result["host_ip"]; !ok {
+		result["host_ip"] = "127.0.0.1"
+	}
+	return result
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ochinchina/go-ini"
+	log "github.com/sirupsen/logrus"
+)
+
+// ParamKind the kind of the value of a program parameter
+type ParamKind int
+
+const (
+	// StringParam any string
+	StringParam ParamKind = iota
+	// IntParam an integer
+	IntParam
+	// BoolParam true or false
+	BoolParam
+	// BytesParam a size like "512MB", "1GB" or "1024"
+	BytesParam
+	// DurationParam seconds or go duration like "1m30s"
+	DurationParam
+	// SignalsParam the space separated signal names like "SIGTERM SIGKILL"
+	SignalsParam
+	// ExitCodesParam the comma separated exit codes like "0,2"
+	ExitCodesParam
+	// AutorestartParam true, false or unexpected
+	AutorestartParam
+)
+
+// ProgramParams the parameters of the [program:x] section and the kinds of their values. The parameters not
+// in it are warned when the configuration is loaded
+var ProgramParams = map[string]ParamKind{
+	"autorestart":                        AutorestartParam,
+	"autostart":                          BoolParam,
+	"command":                            StringParam,
+	"conf_file":                          StringParam,
+	"copy_env":                           BoolParam,
+	"cpu_threshold":                      IntParam,
+	"cron":                               StringParam,
+	"depends_on":                         StringParam,
+	"directory":                          StringParam,
+	"envFiles":                           StringParam,
+	"environment":                        StringParam,
+	"exitcodes":                          ExitCodesParam,
+	"healthcheck_cmd":                    StringParam,
+	"healthcheck_interval":               DurationParam,
+	"healthcheck_restart":                BoolParam,
+	"healthcheck_retries":                IntParam,
+	"healthcheck_tcp":                    StringParam,
+	"healthcheck_timeout":                DurationParam,
+	"healthcheck_url":                    StringParam,
+	"killasgroup":                        BoolParam,
+	"killwaitsecs":                       IntParam,
+	"labels":                             StringParam,
+	"logfile_quota":                      BytesParam,
+	"max_memory":                         BytesParam,
+	"max_memory_interval":                DurationParam,
+	"max_memory_samples":                 IntParam,
+	"max_runtime":                        DurationParam,
+	"memory_threshold":                   BytesParam,
+	"numprocs":                           IntParam,
+	"numprocs_start":                     IntParam,
+	"priority":                           IntParam,
+	"process_name":                       StringParam,
+	"ready_interval":                     DurationParam,
+	"ready_log_pattern":                  StringParam,
+	"ready_tcp":                          StringParam,
+	"ready_timeout":                      DurationParam,
+	"ready_url":                          StringParam,
+	"redirect_stderr":                    BoolParam,
+	"reload_signal":                      SignalsParam,
+	"restart_cmd_when_binary_changed":    StringParam,
+	"restart_cmd_when_file_changed":      StringParam,
+	"restart_cron":                       StringParam,
+	"restart_directory_monitor":          StringParam,
+	"restart_file_pattern":               StringParam,
+	"restart_signal_when_binary_changed": SignalsParam,
+	"restart_signal_when_file_changed":   SignalsParam,
+	"restart_when_binary_changed":        BoolParam,
+	"restartpause":                       IntParam,
+	"shared_stdout":                      BoolParam,
+	"shared_stdout_color":                BoolParam,
+	"standby_for":                        StringParam,
+	"startretries":                       IntParam,
+	"startsecs":                          IntParam,
+	"stderr_capture_maxbytes":            BytesParam,
+	"stderr_events_enabled":              BoolParam,
+	"stderr_logfile":                     StringParam,
+	"stderr_logfile_backups":             IntParam,
+	"stderr_logfile_maxbytes":            BytesParam,
+	"stderr_logfile_prefix_timestamp":    BoolParam,
+	"stderr_ringbuffer_maxbytes":         BytesParam,
+	"stdout_capture_maxbytes":            BytesParam,
+	"stdout_events_enabled":              BoolParam,
+	"stdout_logfile":                     StringParam,
+	"stdout_logfile_backups":             IntParam,
+	"stdout_logfile_maxbytes":            BytesParam,
+	"stdout_logfile_prefix_timestamp":    BoolParam,
+	"stdout_ringbuffer_maxbytes":         BytesParam,
+	"stopasgroup":                        BoolParam,
+	"stopsignal":                         SignalsParam,
+	"stopwaitsecs":                       IntParam,
+	"strip_ansi":                         BoolParam,
+	"syslog_facility":                    StringParam,
+	"syslog_stderr_priority":             StringParam,
+	"syslog_stdout_priority":             StringParam,
+	"syslog_tag":                         StringParam,
+	"user":                               StringParam,
+	"watch_files":                        StringParam,
+	"watch_files_delay":                  DurationParam,
+}
+
+// the prefixes of the program parameters named by the user, like "stdout_log_route_errors"
+var programParamPrefixes = []string{"stdout_log_route_", "stderr_log_route_"}
+
+// IsProgramParam checks if the key is a parameter of the [program:x] section
+func IsProgramParam(key string) bool {
+	if _, ok := ProgramParams[key]; ok {
+		return true
+	}
+	for _, prefix := range programParamPrefixes {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// warn the parameters of the [program:x] section which are not program parameters
+func warnUnknownProgramParams(section *ini.Section) {
+	for _, key := range section.Keys() {
+		if !IsProgramParam(key.Name()) {
+			log.WithFields(log.Fields{"section": section.Name, "key": key.Name()}).Warn("unknown parameter")
+		}
+	}
+}
+
+// check if the process_name of the [program:x] or [eventlistener:x] section has %(process_num) when there
+// are more than one processes
+func checkProcessName(section *ini.Section) error {
+	numProcs, err := section.GetInt("numprocs")
+	if err != nil || numProcs <= 1 {
+		return nil
+	}
+	if procName, err := section.GetValue("process_name"); err != nil || !strings.Contains(procName, "%(process_num)") {
+		return fmt.Errorf("process_name should have %%(process_num) if numprocs is more than 1")
+	}
+	return nil
+}
+
+// ParseProgramSection parses the text of the [program:name] section like it is loaded with the configuration,
+// the parameters of the program-default section are applied. The loaded configuration is not changed.
+//
+// Return the entries of the programs created from the section, or the error if the section can't be loaded
+// like it has no %(process_num) in the process_name of more than one processes, the expressions in the
+// command can't be evaluated or the programs conflict with the programs of the other sections
+func (c *Config) ParseProgramSection(name string, text string) ([]*Entry, error) {
+	myini := ini.NewIni()
+	myini.LoadString(text)
+	sections := myini.Sections()
+	if len(sections) != 1 || sections[0].Name != "program:"+name {
+		return nil, fmt.Errorf("the text should be only the [program:%s] section", name)
+	}
+	section := sections[0]
+	if err := checkProcessName(section); err != nil {
+		return nil, err
+	}
+	if programDefault, ok := c.entries["program-default"]; ok {
+		for key, value := range programDefault.keyValues {
+			if !section.HasKey(key) {
+				section.Add(key, value)
+			}
+		}
+	}
+	numProcs, err := section.GetInt("numprocs")
+	if err != nil {
+		numProcs = 1
+	}
+
+	parsed := NewConfig(c.configFile)
+	parsed.ProgramGroup = c.ProgramGroup.Clone()
+	if programs := parsed.parseProgram(myini); numProcs > 0 && len(programs) != numProcs {
+		return nil, fmt.Errorf("fail to evaluate the command or process_name of [program:%s]", name)
+	}
+	return c.getReloadedProgramSection(parsed, name)
+}
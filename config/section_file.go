@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochinchina/go-ini"
+)
+
+// NewSectionEntry creates the configuration entry of the section as it is, without the parameters of the
+// program-default section and the ones set by the parser for every process of the program
+func NewSectionEntry(configDir string, section *ini.Section) *Entry {
+	entry := NewEntry(configDir)
+	entry.parse(section)
+	return entry
+}
+
+// get the configuration files in the order they are loaded by Load
+func (c *Config) getConfigFiles() []string {
+	myini := ini.NewIni()
+	myini.LoadFile(c.configFile)
+	files := []string{c.configFile}
+	loadedFiles := map[string]bool{c.configFile: true}
+	for _, f := range c.getIncludeFiles(myini) {
+		myini.LoadFile(f)
+		files = append(files, f)
+		loadedFiles[f] = true
+	}
+	for _, f := range c.getProgramConfFiles(myini) {
+		if !loadedFiles[f] {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// ProgramSectionFile returns the configuration file where the [program:name] section is defined, the
+// last one if it is in more than one file. The section is editable only if it is in an included file or
+// in program_conf_dir, the main configuration file is never written
+func (c *Config) ProgramSectionFile(name string) (fileName string, editable bool, err error) {
+	for _, f := range c.getConfigFiles() {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		if start, _ := findSection(strings.Split(string(b), "\n"), "program:"+name); start >= 0 {
+			fileName = f
+		}
+	}
+	if fileName == "" {
+		return "", false, fmt.Errorf("no [program:%s] section in the configuration files", name)
+	}
+	return fileName, fileName != c.configFile, nil
+}
+
+// ReadProgramSection returns the configuration file of the [program:name] section and the text of the
+// section in it, from the section header to the next section
+func (c *Config) ReadProgramSection(name string) (fileName string, text string, err error) {
+	fileName, _, err = c.ProgramSectionFile(name)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(string(b), "\n")
+	start, end := findSection(lines, "program:"+name)
+	return fileName, strings.Join(lines[start:end], "\n") + "\n", nil
+}
+
+// WriteProgramSection replaces the [program:name] section in its configuration file with the text, the
+// other sections and the comments before the next section are kept. The file is replaced at once so it is
+// never loaded half written
+func (c *Config) WriteProgramSection(name string, text string) error {
+	fileName, content, err := c.replaceProgramSection(name, text)
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(fileName, content)
+}
+
+// get the configuration file of the [program:name] section and its content with the section replaced by
+// the text
+func (c *Config) replaceProgramSection(name string, text string) (fileName string, content string, err error) {
+	fileName, editable, err := c.ProgramSectionFile(name)
+	if err != nil {
+		return "", "", err
+	}
+	if !editable {
+		return "", "", fmt.Errorf("the [program:%s] section is in the main configuration file %s", name, fileName)
+	}
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(string(b), "\n")
+	start, end := findSection(lines, "program:"+name)
+	section := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	result := append(append(append(make([]string, 0), lines[:start]...), section...), lines[end:]...)
+	return fileName, strings.Join(result, "\n"), nil
+}
+
+// replace the file with the content by renaming a temporary file to it, the mode of the file is kept
+func writeConfigFile(fileName string, content string) error {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFile.Name(), info.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), fileName)
+}
+
+// UpdateProgramSection replaces the [program:name] section in its configuration file with the text and
+// reloads the programs of the section like ReloadProgramSection. The configuration with the new section is
+// parsed before the file is written, and the file is restored if the section fails to be reloaded from it.
+//
+// Return the program names of the reloaded section
+func (c *Config) UpdateProgramSection(name string, text string) ([]string, error) {
+	fileName, content, err := c.replaceProgramSection(name, text)
+	if err != nil {
+		return nil, err
+	}
+	oldContent, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	parsed := NewConfig(c.configFile)
+	if _, err = parsed.load(map[string]string{fileName: content}); err != nil {
+		return nil, err
+	}
+	if _, err = c.getReloadedProgramSection(parsed, name); err != nil {
+		return nil, err
+	}
+	if err = writeConfigFile(fileName, content); err != nil {
+		return nil, err
+	}
+	programs, err := c.ReloadProgramSection(name)
+	if err != nil {
+		if restoreErr := writeConfigFile(fileName, string(oldContent)); restoreErr != nil {
+			return nil, fmt.Errorf("%v, and fail to restore %s: %v", err, fileName, restoreErr)
+		}
+		return nil, err
+	}
+	return programs, nil
+}
+
+// get the lines [start, end) of the section, start is -1 if there is no such section. The blank and comment
+// lines before the next section are not in the section
+func findSection(lines []string, sectionName string) (start int, end int) {
+	start = -1
+	for i, line := range lines {
+		if name := parseSectionHeader(line); name == sectionName {
+			start = i
+		} else if start >= 0 && name != "" {
+			end = i
+			break
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	if end <= start {
+		end = len(lines)
+	}
+	for end > start+1 {
+		line := strings.TrimSpace(lines[end-1])
+		if line != "" && line[0] != ';' && line[0] != '#' {
+			break
+		}
+		end--
+	}
+	return start, end
+}
+
+// get the section name of the "[name]" line, or empty string if the line is not a section header
+func parseSectionHeader(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		return strings.TrimSpace(line[1 : len(line)-1])
+	}
+	return ""
+}
+
+// ReloadProgramSection loads the configuration files again and replaces the programs of the [program:name]
+// section with the reloaded ones, the other programs and sections are not changed.
+//
+// Return the program names of the reloaded section
+func (c *Config) ReloadProgramSection(name string) ([]string, error) {
+	reread, err := c.Reread()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.getReloadedProgramSection(reread, name)
+	if err != nil {
+		return nil, err
+	}
+	programs := make([]string, 0)
+	for _, entry := range entries {
+		programs = append(programs, entry.GetProgramName())
+	}
+	for _, entry := range c.GetProgramSection(name) {
+		delete(c.entries, entry.GetProgramName())
+	}
+	for _, entry := range entries {
+		c.entries[entry.GetProgramName()] = entry
+	}
+	return programs, nil
+}
+
+// get the programs of the [program:name] section in the reloaded configuration, which must not have the same
+// names as the programs of the other sections
+func (c *Config) getReloadedProgramSection(reloaded *Config, name string) ([]*Entry, error) {
+	entries := reloaded.GetProgramSection(name)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no program is created from [program:%s]", name)
+	}
+	for _, entry := range entries {
+		if existing, ok := c.entries[entry.GetProgramName()]; ok && existing.program != name {
+			return nil, fmt.Errorf("program %s already exists", entry.GetProgramName())
+		}
+	}
+	return entries, nil
+}
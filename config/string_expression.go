@@ -25,9 +25,8 @@ func NewStringExpression(envs ...string) *StringExpression {
 		se.env[envs[i]] = envs[i+1]
 	}
 
-	hostname, err := os.Hostname()
-	if err == nil {
-		se.env["host_node_name"] = hostname
+	for name, value := range getHostVariables() {
+		se.env[name] = value
 	}
 
 	return se
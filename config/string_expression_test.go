@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net"
 	"os"
 	"testing"
 )
@@ -28,3 +29,13 @@ func TestEnv(t *testing.T) {
 		t.Errorf("fail to replace the environment: %s", r)
 	}
 }
+
+func TestHostIP(t *testing.T) {
+	se := NewStringExpression()
+
+	r, err := se.Eval("%(host_ip)s")
+
+	if err != nil || net.ParseIP(r) == nil {
+		t.Errorf("fail to replace the host ip: %s, %v", r, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/types"
+)
+
+// compare the programs of the loaded configuration with the reread ones, the changes are sorted by the
+// program name and the keys of every change by the key. The group of the program is compared as the
+// "group" key
+func diffProgramConfigs(current []*config.Entry, reread []*config.Entry) []types.ProgramConfigChange {
+	currentPrograms := programKeyValues(current)
+	rereadPrograms := programKeyValues(reread)
+	names := make([]string, 0)
+	for name := range currentPrograms {
+		names = append(names, name)
+	}
+	for name := range rereadPrograms {
+		if _, ok := currentPrograms[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]types.ProgramConfigChange, 0)
+	for _, name := range names {
+		old, inCurrent := currentPrograms[name]
+		new, inReread := rereadPrograms[name]
+		change := types.ProgramConfigChange{Name: name, Change: "changed"}
+		if !inCurrent {
+			change.Change = "added"
+		} else if !inReread {
+			change.Change = "removed"
+		}
+		change.Keys = diffKeyValues(old, new)
+		if len(change.Keys) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// the keys set by the configuration parser for every process of the program, the process name is the name
+// of the program in the diff
+var generatedProgramKeys = []string{"process_name", "process_num", "numprocs_start"}
+
+// get the keys and values of the programs by the program name
+func programKeyValues(entries []*config.Entry) map[string]map[string]string {
+	programs := make(map[string]map[string]string)
+	for _, entry := range entries {
+		keyValues := entry.KeyValues()
+		for _, key := range generatedProgramKeys {
+			delete(keyValues, key)
+		}
+		if entry.Group != entry.GetProgramName() {
+			keyValues["group"] = entry.Group
+		}
+		programs[entry.GetProgramName()] = keyValues
+	}
+	return programs
+}
+
+// get the keys added, changed and removed from old to new sorted by the key
+func diffKeyValues(old map[string]string, new map[string]string) []types.ConfigKeyChange {
+	keys := make([]string, 0)
+	for key, value := range old {
+		if newValue, ok := new[key]; !ok || newValue != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	changes := make([]types.ConfigKeyChange, 0, len(keys))
+	for _, key := range keys {
+		changes = append(changes, types.ConfigKeyChange{Key: key, Old: old[key], New: new[key]})
+	}
+	return changes
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/types"
+)
+
+// load the programs of the configuration
+func loadTestPrograms(t *testing.T, conf string) []*config.Entry {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supervisord.conf")
+	if err := ioutil.WriteFile(confFile, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	myconfig := config.NewConfig(confFile)
+	if _, err := myconfig.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return myconfig.GetPrograms()
+}
+
+func TestDiffProgramConfigs(t *testing.T) {
+	current := loadTestPrograms(t, `
+[program:web]
+command=python app.py
+autostart=true
+
+[program:old]
+command=sleep 10
+`)
+	reread := loadTestPrograms(t, `
+[program:web]
+command=python app.py --workers 4
+startsecs=3
+
+[program:new]
+command=sleep 20
+
+[group:api]
+programs=web
+`)
+	expected := []types.ProgramConfigChange{
+		{Name: "new", Change: "added", Keys: []types.ConfigKeyChange{{Key: "command", New: "sleep 20"}}},
+		{Name: "old", Change: "removed", Keys: []types.ConfigKeyChange{{Key: "command", Old: "sleep 10"}}},
+		{Name: "web", Change: "changed", Keys: []types.ConfigKeyChange{
+			{Key: "autostart", Old: "true"},
+			{Key: "command", Old: "python app.py", New: "python app.py --workers 4"},
+			{Key: "group", New: "api"},
+			{Key: "startsecs", New: "3"},
+		}},
+	}
+	if changes := diffProgramConfigs(current, reread); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("the changes should be %v, but they are %v", expected, changes)
+	}
+	if changes := diffProgramConfigs(current, current); len(changes) != 0 {
+		t.Errorf("no change is expected, but they are %v", changes)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	ini "github.com/ochinchina/go-ini"
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/signals"
+)
+
+// validate the text of the [program:name] section. The section is parsed like it is loaded with the configuration
+// c, and the values are checked by the kinds of the program parameters. The errors are the problems which make
+// the section rejected, like the invalid values, and the warnings are the parameters unknown to the configuration
+func validateProgramSection(c *config.Config, name string, text string) (errors []string, warnings []string) {
+	errors = make([]string, 0)
+	warnings = make([]string, 0)
+	myini := ini.NewIni()
+	myini.LoadString(text)
+	sections := myini.Sections()
+	if len(sections) != 1 || sections[0].Name != "program:"+name {
+		return append(errors, fmt.Sprintf("the text should be only the [program:%s] section", name)), warnings
+	}
+	section := sections[0]
+	if !section.HasKey("command") {
+		errors = append(errors, "command is required")
+	}
+	entry := config.NewSectionEntry("", section)
+	keys := make([]string, 0)
+	for _, key := range section.Keys() {
+		keys = append(keys, key.Name())
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !config.IsProgramParam(key) {
+			warnings = append(warnings, fmt.Sprintf("unknown parameter %s", key))
+			continue
+		}
+		if err := validateProgramParam(entry, key, config.ProgramParams[key]); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid %s: %v", key, err))
+		}
+	}
+	if _, err := c.ParseProgramSection(name, text); err != nil {
+		errors = append(errors, err.Error())
+	}
+	return errors, warnings
+}
+
+// check the value of the parameter by its kind. The value is invalid if the getter of the entry falls back
+// to the default value, which is told by getting it with two different defaults
+func validateProgramParam(entry *config.Entry, key string, kind config.ParamKind) error {
+	value := entry.GetString(key, "")
+	switch kind {
+	case config.IntParam:
+		if entry.GetInt(key, 0) != entry.GetInt(key, 1) {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case config.BoolParam:
+		if entry.GetBool(key, false) != entry.GetBool(key, true) {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	case config.BytesParam:
+		if entry.GetBytes(key, 0) != entry.GetBytes(key, 1) {
+			return fmt.Errorf("%q is not a size like 512MB", value)
+		}
+	case config.DurationParam:
+		if entry.GetDuration(key, 0) != entry.GetDuration(key, 1) {
+			return fmt.Errorf("%q is not seconds or a duration like 1m30s", value)
+		}
+	case config.SignalsParam:
+		for _, name := range strings.Fields(value) {
+			// the unknown signal is converted to SIGTERM
+			if sig, _ := signals.ToSignal(name); sig == syscall.SIGTERM && strings.TrimPrefix(name, "SIG") != "TERM" {
+				return fmt.Errorf("unknown signal %s", name)
+			}
+		}
+	case config.ExitCodesParam:
+		for _, code := range strings.Split(value, ",") {
+			if _, err := strconv.Atoi(code); err != nil {
+				return fmt.Errorf("%q is not an exit code", code)
+			}
+		}
+	case config.AutorestartParam:
+		if value != "true" && value != "false" && value != "unexpected" {
+			return fmt.Errorf("%q is not true, false or unexpected", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ochinchina/supervisord/config"
+)
+
+func TestValidateProgramSection(t *testing.T) {
+	myconfig := config.NewConfig("")
+	errors, warnings := validateProgramSection(myconfig, "web", `[program:web]
+command=python app.py
+autostart=true
+autorestart=unexpected
+startsecs=5
+stopsignal=INT KILL
+exitcodes=0,2
+stdout_logfile_maxbytes=50MB
+max_runtime=1h30m
+stdout_log_route_errors=ERROR
+`)
+	if len(errors) != 0 || len(warnings) != 0 {
+		t.Errorf("Expect the section valid, but get errors %v and warnings %v", errors, warnings)
+	}
+
+	errors, warnings = validateProgramSection(myconfig, "web", `[program:web]
+autostart=yes
+autorestart=always
+startsecs=five
+stopsignal=SIGTEM
+exitcodes=0, 2
+stdout_logfile_maxbytes=50M
+max_runtime=1 hour
+numprocs=2
+auto_start=true
+`)
+	expected := []string{
+		"command is required",
+		`invalid autorestart: "always" is not true, false or unexpected`,
+		`invalid autostart: "yes" is not true or false`,
+		`invalid exitcodes: " 2" is not an exit code`,
+		`invalid max_runtime: "1 hour" is not seconds or a duration like 1m30s`,
+		`invalid startsecs: "five" is not an integer`,
+		`invalid stdout_logfile_maxbytes: "50M" is not a size like 512MB`,
+		"invalid stopsignal: unknown signal SIGTEM",
+		"process_name should have %(process_num) if numprocs is more than 1",
+	}
+	if !reflect.DeepEqual(errors, expected) {
+		t.Errorf("Expect errors %v, but get %v", expected, errors)
+	}
+	if !reflect.DeepEqual(warnings, []string{"unknown parameter auto_start"}) {
+		t.Errorf("Expect the unknown parameter warned, but get %v", warnings)
+	}
+
+	if errors, _ := validateProgramSection(myconfig, "web", "[program:web]\ncommand=%(ENV_NO_SUCH_VAR)s\n"); len(errors) != 1 {
+		t.Errorf("Expect the command which can't be evaluated rejected, but get %v", errors)
+	}
+
+	for _, text := range []string{"[program:api]\ncommand=ls\n", "[program:web]\ncommand=ls\n[program:api]\ncommand=ls\n", "command=ls\n"} {
+		if errors, _ := validateProgramSection(myconfig, "web", text); len(errors) != 1 || !strings.Contains(errors[0], "[program:web]") {
+			t.Errorf("Expect only the [program:web] section accepted, but get %v for %q", errors, text)
+		}
+	}
+}
+
+// the keys read from the program entries by the process package should all be program parameters
+func TestProgramParamsCoverProcess(t *testing.T) {
+	files, _ := filepath.Glob("process/*.go")
+	getter := regexp.MustCompile(`\.Get(?:String|Int|Bool|Bytes|Duration|StringExpression|StringArray)\("([A-Za-z_]+)"`)
+	// the parameters of the [eventlistener:x] section
+	eventListenerParams := map[string]bool{"buffer_size": true, "events": true, "filter": true}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range getter.FindAllStringSubmatch(string(b), -1) {
+			if !config.IsProgramParam(m[1]) && !eventListenerParams[m[1]] {
+				t.Errorf("the parameter %s read in %s is not a program parameter", m[1], file)
+			}
+		}
+	}
+}
+
+func TestWriteProgramSection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "section")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supervisord.conf")
+	includeFile := filepath.Join(dir, "programs.conf")
+	ioutil.WriteFile(confFile, []byte("[include]\nfiles=programs.conf\n\n[program:main]\ncommand=ls\n"), 0644)
+	ioutil.WriteFile(includeFile, []byte("[program:web]\ncommand=python app.py\n\n; the api server\n[program:api]\ncommand=./api\n"), 0600)
+	myconfig := config.NewConfig(confFile)
+	if _, err := myconfig.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fileName, editable, err := myconfig.ProgramSectionFile("main"); err != nil || fileName != confFile || editable {
+		t.Errorf("Expect the section in the main file read only, but get %s %v %v", fileName, editable, err)
+	}
+	if err := myconfig.WriteProgramSection("main", "[program:main]\ncommand=pwd\n"); err == nil {
+		t.Error("Expect the main file not written")
+	}
+	if fileName, text, err := myconfig.ReadProgramSection("web"); err != nil || fileName != includeFile || text != "[program:web]\ncommand=python app.py\n" {
+		t.Errorf("Expect the web section read from the included file, but get %s %q %v", fileName, text, err)
+	}
+
+	if err := myconfig.WriteProgramSection("web", "[program:web]\ncommand=python app.py --port 8080\nnumprocs=2\nprocess_name=web_%(process_num)d\n"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(includeFile)
+	expected := "[program:web]\ncommand=python app.py --port 8080\nnumprocs=2\nprocess_name=web_%(process_num)d\n\n; the api server\n[program:api]\ncommand=./api\n"
+	if string(b) != expected {
+		t.Errorf("Expect the other sections kept, but get %q", string(b))
+	}
+	if info, _ := os.Stat(includeFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expect the file mode kept, but get %v", info.Mode())
+	}
+
+	programs, err := myconfig.ReloadProgramSection("web")
+	if err != nil || !reflect.DeepEqual(programs, []string{"web_1", "web_2"}) {
+		t.Errorf("Expect the web section reloaded, but get %v %v", programs, err)
+	}
+	if myconfig.GetProgram("web") != nil || myconfig.GetProgram("web_1").GetString("command", "") != "python app.py --port 8080" {
+		t.Error("Expect the programs of the web section replaced")
+	}
+	if !reflect.DeepEqual(myconfig.GetProgramSectionNames(), []string{"api", "main", "web"}) {
+		t.Errorf("Expect the other sections kept, but get %v", myconfig.GetProgramSectionNames())
+	}
+
+	b, _ = ioutil.ReadFile(includeFile)
+	if _, err := myconfig.UpdateProgramSection("web", "[program:web]\ncommand=./api\nprocess_name=api\n"); err == nil {
+		t.Error("Expect the section conflicting with the other programs rejected")
+	}
+	if after, _ := ioutil.ReadFile(includeFile); string(after) != string(b) {
+		t.Errorf("Expect the file not written for the rejected section, but get %q", string(after))
+	}
+	programs, err = myconfig.UpdateProgramSection("web", "[program:web]\ncommand=python app.py\n")
+	if err != nil || !reflect.DeepEqual(programs, []string{"web"}) || myconfig.GetProgram("web_1") != nil {
+		t.Errorf("Expect the web section updated, but get %v %v", programs, err)
+	}
+}
+
+func TestSaveProgramSectionContentType(t *testing.T) {
+	handler := NewConfApi(NewSupervisor("")).CreateHandler()
+	for contentType, expected := range map[string]int{
+		"text/plain":                        http.StatusUnsupportedMediaType,
+		"application/x-www-form-urlencoded": http.StatusUnsupportedMediaType,
+		"":                                  http.StatusUnsupportedMediaType,
+		"application/json; charset=utf-8":   http.StatusOK,
+	} {
+		r := httptest.NewRequest("POST", "/conf/section/web/validate", strings.NewReader(`{"text": "[program:web]\ncommand=ls\n"}`))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("Expect %d for the content type %q, but get %d", expected, contentType, w.Code)
+		}
+	}
+}
@@ -113,7 +113,7 @@ events=PROCESS_STATE
 serverurl = unix:///tmp/supervisor.sock
 username = chris
 password = 123
-#prompt = not support
+#prompt = mysupervisor
 `
 
 // InitTemplateCommand implements flags.Commander interface
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// corsHandler allows the browser based dashboards on the allowed origins to call the http API
+type corsHandler struct {
+	// the origins like https://dashboard.example.com, may be a pattern like https://*.example.com or "*" for all
+	origins []string
+	handler http.Handler
+}
+
+// wrap the handler to answer the CORS requests from the comma separated allowedOrigins, the handler is
+// returned as it is if no origin is allowed
+func newCORSHandler(allowedOrigins string, handler http.Handler) http.Handler {
+	origins := strings.FieldsFunc(allowedOrigins, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+	if len(origins) == 0 {
+		return handler
+	}
+	return &corsHandler{origins: origins, handler: handler}
+}
+
+// check if the origin is allowed, and if it is allowed only by "*" which allows any origin without credentials
+func (c *corsHandler) allowed(origin string) (allowed bool, anyOrigin bool) {
+	for _, pattern := range c.origins {
+		if pattern == "*" {
+			anyOrigin = true
+			continue
+		}
+		if strings.EqualFold(pattern, origin) {
+			return true, false
+		}
+		if matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(origin)); err == nil && matched {
+			return true, false
+		}
+	}
+	return anyOrigin, anyOrigin
+}
+
+func (c *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	allowed, anyOrigin := c.allowed(origin)
+	if origin == "" || !allowed {
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+	if anyOrigin {
+		// the browser doesn't send the credentials to any website, only to the listed origins
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	// the preflight request is answered without authentication
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		headers := r.Header.Get("Access-Control-Request-Headers")
+		if headers == "" {
+			headers = "Authorization, Content-Type"
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Access-Control-Expose-Headers", "Retry-After, WWW-Authenticate")
+	c.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSHandler(t *testing.T) {
+	handler := newCORSHandler("https://dashboard.example.com, https://*.internal.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	request := func(method string, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1/processes", nil)
+		r.Header.Set("Origin", origin)
+		if method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request("OPTIONS", "https://ops.internal.example.com"); w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://ops.internal.example.com" {
+		t.Errorf("the preflight request from the allowed origin should be answered, but got %d", w.Code)
+	}
+	if w := request("GET", "https://dashboard.example.com"); w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") != "https://dashboard.example.com" {
+		t.Error("the request from the allowed origin should be handled with the CORS headers")
+	}
+	if w := request("OPTIONS", "https://evil.example.com"); w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("the request from the other origin should not get the CORS headers")
+	}
+
+	handler = newCORSHandler("*, https://dashboard.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if w := request("GET", "https://evil.example.com"); w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("any origin should be allowed by * without credentials")
+	}
+	if w := request("GET", "https://dashboard.example.com"); w.Header().Get("Access-Control-Allow-Origin") != "https://dashboard.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("the listed origin should be allowed with credentials")
+	}
+}
@@ -1,31 +1,58 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
-	"net/http"
+	"math"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/process"
 	"github.com/ochinchina/supervisord/types"
 	"github.com/ochinchina/supervisord/xmlrpcclient"
+	"golang.org/x/term"
 )
 
 // CtlCommand the entry of ctl command
 type CtlCommand struct {
-	ServerURL string `short:"s" long:"serverurl" description:"URL on which supervisord server is listening"`
-	User      string `short:"u" long:"user" description:"the user name"`
-	Password  string `short:"P" long:"password" description:"the password"`
-	Verbose   bool   `short:"v" long:"verbose" description:"Show verbose debug information"`
+	ServerURL    []string      `short:"s" long:"serverurl" description:"URL on which supervisord server is listening, repeat it to run the command against multiple servers"`
+	ServersFile  string        `long:"servers-file" description:"the file of the server URLs one per line to run the command against"`
+	User         string        `short:"u" long:"user" description:"the user name"`
+	Password     string        `short:"P" long:"password" description:"the password"`
+	Token        string        `short:"t" long:"token" env:"SUPERVISOR_TOKEN" description:"the bearer token used instead of the user name and password"`
+	CAFile       string        `long:"cafile" description:"the CA certificate file to verify the https server"`
+	CertFile     string        `long:"certfile" description:"the client certificate file for the https server requiring it"`
+	KeyFile      string        `long:"keyfile" description:"the private key file of the client certificate"`
+	Profile      string        `long:"profile" env:"SUPERVISOR_PROFILE" description:"the connection profile in ~/.supervisord/ctl.toml whose settings are used if the options are not given"`
+	ProfilesFile string        `long:"profiles-file" description:"the file of the connection profiles instead of ~/.supervisord/ctl.toml"`
+	Proxy        string        `long:"proxy" description:"the http or socks5 proxy URL to connect to the server, the proxies of HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used if not given"`
+	Insecure     bool          `short:"k" long:"insecure" description:"do not verify the certificate of the https server, only for testing"`
+	Verbose      bool          `short:"v" long:"verbose" description:"Show verbose debug information"`
+	Retries      int           `long:"retries" default:"0" description:"the times to retry the call if supervisord refuses the connection, like it is restarting"`
+	RetryBackoff time.Duration `long:"retry-backoff" default:"500ms" description:"the delay before the first retry, doubled for every next retry up to 10s"`
+	DryRun       bool          `long:"dry-run" description:"print the programs start, stop, restart, signal and clear would be applied to without applying"`
+	NoColor      bool          `long:"no-color" description:"do not color the program states, they are not colored if the output is not a terminal or NO_COLOR is set"`
+	Output       string        `short:"o" long:"output" default:"text" choice:"text" choice:"json" choice:"yaml" choice:"tsv" description:"the output format of the results"`
+	// the records kept to print in the json, yaml or tsv output
+	records [][]ctlField
+	// the "supervisorctl" section of the configuration, nil if not found
+	supervisorctl       *config.Entry
+	supervisorctlLoaded bool
 }
 
 // StatusCommand get the status of all supervisor managed programs
 type StatusCommand struct {
+	Verbose bool `short:"v" long:"verbose" description:"show the CPU, memory, fd, thread and child process usage of the running programs"`
 }
 
 // StartCommand start the given program
 type StartCommand struct {
+	Env []string `short:"e" long:"env" description:"extra environment variable KEY=VALUE only for this run"`
 }
 
 // StopCommand stop the given program
@@ -34,6 +61,7 @@ type StopCommand struct {
 
 // RestartCommand restart the given program
 type RestartCommand struct {
+	Env []string `short:"e" long:"env" description:"environment override KEY=VALUE only for this run"`
 }
 
 // ShutdownCommand shutdown the supervisor
@@ -44,10 +72,52 @@ type ShutdownCommand struct {
 type ReloadCommand struct {
 }
 
+// UpdateCommand reload the configuration and apply the changes like supervisorctl update
+type UpdateCommand struct {
+}
+
+// DiffCommand show the changes of the programs in the configuration file which are applied by update
+type DiffCommand struct {
+}
+
+// AddCommand add the process groups in the configuration
+type AddCommand struct {
+}
+
+// RemoveCommand stop and remove the process groups
+type RemoveCommand struct {
+}
+
+// ClearCommand clear the logs of programs
+type ClearCommand struct {
+}
+
+// RemoteVersionCommand get the version of the running supervisord
+type RemoteVersionCommand struct {
+}
+
+// AvailCommand list the programs in the configuration
+type AvailCommand struct {
+}
+
 // PidCommand get the pid of program
 type PidCommand struct {
 }
 
+// EnvCommand get the environment variables of program
+type EnvCommand struct {
+}
+
+// FgCommand attach to the program, its output is printed and the input is sent to its stdin
+type FgCommand struct {
+}
+
+// WaitCommand wait until the programs reach the state
+type WaitCommand struct {
+	State   string        `long:"state" default:"RUNNING" description:"the state to wait for like RUNNING or STOPPED"`
+	Timeout time.Duration `long:"timeout" default:"60s" description:"the time to wait for all the programs like 30s or 2m"`
+}
+
 // SignalCommand send signal of program
 type SignalCommand struct {
 }
@@ -56,6 +126,18 @@ type SignalCommand struct {
 type LogtailCommand struct {
 }
 
+// TailCommand tail the stdout/stderr log of program through XML-RPC
+type TailCommand struct {
+	Follow bool `short:"f" long:"follow" description:"output the appended log until interrupted"`
+	Bytes  int  `short:"n" long:"bytes" default:"1600" description:"the number of bytes to output from the end of log"`
+}
+
+// MaintailCommand tail the supervisord log through XML-RPC
+type MaintailCommand struct {
+	Follow bool `short:"f" long:"follow" description:"output the appended log until interrupted"`
+	Bytes  int  `short:"n" long:"bytes" default:"1600" description:"the number of bytes to output from the end of log"`
+}
+
 // CmdCheckWrapperCommand A wrapper can be used to check whether
 // number of parameters is valid or not
 type CmdCheckWrapperCommand struct {
@@ -69,77 +151,103 @@ type CmdCheckWrapperCommand struct {
 }
 
 var ctlCommand CtlCommand
-var statusCommand = CmdCheckWrapperCommand{&StatusCommand{}, 0, ""}
-var startCommand = CmdCheckWrapperCommand{&StartCommand{}, 0, ""}
+
+// the context of the XML-RPC calls of the ctl subcommands
+var ctlContext = context.Background()
+var statusCommand = StatusCommand{} // not wrapped, CmdCheckWrapperCommand hides the options from parser
+var startCommand = StartCommand{}   // not wrapped, CmdCheckWrapperCommand hides the options from parser
 var stopCommand = CmdCheckWrapperCommand{&StopCommand{}, 0, ""}
-var restartCommand = CmdCheckWrapperCommand{&RestartCommand{}, 0, ""}
+var restartCommand = RestartCommand{}
 var shutdownCommand = CmdCheckWrapperCommand{&ShutdownCommand{}, 0, ""}
 var reloadCommand = CmdCheckWrapperCommand{&ReloadCommand{}, 0, ""}
-var pidCommand = CmdCheckWrapperCommand{&PidCommand{}, 1, "pid <program>"}
-var signalCommand = CmdCheckWrapperCommand{&SignalCommand{}, 2, "signal <signal_name> <program>[...]"}
+var updateCommand = CmdCheckWrapperCommand{&UpdateCommand{}, 0, ""}
+var diffCommand = CmdCheckWrapperCommand{&DiffCommand{}, 0, ""}
+var addCommand = CmdCheckWrapperCommand{&AddCommand{}, 1, "add <group>[...]"}
+var removeCommand = CmdCheckWrapperCommand{&RemoveCommand{}, 1, "remove <group>[...]"}
+var clearCommand = CmdCheckWrapperCommand{&ClearCommand{}, 1, "clear <program>[...]|all"}
+var remoteVersionCommand = CmdCheckWrapperCommand{&RemoteVersionCommand{}, 0, ""}
+var availCommand = CmdCheckWrapperCommand{&AvailCommand{}, 0, ""}
+var pidCommand = CmdCheckWrapperCommand{&PidCommand{}, 0, ""}
+var envCommand = CmdCheckWrapperCommand{&EnvCommand{}, 1, "env <program>"}
+var fgCommand = CmdCheckWrapperCommand{&FgCommand{}, 1, "fg <program>"}
+var waitCommand = WaitCommand{} // not wrapped, CmdCheckWrapperCommand hides the options from parser
+var signalCommand = CmdCheckWrapperCommand{&SignalCommand{}, 2, "signal <signal_name> <program>|<group>:*|all [...]"}
 var logtailCommand = CmdCheckWrapperCommand{&LogtailCommand{}, 1, "logtail <program>"}
+var tailCommand = TailCommand{}
+var maintailCommand = MaintailCommand{}
 
-func (x *CtlCommand) getServerURL() string {
-	options.Configuration, _ = findSupervisordConf()
-
-	if x.ServerURL != "" {
-		return x.ServerURL
-	} else if _, err := os.Stat(options.Configuration); err == nil {
-		myconfig := config.NewConfig(options.Configuration)
-		myconfig.Load()
-		if entry, ok := myconfig.GetSupervisorctl(); ok {
-			serverurl := entry.GetString("serverurl", "")
-			if serverurl != "" {
-				return serverurl
-			}
+// get the value of the key in the "supervisorctl" section of the configuration given by -c or found in
+// the default locations, the configuration is loaded only once
+func (x *CtlCommand) getSupervisorctlConfig(key string, defValue string) string {
+	if !x.supervisorctlLoaded {
+		x.supervisorctlLoaded = true
+		options.Configuration, _ = findSupervisordConf()
+		if _, err := os.Stat(options.Configuration); err == nil {
+			myconfig := config.NewConfig(options.Configuration)
+			myconfig.Load()
+			x.supervisorctl, _ = myconfig.GetSupervisorctl()
 		}
 	}
+	if x.supervisorctl == nil {
+		return defValue
+	}
+	return x.supervisorctl.GetString(key, defValue)
+}
+
+func (x *CtlCommand) getServerURL() string {
+	if servers, err := x.getServerURLs(); err == nil && len(servers) > 0 {
+		return servers[0]
+	}
+	if serverurl := x.getSupervisorctlConfig("serverurl", ""); serverurl != "" {
+		return serverurl
+	}
 	return "http://localhost:9001"
 }
 
 func (x *CtlCommand) getUser() string {
-	options.Configuration, _ = findSupervisordConf()
-
 	if x.User != "" {
 		return x.User
-	} else if _, err := os.Stat(options.Configuration); err == nil {
-		myconfig := config.NewConfig(options.Configuration)
-		myconfig.Load()
-		if entry, ok := myconfig.GetSupervisorctl(); ok {
-			user := entry.GetString("username", "")
-			return user
-		}
 	}
-	return ""
+	return x.getSupervisorctlConfig("username", "")
 }
 
 func (x *CtlCommand) getPassword() string {
-	options.Configuration, _ = findSupervisordConf()
-
 	if x.Password != "" {
 		return x.Password
-	} else if _, err := os.Stat(options.Configuration); err == nil {
-		myconfig := config.NewConfig(options.Configuration)
-		myconfig.Load()
-		if entry, ok := myconfig.GetSupervisorctl(); ok {
-			password := entry.GetString("password", "")
-			return password
-		}
 	}
-	return ""
+	return x.getSupervisorctlConfig("password", "")
+}
+
+// get the prompt of the ctl shell
+func (x *CtlCommand) getPrompt() string {
+	return x.getSupervisorctlConfig("prompt", "supervisor")
 }
 
 func (x *CtlCommand) createRPCClient() *xmlrpcclient.XMLRPCClient {
 	rpcc := xmlrpcclient.NewXMLRPCClient(x.getServerURL(), x.Verbose)
 	rpcc.SetUser(x.getUser())
 	rpcc.SetPassword(x.getPassword())
+	rpcc.SetToken(x.Token)
+	rpcc.SetRetryPolicy(xmlrpcclient.RetryPolicy{MaxRetries: x.Retries, Backoff: x.RetryBackoff, MaxBackoff: 10 * time.Second})
+	if x.CAFile != "" || x.CertFile != "" || x.Insecure {
+		err := rpcc.SetTLSOptions(xmlrpcclient.TLSOptions{CAFile: x.CAFile, CertFile: x.CertFile, KeyFile: x.KeyFile,
+			InsecureSkipVerify: x.Insecure})
+		if err != nil {
+			fmt.Printf("Fail to load the TLS files: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := rpcc.SetProxy(x.Proxy); err != nil {
+		fmt.Printf("Invalid proxy: %v\n", err)
+		os.Exit(1)
+	}
 	return rpcc
 }
 
 // Execute implements flags.Commander interface to execute the control commands
 func (x *CtlCommand) Execute(args []string) error {
 	if len(args) == 0 {
-		return nil
+		return x.runShell()
 	}
 
 	rpcc := x.createRPCClient()
@@ -151,13 +259,15 @@ func (x *CtlCommand) Execute(args []string) error {
 	// STATUS
 	////////////////////////////////////////////////////////////////////////////////
 	case "status":
-		x.status(rpcc, args[1:])
+		x.status(rpcc, args[1:], false)
 
 		////////////////////////////////////////////////////////////////////////////////
 		// START or STOP
 		////////////////////////////////////////////////////////////////////////////////
 	case "start", "stop":
 		x.startStopProcesses(rpcc, verb, args[1:])
+	case "restart":
+		x.restartProcesses(rpcc, args[1:])
 
 		////////////////////////////////////////////////////////////////////////////////
 		// SHUTDOWN
@@ -166,11 +276,25 @@ func (x *CtlCommand) Execute(args []string) error {
 		x.shutdown(rpcc)
 	case "reload":
 		x.reload(rpcc)
+	case "update":
+		x.update(rpcc)
+	case "diff":
+		x.diff(rpcc)
+	case "add":
+		x.addGroups(rpcc, args[1:])
+	case "remove":
+		x.removeGroups(rpcc, args[1:])
+	case "clear":
+		x.clearLogs(rpcc, args[1:])
+	case "version":
+		x.version(rpcc)
+	case "avail":
+		x.avail(rpcc)
 	case "signal":
 		sigName, processes := args[1], args[2:]
 		x.signal(rpcc, sigName, processes)
 	case "pid":
-		x.getPid(rpcc, args[1])
+		x.getPid(rpcc, args[1:])
 	default:
 		fmt.Println("unknown command")
 	}
@@ -179,21 +303,98 @@ func (x *CtlCommand) Execute(args []string) error {
 }
 
 // get the status of processes
-func (x *CtlCommand) status(rpcc *xmlrpcclient.XMLRPCClient, processes []string) {
+func (x *CtlCommand) status(rpcc *xmlrpcclient.XMLRPCClient, processes []string, verbose bool) {
 	processesMap := make(map[string]bool)
 	for _, process := range processes {
 		processesMap[process] = true
 	}
-	if reply, err := rpcc.GetAllProcessInfo(); err == nil {
+	reply, err := rpcc.GetAllProcessInfo(ctlContext)
+	if err != nil {
+		x.printError("Fail to get the programs: %v\n", err)
+		x.exit(1)
+	}
+	if !verbose {
 		x.showProcessInfo(&reply, processesMap)
 	} else {
-		os.Exit(1)
+		allUsage, err := rpcc.GetAllProcessResourceUsage(ctlContext)
+		if err != nil {
+			x.printError("Fail to get the resource usage: %v\n", err)
+			x.exit(1)
+		}
+		usages := make(map[string]types.ProcessResourceUsage)
+		for _, usage := range allUsage {
+			usages[types.ProcessInfo{Name: usage.Name, Group: usage.Group}.GetFullName()] = usage
+		}
+		x.showProcessInfoWithUsage(&reply, processesMap, usages)
+	}
+	for _, process := range processes {
+		if !x.anyProcessMatches(reply.Value, process) {
+			x.print([]ctlField{{"name", process}, {"state", "UNKNOWN"}, {"error", "no such process"}},
+				"%s: ERROR (no such process)\n", process)
+		}
+	}
+	if code := x.statusExitCode(reply.Value, processes); code != statusAllRunning {
+		x.exit(code)
 	}
 }
 
+// the exit codes of ctl status
+const (
+	// all the queried programs are running
+	statusAllRunning = 0
+	// any queried program is in FATAL or BACKOFF state
+	statusFailed = 2
+	// any queried program is not running, like STOPPED, EXITED or STARTING
+	statusNotRunning = 3
+	// any queried program is not found
+	statusNoSuchProcess = 4
+)
+
+// get the exit code of ctl status of the processes, the code of the worst state is returned: the not
+// found program, then the failed one and then the one not running
+func (x *CtlCommand) statusExitCode(infos []types.ProcessInfo, processes []string) int {
+	for _, process := range processes {
+		if !x.anyProcessMatches(infos, process) {
+			return statusNoSuchProcess
+		}
+	}
+	processesMap := make(map[string]bool)
+	for _, process := range processes {
+		processesMap[process] = true
+	}
+	code := statusAllRunning
+	for i := range infos {
+		if !x.inProcessMap(&infos[i], processesMap) {
+			continue
+		}
+		switch strings.ToUpper(infos[i].Statename) {
+		case "RUNNING":
+		case "FATAL", "BACKOFF":
+			return statusFailed
+		default:
+			code = statusNotRunning
+		}
+	}
+	return code
+}
+
+// check if any of the programs matches the name
+func (x *CtlCommand) anyProcessMatches(infos []types.ProcessInfo, process string) bool {
+	for i := range infos {
+		if x.inProcessMap(&infos[i], map[string]bool{process: true}) {
+			return true
+		}
+	}
+	return false
+}
+
 // start or stop the processes
 // verb must be: start or stop
 func (x *CtlCommand) startStopProcesses(rpcc *xmlrpcclient.XMLRPCClient, verb string, processes []string) {
+	processes, ok := x.expandProcesses(rpcc, verb, processes)
+	if !ok {
+		return
+	}
 	state := map[string]string{
 		"start": "started",
 		"stop":  "stopped",
@@ -203,57 +404,120 @@ func (x *CtlCommand) startStopProcesses(rpcc *xmlrpcclient.XMLRPCClient, verb st
 
 func (x *CtlCommand) _startStopProcesses(rpcc *xmlrpcclient.XMLRPCClient, verb string, processes []string, state string, showProcessInfo bool) {
 	if len(processes) <= 0 {
-		fmt.Printf("Please specify process for %s\n", verb)
+		x.printError("Please specify process for %s\n", verb)
 	}
 	for _, pname := range processes {
-		if pname == "all" {
-			reply, err := rpcc.ChangeAllProcessState(verb)
+		if pname == "all" && showProcessInfo && x.showProgress() {
+			if err := x.changeAllProcessStateWithProgress(rpcc, verb, state); err != nil {
+				x.printError("Fail to change all process state to %s\n", state)
+			}
+		} else if pname == "all" {
+			reply, err := rpcc.ChangeAllProcessState(ctlContext, verb)
 			if err == nil {
 				if showProcessInfo {
 					x.showProcessInfo(&reply, make(map[string]bool))
 				}
 			} else {
-				fmt.Printf("Fail to change all process state to %s", state)
+				x.printError("Fail to change all process state to %s\n", state)
 			}
 		} else {
-			if reply, err := rpcc.ChangeProcessState(verb, pname); err == nil {
+			if reply, err := rpcc.ChangeProcessState(ctlContext, verb, pname); err == nil {
 				if showProcessInfo {
-					fmt.Printf("%s: ", pname)
+					result := state
 					if !reply.Value {
-						fmt.Printf("not ")
+						result = "not " + state
 					}
-					fmt.Printf("%s\n", state)
+					x.print([]ctlField{{"name", pname}, {"result", result}}, "%s: %s\n", pname, result)
 				}
 			} else {
-				fmt.Printf("%s: failed [%v]\n", pname, err)
-				os.Exit(1)
+				x.print([]ctlField{{"name", pname}, {"result", "failed"}, {"error", err.Error()}}, "%s: failed [%v]\n", pname, err)
+				x.exit(1)
 			}
 		}
 	}
 }
 
+// restart the processes on the server side, "all" is stopped and started again
 func (x *CtlCommand) restartProcesses(rpcc *xmlrpcclient.XMLRPCClient, processes []string) {
-	x._startStopProcesses(rpcc, "stop", processes, "stopped", false)
-	x._startStopProcesses(rpcc, "start", processes, "restarted", true)
+	processes, ok := x.expandProcesses(rpcc, "restart", processes)
+	if !ok {
+		return
+	}
+	if len(processes) <= 0 {
+		x.printError("Please specify process for restart\n")
+	}
+	for _, pname := range processes {
+		if pname == "all" {
+			x._startStopProcesses(rpcc, "stop", []string{pname}, "stopped", false)
+			x._startStopProcesses(rpcc, "start", []string{pname}, "restarted", true)
+		} else if _, err := rpcc.RestartProcess(ctlContext, pname, true); err == nil {
+			x.print([]ctlField{{"name", pname}, {"result", "restarted"}}, "%s: restarted\n", pname)
+		} else {
+			x.print([]ctlField{{"name", pname}, {"result", "failed"}, {"error", err.Error()}}, "%s: failed [%v]\n", pname, err)
+			x.exit(1)
+		}
+	}
+}
+
+// start the processes with extra environment variables only for this run
+func (x *CtlCommand) startProcessesWithEnv(rpcc *xmlrpcclient.XMLRPCClient, processes []string, env []string) {
+	processes, ok := x.expandProcesses(rpcc, "start", processes)
+	if !ok {
+		return
+	}
+	if len(processes) <= 0 {
+		x.printError("Please specify process for start\n")
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.StartProcessWithEnv(ctlContext, pname, env, true); err == nil {
+			x.print([]ctlField{{"name", pname}, {"result", "started with env override"}}, "%s: started with env override\n", pname)
+		} else {
+			x.print([]ctlField{{"name", pname}, {"result", "failed"}, {"error", err.Error()}}, "%s: failed [%v]\n", pname, err)
+			x.exit(1)
+		}
+	}
+}
+
+// restart the processes with environment overrides only for this run
+func (x *CtlCommand) restartProcessesWithEnv(rpcc *xmlrpcclient.XMLRPCClient, processes []string, env []string) {
+	processes, ok := x.expandProcesses(rpcc, "restart", processes)
+	if !ok {
+		return
+	}
+	if len(processes) <= 0 {
+		x.printError("Please specify process for restart\n")
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.RestartProcessWithEnv(ctlContext, pname, env, true); err == nil {
+			x.print([]ctlField{{"name", pname}, {"result", "restarted with env override"}}, "%s: restarted with env override\n", pname)
+		} else {
+			x.print([]ctlField{{"name", pname}, {"result", "failed"}, {"error", err.Error()}}, "%s: failed [%v]\n", pname, err)
+			x.exit(1)
+		}
+	}
 }
 
 // shutdown the supervisord
 func (x *CtlCommand) shutdown(rpcc *xmlrpcclient.XMLRPCClient) {
-	if reply, err := rpcc.Shutdown(); err == nil {
+	if reply, err := rpcc.Shutdown(ctlContext); err == nil {
 		if reply.Value {
-			fmt.Printf("Shut Down\n")
+			x.print([]ctlField{{"result", "shut down"}}, "Shut Down\n")
 		} else {
-			fmt.Printf("Hmmm! Something gone wrong?!\n")
+			x.print([]ctlField{{"result", "failed"}}, "Hmmm! Something gone wrong?!\n")
 		}
 	} else {
-		os.Exit(1)
+		x.exit(1)
 	}
 }
 
 // reload all the programs in the supervisord
 func (x *CtlCommand) reload(rpcc *xmlrpcclient.XMLRPCClient) {
-	if reply, err := rpcc.ReloadConfig(); err == nil {
+	if reply, err := rpcc.ReloadConfig(ctlContext); err == nil {
 
+		if x.structuredOutput() {
+			x.printGroupChanges(reply)
+			return
+		}
 		if len(reply.AddedGroup) > 0 {
 			fmt.Printf("Added Groups: %s\n", strings.Join(reply.AddedGroup, ","))
 		}
@@ -264,46 +528,351 @@ func (x *CtlCommand) reload(rpcc *xmlrpcclient.XMLRPCClient) {
 			fmt.Printf("Removed Groups: %s\n", strings.Join(reply.RemovedGroup, ","))
 		}
 	} else {
-		os.Exit(1)
+		x.exit(1)
+	}
+}
+
+// reload the configuration and print the changed groups like supervisorctl update, the added groups
+// are started, the removed ones are stopped and the changed ones are restarted by supervisord
+func (x *CtlCommand) update(rpcc *xmlrpcclient.XMLRPCClient) {
+	reply, err := rpcc.ReloadConfig(ctlContext)
+	if err != nil {
+		x.printError("Fail to update: %v\n", err)
+		x.exit(1)
+	}
+	x.printGroupChanges(reply)
+}
+
+// print the programs added, changed and removed in the configuration file like the unified diff, the old
+// values are prefixed by "-" and the new ones by "+" under the section header of every program
+func (x *CtlCommand) diff(rpcc *xmlrpcclient.XMLRPCClient) {
+	changes, err := rpcc.GetConfigDiff(ctlContext)
+	if err != nil {
+		x.printError("Fail to get the configuration changes: %v\n", err)
+		x.exit(1)
+	}
+	if x.structuredOutput() {
+		for _, change := range changes {
+			for _, key := range change.Keys {
+				x.print([]ctlField{{"name", change.Name}, {"change", change.Change}, {"key", key.Key}, {"old", key.Old}, {"new", key.New}}, "")
+			}
+		}
+		return
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+	red, green, reset := "", "", ""
+	if x.colored() {
+		red, green, reset = "\x1b[0;31m", "\x1b[0;32m", "\x1b[0m"
+	}
+	for i, change := range changes {
+		if i > 0 {
+			fmt.Println()
+		}
+		switch change.Change {
+		case "added":
+			fmt.Printf("%s+[program:%s]%s\n", green, change.Name, reset)
+		case "removed":
+			fmt.Printf("%s-[program:%s]%s\n", red, change.Name, reset)
+		default:
+			fmt.Printf(" [program:%s]\n", change.Name)
+		}
+		for _, key := range change.Keys {
+			// the empty value of the changed program is the key not in the old or new section
+			if change.Change == "removed" || (change.Change == "changed" && key.Old != "") {
+				fmt.Printf("%s-%s=%s%s\n", red, key.Key, key.Old, reset)
+			}
+			if change.Change == "added" || (change.Change == "changed" && key.New != "") {
+				fmt.Printf("%s+%s=%s%s\n", green, key.Key, key.New, reset)
+			}
+		}
+	}
+}
+
+// print the added, changed and removed groups of the reloaded configuration
+func (x *CtlCommand) printGroupChanges(reply types.ReloadConfigResult) {
+	for _, group := range reply.AddedGroup {
+		x.print([]ctlField{{"group", group}, {"change", "added"}}, "%s: added process group\n", group)
+	}
+	for _, group := range reply.ChangedGroup {
+		x.print([]ctlField{{"group", group}, {"change", "updated"}}, "%s: updated process group\n", group)
+	}
+	for _, group := range reply.RemovedGroup {
+		x.print([]ctlField{{"group", group}, {"change", "removed"}}, "%s: removed process group\n", group)
+	}
+}
+
+// add the process groups in the configuration which are not added
+func (x *CtlCommand) addGroups(rpcc *xmlrpcclient.XMLRPCClient, groups []string) {
+	failed := false
+	for _, group := range groups {
+		if _, err := rpcc.AddProcessGroup(ctlContext, group); err == nil {
+			x.print([]ctlField{{"group", group}, {"result", "added"}}, "%s: added process group\n", group)
+		} else {
+			x.print([]ctlField{{"group", group}, {"result", "failed"}, {"error", err.Error()}}, "%s: ERROR (%v)\n", group, err)
+			failed = true
+		}
+	}
+	if failed {
+		x.exit(1)
+	}
+}
+
+// stop and remove the process groups
+func (x *CtlCommand) removeGroups(rpcc *xmlrpcclient.XMLRPCClient, groups []string) {
+	failed := false
+	for _, group := range groups {
+		if _, err := rpcc.RemoveProcessGroup(ctlContext, group); err == nil {
+			x.print([]ctlField{{"group", group}, {"result", "removed"}}, "%s: removed process group\n", group)
+		} else {
+			x.print([]ctlField{{"group", group}, {"result", "failed"}, {"error", err.Error()}}, "%s: ERROR (%v)\n", group, err)
+			failed = true
+		}
+	}
+	if failed {
+		x.exit(1)
+	}
+}
+
+// clear the stdout and stderr logs of the programs, "all" for all the programs
+func (x *CtlCommand) clearLogs(rpcc *xmlrpcclient.XMLRPCClient, processes []string) {
+	processes, ok := x.expandProcesses(rpcc, "clear", processes)
+	if !ok {
+		return
+	}
+	names := make([]string, 0)
+	for _, process := range processes {
+		if process != "all" {
+			names = append(names, process)
+			continue
+		}
+		reply, err := rpcc.GetAllProcessInfo(ctlContext)
+		if err != nil {
+			x.printError("Fail to get the programs: %v\n", err)
+			x.exit(1)
+		}
+		for _, pinfo := range reply.Value {
+			names = append(names, pinfo.GetFullName())
+		}
+	}
+	failed := false
+	for _, name := range names {
+		if _, err := rpcc.ClearProcessLogs(ctlContext, name); err == nil {
+			x.print([]ctlField{{"name", name}, {"result", "cleared"}}, "%s: cleared\n", name)
+		} else {
+			x.print([]ctlField{{"name", name}, {"result", "failed"}, {"error", err.Error()}}, "%s: ERROR (%v)\n", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		x.exit(1)
+	}
+}
+
+// print the version of the running supervisord
+func (x *CtlCommand) version(rpcc *xmlrpcclient.XMLRPCClient) {
+	reply, err := rpcc.GetVersion(ctlContext)
+	if err != nil {
+		x.printError("Fail to get the version: %v\n", err)
+		x.exit(1)
+	}
+	x.print([]ctlField{{"version", reply.Value}}, "%s\n", reply.Value)
+}
+
+// list the programs in the configuration with whether they are in use and autostart like supervisorctl avail
+func (x *CtlCommand) avail(rpcc *xmlrpcclient.XMLRPCClient) {
+	configs, err := rpcc.GetAllConfigInfo(ctlContext)
+	if err != nil {
+		x.printError("Fail to get the programs in the configuration: %v\n", err)
+		x.exit(1)
+	}
+	for _, config := range configs {
+		inuse := "avail"
+		if config.Inuse {
+			inuse = "in use"
+		}
+		autostart := "manual"
+		if config.Autostart {
+			autostart = "auto"
+		}
+		name := config.Name
+		if config.Group != config.Name {
+			name = config.Group + ":" + config.Name
+		}
+		x.print([]ctlField{{"name", config.Name}, {"group", config.Group}, {"inuse", config.Inuse}, {"autostart", config.Autostart}, {"priority", config.Priority}},
+			"%-33s %-9s %-9s %d\n", name, inuse, autostart, config.Priority)
+	}
+}
+
+// wait until the programs reach the state through the waitForState call one by one, it exits with
+// 1 if any program does not reach the state before the timeout
+func (x *CtlCommand) wait(rpcc *xmlrpcclient.XMLRPCClient, processes []string, state string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	state = strings.ToUpper(state)
+	for _, process := range processes {
+		if _, err := rpcc.GetProcessInfo(ctlContext, process); err != nil {
+			x.print([]ctlField{{"name", process}, {"state", state}, {"result", "failed"}, {"error", "no such process"}},
+				"%s: ERROR (no such process)\n", process)
+			x.exit(1)
+		}
+		// the waitForState timeout is in seconds, the remaining time is rounded up
+		seconds := int(math.Ceil(time.Until(deadline).Seconds()))
+		if seconds < 0 {
+			seconds = 0
+		}
+		// the call is given up if supervisord does not answer a while after the timeout
+		ctx, cancel := context.WithDeadline(ctlContext, deadline.Add(10*time.Second))
+		reply, err := rpcc.WaitForState(ctx, process, state, seconds)
+		cancel()
+		if err != nil {
+			x.print([]ctlField{{"name", process}, {"state", state}, {"result", "failed"}, {"error", err.Error()}},
+				"%s: ERROR (%v)\n", process, err)
+			x.exit(1)
+		}
+		if !reply.Success {
+			x.print([]ctlField{{"name", process}, {"state", state}, {"result", "timeout"}},
+				"%s: ERROR (not %s after %v)\n", process, state, timeout)
+			x.exit(1)
+		}
+		x.print([]ctlField{{"name", process}, {"state", state}, {"result", "reached"}}, "%s: %s\n", process, state)
 	}
 }
 
 // send signal to one or more processes
 func (x *CtlCommand) signal(rpcc *xmlrpcclient.XMLRPCClient, sigName string, processes []string) {
+	processes, ok := x.expandProcesses(rpcc, "signal "+sigName, processes)
+	if !ok {
+		return
+	}
 	for _, process := range processes {
 		if process == "all" {
-			reply, err := rpcc.SignalAll(process)
+			reply, err := rpcc.SignalAll(ctlContext, sigName)
 			if err == nil {
 				x.showProcessInfo(&reply, make(map[string]bool))
 			} else {
-				fmt.Printf("Fail to send signal %s to all process", sigName)
-				os.Exit(1)
+				x.printError("Fail to send signal %s to all process: %v\n", sigName, err)
+				x.exit(1)
 			}
 		} else {
-			reply, err := rpcc.SignalProcess(sigName, process)
+			reply, err := rpcc.SignalProcess(ctlContext, sigName, process)
 			if err == nil && reply.Success {
-				fmt.Printf("Succeed to send signal %s to process %s\n", sigName, process)
+				x.print([]ctlField{{"name", process}, {"signal", sigName}, {"result", "signalled"}},
+					"Succeed to send signal %s to process %s\n", sigName, process)
 			} else {
-				fmt.Printf("Fail to send signal %s to process %s\n", sigName, process)
-				os.Exit(1)
+				x.print([]ctlField{{"name", process}, {"signal", sigName}, {"result", "failed"}, {"error", fmt.Sprint(err)}},
+					"Fail to send signal %s to process %s: %v\n", sigName, process, err)
+				x.exit(1)
 			}
 		}
 	}
 }
 
-// get the pid of running program
-func (x *CtlCommand) getPid(rpcc *xmlrpcclient.XMLRPCClient, process string) {
-	procInfo, err := rpcc.GetProcessInfo(process)
+// print the pid of supervisord if no program is given, otherwise the pids of the programs, "all" for
+// all the programs. The pid of the program not running is 0
+func (x *CtlCommand) getPid(rpcc *xmlrpcclient.XMLRPCClient, processes []string) {
+	if len(processes) == 0 {
+		pid, err := rpcc.GetPID(ctlContext)
+		if err != nil {
+			x.printError("Fail to get the pid of supervisord: %v\n", err)
+			x.exit(1)
+		}
+		x.print([]ctlField{{"name", "supervisord"}, {"pid", pid}}, "%d\n", pid)
+		return
+	}
+	for _, process := range processes {
+		if process == "all" {
+			reply, err := rpcc.GetAllProcessInfo(ctlContext)
+			if err != nil {
+				x.printError("Fail to get the programs: %v\n", err)
+				x.exit(1)
+			}
+			for _, procInfo := range reply.Value {
+				x.print([]ctlField{{"name", procInfo.GetFullName()}, {"pid", procInfo.Pid}}, "%d\n", procInfo.Pid)
+			}
+			continue
+		}
+		procInfo, err := rpcc.GetProcessInfo(ctlContext, process)
+		if err != nil {
+			x.printError("program '%s' not found\n", process)
+			x.exit(1)
+		}
+		x.print([]ctlField{{"name", procInfo.GetFullName()}, {"pid", procInfo.Pid}}, "%d\n", procInfo.Pid)
+	}
+}
+
+// attach to the running program, its stdout and stderr are printed and the lines typed are sent to its
+// stdin until Ctrl-C or Ctrl-D is pressed or the program exits
+func (x *CtlCommand) foreground(rpcc *xmlrpcclient.XMLRPCClient, program string) {
+	procInfo, err := rpcc.GetProcessInfo(ctlContext, program)
 	if err != nil {
-		fmt.Printf("program '%s' not found\n", process)
-		os.Exit(1)
-	} else {
-		fmt.Printf("%d\n", procInfo.Pid)
+		x.printError("%s: ERROR (no such process)\n", program)
+		x.exit(1)
+	}
+	if procInfo.State != int(process.Running) {
+		x.printError("%s: ERROR (not running)\n", program)
+		x.exit(1)
+	}
+	x.printError("==> Press Ctrl-C or Ctrl-D to detach from %s <==\n", program)
+
+	detached := make(chan string, 4)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		<-interrupts
+		detached <- ""
+	}()
+	go rpcc.FollowProcessLog(ctlContext, program, "stdout", 0, os.Stdout)
+	go rpcc.FollowProcessLog(ctlContext, program, "stderr", 0, os.Stderr)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				if _, err := rpcc.SendProcessStdin(ctlContext, program, line); err != nil {
+					detached <- fmt.Sprintf("fail to send the input: %v", err)
+					return
+				}
+			}
+			if err != nil {
+				detached <- ""
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			time.Sleep(time.Second)
+			if procInfo, err := rpcc.GetProcessInfo(ctlContext, program); err != nil || procInfo.State != int(process.Running) {
+				detached <- "the program is not running"
+				return
+			}
+		}
+	}()
+	if reason := <-detached; reason != "" {
+		x.printError("\n==> Detached from %s, %s <==\n", program, reason)
+		x.exit(1)
 	}
+	x.printError("\n==> Detached from %s <==\n", program)
 }
 
-func (x *CtlCommand) getProcessInfo(rpcc *xmlrpcclient.XMLRPCClient, process string) (types.ProcessInfo, error) {
-	return rpcc.GetProcessInfo(process)
+// get the environment variables the program is started with, the secrets are masked by supervisord
+func (x *CtlCommand) getEnv(rpcc *xmlrpcclient.XMLRPCClient, process string) {
+	env, err := rpcc.GetProcessEnvironment(ctlContext, process)
+	if err != nil {
+		x.printError("Fail to get the environment of %s: %v\n", process, err)
+		x.exit(1)
+	}
+	for _, e := range env.Env {
+		kv := strings.SplitN(e, "=", 2)
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		x.print([]ctlField{{"name", kv[0]}, {"value", value}}, "%s\n", e)
+	}
 }
 
 // check if group name should be displayed
@@ -318,19 +887,111 @@ func (x *CtlCommand) showGroupName() bool {
 }
 
 func (x *CtlCommand) showProcessInfo(reply *xmlrpcclient.AllProcessInfoReply, processesMap map[string]bool) {
+	x.showProcessInfoWithUsage(reply, processesMap, nil)
+}
+
+// show the process information followed by the resource usage of the running programs in usages
+// by the full name, the text output is a table aligned by the widest value of every column
+func (x *CtlCommand) showProcessInfoWithUsage(reply *xmlrpcclient.AllProcessInfoReply, processesMap map[string]bool, usages map[string]types.ProcessResourceUsage) {
+	rows := [][]string{{"NAME", "STATE", "PID", "UPTIME", "DESCRIPTION"}}
 	for _, pinfo := range reply.Value {
 		description := pinfo.Description
 		if strings.ToLower(description) == "<string></string>" {
 			description = ""
 		}
-		if x.inProcessMap(&pinfo, processesMap) {
-			processName := pinfo.GetFullName()
-			if !x.showGroupName() {
-				processName = pinfo.Name
+		if !x.inProcessMap(&pinfo, processesMap) {
+			continue
+		}
+		record := []ctlField{{"name", pinfo.Name}, {"group", pinfo.Group}, {"state", pinfo.Statename}, {"pid", pinfo.Pid},
+			{"start", pinfo.Start}, {"uptime", pinfo.Uptime}, {"description", description}}
+		processName := pinfo.GetFullName()
+		if !x.showGroupName() {
+			processName = pinfo.Name
+		}
+		pid, uptime := "-", "-"
+		if pinfo.Pid != 0 {
+			pid = fmt.Sprintf("%d", pinfo.Pid)
+		}
+		details := make([]string, 0)
+		if strings.ToUpper(pinfo.Statename) == "RUNNING" {
+			// the pid and uptime in the description are shown in their columns
+			uptime = humanizeDuration(time.Duration(pinfo.Uptime) * time.Second)
+			if pinfo.EnvOverride != "" {
+				details = append(details, "env override: "+pinfo.EnvOverride)
 			}
-			fmt.Printf("%s%-33s%-10s%s%s\n", x.getANSIColor(strings.ToUpper(pinfo.Statename)), processName, pinfo.Statename, description, "\x1b[0m")
+		} else if description != "" {
+			details = append(details, description)
+		}
+		if usage, ok := usages[pinfo.GetFullName()]; ok && usage.Time != 0 {
+			details = append(details, fmt.Sprintf("cpu %.1f%%, rss %.1fMB, fds %d, threads %d, children %d",
+				usage.Cpu, float64(usage.Rss)/(1024*1024), usage.Fds, usage.Threads, usage.Children))
+			record = append(record, ctlField{"cpu", usage.Cpu}, ctlField{"rss", usage.Rss}, ctlField{"fds", usage.Fds},
+				ctlField{"threads", usage.Threads}, ctlField{"children", usage.Children})
+		}
+		if x.structuredOutput() {
+			x.records = append(x.records, record)
+			continue
 		}
+		rows = append(rows, []string{processName, pinfo.Statename, pid, uptime, strings.Join(details, ", ")})
+	}
+	if x.structuredOutput() || len(rows) == 1 {
+		return
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	colored := x.colored()
+	for i, row := range rows {
+		line := ""
+		for j, cell := range row {
+			if j == len(row)-1 {
+				line += cell
+				break
+			}
+			cell = fmt.Sprintf("%-*s  ", widths[j], cell)
+			// the header is not colored, the padding is outside the color to keep the columns aligned
+			if j == 1 && i > 0 && colored {
+				cell = x.getANSIColor(strings.ToUpper(row[j])) + row[j] + "\x1b[0m" + cell[len(row[j]):]
+			}
+			line += cell
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// check if the program states are colored, not if --no-color is given, NO_COLOR is set or the output is
+// not a terminal
+func (x *CtlCommand) colored() bool {
+	if x.NoColor {
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// format the duration like 3d 4h, 2h 5m, 5m 3s or 12s with the two largest units
+func humanizeDuration(d time.Duration) string {
+	seconds := int64(d.Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	days, hours, minutes := seconds/86400, seconds/3600%24, seconds/60%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds%60)
 	}
+	return fmt.Sprintf("%ds", seconds)
 }
 
 func (x *CtlCommand) inProcessMap(procInfo *types.ProcessInfo, processesMap map[string]bool) bool {
@@ -338,7 +999,7 @@ func (x *CtlCommand) inProcessMap(procInfo *types.ProcessInfo, processesMap map[
 		return true
 	}
 	for procName := range processesMap {
-		if procName == procInfo.Name || procName == procInfo.GetFullName() {
+		if procName == "all" || procName == procInfo.Name || procName == procInfo.GetFullName() {
 			return true
 		}
 
@@ -370,13 +1031,17 @@ func (x *CtlCommand) getANSIColor(statename string) string {
 
 // Execute implements flags.Commander interface to get status of program
 func (sc *StatusCommand) Execute(args []string) error {
-	ctlCommand.status(ctlCommand.createRPCClient(), args)
+	ctlCommand.status(ctlCommand.createRPCClient(), args, sc.Verbose)
 	return nil
 }
 
 // Execute start the given programs
 func (sc *StartCommand) Execute(args []string) error {
-	ctlCommand.startStopProcesses(ctlCommand.createRPCClient(), "start", args)
+	if len(sc.Env) > 0 {
+		ctlCommand.startProcessesWithEnv(ctlCommand.createRPCClient(), args, sc.Env)
+	} else {
+		ctlCommand.startStopProcesses(ctlCommand.createRPCClient(), "start", args)
+	}
 	return nil
 }
 
@@ -388,7 +1053,11 @@ func (sc *StopCommand) Execute(args []string) error {
 
 // Execute restart the programs
 func (rc *RestartCommand) Execute(args []string) error {
-	ctlCommand.restartProcesses(ctlCommand.createRPCClient(), args)
+	if len(rc.Env) > 0 {
+		ctlCommand.restartProcessesWithEnv(ctlCommand.createRPCClient(), args, rc.Env)
+	} else {
+		ctlCommand.restartProcesses(ctlCommand.createRPCClient(), args)
+	}
 	return nil
 }
 
@@ -404,6 +1073,59 @@ func (rc *ReloadCommand) Execute(args []string) error {
 	return nil
 }
 
+// Execute reload the configuration and apply the changes
+func (uc *UpdateCommand) Execute(args []string) error {
+	ctlCommand.update(ctlCommand.createRPCClient())
+	return nil
+}
+
+// Execute show the changes of the programs in the configuration file
+func (dc *DiffCommand) Execute(args []string) error {
+	ctlCommand.diff(ctlCommand.createRPCClient())
+	return nil
+}
+
+// Execute add the process groups
+func (ac *AddCommand) Execute(args []string) error {
+	ctlCommand.addGroups(ctlCommand.createRPCClient(), args)
+	return nil
+}
+
+// Execute remove the process groups
+func (rc *RemoveCommand) Execute(args []string) error {
+	ctlCommand.removeGroups(ctlCommand.createRPCClient(), args)
+	return nil
+}
+
+// Execute clear the logs of programs
+func (cc *ClearCommand) Execute(args []string) error {
+	ctlCommand.clearLogs(ctlCommand.createRPCClient(), args)
+	return nil
+}
+
+// Execute get the version of the running supervisord
+func (vc *RemoteVersionCommand) Execute(args []string) error {
+	ctlCommand.version(ctlCommand.createRPCClient())
+	return nil
+}
+
+// Execute list the programs in the configuration
+func (ac *AvailCommand) Execute(args []string) error {
+	ctlCommand.avail(ctlCommand.createRPCClient())
+	return nil
+}
+
+// Execute wait until the programs reach the state
+func (wc *WaitCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		err := fmt.Errorf("Invalid arguments.\nUsage: supervisord ctl wait [--state RUNNING] [--timeout 60s] <program>[...]")
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	ctlCommand.wait(ctlCommand.createRPCClient(), args, wc.State, wc.Timeout)
+	return nil
+}
+
 // Execute send signal to program
 func (rc *SignalCommand) Execute(args []string) error {
 	sigName, processes := args[0], args[1:]
@@ -413,48 +1135,110 @@ func (rc *SignalCommand) Execute(args []string) error {
 
 // Execute get the pid of program
 func (pc *PidCommand) Execute(args []string) error {
-	ctlCommand.getPid(ctlCommand.createRPCClient(), args[0])
+	ctlCommand.getPid(ctlCommand.createRPCClient(), args)
 	return nil
 }
 
-// Execute tail the stdout/stderr of a program through http interface
+// Execute get the environment variables of program
+func (ec *EnvCommand) Execute(args []string) error {
+	ctlCommand.getEnv(ctlCommand.createRPCClient(), args[0])
+	return nil
+}
+
+// Execute attach to the program
+func (fc *FgCommand) Execute(args []string) error {
+	ctlCommand.foreground(ctlCommand.createRPCClient(), args[0])
+	return nil
+}
+
+// Execute tail the stdout/stderr of a program through the /logtail endpoint, or the tail RPC
+// if supervisord does not serve it
 func (lc *LogtailCommand) Execute(args []string) error {
 	program := args[0]
+	rpcc := ctlCommand.createRPCClient()
+	if _, err := rpcc.GetProcessInfo(ctlContext, program); err != nil {
+		fmt.Printf("Not exist program %s\n", program)
+		return err
+	}
 	go func() {
-		lc.tailLog(program, "stderr")
+		rpcc.FollowProcessLog(ctlContext, program, "stderr", 10240, os.Stderr)
 	}()
-	return lc.tailLog(program, "stdout")
+	return rpcc.FollowProcessLog(ctlContext, program, "stdout", 10240, os.Stdout)
 }
 
-func (lc *LogtailCommand) tailLog(program string, dev string) error {
-	_, err := ctlCommand.getProcessInfo(ctlCommand.createRPCClient(), program)
-	if err != nil {
-		fmt.Printf("Not exist program %s\n", program)
+// Execute tail the stdout (default) or stderr log of a program
+func (tc *TailCommand) Execute(args []string) error {
+	if len(args) < 1 || len(args) > 2 || (len(args) == 2 && args[1] != "stdout" && args[1] != "stderr") {
+		err := fmt.Errorf("Invalid arguments.\nUsage: supervisord ctl tail [-f] [-n bytes] <program> [stdout|stderr]")
+		fmt.Printf("%v\n", err)
 		return err
 	}
-	url := fmt.Sprintf("%s/logtail/%s/%s", ctlCommand.getServerURL(), program, dev)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+	program := args[0]
+	rpcc := ctlCommand.createRPCClient()
+	stdType := "stdout"
+	if len(args) == 2 {
+		stdType = args[1]
+	}
+	if tc.Follow {
+		err := rpcc.TailFollow(ctlContext, program, stdType, tc.Bytes, func(chunk string) error {
+			_, err := os.Stdout.WriteString(chunk)
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Fail to follow the log of program %s: %v\n", program, err)
+		}
 		return err
 	}
-	req.SetBasicAuth(ctlCommand.getUser(), ctlCommand.getPassword())
-	client := http.Client{}
-	resp, err := client.Do(req)
+	if stdType == "stderr" {
+		return tc.tail(program, rpcc.TailProcessStderrLog)
+	}
+	return tc.tail(program, rpcc.TailProcessStdoutLog)
+}
+
+// output the last tc.Bytes bytes of log by the tail function of stdout or stderr
+func (tc *TailCommand) tail(program string, tailFunc func(context.Context, string, int, int) (xmlrpcclient.TailLogReply, error)) error {
+	err := printLogTail(func(offset int, length int) (xmlrpcclient.TailLogReply, error) {
+		return tailFunc(ctlContext, program, offset, length)
+	}, tc.Bytes)
+	if err != nil {
+		fmt.Printf("Fail to tail the log of program %s: %v\n", program, err)
+	}
+	return err
+}
+
+// Execute tail the supervisord log
+func (mc *MaintailCommand) Execute(args []string) error {
+	rpcc := ctlCommand.createRPCClient()
+	var err error
+	if mc.Follow {
+		err = rpcc.FollowLog(ctlContext, mc.Bytes, os.Stdout)
+	} else {
+		err = printLogTail(func(offset int, length int) (xmlrpcclient.TailLogReply, error) {
+			return rpcc.TailLog(ctlContext, offset, length)
+		}, mc.Bytes)
+	}
+	if err != nil {
+		fmt.Printf("Fail to tail the log of supervisord: %v\n", err)
+	}
+	return err
+}
+
+// output the last bytes of log by the tail function
+func printLogTail(tailFunc func(int, int) (xmlrpcclient.TailLogReply, error), bytes int) error {
+	// the end of log is returned if the offset exceeds it
+	reply, err := tailFunc(math.MaxInt32, 0)
 	if err != nil {
 		return err
 	}
-	buf := make([]byte, 10240)
-	for {
-		n, err := resp.Body.Read(buf)
-		if err != nil {
-			return err
-		}
-		if dev == "stdout" {
-			os.Stdout.Write(buf[0:n])
-		} else {
-			os.Stderr.Write(buf[0:n])
-		}
+	offset := reply.Offset - bytes
+	if offset < 0 {
+		offset = 0
+	}
+	reply, err = tailFunc(offset, reply.Offset-offset)
+	if err != nil {
+		return err
 	}
+	fmt.Print(reply.LogData)
 	return nil
 }
 
@@ -473,6 +1257,8 @@ func init() {
 		"Control a running daemon",
 		"The ctl subcommand resembles supervisorctl command of original daemon.",
 		&ctlCommand)
+	// the interactive shell is started without subcommand
+	ctlCmd.SubcommandsOptional = true
 	ctlCmd.AddCommand("status",
 		"show program status",
 		"show all or some program status",
@@ -497,17 +1283,65 @@ func init() {
 		"reload the programs",
 		"reload the programs",
 		&reloadCommand)
+	ctlCmd.AddCommand("update",
+		"reload the configuration and apply the changes",
+		"reload the configuration, start the added groups, stop the removed ones and restart the changed ones",
+		&updateCommand)
+	ctlCmd.AddCommand("diff",
+		"show the changes of the configuration file",
+		"reread the configuration file and show the programs added, changed and removed with their changed keys like the unified diff, without applying them",
+		&diffCommand)
+	ctlCmd.AddCommand("add",
+		"add process groups",
+		"add the process groups in the configuration which are not added",
+		&addCommand)
+	ctlCmd.AddCommand("remove",
+		"remove process groups",
+		"stop and remove the process groups",
+		&removeCommand)
+	ctlCmd.AddCommand("clear",
+		"clear the logs of programs",
+		"clear the stdout and stderr logs of one or more programs",
+		&clearCommand)
+	ctlCmd.AddCommand("version",
+		"show the version of the running supervisord",
+		"show the version of the running supervisord",
+		&remoteVersionCommand)
+	ctlCmd.AddCommand("avail",
+		"list the programs in the configuration",
+		"list the programs in the configuration with whether they are in use, autostart and their priority",
+		&availCommand)
+	ctlCmd.AddCommand("wait",
+		"wait until programs reach the state",
+		"wait until the programs reach the state like RUNNING, exit with 1 if any does not before the timeout",
+		&waitCommand)
 	ctlCmd.AddCommand("signal",
 		"send signal to program",
 		"send signal to program",
 		&signalCommand)
 	ctlCmd.AddCommand("pid",
-		"get the pid of specified program",
-		"get the pid of specified program",
+		"get the pid of supervisord or programs",
+		"get the pid of supervisord without argument, or the pids of the specified programs, all for all the programs",
 		&pidCommand)
+	ctlCmd.AddCommand("env",
+		"get the environment variables of program",
+		"get the environment variables the program is started with, the values of the secrets are masked",
+		&envCommand)
+	ctlCmd.AddCommand("fg",
+		"attach to the program",
+		"print the output of the running program and send the lines typed to its stdin until Ctrl-C or Ctrl-D is pressed",
+		&fgCommand)
 	ctlCmd.AddCommand("logtail",
 		"get the standard output&standard error of the program",
 		"get the standard output&standard error of the program",
 		&logtailCommand)
+	ctlCmd.AddCommand("tail",
+		"get the tail of the standard output or standard error of the program",
+		"get the tail of the standard output (default) or standard error of the program, use -f to follow the log",
+		&tailCommand)
+	ctlCmd.AddCommand("maintail",
+		"get the tail of the supervisord log",
+		"get the tail of the supervisord log, use -f to follow the log",
+		&maintailCommand)
 
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// get the URLs of the servers given by the repeated -s options and the --servers-file
+func (x *CtlCommand) getServerURLs() ([]string, error) {
+	servers := append([]string(nil), x.ServerURL...)
+	if x.ServersFile != "" {
+		f, err := os.Open(x.ServersFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				servers = append(servers, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return servers, nil
+}
+
+// check if the ctl subcommand should be run against multiple servers, the shell started without
+// subcommand runs every command against them
+func (x *CtlCommand) isFanout(active *flags.Command) bool {
+	if active == nil || active.Name != "ctl" || active.Active == nil {
+		return false
+	}
+	servers, err := x.getServerURLs()
+	if err != nil {
+		fmt.Printf("Fail to read the servers file %s: %v\n", x.ServersFile, err)
+		os.Exit(1)
+	}
+	return len(servers) > 1
+}
+
+// the ctl subcommands whose output is printed as it is written when they are run against multiple servers,
+// the output of the others is printed server by server after they end
+var ctlStreamingCommands = map[string]bool{"tail": true, "maintail": true, "logtail": true}
+
+// run the ctl subcommand in a child process for every server at the same time, the output lines are
+// prefixed by the server and the records of the json, yaml or tsv output are merged with the server field.
+// The highest exit code of the children is returned
+func (x *CtlCommand) fanout(subcommand string) int {
+	if subcommand == "fg" {
+		fmt.Println("fg can not be run against multiple servers")
+		return 1
+	}
+	servers, _ := x.getServerURLs()
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	args := stripCtlServerOptions(os.Args[1:], x.structuredOutput())
+	ctlIndex := 0
+	for i, arg := range args {
+		if arg == "ctl" {
+			ctlIndex = i
+			break
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	codes := make([]int, len(servers))
+	outputs := make([]bytes.Buffer, len(servers))
+	for i, server := range servers {
+		serverArgs := append([]string{}, args[:ctlIndex+1]...)
+		serverArgs = append(serverArgs, "-s", server)
+		if x.structuredOutput() {
+			serverArgs = append(serverArgs, "-o", "json")
+		}
+		serverArgs = append(serverArgs, args[ctlIndex+1:]...)
+		cmd := exec.Command(executable, serverArgs...)
+		prefix := fmt.Sprintf("[%s] ", serverLabel(server))
+		stdout := &prefixWriter{prefix: prefix, out: os.Stdout, lock: &lock}
+		stderr := &prefixWriter{prefix: prefix, out: os.Stderr, lock: &lock}
+		if x.structuredOutput() {
+			cmd.Stdout = &outputs[i]
+		} else if ctlStreamingCommands[subcommand] {
+			cmd.Stdout = stdout
+		} else {
+			stdout.out = &outputs[i]
+			cmd.Stdout = stdout
+		}
+		cmd.Stderr = stderr
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			defer stdout.Flush()
+			defer stderr.Flush()
+			if err := cmd.Run(); err != nil {
+				codes[i] = 1
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					codes[i] = exitErr.ExitCode()
+				} else {
+					stderr.Write([]byte(fmt.Sprintf("Fail to run ctl against %s: %v\n", server, err)))
+				}
+			}
+		}(i, server)
+	}
+	wg.Wait()
+
+	code := 0
+	for i, server := range servers {
+		if codes[i] > code {
+			code = codes[i]
+		}
+		if !x.structuredOutput() {
+			os.Stdout.Write(outputs[i].Bytes())
+			continue
+		}
+		records, err := decodeCtlRecords(outputs[i].Bytes())
+		if err != nil {
+			if outputs[i].Len() > 0 {
+				fmt.Fprintf(os.Stderr, "[%s] Fail to parse the output: %v\n", serverLabel(server), err)
+			}
+			continue
+		}
+		for _, record := range records {
+			x.records = append(x.records, append([]ctlField{{"server", server}}, record...))
+		}
+	}
+	return code
+}
+
+// remove the -s, --serverurl and --servers-file options, and the -o and --output options if removeOutput is
+// true, from the command line arguments
+func stripCtlServerOptions(args []string, removeOutput bool) []string {
+	withValue := map[string]bool{"-s": true, "--serverurl": true, "--servers-file": true}
+	if removeOutput {
+		withValue["-o"] = true
+		withValue["--output"] = true
+	}
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+		if withValue[arg] {
+			i++
+			continue
+		}
+		name := arg
+		if pos := strings.Index(arg, "="); pos != -1 && strings.HasPrefix(arg, "--") {
+			name = arg[:pos]
+		} else if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+			name = arg[:2]
+		}
+		if name != arg && withValue[name] {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// get the host of the server URL, or the socket path for the unix URL
+func serverLabel(server string) string {
+	u, err := url.Parse(server)
+	if err != nil {
+		return server
+	}
+	if u.Host != "" {
+		return u.Host
+	}
+	if u.Path != "" {
+		return u.Path
+	}
+	return server
+}
+
+// decode the records printed by the json output, the fields are kept in order
+func decodeCtlRecords(data []byte) ([][]ctlField, error) {
+	var objects []json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	records := make([][]ctlField, 0, len(objects))
+	for _, object := range objects {
+		decoder := json.NewDecoder(bytes.NewReader(object))
+		decoder.UseNumber()
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		record := make([]ctlField, 0)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			record = append(record, ctlField{fmt.Sprint(key), value})
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// prefixWriter writes every line with the prefix, the lines of the writers sharing the lock are
+// not mixed
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	lock   *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		pos := bytes.IndexByte(w.buf, '\n')
+		if pos == -1 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:pos+1])
+		w.buf = w.buf[pos+1:]
+	}
+}
+
+// Flush write the last line not ended by newline
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.out.Write(append([]byte(w.prefix), line...))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripCtlServerOptions(t *testing.T) {
+	args := []string{"ctl", "-s", "http://a:9001", "--serverurl=http://b:9001", "-shttp://c:9001", "--servers-file", "servers.txt",
+		"-o", "yaml", "-u", "admin", "status", "web"}
+	expected := []string{"ctl", "-o", "yaml", "-u", "admin", "status", "web"}
+	if result := stripCtlServerOptions(args, false); !reflect.DeepEqual(result, expected) {
+		t.Errorf("the stripped arguments should be %v, but they are %v", expected, result)
+	}
+	expected = []string{"ctl", "-u", "admin", "status", "web"}
+	if result := stripCtlServerOptions(args, true); !reflect.DeepEqual(result, expected) {
+		t.Errorf("the stripped arguments should be %v, but they are %v", expected, result)
+	}
+}
+
+func TestDecodeCtlRecords(t *testing.T) {
+	records, err := decodeCtlRecords([]byte(`[{"name": "web", "pid": 12, "inuse": true}]`))
+	if err != nil {
+		t.Fatalf("Fail to decode the records: %v", err)
+	}
+	if len(records) != 1 || len(records[0]) != 3 || records[0][0].Key != "name" || records[0][1].Key != "pid" || records[0][2].Key != "inuse" {
+		t.Errorf("the fields should be decoded in order, but they are %v", records)
+	}
+	if _, err := decodeCtlRecords([]byte("web: RUNNING")); err == nil {
+		t.Error("the text output should not be decoded")
+	}
+}
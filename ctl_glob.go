@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ochinchina/supervisord/types"
+	"github.com/ochinchina/supervisord/xmlrpcclient"
+)
+
+// check if the program name is a pattern like worker-* or web:*
+func isProcessPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// expand the patterns like worker-* and web:* in the program names against the programs of supervisord
+// before the verb is applied to them, "all" is expanded only in the dry run. The names affected are printed
+// instead if --dry-run is given, false is returned then and the verb should not be applied
+func (x *CtlCommand) expandProcesses(rpcc *xmlrpcclient.XMLRPCClient, verb string, processes []string) ([]string, bool) {
+	needInfo := x.DryRun
+	for _, process := range processes {
+		needInfo = needInfo || isProcessPattern(process)
+	}
+	if !needInfo {
+		return processes, true
+	}
+	reply, err := rpcc.GetAllProcessInfo(ctlContext)
+	if err != nil {
+		x.printError("Fail to get the programs: %v\n", err)
+		x.exit(1)
+	}
+	expanded, unmatched := expandProcessPatterns(reply.Value, processes, x.DryRun)
+	for _, pattern := range unmatched {
+		x.print([]ctlField{{"name", pattern}, {"result", "failed"}, {"error", "no such process"}},
+			"%s: ERROR (no such process)\n", pattern)
+	}
+	if len(unmatched) > 0 {
+		x.exit(1)
+	}
+	if !x.DryRun {
+		return expanded, true
+	}
+	for _, name := range expanded {
+		x.print([]ctlField{{"name", name}, {"action", verb}}, "%s: would %s\n", name, verb)
+	}
+	return nil, false
+}
+
+// expand the patterns in the names to the names of the matched programs in the order of infos, group:name
+// if the group is not named after the program. The plain names are kept as they are and "all" is expanded
+// if expandAll is true. The patterns not matching any program are returned as unmatched
+func expandProcessPatterns(infos []types.ProcessInfo, names []string, expandAll bool) ([]string, []string) {
+	expanded := make([]string, 0)
+	unmatched := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			expanded = append(expanded, name)
+		}
+	}
+	for _, name := range names {
+		if !isProcessPattern(name) && !(expandAll && name == "all") {
+			add(name)
+			continue
+		}
+		matched := false
+		for _, info := range infos {
+			if name == "all" || matchProcessPattern(name, info) {
+				if info.Group == info.Name {
+					add(info.Name)
+				} else {
+					add(info.GetFullName())
+				}
+				matched = true
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, name)
+		}
+	}
+	return expanded, unmatched
+}
+
+// check if the program matches the pattern of the program name, or the pattern of group:program where
+// the empty program matches all the programs of the group
+func matchProcessPattern(pattern string, info types.ProcessInfo) bool {
+	if pos := strings.Index(pattern, ":"); pos != -1 {
+		program := pattern[pos+1:]
+		if program == "" {
+			program = "*"
+		}
+		groupMatched, _ := path.Match(pattern[:pos], info.Group)
+		programMatched, _ := path.Match(program, info.Name)
+		return groupMatched && programMatched
+	}
+	matched, _ := path.Match(pattern, info.Name)
+	return matched
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ochinchina/supervisord/types"
+)
+
+func TestExpandProcessPatterns(t *testing.T) {
+	infos := []types.ProcessInfo{{Name: "web", Group: "api"}, {Name: "worker-1", Group: "worker-1"},
+		{Name: "worker-2", Group: "worker-2"}, {Name: "cron", Group: "cron"}}
+	tests := []struct {
+		names     []string
+		expandAll bool
+		expanded  []string
+		unmatched []string
+	}{
+		{[]string{"worker-*", "api:*"}, false, []string{"worker-1", "worker-2", "api:web"}, []string{}},
+		{[]string{"cron", "worker-[2]", "worker-2"}, false, []string{"cron", "worker-2"}, []string{}},
+		{[]string{"api:", "all"}, false, []string{"api:", "all"}, []string{}},
+		{[]string{"all"}, true, []string{"api:web", "worker-1", "worker-2", "cron"}, []string{}},
+		{[]string{"db-*", "web"}, false, []string{"web"}, []string{"db-*"}},
+	}
+	for _, test := range tests {
+		expanded, unmatched := expandProcessPatterns(infos, test.names, test.expandAll)
+		if !reflect.DeepEqual(expanded, test.expanded) || !reflect.DeepEqual(unmatched, test.unmatched) {
+			t.Errorf("%v should be expanded to %v with %v unmatched, but it is %v with %v unmatched", test.names,
+				test.expanded, test.unmatched, expanded, unmatched)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ctlField a field of the record printed by the ctl subcommands in the machine-readable output
+type ctlField struct {
+	Key   string
+	Value interface{}
+}
+
+// check if the records are printed in the json, yaml or tsv output instead of the text
+func (x *CtlCommand) structuredOutput() bool {
+	return x.Output != "" && x.Output != "text"
+}
+
+// print the text in the text output, otherwise keep the record which is printed in the machine-readable
+// output when the subcommand ends
+func (x *CtlCommand) print(record []ctlField, format string, a ...interface{}) {
+	if x.structuredOutput() {
+		x.records = append(x.records, record)
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// print the error to stdout in the text output and to stderr in the machine-readable output, so the
+// output can still be parsed
+func (x *CtlCommand) printError(format string, a ...interface{}) {
+	if x.structuredOutput() {
+		fmt.Fprintf(os.Stderr, format, a...)
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// print the kept records and exit with the code
+func (x *CtlCommand) exit(code int) {
+	x.flushOutput()
+	os.Exit(code)
+}
+
+// print the kept records in the machine-readable output
+func (x *CtlCommand) flushOutput() {
+	if !x.structuredOutput() {
+		return
+	}
+	writeCtlRecords(os.Stdout, x.Output, x.records)
+	x.records = nil
+}
+
+// write the records in the json, yaml or tsv format, the fields are kept in order
+func writeCtlRecords(w io.Writer, format string, records [][]ctlField) {
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		buf.WriteString("[")
+		for i, record := range records {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("{")
+			for j, field := range record {
+				if j > 0 {
+					buf.WriteString(",")
+				}
+				key, _ := json.Marshal(field.Key)
+				value, _ := json.Marshal(field.Value)
+				buf.Write(key)
+				buf.WriteString(":")
+				buf.Write(value)
+			}
+			buf.WriteString("}")
+		}
+		buf.WriteString("]")
+		var out bytes.Buffer
+		json.Indent(&out, buf.Bytes(), "", "  ")
+		out.WriteString("\n")
+		w.Write(out.Bytes())
+	case "yaml":
+		if len(records) == 0 {
+			fmt.Fprintln(w, "[]")
+		}
+		for _, record := range records {
+			for j, field := range record {
+				prefix := "  "
+				if j == 0 {
+					prefix = "- "
+				}
+				// the quoted JSON string is a valid YAML scalar
+				value, _ := json.Marshal(field.Value)
+				fmt.Fprintf(w, "%s%s: %s\n", prefix, field.Key, value)
+			}
+		}
+	case "tsv":
+		columns := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, record := range records {
+			for _, field := range record {
+				if !seen[field.Key] {
+					seen[field.Key] = true
+					columns = append(columns, field.Key)
+				}
+			}
+		}
+		if len(columns) == 0 {
+			return
+		}
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+		escaper := strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+		for _, record := range records {
+			values := make(map[string]string)
+			for _, field := range record {
+				values[field.Key] = escaper.Replace(fmt.Sprint(field.Value))
+			}
+			row := make([]string, 0, len(columns))
+			for _, column := range columns {
+				row = append(row, values[column])
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jessevdk/go-flags"
+)
+
+// the file under the home directory keeping the ctl connection profiles
+var ctlProfilesFile = filepath.Join(".supervisord", "ctl.toml")
+
+// ctlProfile the connection settings of a profile
+type ctlProfile struct {
+	URL      string `toml:"url"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Token    string `toml:"token"`
+	CAFile   string `toml:"cafile"`
+	CertFile string `toml:"certfile"`
+	KeyFile  string `toml:"keyfile"`
+	Proxy    string `toml:"proxy"`
+	Insecure bool   `toml:"insecure"`
+}
+
+// fill the options not given in the command line from the profile selected by --profile, only the
+// ctl command uses the profiles so the other commands ignore it
+func (x *CtlCommand) applyProfile(active *flags.Command) {
+	if x.Profile == "" || active == nil || active.Name != "ctl" {
+		return
+	}
+	file := x.ProfilesFile
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Printf("Fail to find the home directory: %v\n", err)
+			os.Exit(1)
+		}
+		file = filepath.Join(home, ctlProfilesFile)
+	}
+	profile, err := loadCtlProfile(file, x.Profile)
+	if err != nil {
+		fmt.Printf("Fail to load the profile %s: %v\n", x.Profile, err)
+		os.Exit(1)
+	}
+	if len(x.ServerURL) == 0 && x.ServersFile == "" && profile.URL != "" {
+		x.ServerURL = []string{profile.URL}
+	}
+	for field, value := range map[*string]string{&x.User: profile.Username, &x.Password: profile.Password, &x.Token: profile.Token,
+		&x.CAFile: profile.CAFile, &x.CertFile: profile.CertFile, &x.KeyFile: profile.KeyFile, &x.Proxy: profile.Proxy} {
+		if *field == "" {
+			*field = value
+		}
+	}
+	if profile.Insecure {
+		x.Insecure = true
+	}
+}
+
+// load the profile from the TOML file, every profile is a table like:
+//
+//	[prod-web1]
+//	url = "https://web1.example.com:9001"
+//	username = "admin"
+//	password = "secret"
+//	cafile = "/etc/supervisord/ca.pem"
+func loadCtlProfile(file string, name string) (*ctlProfile, error) {
+	profiles := make(map[string]*ctlProfile)
+	meta, err := toml.DecodeFile(file, &profiles)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return nil, fmt.Errorf("unknown keys %s in %s", strings.Join(keys, ", "), file)
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile %s in %s", name, file)
+	}
+	return profile, nil
+}
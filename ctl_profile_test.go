@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCtlProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ctl.toml")
+	content := `# the daemons
+[prod-web1]
+url = "https://web1.example.com:9001" # the first web server
+username = "admin"
+password = 'p#ss"word'
+insecure = true
+
+["staging web"]
+token = "a\"b"
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := loadCtlProfile(file, "prod-web1")
+	if err != nil {
+		t.Fatalf("Fail to load the profile: %v", err)
+	}
+	if profile.URL != "https://web1.example.com:9001" || profile.Username != "admin" || profile.Password != `p#ss"word` || !profile.Insecure {
+		t.Errorf("the profile is not loaded correctly: %v", profile)
+	}
+	if profile, err = loadCtlProfile(file, "staging web"); err != nil || profile.Token != `a"b` || profile.Insecure {
+		t.Errorf("the quoted profile is not loaded correctly: %v, %v", profile, err)
+	}
+	if _, err = loadCtlProfile(file, "prod-web2"); err == nil {
+		t.Error("the missing profile should not be loaded")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("[prod]\nurl = \"http://a\"\nuser = \"admin\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadCtlProfile(file, "prod"); err == nil {
+		t.Error("the profile with unknown key should not be loaded")
+	}
+}
+
+func TestApplyProfileOnlyToCtl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ctl.toml")
+	if err := ioutil.WriteFile(file, []byte("[prod]\nurl = \"http://web1:9001\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the missing profile would exit if a non-ctl command used it
+	x := &CtlCommand{Profile: "missing", ProfilesFile: filepath.Join(dir, "missing.toml")}
+	x.applyProfile(parser.Find("version"))
+	if len(x.ServerURL) != 0 {
+		t.Errorf("the version command should ignore the profile, but get %v", x.ServerURL)
+	}
+
+	x = &CtlCommand{Profile: "prod", ProfilesFile: file}
+	x.applyProfile(parser.Find("ctl"))
+	if len(x.ServerURL) != 1 || x.ServerURL[0] != "http://web1:9001" {
+		t.Errorf("the ctl command should use the profile, but get %v", x.ServerURL)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ochinchina/supervisord/types"
+	"github.com/ochinchina/supervisord/xmlrpcclient"
+	"golang.org/x/term"
+)
+
+// the interval to poll the program states while all the programs are started or stopped
+const progressPollInterval = 200 * time.Millisecond
+
+// the spinner and the action shown in the progress line
+var progressSpinner = []string{"|", "/", "-", "\\"}
+var progressActions = map[string]string{"start": "starting", "stop": "stopping"}
+
+// the state a program reaches if it is started or stopped, and the other states it may end in
+var progressTargetStates = map[string]string{"start": "RUNNING", "stop": "STOPPED"}
+var progressDoneStates = map[string]map[string]bool{
+	"start": {"RUNNING": true, "FATAL": true, "EXITED": true},
+	"stop":  {"STOPPED": true, "EXITED": true, "FATAL": true},
+}
+
+// check if the progress of starting or stopping all the programs is shown, only in the text output to
+// the terminal
+func (x *CtlCommand) showProgress() bool {
+	return !x.structuredOutput() && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// start or stop all the programs and print the result of every program as soon as it ends in its state,
+// under them a live line counts the programs done. The states are polled until the call returns
+func (x *CtlCommand) changeAllProcessStateWithProgress(rpcc *xmlrpcclient.XMLRPCClient, verb string, state string) error {
+	reply, err := rpcc.GetAllProcessInfo(ctlContext)
+	if err != nil {
+		return err
+	}
+	progress := &bulkProgress{verb: verb, state: state, total: len(reply.Value), done: make(map[string]bool), initial: make(map[string]types.ProcessInfo)}
+	// the running programs are not started again and the programs not running are not stopped by supervisord
+	for _, info := range reply.Value {
+		progress.initial[info.GetFullName()] = info
+		statename := strings.ToUpper(info.Statename)
+		if statename == progressTargetStates[verb] || (verb == "stop" && progressDoneStates[verb][statename]) {
+			progress.finish(info, "already "+state)
+		}
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := rpcc.ChangeAllProcessState(ctlContext, verb)
+		result <- err
+	}()
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		progress.printStatus(frame)
+		select {
+		case err := <-result:
+			if reply, pollErr := rpcc.GetAllProcessInfo(ctlContext); pollErr == nil {
+				progress.update(reply.Value, true)
+			}
+			fmt.Print("\r\033[K")
+			if err == nil {
+				fmt.Printf("%d of %d programs %s\n", progress.succeeded, progress.total, state)
+			}
+			return err
+		case <-ticker.C:
+			if reply, err := rpcc.GetAllProcessInfo(ctlContext); err == nil {
+				progress.update(reply.Value, false)
+			}
+		}
+	}
+}
+
+// bulkProgress the programs done while all the programs are started or stopped
+type bulkProgress struct {
+	verb      string
+	state     string
+	total     int
+	succeeded int
+	done      map[string]bool
+	// the programs before they are started or stopped
+	initial map[string]types.ProcessInfo
+}
+
+// print the result of the programs which end in their states, or all the programs not done yet if final. A
+// program fails only after it leaves its state before the call, so the program FATAL before it is started is
+// not counted as failed before it is started again
+func (p *bulkProgress) update(infos []types.ProcessInfo, final bool) {
+	for _, info := range infos {
+		if p.done[info.GetFullName()] {
+			continue
+		}
+		statename := strings.ToUpper(info.Statename)
+		if statename == progressTargetStates[p.verb] || (p.verb == "stop" && statename == "EXITED") {
+			p.finish(info, p.state)
+		} else if final || progressDoneStates[p.verb][statename] && p.isChanged(info) {
+			result := fmt.Sprintf("ERROR (%s)", statename)
+			if description := info.Description; description != "" && strings.ToLower(description) != "<string></string>" {
+				result = fmt.Sprintf("ERROR (%s: %s)", statename, description)
+			}
+			p.finish(info, result)
+		}
+	}
+}
+
+// check if the program is started or stopped since the call, its state or its start or stop time is changed
+func (p *bulkProgress) isChanged(info types.ProcessInfo) bool {
+	initial, ok := p.initial[info.GetFullName()]
+	return !ok || initial.Statename != info.Statename || initial.Start != info.Start || initial.Stop != info.Stop
+}
+
+// print the result of the program over the progress line
+func (p *bulkProgress) finish(info types.ProcessInfo, result string) {
+	p.done[info.GetFullName()] = true
+	if !strings.HasPrefix(result, "ERROR") {
+		p.succeeded++
+	}
+	name := info.GetFullName()
+	if info.Group == info.Name {
+		name = info.Name
+	}
+	fmt.Printf("\r\033[K%s: %s\n", name, result)
+}
+
+// print the progress line like "| starting 3/10"
+func (p *bulkProgress) printStatus(frame int) {
+	fmt.Printf("\r\033[K%s %s %d/%d", progressSpinner[frame%len(progressSpinner)], progressActions[p.verb], len(p.done), p.total)
+}
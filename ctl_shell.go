@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ochinchina/supervisord/xmlrpcclient"
+	"golang.org/x/term"
+)
+
+// the file under the home directory keeping the command history of the ctl shell
+const ctlShellHistoryFile = ".supervisord_ctl_history"
+
+// the max number of the commands kept in the history
+const ctlShellMaxHistory = 1000
+
+// ctlShell the interactive shell started by ctl without subcommand, every command line is run as
+// a ctl subcommand with the same options
+type ctlShell struct {
+	rpcc        *xmlrpcclient.XMLRPCClient
+	history     []string
+	historyFile string
+}
+
+// shellTerminal the input and output of the terminal of the ctl shell, the output is discarded while
+// the saved history is replayed into the terminal
+type shellTerminal struct {
+	io.Reader
+	out io.Writer
+}
+
+func (t *shellTerminal) Write(b []byte) (int, error) {
+	return t.out.Write(b)
+}
+
+// start the interactive shell and run the commands until exit, quit or EOF
+func (x *CtlCommand) runShell() error {
+	sh := &ctlShell{rpcc: x.createRPCClient()}
+	if home, err := os.UserHomeDir(); err == nil {
+		sh.historyFile = filepath.Join(home, ctlShellHistoryFile)
+		sh.loadHistory()
+	}
+	defer sh.saveHistory()
+
+	readLine := sh.newLineReader(x.getPrompt() + "> ")
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		sh.addHistory(line)
+		switch args[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Printf("commands: %s, help, exit, quit\n", strings.Join(getCtlCommandNames(), ", "))
+		default:
+			sh.run(args)
+		}
+	}
+}
+
+// create the function reading a line after printing the prompt. If the input is a terminal, the line
+// is edited in the raw mode with the history by the up and down keys and the completion by the tab
+// key, otherwise the lines are read without editing
+func (sh *ctlShell) newLineReader(prompt string) func() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		reader := bufio.NewReader(os.Stdin)
+		return func() (string, error) {
+			fmt.Print(prompt)
+			line, err := reader.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return strings.TrimRight(line, "\r\n"), err
+		}
+	}
+
+	input := &shellTerminal{Reader: os.Stdin, out: ioutil.Discard}
+	if len(sh.history) > 0 {
+		input.Reader = io.MultiReader(strings.NewReader(strings.Join(sh.history, "\r")+"\r"), os.Stdin)
+	}
+	terminal := term.NewTerminal(input, "")
+	for range sh.history {
+		terminal.ReadLine()
+	}
+	input.out = os.Stdout
+	terminal.SetPrompt(prompt)
+	terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		completed, candidates := completeWord(line[:pos], sh.complete(line[:pos]))
+		if len(candidates) > 1 {
+			fmt.Fprintf(terminal, "%s\n", strings.Join(candidates, "  "))
+		}
+		return completed + line[pos:], len(completed), true
+	}
+	return func() (string, error) {
+		// the terminal is only in the raw mode while reading, so the commands get the normal terminal
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(fd, oldState)
+		return terminal.ReadLine()
+	}
+}
+
+// run the command line as the ctl subcommand in a child process, so the failed command does not
+// exit the shell and Ctrl-C only interrupts the command
+func (sh *ctlShell) run(args []string) {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	cmd := exec.Command(executable, append(os.Args[1:], args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Printf("Fail to run %s: %v\n", args[0], err)
+		}
+	}
+}
+
+// get the candidates to complete the last word of the line, the commands for the first word,
+// the programs, groups and "all" for the others
+func (sh *ctlShell) complete(line string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 || (len(words) == 1 && !strings.HasSuffix(line, " ")) {
+		return append(getCtlCommandNames(), "help", "exit", "quit")
+	}
+	if words[0] == "tail" {
+		// the words before the one being completed which are not the options
+		completed := words[1:]
+		if !strings.HasSuffix(line, " ") {
+			completed = completed[:len(completed)-1]
+		}
+		args := 0
+		for _, word := range completed {
+			if !strings.HasPrefix(word, "-") {
+				args++
+			}
+		}
+		if args == 1 {
+			return []string{"stdout", "stderr"}
+		}
+	}
+	reply, err := sh.rpcc.GetAllProcessInfo(ctlContext)
+	if err != nil {
+		return nil
+	}
+	candidates := []string{"all"}
+	groups := make(map[string]bool)
+	for _, pinfo := range reply.Value {
+		candidates = append(candidates, pinfo.Name)
+		if pinfo.Group != pinfo.Name {
+			candidates = append(candidates, pinfo.GetFullName())
+		}
+		if !groups[pinfo.Group] {
+			groups[pinfo.Group] = true
+			candidates = append(candidates, pinfo.Group+":*")
+		}
+	}
+	return candidates
+}
+
+// load the history saved by the previous shells
+func (sh *ctlShell) loadHistory() {
+	b, err := ioutil.ReadFile(sh.historyFile)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) != "" {
+			sh.addHistory(line)
+		}
+	}
+}
+
+// add the line to the history, the same line as the last one is not added again
+func (sh *ctlShell) addHistory(line string) {
+	if len(sh.history) > 0 && sh.history[len(sh.history)-1] == line {
+		return
+	}
+	sh.history = append(sh.history, line)
+	if len(sh.history) > ctlShellMaxHistory {
+		sh.history = sh.history[len(sh.history)-ctlShellMaxHistory:]
+	}
+}
+
+// save the latest commands of the history
+func (sh *ctlShell) saveHistory() {
+	if sh.historyFile == "" || len(sh.history) == 0 {
+		return
+	}
+	if err := ioutil.WriteFile(sh.historyFile, []byte(strings.Join(sh.history, "\n")+"\n"), 0600); err != nil {
+		fmt.Printf("Fail to save the history to %s: %v\n", sh.historyFile, err)
+	}
+}
+
+// get the names of the ctl subcommands
+func getCtlCommandNames() []string {
+	names := make([]string, 0)
+	if ctlCmd := parser.Find("ctl"); ctlCmd != nil {
+		for _, cmd := range ctlCmd.Commands() {
+			names = append(names, cmd.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// complete the last word of the line by the candidates starting with it, the word is extended to
+// the common prefix of the matched candidates and followed by a space if only one is matched. The
+// matched candidates are returned to be listed
+func completeWord(line string, candidates []string) (string, []string) {
+	start := strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+	matched := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && !seen[candidate] {
+			seen[candidate] = true
+			matched = append(matched, candidate)
+		}
+	}
+	if len(matched) == 0 {
+		return line, matched
+	}
+	if len(matched) == 1 {
+		return line[:start] + matched[0] + " ", matched
+	}
+	sort.Strings(matched)
+	prefix := matched[0]
+	for _, candidate := range matched[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return line[:start] + prefix, matched
+}
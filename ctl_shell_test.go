@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleteWord(t *testing.T) {
+	candidates := []string{"start", "status", "stop", "web:*", "web:web-1"}
+	tests := []struct {
+		line      string
+		completed string
+		matched   []string
+	}{
+		{"sto", "stop ", []string{"stop"}},
+		{"sta", "sta", []string{"start", "status"}},
+		{"stop we", "stop web:", []string{"web:*", "web:web-1"}},
+		{"stop x", "stop x", []string{}},
+	}
+	for _, test := range tests {
+		completed, matched := completeWord(test.line, candidates)
+		if completed != test.completed || !reflect.DeepEqual(matched, test.matched) {
+			t.Errorf("%q should be completed to %q with %v, but got %q with %v", test.line, test.completed, test.matched, completed, matched)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ochinchina/supervisord/types"
+)
+
+func TestStatusExitCode(t *testing.T) {
+	infos := []types.ProcessInfo{{Name: "web", Group: "web", Statename: "Running"},
+		{Name: "worker-1", Group: "worker", Statename: "Stopped"},
+		{Name: "cron", Group: "cron", Statename: "Fatal"}}
+	tests := []struct {
+		processes []string
+		code      int
+	}{
+		{[]string{"web"}, statusAllRunning},
+		{[]string{"web", "worker:*"}, statusNotRunning},
+		{nil, statusFailed},
+		{[]string{"all"}, statusFailed},
+		{[]string{"web", "nosuch"}, statusNoSuchProcess},
+	}
+	var x CtlCommand
+	for _, test := range tests {
+		if code := x.statusExitCode(infos, test.processes); code != test.code {
+			t.Errorf("the exit code of status %v should be %d, but it is %d", test.processes, test.code, code)
+		}
+	}
+}
+
+func TestWriteCtlRecords(t *testing.T) {
+	records := [][]ctlField{{{"name", "web"}, {"pid", 12}},
+		{{"name", "worker\t1"}, {"pid", 0}, {"error", "no such process"}}}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"json", "[\n  {\n    \"name\": \"web\",\n    \"pid\": 12\n  },\n  {\n    \"name\": \"worker\\t1\",\n    \"pid\": 0,\n    \"error\": \"no such process\"\n  }\n]\n"},
+		{"yaml", "- name: \"web\"\n  pid: 12\n- name: \"worker\\t1\"\n  pid: 0\n  error: \"no such process\"\n"},
+		{"tsv", "name\tpid\terror\nweb\t12\t\nworker\\t1\t0\tno such process\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		writeCtlRecords(&buf, test.format, records)
+		if buf.String() != test.expected {
+			t.Errorf("the %s output should be %q, but it is %q", test.format, test.expected, buf.String())
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{12 * time.Second, "12s"},
+		{5*time.Minute + 3*time.Second, "5m 3s"},
+		{2*time.Hour + 5*time.Minute + 9*time.Second, "2h 5m"},
+		{76*time.Hour + 30*time.Minute, "3d 4h"},
+	}
+	for _, test := range tests {
+		if s := humanizeDuration(test.duration); s != test.expected {
+			t.Errorf("the duration %v should be humanized to %s, but it is %s", test.duration, test.expected, s)
+		}
+	}
+}
+
+func TestSupervisorctlConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supervisord.conf")
+	conf := "[supervisorctl]\nserverurl=unix:///tmp/supervisor.sock\nusername=chris\npassword=123\nprompt=web1\n"
+	if err := ioutil.WriteFile(confFile, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configuration := options.Configuration
+	defer func() { options.Configuration = configuration }()
+	options.Configuration = confFile
+
+	x := CtlCommand{User: "admin"}
+	if url := x.getServerURL(); url != "unix:///tmp/supervisor.sock" {
+		t.Errorf("the serverurl should be read from the supervisorctl section, but it is %s", url)
+	}
+	if user := x.getUser(); user != "admin" {
+		t.Errorf("the user given by the option should be used, but it is %s", user)
+	}
+	if password := x.getPassword(); password != "123" {
+		t.Errorf("the password should be read from the supervisorctl section, but it is %s", password)
+	}
+	if prompt := x.getPrompt(); prompt != "web1" {
+		t.Errorf("the prompt should be read from the supervisorctl section, but it is %s", prompt)
+	}
+}
+
+func TestBulkProgressUpdate(t *testing.T) {
+	web := types.ProcessInfo{Name: "web", Group: "web", Statename: "FATAL", Start: 100, Stop: 101}
+	api := types.ProcessInfo{Name: "api", Group: "api", Statename: "STOPPED"}
+	progress := &bulkProgress{verb: "start", state: "started", total: 2, done: map[string]bool{},
+		initial: map[string]types.ProcessInfo{"web:web": web, "api:api": api}}
+	progress.update([]types.ProcessInfo{web, api}, false)
+	if len(progress.done) != 0 {
+		t.Errorf("the FATAL program not started yet should not be done, but get %v", progress.done)
+	}
+	web.Start, web.Stop = 200, 201
+	api.Statename = "RUNNING"
+	progress.update([]types.ProcessInfo{web, api}, false)
+	if !progress.done["web:web"] || !progress.done["api:api"] || progress.succeeded != 1 {
+		t.Errorf("the program failed again should be done as failed, but get %v %d", progress.done, progress.succeeded)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventSinkClient publishes the messages to the message broker
+type eventSinkClient interface {
+	publish(topic string, payload []byte) error
+	close()
+}
+
+// the settings to connect the message broker
+type eventSinkOptions struct {
+	// the address of broker like "host:port"
+	addr     string
+	username string
+	password string
+	// the token of NATS
+	token    string
+	clientID string
+	timeout  time.Duration
+	// retain the last message of topic in MQTT broker
+	retain bool
+	// connect the broker over TLS, the NATS connection is also upgraded to TLS if the server requires it
+	tls       bool
+	tlsConfig *tls.Config
+}
+
+// get the TLS configuration to connect the broker, the server name is the host of addr if it is not set
+func (o eventSinkOptions) clientTLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{}
+	if o.tlsConfig != nil {
+		tlsConfig = o.tlsConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(o.addr)
+	}
+	return tlsConfig
+}
+
+// start the TLS handshake on the connection before its deadline
+func upgradeToTLS(conn net.Conn, options eventSinkOptions) (net.Conn, error) {
+	tlsConn := tls.Client(conn, options.clientTLSConfig())
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// natsClient publishes the messages by the text protocol of NATS
+type natsClient struct {
+	conn    net.Conn
+	timeout time.Duration
+	// held while writing to the connection
+	lock sync.Mutex
+	err  error
+}
+
+func dialNATS(options eventSinkOptions) (*natsClient, error) {
+	conn, err := net.DialTimeout("tcp", options.addr, options.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(options.timeout))
+	reader := bufio.NewReader(conn)
+	// the server sends INFO first
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, fmt.Errorf("fail to read INFO from NATS server: %v %s", err, line)
+	}
+	info := struct {
+		TLSRequired bool `json:"tls_required"`
+	}{}
+	json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "INFO"))), &info)
+	if options.tls || info.TLSRequired {
+		if conn, err = upgradeToTLS(conn, options); err != nil {
+			return nil, err
+		}
+		reader = bufio.NewReader(conn)
+	}
+	connectOptions := map[string]interface{}{"verbose": false, "pedantic": false, "name": options.clientID, "lang": "go"}
+	if options.username != "" {
+		connectOptions["user"] = options.username
+		connectOptions["pass"] = options.password
+	}
+	if options.token != "" {
+		connectOptions["auth_token"] = options.token
+	}
+	connect, _ := json.Marshal(connectOptions)
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the server replies PONG if it accepts the connection, -ERR if not
+	for {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if strings.HasPrefix(line, "PONG") {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("NATS server rejects the connection: %s", strings.TrimSpace(line))
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	c := &natsClient{conn: conn, timeout: options.timeout}
+	go c.readLoop(reader)
+	return c, nil
+}
+
+// reply the PING of server to keep the connection
+func (c *natsClient) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if strings.HasPrefix(line, "PING") {
+			c.write([]byte("PONG\r\n"))
+		} else if strings.HasPrefix(line, "-ERR") {
+			c.fail(fmt.Errorf("NATS server error: %s", strings.TrimSpace(line)))
+			return
+		}
+	}
+}
+
+func (c *natsClient) publish(topic string, payload []byte) error {
+	msg := bytes.NewBufferString(fmt.Sprintf("PUB %s %d\r\n", topic, len(payload)))
+	msg.Write(payload)
+	msg.WriteString("\r\n")
+	return c.write(msg.Bytes())
+}
+
+func (c *natsClient) write(data []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.err = err
+		c.conn.Close()
+	}
+	return c.err
+}
+
+// mark the connection as failed, the following publishing returns the error
+func (c *natsClient) fail(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err == nil {
+		c.err = err
+		c.conn.Close()
+	}
+}
+
+func (c *natsClient) close() {
+	c.fail(io.ErrClosedPipe)
+}
+
+// the MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttPingReq    = 0xc0
+	mqttDisconnect = 0xe0
+)
+
+// the keep alive of MQTT connection, the PINGREQ is sent every half of it
+const mqttKeepAlive = 60 * time.Second
+
+// mqttClient publishes the messages with QoS 0 by MQTT 3.1.1
+type mqttClient struct {
+	conn    net.Conn
+	timeout time.Duration
+	retain  bool
+	// held while writing to the connection
+	lock sync.Mutex
+	err  error
+	stop chan struct{}
+}
+
+func dialMQTT(options eventSinkOptions) (*mqttClient, error) {
+	conn, err := net.DialTimeout("tcp", options.addr, options.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(options.timeout))
+	if options.tls {
+		if conn, err = upgradeToTLS(conn, options); err != nil {
+			return nil, err
+		}
+	}
+
+	// CONNECT with clean session
+	flags := byte(0x02)
+	payload := mqttString(options.clientID)
+	if options.username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(options.username)...)
+		if options.password != "" {
+			flags |= 0x40
+			payload = append(payload, mqttString(options.password)...)
+		}
+	}
+	packet := append(mqttString("MQTT"), 4, flags)
+	packet = append(packet, byte(mqttKeepAlive/time.Second>>8), byte(mqttKeepAlive/time.Second&0xff))
+	packet = append(packet, payload...)
+	if _, err = conn.Write(mqttPacket(mqttConnect, packet)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// CONNACK with return code 0 if the connection is accepted
+	ack := make([]byte, 4)
+	if _, err = io.ReadFull(conn, ack); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ack[0] != mqttConnAck || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("MQTT broker rejects the connection with return code %d", ack[3])
+	}
+	conn.SetDeadline(time.Time{})
+	c := &mqttClient{conn: conn, timeout: options.timeout, retain: options.retain, stop: make(chan struct{})}
+	go c.readLoop()
+	go c.keepAlive()
+	return c, nil
+}
+
+// encode the string as 2 bytes length followed by the UTF-8 bytes
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s) & 0xff)}, s...)
+}
+
+// add the fixed header to the packet
+func mqttPacket(packetType byte, packet []byte) []byte {
+	header := []byte{packetType}
+	// the remaining length is encoded with 7 bits per byte
+	length := len(packet)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		header = append(header, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(header, packet...)
+}
+
+// discard the PINGRESP and detect the closed connection
+func (c *mqttClient) readLoop() {
+	_, err := io.Copy(io.Discard, c.conn)
+	if err == nil {
+		err = io.EOF
+	}
+	c.fail(err)
+}
+
+func (c *mqttClient) keepAlive() {
+	ticker := time.NewTicker(mqttKeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if c.write([]byte{mqttPingReq, 0}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *mqttClient) publish(topic string, payload []byte) error {
+	packetType := byte(mqttPublish)
+	if c.retain {
+		packetType |= 0x01
+	}
+	return c.write(mqttPacket(packetType, append(mqttString(topic), payload...)))
+}
+
+func (c *mqttClient) write(data []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.err = err
+		c.conn.Close()
+	}
+	return c.err
+}
+
+// mark the connection as failed, the following publishing returns the error
+func (c *mqttClient) fail(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err == nil {
+		c.err = err
+		c.conn.Close()
+		close(c.stop)
+	}
+}
+
+func (c *mqttClient) close() {
+	c.write([]byte{mqttDisconnect, 0})
+	c.fail(io.ErrClosedPipe)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// create the TLS configurations of the fake broker on 127.0.0.1 and the client trusting its certificate
+func newTestBrokerTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1),
+		Subject:     pkix.Name{CommonName: "broker"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(time.Hour),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, &tls.Config{RootCAs: roots}
+}
+
+// start the fake broker handling one connection, the error of the handler is sent to the returned channel
+func startTestBroker(t *testing.T, handler func(conn net.Conn) error) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		result <- handler(conn)
+	}()
+	return listener.Addr().String(), result
+}
+
+// read the lines from the NATS client until the PUB message and return them
+func readNATSMessages(conn net.Conn) ([]string, error) {
+	reader := bufio.NewReader(conn)
+	lines := make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, strings.TrimSpace(line))
+		if strings.HasPrefix(line, "PING") {
+			conn.Write([]byte("PONG\r\n"))
+		}
+		if strings.HasPrefix(line, "PUB") {
+			payload, err := reader.ReadString('\n')
+			return append(lines, strings.TrimSpace(payload)), err
+		}
+	}
+}
+
+func TestNATSClient(t *testing.T) {
+	serverTLS, clientTLS := newTestBrokerTLS(t)
+	for _, tlsRequired := range []bool{false, true} {
+		var lines []string
+		addr, result := startTestBroker(t, func(conn net.Conn) (err error) {
+			if tlsRequired {
+				conn.Write([]byte(`INFO {"server_id":"test","tls_required":true}` + "\r\n"))
+				tlsConn := tls.Server(conn, serverTLS)
+				if err = tlsConn.Handshake(); err != nil {
+					return err
+				}
+				conn = tlsConn
+			} else {
+				conn.Write([]byte(`INFO {"server_id":"test"}` + "\r\n"))
+			}
+			lines, err = readNATSMessages(conn)
+			return err
+		})
+		client, err := dialNATS(eventSinkOptions{addr: addr, username: "user", password: "secret", timeout: 5 * time.Second, tlsConfig: clientTLS})
+		if err != nil {
+			t.Fatalf("fail to connect the NATS server with tls_required %v: %v", tlsRequired, err)
+		}
+		if err = client.publish("supervisord.web", []byte(`{"event":"PROCESS_STATE_RUNNING"}`)); err != nil {
+			t.Fatal(err)
+		}
+		if err = <-result; err != nil {
+			t.Fatal(err)
+		}
+		client.close()
+		if len(lines) != 4 || !strings.Contains(lines[0], `"user":"user"`) || lines[2] != "PUB supervisord.web 33" || lines[3] != `{"event":"PROCESS_STATE_RUNNING"}` {
+			t.Errorf("the NATS server should get CONNECT, PING and PUB, but get %v", lines)
+		}
+	}
+}
+
+// read one MQTT control packet and return its type and body
+func readMQTTPacket(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(reader, b); err != nil {
+			return 0, nil, err
+		}
+		length += int(b[0]&0x7f) * multiplier
+		multiplier *= 128
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(reader, body)
+	return header[0], body, err
+}
+
+func TestMQTTClient(t *testing.T) {
+	serverTLS, clientTLS := newTestBrokerTLS(t)
+	var connect, publish []byte
+	var publishType byte
+	addr, result := startTestBroker(t, func(conn net.Conn) (err error) {
+		conn = tls.Server(conn, serverTLS)
+		if _, connect, err = readMQTTPacket(conn); err != nil {
+			return err
+		}
+		conn.Write([]byte{mqttConnAck, 2, 0, 0})
+		publishType, publish, err = readMQTTPacket(conn)
+		return err
+	})
+	client, err := dialMQTT(eventSinkOptions{addr: addr, clientID: "supervisord-test", timeout: 5 * time.Second, retain: true, tls: true, tlsConfig: clientTLS})
+	if err != nil {
+		t.Fatalf("fail to connect the MQTT broker over TLS: %v", err)
+	}
+	if err = client.publish("supervisord/web", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-result; err != nil {
+		t.Fatal(err)
+	}
+	client.close()
+	if !strings.Contains(string(connect), "MQTT") || !strings.HasSuffix(string(connect), "supervisord-test") {
+		t.Errorf("the MQTT broker should get CONNECT with the client id, but get %q", connect)
+	}
+	if publishType != mqttPublish|0x01 || string(publish) != "\x00\x0fsupervisord/web{}" {
+		t.Errorf("the MQTT broker should get the retained PUBLISH, but get %x %q", publishType, publish)
+	}
+}
+
+func TestEventSinkTopic(t *testing.T) {
+	payload := EventWebhookPayload{EventName: "PROCESS_STATE_RUNNING", Fields: map[string]string{"groupname": "web.v2", "processname": "api/#1 >x"}}
+	nats := &EventSink{protocol: "nats", server: "host.example.com", topic: "supervisord.{server}.{group}.{program}.{event}"}
+	if topic := nats.createTopic(payload); topic != "supervisord.host_example_com.web_v2.api/#1__x.PROCESS_STATE_RUNNING" {
+		t.Errorf("the separators and wildcards of NATS should be escaped, but get %s", topic)
+	}
+	mqtt := &EventSink{protocol: "mqtt", server: "host.example.com", topic: "supervisord/{server}/{group}/{program}"}
+	if topic := mqtt.createTopic(payload); topic != "supervisord/host.example.com/web.v2/api__1_>x" {
+		t.Errorf("the separators and wildcards of MQTT should be escaped, but get %s", topic)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/events"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "node",
+		Subsystem: "supervisord",
+		Name:      "event_webhook_deliveries_total",
+		Help:      "The number of events delivered by the event webhook",
+	}, []string{"name"})
+	webhookFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "node",
+		Subsystem: "supervisord",
+		Name:      "event_webhook_failures_total",
+		Help:      "The number of events failed to be delivered by the event webhook after all the retries or discarded because the buffer is full",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(webhookDeliveries, webhookFailures)
+}
+
+// EventWebhookPayload the JSON posted to the webhook or published to the event sink for an event
+type EventWebhookPayload struct {
+	Ver       string `json:"ver"`
+	Server    string `json:"server"`
+	Serial    uint64 `json:"serial"`
+	Pool      string `json:"pool"`
+	EventName string `json:"eventname"`
+	Time      int64  `json:"time"`
+	// the "key:value" fields in the first line of the event body
+	Fields map[string]string `json:"fields"`
+	// the whole event body
+	Payload string `json:"payload"`
+}
+
+// EventWebhook posts the events configured in the [eventwebhook:x] section as JSON to the url
+type EventWebhook struct {
+	name          string
+	server        string
+	url           string
+	secret        string
+	retries       int
+	retryInterval time.Duration
+	client        *http.Client
+	queue         chan events.Event
+	stop          chan struct{}
+}
+
+// NewEventWebhook creates EventWebhook object from the [eventwebhook:x] section and starts to deliver the events
+func NewEventWebhook(server string, entry *config.Entry) *EventWebhook {
+	w := &EventWebhook{name: entry.GetEventWebhookName(),
+		server:        server,
+		url:           entry.GetString("url", ""),
+		secret:        entry.GetString("secret", ""),
+		retries:       entry.GetInt("retries", 3),
+		retryInterval: entry.GetDuration("retry_interval", time.Second),
+		client:        &http.Client{Timeout: entry.GetDuration("timeout", 10*time.Second)},
+		queue:         make(chan events.Event, entry.GetInt("buffer_size", 100)),
+		stop:          make(chan struct{})}
+	go w.run()
+	return w
+}
+
+// HandleEvent queues the event to be delivered, the event is discarded if the buffer is full
+func (w *EventWebhook) HandleEvent(event events.Event) {
+	select {
+	case w.queue <- event:
+	default:
+		log.WithFields(log.Fields{"eventWebhook": w.name, "event": event.GetType()}).Error("events reaches the buffer_size of webhook, discard the event")
+		webhookFailures.WithLabelValues(w.name).Inc()
+	}
+}
+
+// Stop stops delivering the events, the queued events are discarded
+func (w *EventWebhook) Stop() {
+	close(w.stop)
+}
+
+func (w *EventWebhook) run() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case event := <-w.queue:
+			w.deliver(event)
+		}
+	}
+}
+
+// post the event and retry with doubled interval if it fails
+func (w *EventWebhook) deliver(event events.Event) {
+	body, err := json.Marshal(newEventPayload(w.server, w.name, event))
+	if err != nil {
+		log.WithFields(log.Fields{"eventWebhook": w.name, log.ErrorKey: err}).Error("fail to encode the event")
+		webhookFailures.WithLabelValues(w.name).Inc()
+		return
+	}
+	interval := w.retryInterval
+	for i := 0; i <= w.retries; i++ {
+		if i > 0 {
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(interval):
+			}
+			interval *= 2
+		}
+		if err = w.post(event.GetType(), body); err == nil {
+			webhookDeliveries.WithLabelValues(w.name).Inc()
+			return
+		}
+		log.WithFields(log.Fields{"eventWebhook": w.name, "event": event.GetType(), log.ErrorKey: err}).Warn("fail to post the event to webhook")
+	}
+	log.WithFields(log.Fields{"eventWebhook": w.name, "event": event.GetType()}).Error("fail to deliver the event to webhook after all the retries")
+	webhookFailures.WithLabelValues(w.name).Inc()
+}
+
+// create the JSON payload of the event, the pool is the name of webhook or sink
+func newEventPayload(server string, pool string, event events.Event) EventWebhookPayload {
+	body := event.GetBody()
+	return EventWebhookPayload{Ver: events.EventSysVersion,
+		Server:    server,
+		Serial:    event.GetSerial(),
+		Pool:      pool,
+		EventName: event.GetType(),
+		Time:      time.Now().Unix(),
+		Fields:    events.ParseEventFields(body),
+		Payload:   body}
+}
+
+// post the JSON body with the HMAC-SHA256 signature of body in X-Supervisord-Signature header if secret is set
+func (w *EventWebhook) post(eventType string, body []byte) error {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Supervisord-Event", eventType)
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set("X-Supervisord-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook returns status %s", resp.Status)
+	}
+	return nil
+}
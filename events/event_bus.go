@@ -0,0 +1,63 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SubscriptionBufferSize the number of events buffered in the channel returned by Subscribe, the
+// new events are discarded if the subscriber does not receive them in time
+const SubscriptionBufferSize = 100
+
+// subscription delivers the events to the channel of a subscriber
+type subscription struct {
+	name string
+	ch   chan Event
+}
+
+var subscriptionsLock sync.Mutex
+var subscriptions = make(map[<-chan Event]*subscription)
+var subscriptionSerial uint64
+
+// Subscribe subscribes the events for the Go programs embedding supervisord. The events can be the
+// abstract events like PROCESS_STATE, all the events are subscribed if no event is given. The events
+// are the typed objects like *ProcessStateEvent, so the subscriber gets the fields of the event
+// without parsing the body. The channel must be released by Unsubscribe
+func Subscribe(events ...string) <-chan Event {
+	if len(events) == 0 {
+		events = []string{"EVENT"}
+	}
+	s := &subscription{name: fmt.Sprintf("subscription:%d", atomic.AddUint64(&subscriptionSerial, 1)),
+		ch: make(chan Event, SubscriptionBufferSize)}
+
+	subscriptionsLock.Lock()
+	subscriptions[s.ch] = s
+	subscriptionsLock.Unlock()
+	RegisterEventHandler(s.name, events, s)
+	return s.ch
+}
+
+// Unsubscribe stops delivering the events to the channel returned by Subscribe and closes it
+func Unsubscribe(ch <-chan Event) {
+	subscriptionsLock.Lock()
+	s, ok := subscriptions[ch]
+	delete(subscriptions, ch)
+	subscriptionsLock.Unlock()
+	if ok {
+		// no event is being delivered to the channel after it is unregistered
+		UnregisterEventHandler(s.name)
+		close(s.ch)
+	}
+}
+
+// HandleEvent sends the event to the channel without blocking the other event handlers
+func (s *subscription) HandleEvent(event Event) {
+	select {
+	case s.ch <- event:
+	default:
+		log.WithFields(log.Fields{"subscription": s.name, "event": event.GetType()}).Warn("the subscriber does not receive the events in time, discard the event")
+	}
+}
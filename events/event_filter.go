@@ -0,0 +1,334 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EventFilter the filter expression evaluated with the fields of an event before it is sent to the
+// event handler, like "group == 'web' && expected == false". The identifiers are the "key:value"
+// fields in the first line of the event body, "eventname" is the name of the event, and "group",
+// "program" and "event" are the short names of groupname, processname and eventname. The
+// operators are ||, &&, !, ==, !=, <, <=, >, >=, =~ (matches the regular expression) and parentheses
+type EventFilter struct {
+	expr string
+	root filterNode
+}
+
+// the kinds of the values in the filter expression
+const (
+	filterString = iota
+	filterNumber
+	filterBool
+)
+
+type filterValue struct {
+	kind int
+	str  string
+	b    bool
+}
+
+type filterNode func(fields map[string]string) filterValue
+
+var filterFieldAliases = map[string]string{"group": "groupname",
+	"program": "processname",
+	"process": "processname",
+	"event":   "eventname"}
+
+// NewEventFilter parses the filter expression, nil is returned without error if the expression is empty
+func NewEventFilter(expr string) (*EventFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s in event filter", p.tokens[p.pos].text)
+	}
+	return &EventFilter{expr: expr, root: root}, nil
+}
+
+// String returns the filter expression
+func (f *EventFilter) String() string {
+	return f.expr
+}
+
+// Match checks if the event matches the filter
+func (f *EventFilter) Match(event Event) bool {
+	fields := ParseEventFields(event.GetBody())
+	if _, ok := fields["eventname"]; !ok {
+		fields["eventname"] = event.GetType()
+	}
+	return f.root(fields).toBool()
+}
+
+// ParseEventFields parses the "key:value" fields in the first line of the event body
+func ParseEventFields(body string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Fields(strings.SplitN(body, "\n", 2)[0]) {
+		if kv := strings.SplitN(field, ":", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
+// the value is true if it is not empty, "0" or "false"
+func (v filterValue) toBool() bool {
+	if v.kind == filterBool {
+		return v.b
+	}
+	return v.str != "" && v.str != "0" && strings.ToLower(v.str) != "false"
+}
+
+func compareFilterValues(op string, a filterValue, b filterValue) bool {
+	if a.kind == filterBool || b.kind == filterBool {
+		switch op {
+		case "==":
+			return a.toBool() == b.toBool()
+		case "!=":
+			return a.toBool() != b.toBool()
+		}
+		return false
+	}
+	result := strings.Compare(a.str, b.str)
+	if x, err := strconv.ParseFloat(a.str, 64); err == nil {
+		if y, err := strconv.ParseFloat(b.str, 64); err == nil {
+			result = 0
+			if x < y {
+				result = -1
+			} else if x > y {
+				result = 1
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	}
+	return false
+}
+
+// the kinds of the tokens in the filter expression
+const (
+	tokenIdent = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type filterToken struct {
+	kind int
+	text string
+}
+
+var filterOperators = []string{"||", "&&", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in event filter")
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '-':
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			found := false
+			for _, op := range filterOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, filterToken{kind: tokenOperator, text: op})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q in event filter", r)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// check if the next token is the operator and skip it
+func (p *filterParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(fields map[string]string) filterValue {
+			return filterValue{kind: filterBool, b: l(fields).toBool() || right(fields).toBool()}
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(fields map[string]string) filterValue {
+			return filterValue{kind: filterBool, b: l(fields).toBool() && right(fields).toBool()}
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(fields map[string]string) filterValue {
+			return filterValue{kind: filterBool, b: !node(fields).toBool()}
+		}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("=~") {
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenString {
+			return nil, fmt.Errorf("the right side of =~ must be a string in event filter")
+		}
+		pattern, err := regexp.Compile(p.tokens[p.pos].text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		return func(fields map[string]string) filterValue {
+			return filterValue{kind: filterBool, b: pattern.MatchString(left(fields).str)}
+		}, nil
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			op := op
+			return func(fields map[string]string) filterValue {
+				return filterValue{kind: filterBool, b: compareFilterValues(op, left(fields), right(fields))}
+			}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of event filter")
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) in event filter")
+		}
+		return node, nil
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case tokenString:
+		value := filterValue{kind: filterString, str: token.text}
+		return func(fields map[string]string) filterValue { return value }, nil
+	case tokenNumber:
+		if _, err := strconv.ParseFloat(token.text, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %s in event filter", token.text)
+		}
+		value := filterValue{kind: filterNumber, str: token.text}
+		return func(fields map[string]string) filterValue { return value }, nil
+	case tokenIdent:
+		switch token.text {
+		case "true", "false":
+			value := filterValue{kind: filterBool, b: token.text == "true"}
+			return func(fields map[string]string) filterValue { return value }, nil
+		}
+		name := token.text
+		if alias, ok := filterFieldAliases[name]; ok {
+			name = alias
+		}
+		return func(fields map[string]string) filterValue {
+			return filterValue{kind: filterString, str: fields[name]}
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s in event filter", token.text)
+}
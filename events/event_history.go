@@ -0,0 +1,231 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EventRecord an emitted event kept in the event history
+type EventRecord struct {
+	Serial    uint64    `json:"serial"`
+	EventName string    `json:"eventname"`
+	Time      time.Time `json:"time"`
+	Body      string    `json:"body"`
+}
+
+// keeps the last emitted events in memory and appends them to the journal file
+type eventHistory struct {
+	lock sync.Mutex
+	// the ring of the kept events, next is the position of the next event
+	records []*EventRecord
+	next    int
+	count   int
+	// the journal file, the journal is rotated to "<journal>.1" if it exceeds journalMaxBytes
+	journalFile     string
+	journalMaxBytes int64
+	journal         *os.File
+	journalBytes    int64
+}
+
+var history = &eventHistory{}
+
+// SetEventHistory keeps the last size emitted events except the TICK events in memory, 0 to
+// disable the history. If journalFile is not empty, the events are appended to it as JSON lines
+// and the last events in it are loaded when the journal is set, so the history survives restarts
+func SetEventHistory(size int, journalFile string, journalMaxBytes int64) error {
+	return history.setup(size, journalFile, journalMaxBytes)
+}
+
+// GetEventHistory gets the kept events in the order they are emitted. The events are filtered by
+// the comma separated names like "PROCESS_STATE_FATAL" or the abstract ones like "PROCESS_STATE",
+// and by "key:value" fields like "processname:web" in the first line of the event body. The events
+// emitted before since are skipped if since is not zero
+func GetEventHistory(filter string, since time.Time) []EventRecord {
+	eventNames := make([]string, 0)
+	fields := make(map[string]string)
+	for _, item := range strings.Split(filter, ",") {
+		item = strings.TrimSpace(item)
+		if kv := strings.SplitN(item, ":", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		} else if item != "" {
+			eventNames = append(eventNames, item)
+		}
+	}
+
+	result := make([]EventRecord, 0)
+	for _, record := range history.getRecords() {
+		if !since.IsZero() && record.Time.Before(since) {
+			continue
+		}
+		if len(eventNames) > 0 && !isEventOf(record.EventName, eventNames) {
+			continue
+		}
+		if len(fields) > 0 && !hasEventFields(record.Body, fields) {
+			continue
+		}
+		result = append(result, *record)
+	}
+	return result
+}
+
+// check if the event is one of the events or derives from one of them
+func isEventOf(eventName string, events []string) bool {
+	for _, event := range events {
+		if event == eventName {
+			return true
+		}
+		for _, derive := range eventTypeDerives[eventName] {
+			if derive == event {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasEventFields(body string, fields map[string]string) bool {
+	bodyFields := ParseEventFields(body)
+	for k, v := range fields {
+		if bodyFields[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *eventHistory) setup(size int, journalFile string, journalMaxBytes int64) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+	if size != len(h.records) {
+		records := h.doGetRecords()
+		h.records = make([]*EventRecord, size)
+		h.next = 0
+		h.count = 0
+		for _, record := range records {
+			h.add(record)
+		}
+	}
+	h.journalMaxBytes = journalMaxBytes
+	if journalFile == h.journalFile {
+		return nil
+	}
+	if h.journal != nil {
+		h.journal.Close()
+		h.journal = nil
+	}
+	h.journalFile = journalFile
+	if journalFile == "" {
+		return nil
+	}
+	h.loadJournal()
+	journal, err := os.OpenFile(journalFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	h.journal = journal
+	if info, err := journal.Stat(); err == nil {
+		h.journalBytes = info.Size()
+	}
+	return nil
+}
+
+// load the events in the journal to the history, the lock must be held
+func (h *eventHistory) loadJournal() {
+	if len(h.records) == 0 {
+		return
+	}
+	file, err := os.Open(h.journalFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		record := &EventRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err == nil {
+			h.add(record)
+		}
+	}
+}
+
+// add the record to the ring, the lock must be held
+func (h *eventHistory) add(record *EventRecord) {
+	if len(h.records) == 0 {
+		return
+	}
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.count < len(h.records) {
+		h.count++
+	}
+}
+
+func (h *eventHistory) record(event Event) {
+	if strings.HasPrefix(event.GetType(), "TICK_") {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.records) == 0 && h.journal == nil {
+		return
+	}
+	record := &EventRecord{Serial: event.GetSerial(), EventName: event.GetType(), Time: time.Now(), Body: event.GetBody()}
+	h.add(record)
+	if h.journal != nil {
+		h.writeJournal(record)
+	}
+}
+
+// append the record to the journal, the lock must be held
+func (h *eventHistory) writeJournal(record *EventRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	if h.journalMaxBytes > 0 && h.journalBytes > 0 && h.journalBytes+int64(len(line)) > h.journalMaxBytes {
+		h.journal.Close()
+		os.Rename(h.journalFile, h.journalFile+".1")
+		journal, err := os.OpenFile(h.journalFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			log.WithFields(log.Fields{"file": h.journalFile, log.ErrorKey: err}).Error("fail to rotate the event journal")
+			h.journal = nil
+			h.journalFile = ""
+			return
+		}
+		h.journal = journal
+		h.journalBytes = 0
+	}
+	if n, err := h.journal.Write(line); err == nil {
+		h.journalBytes += int64(n)
+	} else {
+		log.WithFields(log.Fields{"file": h.journalFile, log.ErrorKey: err}).Error("fail to write the event journal")
+	}
+}
+
+func (h *eventHistory) getRecords() []*EventRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.doGetRecords()
+}
+
+// get the records from the oldest to the newest, the lock must be held
+func (h *eventHistory) doGetRecords() []*EventRecord {
+	result := make([]*EventRecord, 0, h.count)
+	for i := 0; i < h.count; i++ {
+		result = append(result, h.records[(h.next-h.count+i+len(h.records))%len(h.records)])
+	}
+	return result
+}
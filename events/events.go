@@ -49,12 +49,21 @@ func (be *BaseEvent) GetType() string {
 	return be.eventType
 }
 
+// EventHandler handles the emitted events, like the EventListener sending the events to the
+// event listener program
+type EventHandler interface {
+	HandleEvent(event Event)
+}
+
 // EventListenerManager manage the event listeners
 type EventListenerManager struct {
+	lock sync.RWMutex
 	// mapping between the event listener name and the listener
-	namedListeners map[string]*EventListener
-	// mapping between the event name and the event listeners
-	eventListeners map[string]map[*EventListener]bool
+	namedListeners map[string]EventHandler
+	// mapping between the event name and the event listeners with their names
+	eventListeners map[string]map[EventHandler]string
+	// mapping between the event listener name and its filter
+	filters map[string]*EventFilter
 }
 
 // EventPoolSerial manage the event serial generation
@@ -80,7 +89,21 @@ func (eps *EventPoolSerial) nextSerial(pool string) uint64 {
 	return r
 }
 
-// EventListener the event listener object
+// the states of the event listener in the protocol
+const (
+	// the listener has acknowledged the last event and is expected to send READY
+	listenerAcknowledged = "ACKNOWLEDGED"
+	// the listener is ready to receive an event
+	listenerReady = "READY"
+	// an event is sent to the listener and its result is not received yet
+	listenerBusy = "BUSY"
+	// the listener fails to follow the protocol or exits
+	listenerUnknown = "UNKNOWN"
+)
+
+// EventListener the event listener object. The events are sent one by one to the listener
+// after it sends READY, and an event is removed only after the listener sends "RESULT 2\nOK".
+// The FAILed events are sent again
 type EventListener struct {
 	pool       string
 	server     string
@@ -89,6 +112,18 @@ type EventListener struct {
 	stdin      *bufio.Reader
 	stdout     io.Writer
 	bufferSize int
+	// the event sent to the listener and waiting for the result
+	current *bufferedEvent
+	state   string
+	// true if the events are taken over by the listener of the restarted program
+	stopped bool
+}
+
+// bufferedEvent the encoded event waiting to be sent to the listener
+type bufferedEvent struct {
+	eventType string
+	serial    uint64
+	data      []byte
 }
 
 // NewEventListener creates NewEventListener object
@@ -103,35 +138,76 @@ func NewEventListener(pool string,
 		events:     list.New(),
 		stdin:      bufio.NewReader(stdin),
 		stdout:     stdout,
-		bufferSize: bufferSize}
+		bufferSize: bufferSize,
+		state:      listenerAcknowledged}
 	evtListener.start()
 	return evtListener
 }
 
-func (el *EventListener) getFirstEvent() ([]byte, bool) {
+// take the event not acknowledged and the buffered events of the old listener of the same pool,
+// so they are sent to the restarted listener program. The old listener is stopped
+func (el *EventListener) takeOver(old *EventListener) {
+	old.cond.L.Lock()
+	defer old.cond.L.Unlock()
 	el.cond.L.Lock()
+	defer el.cond.L.Unlock()
 
+	for elem := old.events.Back(); elem != nil; elem = elem.Prev() {
+		el.events.PushFront(elem.Value)
+	}
+	if old.current != nil {
+		el.events.PushFront(old.current)
+	}
+	old.events.Init()
+	old.current = nil
+	old.stopped = true
+	old.cond.Broadcast()
+	if el.events.Len() > 0 {
+		log.WithFields(log.Fields{"eventListener": el.pool, "events": el.events.Len()}).Info("the buffered events are sent to the restarted event listener")
+		el.cond.Signal()
+	}
+}
+
+func (el *EventListener) setState(state string) {
+	el.cond.L.Lock()
 	defer el.cond.L.Unlock()
+	el.changeState(state)
+}
 
-	for el.events.Len() <= 0 {
-		el.cond.Wait()
+// change the state of listener, the lock must be held
+func (el *EventListener) changeState(state string) {
+	if el.state != state {
+		log.WithFields(log.Fields{"eventListener": el.pool, "from": el.state, "to": state}).Debug("event listener state changed")
+		el.state = state
 	}
+}
 
-	if el.events.Len() > 0 {
-		elem := el.events.Front()
-		value := elem.Value
-		b, ok := value.([]byte)
-		return b, ok
+// wait for the next event and take it as the event waiting for result, false if the listener is stopped
+func (el *EventListener) nextEvent() (*bufferedEvent, bool) {
+	el.cond.L.Lock()
+	defer el.cond.L.Unlock()
+
+	for el.events.Len() <= 0 && !el.stopped {
+		el.cond.Wait()
+	}
+	if el.stopped {
+		return nil, false
 	}
-	return nil, false
+	el.current = el.events.Remove(el.events.Front()).(*bufferedEvent)
+	el.changeState(listenerBusy)
+	return el.current, true
 }
 
-func (el *EventListener) removeFirstEvent() {
+// finish the event waiting for result, the event is buffered again to be sent later if it is not
+// acknowledged by the listener
+func (el *EventListener) finishEvent(acknowledged bool) {
 	el.cond.L.Lock()
 	defer el.cond.L.Unlock()
-	if el.events.Len() > 0 {
-		el.events.Remove(el.events.Front())
+
+	if !acknowledged && el.current != nil {
+		el.events.PushFront(el.current)
 	}
+	el.current = nil
 }
 
 func (el *EventListener) start() {
@@ -141,32 +217,37 @@ func (el *EventListener) start() {
 			err := el.waitForReady()
 			if err != nil {
 				log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to read from event listener, the event listener may exit")
-				break
+				el.setState(listenerUnknown)
+				return
 			}
-			for {
-				if b, ok := el.getFirstEvent(); ok {
-					_, err := el.stdout.Write(b)
-					if err != nil {
-						log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to send event")
-						break
-					}
-					result, err := el.readResult()
-					if err != nil {
-						log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to read result")
-						break
-					}
-					if result == "OK" { // remove the event if succeed
-						log.WithFields(log.Fields{"eventListener": el.pool}).Info("succeed to send the event")
-						el.removeFirstEvent()
-						break
-					} else if result == "FAIL" {
-						log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to send the event")
-						break
-					} else {
-						log.WithFields(log.Fields{"eventListener": el.pool, "result": result}).Warn("unknown result from listener")
-					}
-				}
+			event, ok := el.nextEvent()
+			if !ok {
+				return
+			}
+			if _, err = el.stdout.Write(event.data); err != nil {
+				log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to send event")
+				el.finishEvent(false)
+				el.setState(listenerUnknown)
+				return
 			}
+			result, err := el.readResult()
+			if err != nil {
+				log.WithFields(log.Fields{"eventListener": el.pool}).Warn("fail to read result")
+				el.finishEvent(false)
+				el.setState(listenerUnknown)
+				return
+			}
+			if result == "OK" { // remove the event if succeed
+				log.WithFields(log.Fields{"eventListener": el.pool}).Info("succeed to send the event")
+				el.finishEvent(true)
+			} else if result == "FAIL" {
+				log.WithFields(log.Fields{"eventListener": el.pool}).Warn("the event is rejected by the listener, send it again")
+				el.finishEvent(false)
+			} else {
+				log.WithFields(log.Fields{"eventListener": el.pool, "result": result}).Warn("unknown result from listener, send the event again")
+				el.finishEvent(false)
+			}
+			el.setState(listenerAcknowledged)
 		}
 	}()
 }
@@ -180,8 +261,10 @@ func (el *EventListener) waitForReady() error {
 		}
 		if line == "READY\n" {
 			log.WithFields(log.Fields{"eventListener": el.pool}).Debug("the event listener is ready")
+			el.setState(listenerReady)
 			return nil
 		}
+		log.WithFields(log.Fields{"eventListener": el.pool, "line": strings.TrimSpace(line)}).Warn("ignore the unexpected output of the event listener")
 	}
 }
 
@@ -215,17 +298,27 @@ func (el *EventListener) readResult() (string, error) {
 	return "", fmt.Errorf("Fail to read the result")
 }
 
-// HandleEvent handles emitted event
+// HandleEvent handles emitted event. If the buffer is full, the oldest buffered event is discarded
+// and an EVENT_BUFFER_OVERFLOW event is emitted, but the EVENT_BUFFER_OVERFLOW event itself is
+// discarded instead of the buffered events
 func (el *EventListener) HandleEvent(event Event) {
-	encodedEvent := el.encodeEvent(event)
+	encodedEvent := &bufferedEvent{eventType: event.GetType(),
+		serial: event.GetSerial(),
+		data:   el.encodeEvent(event)}
 	el.cond.L.Lock()
 	defer el.cond.L.Unlock()
-	if el.events.Len() <= el.bufferSize {
-		el.events.PushBack(encodedEvent)
-		el.cond.Signal()
-	} else {
-		log.WithFields(log.Fields{"eventListener": el.pool}).Error("events reaches the bufferSize, discard the events")
+	if el.events.Len() >= el.bufferSize && el.events.Len() > 0 {
+		if event.GetType() == "EVENT_BUFFER_OVERFLOW" {
+			log.WithFields(log.Fields{"eventListener": el.pool}).Warn("events reaches the bufferSize, discard the buffer overflow event")
+			return
+		}
+		dropped := el.events.Remove(el.events.Front()).(*bufferedEvent)
+		log.WithFields(log.Fields{"eventListener": el.pool, "event": dropped.eventType, "serial": dropped.serial}).Error("events reaches the bufferSize, discard the oldest event")
+		// the listeners are locked by the emitter of this event
+		go EmitEvent(CreateEventBufferOverflowEvent(el.pool, el.bufferSize, dropped.eventType, dropped.serial))
 	}
+	el.events.PushBack(encodedEvent)
+	el.cond.Signal()
 }
 
 func (el *EventListener) encodeEvent(event Event) []byte {
@@ -267,7 +360,13 @@ var eventTypeDerives = map[string][]string{
 	"TICK_60":                          {"EVENT", "TICK"},
 	"TICK_3600":                        {"EVENT", "TICK"},
 	"PROCESS_GROUP_ADDED":              {"EVENT", "PROCESS_GROUP"},
-	"PROCESS_GROUP_REMOVED":            {"EVENT", "PROCESS_GROUP"}}
+	"PROCESS_GROUP_REMOVED":            {"EVENT", "PROCESS_GROUP"},
+	"PROCESS_MEMORY_EXCEEDED":          {"EVENT"},
+	"PROCESS_SCHEDULED_RESTART":        {"EVENT"},
+	"PROCESS_STANDBY_ACTIVATED":        {"EVENT", "PROCESS_STANDBY"},
+	"PROCESS_STANDBY_DEACTIVATED":      {"EVENT", "PROCESS_STANDBY"},
+	"PROCESS_SPAWN_ERROR":              {"EVENT"},
+	"EVENT_BUFFER_OVERFLOW":            {"EVENT"}}
 var eventSerial uint64
 var eventListenerManager = NewEventListenerManager()
 var eventPoolSerial = NewEventPoolSerial()
@@ -307,15 +406,35 @@ func nextEventSerial() uint64 {
 
 // NewEventListenerManager creates EventListenerManager object
 func NewEventListenerManager() *EventListenerManager {
-	return &EventListenerManager{namedListeners: make(map[string]*EventListener),
-		eventListeners: make(map[string]map[*EventListener]bool)}
+	return &EventListenerManager{namedListeners: make(map[string]EventHandler),
+		eventListeners: make(map[string]map[EventHandler]string),
+		filters:        make(map[string]*EventFilter)}
 }
 
 func (em *EventListenerManager) registerEventListener(eventListenerName string,
 	events []string,
 	listener *EventListener) {
+	em.registerEventHandler(eventListenerName, events, nil, listener)
+}
 
+func (em *EventListenerManager) registerEventHandler(eventListenerName string,
+	events []string,
+	filter *EventFilter,
+	listener EventHandler) {
+	em.lock.Lock()
+	defer em.lock.Unlock()
+
+	old := em.doUnregisterEventListener(eventListenerName)
+	// the listener program is restarted, send the events not handled by the old one to the new one
+	oldListener, oldOk := old.(*EventListener)
+	newListener, newOk := listener.(*EventListener)
+	if oldOk && newOk {
+		newListener.takeOver(oldListener)
+	}
 	em.namedListeners[eventListenerName] = listener
+	if filter != nil {
+		em.filters[eventListenerName] = filter
+	}
 	allEvents := make(map[string]bool)
 	for _, event := range events {
 		for k, values := range eventTypeDerives {
@@ -333,9 +452,9 @@ func (em *EventListenerManager) registerEventListener(eventListenerName string,
 	for event := range allEvents {
 		log.WithFields(log.Fields{"eventListener": eventListenerName, "event": event}).Info("register event listener")
 		if _, ok := em.eventListeners[event]; !ok {
-			em.eventListeners[event] = make(map[*EventListener]bool)
+			em.eventListeners[event] = make(map[EventHandler]string)
 		}
-		em.eventListeners[event][listener] = true
+		em.eventListeners[event][listener] = eventListenerName
 	}
 }
 
@@ -346,10 +465,41 @@ func RegisterEventListener(eventListenerName string,
 	eventListenerManager.registerEventListener(eventListenerName, events, listener)
 }
 
+// RegisterEventHandler registers the handler by name to accept the emitted events, the events
+// can be the abstract events like PROCESS_STATE. The handler registered before with the same
+// name is replaced
+func RegisterEventHandler(name string, events []string, handler EventHandler) {
+	eventListenerManager.registerEventHandler(name, events, nil, handler)
+}
+
+// RegisterFilteredEventHandler registers the handler like RegisterEventHandler, only the events
+// matching the filter are sent to the handler if the filter is not nil
+func RegisterFilteredEventHandler(name string, events []string, filter *EventFilter, handler EventHandler) {
+	eventListenerManager.registerEventHandler(name, events, filter, handler)
+}
+
+// UnregisterEventHandler unregisters the event handler by its name
+func UnregisterEventHandler(name string) {
+	eventListenerManager.unregisterEventHandler(name)
+}
+
 func (em *EventListenerManager) unregisterEventListener(eventListenerName string) *EventListener {
+	listener, _ := em.unregisterEventHandler(eventListenerName).(*EventListener)
+	return listener
+}
+
+func (em *EventListenerManager) unregisterEventHandler(name string) EventHandler {
+	em.lock.Lock()
+	defer em.lock.Unlock()
+	return em.doUnregisterEventListener(name)
+}
+
+// unregister the event listener, the lock must be held
+func (em *EventListenerManager) doUnregisterEventListener(eventListenerName string) EventHandler {
 	listener, ok := em.namedListeners[eventListenerName]
 	if ok {
 		delete(em.namedListeners, eventListenerName)
+		delete(em.filters, eventListenerName)
 		for event, listeners := range em.eventListeners {
 			if _, ok = listeners[listener]; ok {
 				log.WithFields(log.Fields{"eventListener": eventListenerName, "event": event}).Info("unregister event listener")
@@ -369,11 +519,16 @@ func UnregisterEventListener(eventListenerName string) *EventListener {
 
 // EmitEvent emits event to all listeners managed by this manager
 func (em *EventListenerManager) EmitEvent(event Event) {
+	em.lock.RLock()
+	defer em.lock.RUnlock()
 	listeners, ok := em.eventListeners[event.GetType()]
 	if ok {
 		log.WithFields(log.Fields{"event": event.GetType()}).Info("process event")
-		for listener := range listeners {
-			log.WithFields(log.Fields{"eventListener": listener.pool, "event": event.GetType()}).Info("receive event on listener")
+		for listener, name := range listeners {
+			if filter, ok := em.filters[name]; ok && !filter.Match(event) {
+				continue
+			}
+			log.WithFields(log.Fields{"eventListener": name, "event": event.GetType()}).Info("receive event on listener")
 			listener.HandleEvent(event)
 		}
 	}
@@ -426,8 +581,9 @@ func (p *ProcCommEvent) GetBody() string {
 	return fmt.Sprintf("processname:%s groupname:%s pid:%d\n%s", p.processName, p.groupName, p.pid, p.data)
 }
 
-// EmitEvent emits event to default event listener manager
+// EmitEvent emits event to default event listener manager, and keeps it in the event history
 func EmitEvent(event Event) {
+	history.record(event)
 	eventListenerManager.EmitEvent(event)
 }
 
@@ -458,28 +614,37 @@ type ProcCommEventCapture struct {
 	procName        string
 	groupName       string
 	pid             int
-	eventBuffer     string
-	eventBeginPos   int
+	// the data not processed yet, it may end with a partial token
+	eventBuffer string
+	// true if the begin token is found and the end token is not found yet
+	capturing bool
 }
 
-// NewProcCommEventCapture creates new ProcCommEventCapture object
+// NewProcCommEventCapture creates new ProcCommEventCapture object capturing the events from reader
 func NewProcCommEventCapture(reader io.Reader,
 	captureMaxBytes int,
 	stdType string,
 	procName string,
 	groupName string) *ProcCommEventCapture {
-	pec := &ProcCommEventCapture{reader: reader,
-		captureMaxBytes: captureMaxBytes,
-		stdType:         stdType,
-		procName:        procName,
-		groupName:       groupName,
-		pid:             -1,
-		eventBuffer:     "",
-		eventBeginPos:   -1}
+	pec := NewInlineProcCommEventCapture(captureMaxBytes, stdType, procName, groupName)
+	pec.reader = reader
 	pec.startCapture()
 	return pec
 }
 
+// NewInlineProcCommEventCapture creates new ProcCommEventCapture object capturing the events from
+// the data passed to Capture
+func NewInlineProcCommEventCapture(captureMaxBytes int,
+	stdType string,
+	procName string,
+	groupName string) *ProcCommEventCapture {
+	return &ProcCommEventCapture{captureMaxBytes: captureMaxBytes,
+		stdType:   stdType,
+		procName:  procName,
+		groupName: groupName,
+		pid:       -1}
+}
+
 // SetPid sets pid of the program
 func (pec *ProcCommEventCapture) SetPid(pid int) {
 	pec.pid = pid
@@ -493,60 +658,57 @@ func (pec *ProcCommEventCapture) startCapture() {
 			if err != nil {
 				break
 			}
-			pec.eventBuffer += string(buf[0:n])
-			for {
-				event := pec.captureEvent()
-				if event == nil {
-					break
-				}
-				EmitEvent(event)
-			}
+			pec.Capture(buf[0:n])
 		}
 	}()
 }
 
-func (pec *ProcCommEventCapture) captureEvent() Event {
-	pec.findBeginStr()
-	endPos := pec.findEndStr()
-	if endPos == -1 {
-		return nil
-	}
-	data := pec.eventBuffer[pec.eventBeginPos+len(ProcCommonBeginStr) : endPos]
-	pec.eventBuffer = pec.eventBuffer[endPos+len(ProcCommonEndStr):]
-	pec.eventBeginPos = -1
-	return NewProcCommEvent(pec.stdType,
-		pec.procName,
-		pec.groupName,
-		pec.pid,
-		data)
-}
-
-func (pec *ProcCommEventCapture) findBeginStr() {
-	if pec.eventBeginPos == -1 {
-		pec.eventBeginPos = strings.Index(pec.eventBuffer, ProcCommonBeginStr)
-		if pec.eventBeginPos == -1 {
-			// remove some string
-			n := len(pec.eventBuffer)
-			if n > len(ProcCommonBeginStr) {
-				pec.eventBuffer = pec.eventBuffer[n-len(ProcCommonBeginStr):]
+// Capture emits the data between the <!--XSUPERVISOR:BEGIN--> and <!--XSUPERVISOR:END--> tokens
+// as process communication events, and returns the data out of the tokens. The data which
+// may be the beginning of a token is held until more data is passed
+func (pec *ProcCommEventCapture) Capture(data []byte) []byte {
+	pec.eventBuffer += string(data)
+	result := make([]byte, 0, len(data))
+	for {
+		if !pec.capturing {
+			pos := strings.Index(pec.eventBuffer, ProcCommonBeginStr)
+			if pos == -1 {
+				n := len(pec.eventBuffer) - partialTokenLen(pec.eventBuffer, ProcCommonBeginStr)
+				result = append(result, pec.eventBuffer[0:n]...)
+				pec.eventBuffer = pec.eventBuffer[n:]
+				return result
+			}
+			result = append(result, pec.eventBuffer[0:pos]...)
+			pec.eventBuffer = pec.eventBuffer[pos+len(ProcCommonBeginStr):]
+			pec.capturing = true
+		}
+		pos := strings.Index(pec.eventBuffer, ProcCommonEndStr)
+		if pos == -1 {
+			if len(pec.eventBuffer) > pec.captureMaxBytes+len(ProcCommonEndStr) {
+				log.WithFields(log.Fields{"program": pec.procName}).Warn("The capture buffer is overflow, discard the content")
+				pec.capturing = false
+				pec.eventBuffer = ""
 			}
+			return result
 		}
+		EmitEvent(NewProcCommEvent(pec.stdType,
+			pec.procName,
+			pec.groupName,
+			pec.pid,
+			pec.eventBuffer[0:pos]))
+		pec.eventBuffer = pec.eventBuffer[pos+len(ProcCommonEndStr):]
+		pec.capturing = false
 	}
 }
 
-func (pec *ProcCommEventCapture) findEndStr() int {
-	if pec.eventBeginPos == -1 {
-		return -1
-	}
-	endPos := strings.Index(pec.eventBuffer, ProcCommonEndStr)
-	if endPos == -1 {
-		if len(pec.eventBuffer) > pec.captureMaxBytes {
-			log.WithFields(log.Fields{"program": pec.procName}).Warn("The capture buffer is overflow, discard the content")
-			pec.eventBeginPos = -1
-			pec.eventBuffer = ""
+// get the length of the longest suffix of s which is the beginning of token
+func partialTokenLen(s string, token string) int {
+	for n := len(token) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, token[0:n]) {
+			return n
 		}
 	}
-	return endPos
+	return 0
 }
 
 // ProcessStateEvent process state event definition
@@ -704,6 +866,36 @@ func (pse *ProcessStateEvent) GetBody() string {
 	return body
 }
 
+// GetProcessName returns the name of process
+func (pse *ProcessStateEvent) GetProcessName() string {
+	return pse.processName
+}
+
+// GetGroupName returns the group of process
+func (pse *ProcessStateEvent) GetGroupName() string {
+	return pse.groupName
+}
+
+// GetFromState returns the state of process before this state
+func (pse *ProcessStateEvent) GetFromState() string {
+	return pse.fromState
+}
+
+// GetTries returns the number of starting tries, -1 if it is not in STARTING or BACKOFF state
+func (pse *ProcessStateEvent) GetTries() int {
+	return pse.tries
+}
+
+// GetExpected returns 1 if the exit code of process is expected and 0 if not, -1 if it is not in EXITED state
+func (pse *ProcessStateEvent) GetExpected() int {
+	return pse.expected
+}
+
+// GetPid returns the pid of process, 0 if it is not known
+func (pse *ProcessStateEvent) GetPid() int {
+	return pse.pid
+}
+
 // SupervisorStateChangeEvent supervisor state change event
 type SupervisorStateChangeEvent struct {
 	BaseEvent
@@ -722,7 +914,8 @@ func CreateSupervisorStateChangeRunning() *SupervisorStateChangeEvent {
 	return r
 }
 
-func createSupervisorStateChangeStopping() *SupervisorStateChangeEvent {
+// CreateSupervisorStateChangeStopping creates SupervisorStateChangeEvent object emitted when supervisord is stopping
+func CreateSupervisorStateChangeStopping() *SupervisorStateChangeEvent {
 	r := &SupervisorStateChangeEvent{}
 	r.eventType = "SUPERVISOR_STATE_CHANGE_STOPPING"
 	r.serial = nextEventSerial()
@@ -736,14 +929,17 @@ type ProcessLogEvent struct {
 	groupName   string
 	pid         int
 	data        string
+	// stdout or stderr
+	channel string
 }
 
 // GetBody returns body of process log event
 func (pe *ProcessLogEvent) GetBody() string {
-	return fmt.Sprintf("processname:%s groupname:%s pid:%d\n%s",
+	return fmt.Sprintf("processname:%s groupname:%s pid:%d channel:%s\n%s",
 		pe.processName,
 		pe.groupName,
 		pe.pid,
+		pe.channel,
 		pe.data)
 }
 
@@ -755,6 +951,7 @@ func CreateProcessLogStdoutEvent(processName string,
 	r := &ProcessLogEvent{processName: processName,
 		groupName: groupName,
 		pid:       pid,
+		channel:   "stdout",
 		data:      data}
 	r.eventType = "PROCESS_LOG_STDOUT"
 	r.serial = nextEventSerial()
@@ -769,6 +966,7 @@ func CreateProcessLogStderrEvent(processName string,
 	r := &ProcessLogEvent{processName: processName,
 		groupName: groupName,
 		pid:       pid,
+		channel:   "stderr",
 		data:      data}
 	r.eventType = "PROCESS_LOG_STDERR"
 	r.serial = nextEventSerial()
@@ -786,6 +984,11 @@ func (pe *ProcessGroupEvent) GetBody() string {
 	return fmt.Sprintf("groupname:%s", pe.groupName)
 }
 
+// GetGroupName returns the name of the added or removed group
+func (pe *ProcessGroupEvent) GetGroupName() string {
+	return pe.groupName
+}
+
 // CreateProcessGroupAddedEvent emits create process group added event
 func CreateProcessGroupAddedEvent(groupName string) *ProcessGroupEvent {
 	r := &ProcessGroupEvent{groupName: groupName}
@@ -803,3 +1006,159 @@ func CreateProcessGroupRemovedEvent(groupName string) *ProcessGroupEvent {
 	r.serial = nextEventSerial()
 	return r
 }
+
+// ProcessMemoryEvent the event emitted when the memory of process exceeds its max_memory
+type ProcessMemoryEvent struct {
+	BaseEvent
+	processName string
+	groupName   string
+	pid         int
+	rss         int
+	maxMemory   int
+}
+
+// GetBody returns body of process memory event
+func (pe *ProcessMemoryEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s pid:%d rss:%d max_memory:%d",
+		pe.processName,
+		pe.groupName,
+		pe.pid,
+		pe.rss,
+		pe.maxMemory)
+}
+
+// CreateProcessMemoryExceededEvent creates process memory exceeded event
+func CreateProcessMemoryExceededEvent(processName string,
+	groupName string,
+	pid int,
+	rss int,
+	maxMemory int) *ProcessMemoryEvent {
+	r := &ProcessMemoryEvent{processName: processName,
+		groupName: groupName,
+		pid:       pid,
+		rss:       rss,
+		maxMemory: maxMemory}
+	r.eventType = "PROCESS_MEMORY_EXCEEDED"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// ProcessScheduledRestartEvent the event emitted when the process is restarted by its restart_cron
+type ProcessScheduledRestartEvent struct {
+	BaseEvent
+	processName string
+	groupName   string
+	pid         int
+}
+
+// GetBody returns body of process scheduled restart event
+func (pe *ProcessScheduledRestartEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s pid:%d",
+		pe.processName,
+		pe.groupName,
+		pe.pid)
+}
+
+// CreateProcessScheduledRestartEvent creates process scheduled restart event
+func CreateProcessScheduledRestartEvent(processName string, groupName string, pid int) *ProcessScheduledRestartEvent {
+	r := &ProcessScheduledRestartEvent{processName: processName,
+		groupName: groupName,
+		pid:       pid}
+	r.eventType = "PROCESS_SCHEDULED_RESTART"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// ProcessStandbyEvent the event emitted when the standby program takes over or gives back to its primary
+type ProcessStandbyEvent struct {
+	BaseEvent
+	processName string
+	groupName   string
+	primary     string
+}
+
+// GetBody returns body of process standby event
+func (pe *ProcessStandbyEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s primary:%s",
+		pe.processName,
+		pe.groupName,
+		pe.primary)
+}
+
+// CreateProcessStandbyActivatedEvent creates the event emitted when the standby program is started
+// because its primary is in FATAL state
+func CreateProcessStandbyActivatedEvent(processName string, groupName string, primary string) *ProcessStandbyEvent {
+	r := &ProcessStandbyEvent{processName: processName,
+		groupName: groupName,
+		primary:   primary}
+	r.eventType = "PROCESS_STANDBY_ACTIVATED"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// CreateProcessStandbyDeactivatedEvent creates the event emitted when the standby program is
+// stopped because its primary is running again
+func CreateProcessStandbyDeactivatedEvent(processName string, groupName string, primary string) *ProcessStandbyEvent {
+	r := &ProcessStandbyEvent{processName: processName,
+		groupName: groupName,
+		primary:   primary}
+	r.eventType = "PROCESS_STANDBY_DEACTIVATED"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// ProcessSpawnErrorEvent the event emitted when the program fails to be spawned
+type ProcessSpawnErrorEvent struct {
+	BaseEvent
+	processName string
+	groupName   string
+	spawnErr    string
+}
+
+// GetBody returns body of process spawn error event, the error is in the line after the header
+func (pe *ProcessSpawnErrorEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s\n%s",
+		pe.processName,
+		pe.groupName,
+		pe.spawnErr)
+}
+
+// CreateProcessSpawnErrorEvent creates process spawn error event
+func CreateProcessSpawnErrorEvent(processName string, groupName string, spawnErr string) *ProcessSpawnErrorEvent {
+	r := &ProcessSpawnErrorEvent{processName: processName,
+		groupName: groupName,
+		spawnErr:  spawnErr}
+	r.eventType = "PROCESS_SPAWN_ERROR"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// EventBufferOverflowEvent the event emitted when an event is discarded because the buffer of
+// the event listener is full
+type EventBufferOverflowEvent struct {
+	BaseEvent
+	pool          string
+	bufferSize    int
+	droppedType   string
+	droppedSerial uint64
+}
+
+// GetBody returns body of event buffer overflow event
+func (be *EventBufferOverflowEvent) GetBody() string {
+	return fmt.Sprintf("pool:%s buffer_size:%d dropped_eventname:%s dropped_serial:%d",
+		be.pool,
+		be.bufferSize,
+		be.droppedType,
+		be.droppedSerial)
+}
+
+// CreateEventBufferOverflowEvent creates event buffer overflow event
+func CreateEventBufferOverflowEvent(pool string, bufferSize int, droppedType string, droppedSerial uint64) *EventBufferOverflowEvent {
+	r := &EventBufferOverflowEvent{pool: pool,
+		bufferSize:    bufferSize,
+		droppedType:   droppedType,
+		droppedSerial: droppedSerial}
+	r.eventType = "EVENT_BUFFER_OVERFLOW"
+	r.serial = nextEventSerial()
+	return r
+}
@@ -2,10 +2,13 @@ package events
 
 import (
 	"bufio"
+	"container/list"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -84,6 +87,80 @@ func TestEventListener(t *testing.T) {
 	eventListenerManager.unregisterEventListener("pool-1")
 }
 
+func TestEventListenerRestart(t *testing.T) {
+	r1, w1 := io.Pipe()
+	r2, w2 := io.Pipe()
+	reader := bufio.NewReader(r1)
+	listener := NewEventListener("pool-2", "supervisor", r2, w1, 2)
+	eventListenerManager.registerEventListener("pool-2", []string{"REMOTE_COMMUNICATION"}, listener)
+	EmitEvent(NewRemoteCommunicationEvent("type-1", "first"))
+	w2.Write([]byte("READY\n"))
+	if _, body := readEvent(reader); body != "type:type-1\nfirst" {
+		t.Errorf("The body is not expect, got %q", body)
+	}
+	// the listener program exits without sending the result
+	EmitEvent(NewRemoteCommunicationEvent("type-1", "second"))
+	EmitEvent(NewRemoteCommunicationEvent("type-1", "third"))
+	w2.Close()
+	r1.Close()
+
+	// the restarted listener gets the event not acknowledged and the buffered events
+	r3, w3 := io.Pipe()
+	r4, w4 := io.Pipe()
+	reader = bufio.NewReader(r3)
+	listener = NewEventListener("pool-2", "supervisor", r4, w3, 2)
+	eventListenerManager.registerEventListener("pool-2", []string{"REMOTE_COMMUNICATION"}, listener)
+	for _, expect := range []string{"first", "second", "third"} {
+		w4.Write([]byte("READY\n"))
+		if _, body := readEvent(reader); body != "type:type-1\n"+expect {
+			t.Errorf("The body is not expect, got %q", body)
+		}
+		w4.Write([]byte("RESULT 2\nOK"))
+	}
+	w4.Close()
+	r3.Close()
+
+	eventListenerManager.unregisterEventListener("pool-2")
+}
+
+func TestEventListenerBufferOverflow(t *testing.T) {
+	listener := &EventListener{pool: "pool-3", cond: sync.NewCond(new(sync.Mutex)), events: list.New(), bufferSize: 2}
+	overflowListener := &EventListener{pool: "pool-4", cond: sync.NewCond(new(sync.Mutex)), events: list.New(), bufferSize: 10}
+	eventListenerManager.registerEventListener("pool-4", []string{"EVENT_BUFFER_OVERFLOW"}, overflowListener)
+	defer eventListenerManager.unregisterEventListener("pool-4")
+
+	var dropped *RemoteCommunicationEvent
+	for _, data := range []string{"1", "2", "3"} {
+		event := NewRemoteCommunicationEvent("type-1", data)
+		if dropped == nil {
+			dropped = event
+		}
+		listener.HandleEvent(event)
+	}
+	if listener.events.Len() != 2 || !strings.HasSuffix(string(listener.events.Front().Value.(*bufferedEvent).data), "\n2") {
+		t.Error("Fail to discard the oldest event when the buffer is full")
+	}
+	listener.HandleEvent(CreateEventBufferOverflowEvent("pool-5", 1, "TICK_5", 1))
+	if listener.events.Len() != 2 || !strings.HasSuffix(string(listener.events.Front().Value.(*bufferedEvent).data), "\n2") {
+		t.Error("Fail to discard the buffer overflow event when the buffer is full")
+	}
+
+	overflowListener.cond.L.Lock()
+	defer overflowListener.cond.L.Unlock()
+	for i := 0; i < 100 && overflowListener.events.Len() == 0; i++ {
+		overflowListener.cond.L.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		overflowListener.cond.L.Lock()
+	}
+	if overflowListener.events.Len() != 1 {
+		t.Fatal("Fail to emit the buffer overflow event")
+	}
+	expectBody := fmt.Sprintf("pool:pool-3 buffer_size:2 dropped_eventname:REMOTE_COMMUNICATION dropped_serial:%d", dropped.GetSerial())
+	if body := string(overflowListener.events.Front().Value.(*bufferedEvent).data); !strings.HasSuffix(body, "\n"+expectBody) {
+		t.Errorf("The body of buffer overflow event is not expect, got %q", body)
+	}
+}
+
 func TestProcCommEventCapture(t *testing.T) {
 	r1, w1 := io.Pipe()
 	r2, w2 := io.Pipe()
@@ -119,6 +196,8 @@ func TestProcCommEventCapture(t *testing.T) {
 	r2.Close()
 	r1.Close()
 	w1.Close()
+
+	eventListenerManager.unregisterEventListener("pool-1")
 }
 
 func TestProcessStartingEvent(t *testing.T) {
@@ -201,3 +280,145 @@ func TestProcessUnknownEvent(t *testing.T) {
 		t.Error("Fail to encode the process unknown event")
 	}
 }
+
+func TestSupervisorStateChangeEvent(t *testing.T) {
+	if event := CreateSupervisorStateChangeRunning(); event.GetType() != "SUPERVISOR_STATE_CHANGE_RUNNING" || event.GetBody() != "" {
+		t.Error("Fail to create the supervisor running event")
+	}
+	if event := CreateSupervisorStateChangeStopping(); event.GetType() != "SUPERVISOR_STATE_CHANGE_STOPPING" || event.GetBody() != "" {
+		t.Error("Fail to create the supervisor stopping event")
+	}
+}
+
+func TestProcessGroupEvent(t *testing.T) {
+	if event := CreateProcessGroupAddedEvent("group-1"); event.GetType() != "PROCESS_GROUP_ADDED" || event.GetBody() != "groupname:group-1" {
+		t.Error("Fail to create the process group added event")
+	}
+	if event := CreateProcessGroupRemovedEvent("group-1"); event.GetType() != "PROCESS_GROUP_REMOVED" || event.GetBody() != "groupname:group-1" {
+		t.Error("Fail to create the process group removed event")
+	}
+}
+
+func TestInlineProcCommEventCapture(t *testing.T) {
+	eventCapture := NewInlineProcCommEventCapture(10240,
+		"PROCESS_COMMUNICATION_STDOUT",
+		"proc-1",
+		"group-1")
+	out := string(eventCapture.Capture([]byte("before <!--XSUPER")))
+	out += string(eventCapture.Capture([]byte("VISOR:BEGIN-->event<!--XSUPERVISOR:END--> after")))
+	if out != "before  after" {
+		t.Errorf("Fail to remove the captured data from output, got %q", out)
+	}
+}
+
+func TestProcessLogEvent(t *testing.T) {
+	event := CreateProcessLogStderrEvent("proc-1", "group-1", 2766, "error")
+	if event.GetBody() != "processname:proc-1 groupname:group-1 pid:2766 channel:stderr\nerror" {
+		t.Errorf("Fail to create process log event, got %q", event.GetBody())
+	}
+}
+
+func TestEventHistory(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "events.journal")
+	if err := SetEventHistory(2, journal, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer SetEventHistory(0, "", 0)
+
+	start := time.Now()
+	EmitEvent(CreateProcessStartingEvent("proc-1", "group-1", "STOPPED", 0))
+	EmitEvent(NewTickEvent("TICK_5", start.Unix()))
+	EmitEvent(CreateProcessFatalEvent("proc-1", "group-1", "BACKOFF"))
+	EmitEvent(CreateProcessFatalEvent("proc-2", "group-2", "BACKOFF"))
+	records := GetEventHistory("", start)
+	if len(records) != 2 || records[0].Body != "processname:proc-1 groupname:group-1 from_state:BACKOFF" || records[1].EventName != "PROCESS_STATE_FATAL" {
+		t.Errorf("Fail to keep the last events, got %v", records)
+	}
+	if records = GetEventHistory("PROCESS_STATE,processname:proc-2", time.Time{}); len(records) != 1 || !strings.HasPrefix(records[0].Body, "processname:proc-2 ") {
+		t.Errorf("Fail to filter the events, got %v", records)
+	}
+	if records = GetEventHistory("PROCESS_STATE_RUNNING", time.Time{}); len(records) != 0 {
+		t.Errorf("Fail to filter the events by name, got %v", records)
+	}
+	if records = GetEventHistory("", time.Now().Add(time.Minute)); len(records) != 0 {
+		t.Errorf("Fail to filter the events by time, got %v", records)
+	}
+
+	// the events in the journal are loaded after restart
+	SetEventHistory(0, "", 0)
+	if err := SetEventHistory(10, journal, 0); err != nil {
+		t.Fatal(err)
+	}
+	if records = GetEventHistory("", time.Time{}); len(records) != 3 || records[0].EventName != "PROCESS_STATE_STARTING" {
+		t.Errorf("Fail to load the events from journal, got %v", records)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ch := Subscribe("PROCESS_STATE")
+	EmitEvent(NewRemoteCommunicationEvent("type-1", "not subscribed"))
+	EmitEvent(CreateProcessExitedEvent("proc-1", "group-1", "RUNNING", 0, 2766))
+	select {
+	case event := <-ch:
+		stateEvent, ok := event.(*ProcessStateEvent)
+		if !ok || stateEvent.GetProcessName() != "proc-1" || stateEvent.GetExpected() != 0 || stateEvent.GetPid() != 2766 {
+			t.Errorf("Fail to receive the subscribed event, got %v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Fail to receive the subscribed event")
+	}
+	select {
+	case event := <-ch:
+		t.Errorf("Receive the event not subscribed %v", event)
+	default:
+	}
+
+	Unsubscribe(ch)
+	EmitEvent(CreateProcessFatalEvent("proc-1", "group-1", "BACKOFF"))
+	if _, ok := <-ch; ok {
+		t.Error("Fail to close the channel after unsubscribing")
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	exited := CreateProcessExitedEvent("web-1", "web", "RUNNING", 0, 2766)
+	for expr, expect := range map[string]bool{
+		"group == 'web' && expected == false":           true,
+		"group == \"web\" && expected == true":          false,
+		"program =~ '^web-[0-9]+$' && pid > 1000":       true,
+		"!(event == 'PROCESS_STATE_EXITED') || pid < 9": false,
+		"eventname != 'PROCESS_STATE_FATAL' && tries":   false,
+		"from_state == 'RUNNING' || group == 'db'":      true,
+	} {
+		filter, err := NewEventFilter(expr)
+		if err != nil {
+			t.Errorf("Fail to parse the event filter %s: %v", expr, err)
+		} else if filter.Match(exited) != expect {
+			t.Errorf("The event filter %s returns %v for %q", expr, !expect, exited.GetBody())
+		}
+	}
+	for _, expr := range []string{"group == 'web", "group ==", "(group == 'web'", "group =~ web", "group @ 'web'"} {
+		if _, err := NewEventFilter(expr); err == nil {
+			t.Errorf("Fail to reject the invalid event filter %s", expr)
+		}
+	}
+	if filter, err := NewEventFilter(" "); filter != nil || err != nil {
+		t.Error("The empty event filter is not nil")
+	}
+
+	ch := make(chan Event, 10)
+	filter, _ := NewEventFilter("group == 'web'")
+	RegisterFilteredEventHandler("filter-1", []string{"PROCESS_STATE"}, filter, chanEventHandler(ch))
+	defer UnregisterEventHandler("filter-1")
+	EmitEvent(CreateProcessFatalEvent("db-1", "db", "BACKOFF"))
+	EmitEvent(exited)
+	if len(ch) != 1 || (<-ch).GetSerial() != exited.GetSerial() {
+		t.Error("Fail to filter the events before sending to the handler")
+	}
+}
+
+type chanEventHandler chan Event
+
+func (h chanEventHandler) HandleEvent(event Event) {
+	h <- event
+}
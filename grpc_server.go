@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	xmlrpc "github.com/ochinchina/gorilla-xmlrpc/xml"
+	"github.com/ochinchina/supervisord/faults"
+	"github.com/ochinchina/supervisord/grpcapi"
+	"github.com/ochinchina/supervisord/process"
+	"github.com/ochinchina/supervisord/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// SupervisorGRPC the gRPC service defined in grpcapi/supervisord.proto to manage the programs
+type SupervisorGRPC struct {
+	grpcapi.UnimplementedSupervisorServer
+	supervisor *Supervisor
+	auth       *httpBasicAuth
+}
+
+// NewSupervisorGRPC create a new SupervisorGRPC object, if both user and password are not empty the
+// client must send them or a bearer token of the tokens in the "authorization" metadata like the http
+// authentication. The clients with the verified certificates are authenticated by the certRoles if it is not nil
+func NewSupervisorGRPC(supervisor *Supervisor, user string, password string, tokens *serverTokens, certRoles *clientCertRoles) *SupervisorGRPC {
+	return &SupervisorGRPC{supervisor: supervisor, auth: newHTTPBasicAuth(user, password, tokens, certRoles, supervisor.audit, nil)}
+}
+
+// CreateServer create the gRPC server with the Supervisor service registered and the options like the TLS credentials
+func (g *SupervisorGRPC) CreateServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client, err := g.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		if !isReadOnlyGRPCMethod(info.FullMethod) {
+			result := "ok"
+			if err != nil {
+				result = status.Convert(err).Message()
+			}
+			g.supervisor.audit.record(client, peerAddr(ctx), "grpc", info.FullMethod, grpcAuditArgs(req, g.supervisor.audit.environmentMask()), result)
+		}
+		return resp, err
+	}), grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, err := g.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}))
+	server := grpc.NewServer(opts...)
+	grpcapi.RegisterSupervisorServer(server, g)
+	return server
+}
+
+// check the client certificate, or the "authorization" metadata with the http basic authentication or the
+// bearer token, the readonly client can only call the methods getting the information and reading the logs.
+// The name of the authenticated client is returned
+func (g *SupervisorGRPC) authorize(ctx context.Context, method string) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	req := &http.Request{Header: http.Header{}}
+	for _, value := range md.Get("authorization") {
+		req.Header.Add("Authorization", value)
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			req.TLS = &tlsInfo.State
+		}
+	}
+	role, ok := g.auth.authenticate(req)
+	client := ""
+	if ok {
+		client = g.auth.clientName(req)
+	}
+	if !g.auth.audit.allow(rateLimitKey(client, peerAddr(ctx)), time.Now()) {
+		return "", status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "invalid username, password or token")
+	}
+	if role == roleReadOnly && !isReadOnlyGRPCMethod(method) {
+		return "", status.Error(codes.PermissionDenied, "the readonly client can't change anything")
+	}
+	return client, nil
+}
+
+// get the address of the gRPC client
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// check if the gRPC method like "/supervisord.v1.Supervisor/GetState" only gets the information or reads the logs
+func isReadOnlyGRPCMethod(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	for _, prefix := range []string{"Get", "List", "Read", "Tail"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetState returns the state of supervisord
+func (g *SupervisorGRPC) GetState(ctx context.Context, req *grpcapi.GetStateRequest) (*grpcapi.GetStateResponse, error) {
+	reply := struct{ StateInfo StateInfo }{}
+	g.supervisor.GetState(nil, nil, &reply)
+	return &grpcapi.GetStateResponse{Statecode: int32(reply.StateInfo.Statecode), Statename: reply.StateInfo.Statename}, nil
+}
+
+// ListProcesses returns the information of all the programs
+func (g *SupervisorGRPC) ListProcesses(ctx context.Context, req *grpcapi.ListProcessesRequest) (*grpcapi.ListProcessesResponse, error) {
+	reply := struct{ AllProcessInfo []types.ProcessInfo }{make([]types.ProcessInfo, 0)}
+	if err := g.supervisor.GetAllProcessInfo(nil, nil, &reply); err != nil {
+		return nil, grpcError(err)
+	}
+	response := &grpcapi.ListProcessesResponse{}
+	for i := range reply.AllProcessInfo {
+		response.Processes = append(response.Processes, toGRPCProcessInfo(&reply.AllProcessInfo[i]))
+	}
+	return response, nil
+}
+
+// GetProcess returns the information of the program
+func (g *SupervisorGRPC) GetProcess(ctx context.Context, req *grpcapi.GetProcessRequest) (*grpcapi.ProcessInfo, error) {
+	proc := g.supervisor.GetManager().Find(req.Name)
+	if proc == nil {
+		return nil, status.Errorf(codes.NotFound, "no process named %s", req.Name)
+	}
+	return toGRPCProcessInfo(getProcessInfo(proc)), nil
+}
+
+// StartProcess starts the programs matching the name
+func (g *SupervisorGRPC) StartProcess(ctx context.Context, req *grpcapi.ProcessRequest) (*grpcapi.ProcessResponse, error) {
+	return g.changeProcess(req, g.supervisor.StartProcess)
+}
+
+// StopProcess stops the programs matching the name
+func (g *SupervisorGRPC) StopProcess(ctx context.Context, req *grpcapi.ProcessRequest) (*grpcapi.ProcessResponse, error) {
+	return g.changeProcess(req, g.supervisor.StopProcess)
+}
+
+// RestartProcess stops the programs matching the name and starts them again
+func (g *SupervisorGRPC) RestartProcess(ctx context.Context, req *grpcapi.ProcessRequest) (*grpcapi.ProcessResponse, error) {
+	return g.changeProcess(req, g.supervisor.RestartProcess)
+}
+
+// SignalProcess sends the signal to the programs matching the name
+func (g *SupervisorGRPC) SignalProcess(ctx context.Context, req *grpcapi.SignalProcessRequest) (*grpcapi.ProcessResponse, error) {
+	signal, err := parseSignalName(req.Signal)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	procs := g.supervisor.GetManager().FindMatch(req.Name)
+	if len(procs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no process named %s", req.Name)
+	}
+	reply := struct{ Success bool }{}
+	if err := g.supervisor.SignalProcess(nil, &types.ProcessSignal{Name: req.Name, Signal: signal}, &reply); err != nil {
+		return nil, grpcError(err)
+	}
+	return toProcessResponse(procs), nil
+}
+
+// call the start, stop or restart RPC and return the information of the matched programs
+func (g *SupervisorGRPC) changeProcess(req *grpcapi.ProcessRequest, change func(r *http.Request, args *StartProcessArgs, reply *struct{ Success bool }) error) (*grpcapi.ProcessResponse, error) {
+	procs := g.supervisor.GetManager().FindMatch(req.Name)
+	if len(procs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no process named %s", req.Name)
+	}
+	reply := struct{ Success bool }{}
+	if err := change(nil, &StartProcessArgs{Name: req.Name, Wait: req.Wait, Env: req.Env}, &reply); err != nil {
+		return nil, grpcError(err)
+	}
+	return toProcessResponse(procs), nil
+}
+
+// ReadLog reads the stdout or stderr log of the program from the offset, or the tail of the log
+// if the offset is negative. At most length (default 10240) bytes are returned
+func (g *SupervisorGRPC) ReadLog(ctx context.Context, req *grpcapi.ReadLogRequest) (*grpcapi.ReadLogResponse, error) {
+	proc := g.supervisor.GetManager().Find(req.Name)
+	if proc == nil {
+		return nil, status.Errorf(codes.NotFound, "no process named %s", req.Name)
+	}
+	procLogger := proc.StdoutLog
+	if req.Stream == grpcapi.LogStream_STDERR {
+		procLogger = proc.StderrLog
+	}
+	if procLogger == nil {
+		return nil, status.Errorf(codes.NotFound, "no %s log of %s", logStreamType(req.Stream), req.Name)
+	}
+	length := req.Length
+	if length <= 0 {
+		length = 10240
+	}
+	offset := req.Offset
+	if offset < 0 {
+		// the logger returns the end of log if the offset exceeds it
+		_, end, _, _ := procLogger.ReadTailLog(math.MaxInt64, 0)
+		offset = end - length
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	data, next, overflow, err := procLogger.ReadTailLog(offset, length)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &grpcapi.ReadLogResponse{Data: []byte(data), Offset: next, Overflow: overflow}, nil
+}
+
+// TailLog sends the last length bytes of the log of the program, and then the new output of the
+// program until the client cancels the call if follow is true
+func (g *SupervisorGRPC) TailLog(req *grpcapi.TailLogRequest, stream grpcapi.Supervisor_TailLogServer) error {
+	proc := g.supervisor.GetManager().Find(req.Name)
+	if proc == nil {
+		return status.Errorf(codes.NotFound, "no process named %s", req.Name)
+	}
+	logType := logStreamType(req.Stream)
+	var ch chan []byte
+	if req.Follow {
+		// follow before reading the tail so no log is lost between them
+		var unfollow func()
+		ch, unfollow = proc.FollowLog(logType)
+		defer unfollow()
+	}
+	if req.Length > 0 {
+		if tail := readLogTail(proc, logType, req.Length); len(tail) > 0 {
+			if err := stream.Send(&grpcapi.LogChunk{Data: []byte(tail)}); err != nil {
+				return err
+			}
+		}
+	}
+	if !req.Follow {
+		return nil
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case data, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&grpcapi.LogChunk{Data: data}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// "stdout" or "stderr"
+func logStreamType(stream grpcapi.LogStream) string {
+	return strings.ToLower(stream.String())
+}
+
+// the gRPC status of the error returned by the RPC
+func grpcError(err error) error {
+	var fault *xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	code := codes.Internal
+	switch fault.Code {
+	case faults.BadName, faults.NoFile:
+		code = codes.NotFound
+	case faults.IncorrectParameters, faults.BadArguments, faults.BadSignal:
+		code = codes.InvalidArgument
+	case faults.AlreadyStated, faults.AlreadyAdded:
+		code = codes.AlreadyExists
+	case faults.NotRunning, faults.StillRunning:
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, fault.String)
+}
+
+func toProcessResponse(procs []*process.Process) *grpcapi.ProcessResponse {
+	response := &grpcapi.ProcessResponse{}
+	for _, proc := range procs {
+		response.Processes = append(response.Processes, toGRPCProcessInfo(getProcessInfo(proc)))
+	}
+	return response
+}
+
+func toGRPCProcessInfo(info *types.ProcessInfo) *grpcapi.ProcessInfo {
+	return &grpcapi.ProcessInfo{Name: info.Name,
+		Group:         info.Group,
+		Description:   info.Description,
+		Start:         int64(info.Start),
+		Stop:          int64(info.Stop),
+		Now:           int64(info.Now),
+		State:         int32(info.State),
+		Statename:     info.Statename,
+		Spawnerr:      info.Spawnerr,
+		Exitstatus:    int32(info.Exitstatus),
+		StdoutLogfile: info.StdoutLogfile,
+		StderrLogfile: info.StderrLogfile,
+		Pid:           int32(info.Pid),
+		Health:        info.Health,
+		EnvOverride:   info.EnvOverride,
+		Uptime:        int64(info.Uptime),
+		Labels:        info.Labels}
+}
+
+// the gRPC request written to the audit log, the values of the environment variables matching the envMask
+// patterns are masked
+func grpcAuditArgs(req interface{}, envMask []string) string {
+	if r, ok := req.(*grpcapi.ProcessRequest); ok && len(r.Env) > 0 {
+		return fmt.Sprintf("name:%q wait:%v env:%q", r.Name, r.Wait, maskEnv(r.Env, envMask))
+	}
+	return fmt.Sprintf("%v", req)
+}
@@ -0,0 +1,4 @@
+// Package grpcapi the protobuf messages and the gRPC service of supervisord
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative supervisord.proto
@@ -0,0 +1,453 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: supervisord.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Supervisor_GetState_FullMethodName       = "/supervisord.v1.Supervisor/GetState"
+	Supervisor_ListProcesses_FullMethodName  = "/supervisord.v1.Supervisor/ListProcesses"
+	Supervisor_GetProcess_FullMethodName     = "/supervisord.v1.Supervisor/GetProcess"
+	Supervisor_StartProcess_FullMethodName   = "/supervisord.v1.Supervisor/StartProcess"
+	Supervisor_StopProcess_FullMethodName    = "/supervisord.v1.Supervisor/StopProcess"
+	Supervisor_RestartProcess_FullMethodName = "/supervisord.v1.Supervisor/RestartProcess"
+	Supervisor_SignalProcess_FullMethodName  = "/supervisord.v1.Supervisor/SignalProcess"
+	Supervisor_ReadLog_FullMethodName        = "/supervisord.v1.Supervisor/ReadLog"
+	Supervisor_TailLog_FullMethodName        = "/supervisord.v1.Supervisor/TailLog"
+)
+
+// SupervisorClient is the client API for Supervisor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SupervisorClient interface {
+	// GetState returns the state of supervisord
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateResponse, error)
+	// ListProcesses returns the information of all the programs
+	ListProcesses(ctx context.Context, in *ListProcessesRequest, opts ...grpc.CallOption) (*ListProcessesResponse, error)
+	// GetProcess returns the information of one program
+	GetProcess(ctx context.Context, in *GetProcessRequest, opts ...grpc.CallOption) (*ProcessInfo, error)
+	// StartProcess starts the programs matching the name
+	StartProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// StopProcess stops the programs matching the name
+	StopProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// RestartProcess stops the programs matching the name and starts them again
+	RestartProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// SignalProcess sends the signal to the programs matching the name
+	SignalProcess(ctx context.Context, in *SignalProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// ReadLog reads the stdout or stderr log of the program from the offset
+	ReadLog(ctx context.Context, in *ReadLogRequest, opts ...grpc.CallOption) (*ReadLogResponse, error)
+	// TailLog streams the tail of the log of the program, and the new output
+	// of the program as it is written if follow is true
+	TailLog(ctx context.Context, in *TailLogRequest, opts ...grpc.CallOption) (Supervisor_TailLogClient, error)
+}
+
+type supervisorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSupervisorClient(cc grpc.ClientConnInterface) SupervisorClient {
+	return &supervisorClient{cc}
+}
+
+func (c *supervisorClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateResponse, error) {
+	out := new(GetStateResponse)
+	err := c.cc.Invoke(ctx, Supervisor_GetState_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) ListProcesses(ctx context.Context, in *ListProcessesRequest, opts ...grpc.CallOption) (*ListProcessesResponse, error) {
+	out := new(ListProcessesResponse)
+	err := c.cc.Invoke(ctx, Supervisor_ListProcesses_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) GetProcess(ctx context.Context, in *GetProcessRequest, opts ...grpc.CallOption) (*ProcessInfo, error) {
+	out := new(ProcessInfo)
+	err := c.cc.Invoke(ctx, Supervisor_GetProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) StartProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, Supervisor_StartProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) StopProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, Supervisor_StopProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) RestartProcess(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, Supervisor_RestartProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) SignalProcess(ctx context.Context, in *SignalProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, Supervisor_SignalProcess_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) ReadLog(ctx context.Context, in *ReadLogRequest, opts ...grpc.CallOption) (*ReadLogResponse, error) {
+	out := new(ReadLogResponse)
+	err := c.cc.Invoke(ctx, Supervisor_ReadLog_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *supervisorClient) TailLog(ctx context.Context, in *TailLogRequest, opts ...grpc.CallOption) (Supervisor_TailLogClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Supervisor_ServiceDesc.Streams[0], Supervisor_TailLog_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &supervisorTailLogClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Supervisor_TailLogClient interface {
+	Recv() (*LogChunk, error)
+	grpc.ClientStream
+}
+
+type supervisorTailLogClient struct {
+	grpc.ClientStream
+}
+
+func (x *supervisorTailLogClient) Recv() (*LogChunk, error) {
+	m := new(LogChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SupervisorServer is the server API for Supervisor service.
+// All implementations must embed UnimplementedSupervisorServer
+// for forward compatibility
+type SupervisorServer interface {
+	// GetState returns the state of supervisord
+	GetState(context.Context, *GetStateRequest) (*GetStateResponse, error)
+	// ListProcesses returns the information of all the programs
+	ListProcesses(context.Context, *ListProcessesRequest) (*ListProcessesResponse, error)
+	// GetProcess returns the information of one program
+	GetProcess(context.Context, *GetProcessRequest) (*ProcessInfo, error)
+	// StartProcess starts the programs matching the name
+	StartProcess(context.Context, *ProcessRequest) (*ProcessResponse, error)
+	// StopProcess stops the programs matching the name
+	StopProcess(context.Context, *ProcessRequest) (*ProcessResponse, error)
+	// RestartProcess stops the programs matching the name and starts them again
+	RestartProcess(context.Context, *ProcessRequest) (*ProcessResponse, error)
+	// SignalProcess sends the signal to the programs matching the name
+	SignalProcess(context.Context, *SignalProcessRequest) (*ProcessResponse, error)
+	// ReadLog reads the stdout or stderr log of the program from the offset
+	ReadLog(context.Context, *ReadLogRequest) (*ReadLogResponse, error)
+	// TailLog streams the tail of the log of the program, and the new output
+	// of the program as it is written if follow is true
+	TailLog(*TailLogRequest, Supervisor_TailLogServer) error
+	mustEmbedUnimplementedSupervisorServer()
+}
+
+// UnimplementedSupervisorServer must be embedded to have forward compatible implementations.
+type UnimplementedSupervisorServer struct {
+}
+
+func (UnimplementedSupervisorServer) GetState(context.Context, *GetStateRequest) (*GetStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedSupervisorServer) ListProcesses(context.Context, *ListProcessesRequest) (*ListProcessesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProcesses not implemented")
+}
+func (UnimplementedSupervisorServer) GetProcess(context.Context, *GetProcessRequest) (*ProcessInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProcess not implemented")
+}
+func (UnimplementedSupervisorServer) StartProcess(context.Context, *ProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartProcess not implemented")
+}
+func (UnimplementedSupervisorServer) StopProcess(context.Context, *ProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopProcess not implemented")
+}
+func (UnimplementedSupervisorServer) RestartProcess(context.Context, *ProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartProcess not implemented")
+}
+func (UnimplementedSupervisorServer) SignalProcess(context.Context, *SignalProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignalProcess not implemented")
+}
+func (UnimplementedSupervisorServer) ReadLog(context.Context, *ReadLogRequest) (*ReadLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadLog not implemented")
+}
+func (UnimplementedSupervisorServer) TailLog(*TailLogRequest, Supervisor_TailLogServer) error {
+	return status.Errorf(codes.Unimplemented, "method TailLog not implemented")
+}
+func (UnimplementedSupervisorServer) mustEmbedUnimplementedSupervisorServer() {}
+
+// UnsafeSupervisorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SupervisorServer will
+// result in compilation errors.
+type UnsafeSupervisorServer interface {
+	mustEmbedUnimplementedSupervisorServer()
+}
+
+func RegisterSupervisorServer(s grpc.ServiceRegistrar, srv SupervisorServer) {
+	s.RegisterService(&Supervisor_ServiceDesc, srv)
+}
+
+func _Supervisor_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_GetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_ListProcesses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProcessesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).ListProcesses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_ListProcesses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).ListProcesses(ctx, req.(*ListProcessesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_GetProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).GetProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_GetProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).GetProcess(ctx, req.(*GetProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_StartProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).StartProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_StartProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).StartProcess(ctx, req.(*ProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_StopProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).StopProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_StopProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).StopProcess(ctx, req.(*ProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_RestartProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).RestartProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_RestartProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).RestartProcess(ctx, req.(*ProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_SignalProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignalProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).SignalProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_SignalProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).SignalProcess(ctx, req.(*SignalProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_ReadLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SupervisorServer).ReadLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Supervisor_ReadLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SupervisorServer).ReadLog(ctx, req.(*ReadLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Supervisor_TailLog_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailLogRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SupervisorServer).TailLog(m, &supervisorTailLogServer{stream})
+}
+
+type Supervisor_TailLogServer interface {
+	Send(*LogChunk) error
+	grpc.ServerStream
+}
+
+type supervisorTailLogServer struct {
+	grpc.ServerStream
+}
+
+func (x *supervisorTailLogServer) Send(m *LogChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Supervisor_ServiceDesc is the grpc.ServiceDesc for Supervisor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Supervisor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "supervisord.v1.Supervisor",
+	HandlerType: (*SupervisorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetState",
+			Handler:    _Supervisor_GetState_Handler,
+		},
+		{
+			MethodName: "ListProcesses",
+			Handler:    _Supervisor_ListProcesses_Handler,
+		},
+		{
+			MethodName: "GetProcess",
+			Handler:    _Supervisor_GetProcess_Handler,
+		},
+		{
+			MethodName: "StartProcess",
+			Handler:    _Supervisor_StartProcess_Handler,
+		},
+		{
+			MethodName: "StopProcess",
+			Handler:    _Supervisor_StopProcess_Handler,
+		},
+		{
+			MethodName: "RestartProcess",
+			Handler:    _Supervisor_RestartProcess_Handler,
+		},
+		{
+			MethodName: "SignalProcess",
+			Handler:    _Supervisor_SignalProcess_Handler,
+		},
+		{
+			MethodName: "ReadLog",
+			Handler:    _Supervisor_ReadLog_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TailLog",
+			Handler:       _Supervisor_TailLog_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "supervisord.proto",
+}
@@ -1,3 +1,13 @@
 module github.com/ochinchina/supervisord/logger
 
 go 1.16
+
+require (
+	github.com/ochinchina/supervisord/events v0.0.0-20220721095143-c2527852d28f
+	github.com/ochinchina/supervisord/faults v0.0.0-20220721095143-c2527852d28f
+)
+
+replace (
+	github.com/ochinchina/supervisord/events => ../events
+	github.com/ochinchina/supervisord/faults => ../faults
+)
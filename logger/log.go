@@ -20,6 +20,8 @@ type Logger interface {
 	ReadTailLog(offset int64, length int64) (string, int64, bool, error)
 	ClearCurLogFile() error
 	ClearAllLogFile() error
+	// Reopen closes and opens the log files again, like after they are moved by logrotate
+	Reopen() error
 }
 
 // LogEventEmitter the interface to emit log events
@@ -278,6 +280,15 @@ func (l *FileLogger) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// Reopen closes and opens the log file again, the file is created if it is moved away
+func (l *FileLogger) Reopen() error {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+
+	l.fileSize = 0
+	return l.openFile(false)
+}
+
 // Close file logger
 func (l *FileLogger) Close() error {
 	if l.file != nil {
@@ -346,6 +357,11 @@ func (l *NullLogger) ClearAllLogFile() error {
 	return faults.NewFault(faults.NoFile, "NO_FILE")
 }
 
+// Reopen is a stub function for NullLogger
+func (l *NullLogger) Reopen() error {
+	return nil
+}
+
 // NewChanLogger creates ChanLogger object
 func NewChanLogger(channel chan []byte) *ChanLogger {
 	return &ChanLogger{channel: channel}
@@ -392,6 +408,11 @@ func (l *ChanLogger) ClearAllLogFile() error {
 	return faults.NewFault(faults.NoFile, "NO_FILE")
 }
 
+// Reopen is a stub function for ChanLogger
+func (l *ChanLogger) Reopen() error {
+	return nil
+}
+
 // NewNullLocker creates new NullLocker object
 func NewNullLocker() *NullLocker {
 	return &NullLocker{}
@@ -421,7 +442,7 @@ func NewStdoutLogger(logEventEmitter LogEventEmitter) *StdLogger {
 // Write output to stdout/stderr
 func (l *StdLogger) Write(p []byte) (int, error) {
 	n, err := l.writer.Write(p)
-	if err != nil {
+	if err == nil {
 		l.logEventEmitter.emitLogEvent(string(p))
 	}
 	return n, err
@@ -433,11 +454,12 @@ func NewStderrLogger(logEventEmitter LogEventEmitter) *StdLogger {
 		writer: os.Stderr}
 }
 
-// LogCaptureLogger capture the log for further analysis
+// LogCaptureLogger capture the log for further analysis, the data between the
+// <!--XSUPERVISOR:BEGIN--> and <!--XSUPERVISOR:END--> tokens is emitted as process
+// communication event instead of written to the log
 type LogCaptureLogger struct {
-	underlineLogger        Logger
-	procCommEventCapWriter io.Writer
-	procCommEventCapture   *events.ProcCommEventCapture
+	underlineLogger      Logger
+	procCommEventCapture *events.ProcCommEventCapture
 }
 
 // NewLogCaptureLogger creates new LogCaptureLogger object
@@ -446,26 +468,29 @@ func NewLogCaptureLogger(underlineLogger Logger,
 	stdType string,
 	procName string,
 	groupName string) *LogCaptureLogger {
-	r, w := io.Pipe()
-	eventCapture := events.NewProcCommEventCapture(r,
-		captureMaxBytes,
+	eventCapture := events.NewInlineProcCommEventCapture(captureMaxBytes,
 		stdType,
 		procName,
 		groupName)
 	return &LogCaptureLogger{underlineLogger: underlineLogger,
-		procCommEventCapWriter: w,
-		procCommEventCapture:   eventCapture}
+		procCommEventCapture: eventCapture}
 }
 
 // SetPid sets pid of program
 func (l *LogCaptureLogger) SetPid(pid int) {
 	l.procCommEventCapture.SetPid(pid)
+	l.underlineLogger.SetPid(pid)
 }
 
 // Write log to LogCaptureLogger
 func (l *LogCaptureLogger) Write(p []byte) (int, error) {
-	l.procCommEventCapWriter.Write(p)
-	return l.underlineLogger.Write(p)
+	data := l.procCommEventCapture.Capture(p)
+	if len(data) > 0 {
+		if _, err := l.underlineLogger.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
 }
 
 // Close LogCaptureLogger
@@ -493,6 +518,11 @@ func (l *LogCaptureLogger) ClearAllLogFile() error {
 	return l.underlineLogger.ClearAllLogFile()
 }
 
+// Reopen reopens the log files
+func (l *LogCaptureLogger) Reopen() error {
+	return l.underlineLogger.Reopen()
+}
+
 // NullLogEventEmitter will not emit log to any listener
 type NullLogEventEmitter struct {
 }
@@ -604,18 +634,22 @@ func (cl *CompositeLogger) RemoveLogger(logger Logger) {
 	}
 }
 
-// Write dispatches log data to the loggers in CompositeLogger pool
+// Write dispatches log data to the loggers in CompositeLogger pool, a failed logger does
+// not stop writing to others and the error is returned only if all the loggers fail
 func (cl *CompositeLogger) Write(p []byte) (n int, err error) {
 	cl.lock.Lock()
 	defer cl.lock.Unlock()
 
-	for i, logger := range cl.loggers {
-		if i == 0 {
-			n, err = logger.Write(p)
+	for _, logger := range cl.loggers {
+		if _, writeErr := logger.Write(p); writeErr == nil {
+			n = len(p)
 		} else {
-			logger.Write(p)
+			err = writeErr
 		}
 	}
+	if n == len(p) {
+		err = nil
+	}
 	return
 }
 
@@ -664,6 +698,20 @@ func (cl *CompositeLogger) ClearAllLogFile() error {
 	return cl.loggers[0].ClearAllLogFile()
 }
 
+// Reopen reopens the files of all the loggers in CompositeLogger pool and returns the first error
+func (cl *CompositeLogger) Reopen() error {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+
+	var result error
+	for _, logger := range cl.loggers {
+		if err := logger.Reopen(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
 // NewLogger creates logger for a program with parameters
 func NewLogger(programName string, logFile string, locker sync.Locker, maxBytes int64, backups int, props map[string]string, logEventEmitter LogEventEmitter) Logger {
 	files := splitLogFile(logFile)
@@ -677,6 +725,14 @@ func NewLogger(programName string, logFile string, locker sync.Locker, maxBytes
 		}
 		loggers = append(loggers, lr)
 	}
+	// the log is read from the first logger, so move the first log file to the front
+	for i, lr := range loggers {
+		if _, ok := lr.(*FileLogger); ok {
+			copy(loggers[1:i+1], loggers[0:i])
+			loggers[0] = lr
+			break
+		}
+	}
 	return NewCompositeLogger(loggers)
 }
 
@@ -689,29 +745,16 @@ func splitLogFile(logFile string) []string {
 }
 
 func createLogger(programName string, logFile string, locker sync.Locker, maxBytes int64, backups int, props map[string]string, logEventEmitter LogEventEmitter) Logger {
-	if logFile == "/dev/stdout" {
-		return NewStdoutLogger(logEventEmitter)
-	}
-	if logFile == "/dev/stderr" {
-		return NewStderrLogger(logEventEmitter)
-	}
-	if logFile == "/dev/null" {
-		return NewNullLogger(logEventEmitter)
-	}
-
-	if logFile == "syslog" {
-		return NewSysLogger(programName, props, logEventEmitter)
-	}
-	if strings.HasPrefix(logFile, "syslog") {
-		fields := strings.Split(logFile, "@")
-		fields[0] = strings.TrimSpace(fields[0])
-		fields[1] = strings.TrimSpace(fields[1])
-		if len(fields) == 2 && fields[0] == "syslog" {
-			return NewRemoteSysLogger(programName, fields[1], props, logEventEmitter)
-		}
-	}
-	if len(logFile) > 0 {
-		return NewFileLogger(logFile, maxBytes, backups, logEventEmitter, locker)
-	}
-	return NewNullLogger(logEventEmitter)
+	factory, ok := GetLogBackend(logFile)
+	if !ok {
+		// the destination like "<scheme>://..." without registered log backend is written to the file
+		factory, _ = getLogBackendByName("file")
+	}
+	return factory(LogBackendConfig{ProgramName: programName,
+		LogFile:         logFile,
+		MaxBytes:        maxBytes,
+		Backups:         backups,
+		Props:           props,
+		LogEventEmitter: logEventEmitter,
+		Locker:          locker})
 }
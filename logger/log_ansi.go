@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"sync"
+)
+
+// the states of parsing the ANSI escape sequences
+const (
+	ansiText = iota
+	// after ESC
+	ansiEscape
+	// in the CSI sequence "ESC [ ... <final byte>"
+	ansiCSI
+	// in the OSC sequence "ESC ] ... BEL" or "ESC ] ... ESC \"
+	ansiOSC
+	// after ESC in the OSC sequence
+	ansiOSCEscape
+)
+
+// StripANSILogger removes the ANSI escape sequences like colors from the program output and
+// writes the remaining text to the underlying logger. A sequence split between writes is
+// removed as well
+type StripANSILogger struct {
+	Logger
+	lock  sync.Mutex
+	state int
+}
+
+// NewStripANSILogger creates StripANSILogger object writing to logger
+func NewStripANSILogger(logger Logger) *StripANSILogger {
+	return &StripANSILogger{Logger: logger, state: ansiText}
+}
+
+// Write log without the ANSI escape sequences
+func (l *StripANSILogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	data := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch l.state {
+		case ansiText:
+			if b == 0x1b {
+				l.state = ansiEscape
+			} else {
+				data = append(data, b)
+			}
+		case ansiEscape:
+			switch b {
+			case '[':
+				l.state = ansiCSI
+			case ']':
+				l.state = ansiOSC
+			default:
+				// two bytes sequence like "ESC c"
+				l.state = ansiText
+			}
+		case ansiCSI:
+			if b >= 0x40 && b <= 0x7e {
+				l.state = ansiText
+			}
+		case ansiOSC:
+			if b == 0x07 {
+				l.state = ansiText
+			} else if b == 0x1b {
+				l.state = ansiOSCEscape
+			}
+		case ansiOSCEscape:
+			l.state = ansiText
+		}
+	}
+	if len(data) > 0 {
+		if _, err := l.Logger.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
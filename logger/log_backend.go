@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogBackendConfig the parameters to create the logger of a program
+type LogBackendConfig struct {
+	ProgramName string
+	// the log destination like "kafka://broker:9092/topic"
+	LogFile  string
+	MaxBytes int64
+	Backups  int
+	// the additional settings like syslog_facility
+	Props           map[string]string
+	LogEventEmitter LogEventEmitter
+	// held while writing if the destination is shared
+	Locker sync.Locker
+}
+
+// LogBackendFactory creates the logger writing to the destination in config.LogFile
+type LogBackendFactory func(config LogBackendConfig) Logger
+
+var logBackendsLock sync.RWMutex
+var logBackends = make(map[string]LogBackendFactory)
+
+// register the built-in log backends: /dev/stdout, /dev/stderr, /dev/null, syslog, the remote syslog
+// like "syslog @udp:host:514" registered as "syslog@" and the log files registered as "file"
+func init() {
+	RegisterLogBackend("/dev/stdout", func(config LogBackendConfig) Logger {
+		return NewStdoutLogger(config.LogEventEmitter)
+	})
+	RegisterLogBackend("/dev/stderr", func(config LogBackendConfig) Logger {
+		return NewStderrLogger(config.LogEventEmitter)
+	})
+	RegisterLogBackend("/dev/null", func(config LogBackendConfig) Logger {
+		return NewNullLogger(config.LogEventEmitter)
+	})
+	RegisterLogBackend("syslog", func(config LogBackendConfig) Logger {
+		return NewSysLogger(config.ProgramName, config.Props, config.LogEventEmitter)
+	})
+	RegisterLogBackend("syslog@", func(config LogBackendConfig) Logger {
+		fields := strings.Split(config.LogFile, "@")
+		return NewRemoteSysLogger(config.ProgramName, strings.TrimSpace(fields[1]), config.Props, config.LogEventEmitter)
+	})
+	RegisterLogBackend("file", func(config LogBackendConfig) Logger {
+		return NewFileLogger(config.LogFile, config.MaxBytes, config.Backups, config.LogEventEmitter, config.Locker)
+	})
+}
+
+// RegisterLogBackend registers the factory of logger for the log destinations like "<scheme>://...",
+// so the embedders and plugins can add log backends like "kafka://" or "s3://". The factory of the
+// same scheme registered before is replaced, including the built-in ones registered in init()
+func RegisterLogBackend(scheme string, factory LogBackendFactory) {
+	logBackendsLock.Lock()
+	defer logBackendsLock.Unlock()
+	logBackends[strings.ToLower(scheme)] = factory
+}
+
+// get the name of the log backend registered for the log destination, the scheme of "<scheme>://..."
+// or the name of the built-in log backend
+func getLogBackendName(logFile string) string {
+	if pos := strings.Index(logFile, "://"); pos > 0 {
+		return strings.ToLower(logFile[0:pos])
+	}
+	switch logFile {
+	case "", "/dev/null":
+		return "/dev/null"
+	case "/dev/stdout", "/dev/stderr", "syslog":
+		return logFile
+	}
+	if fields := strings.Split(logFile, "@"); len(fields) == 2 && strings.TrimSpace(fields[0]) == "syslog" {
+		return "syslog@"
+	}
+	return "file"
+}
+
+// GetLogBackend gets the registered factory of logger for the log destination, false if no factory
+// is registered for the scheme of the destination like "<scheme>://..."
+func GetLogBackend(logFile string) (LogBackendFactory, bool) {
+	return getLogBackendByName(getLogBackendName(logFile))
+}
+
+func getLogBackendByName(name string) (LogBackendFactory, bool) {
+	logBackendsLock.RLock()
+	defer logBackendsLock.RUnlock()
+	factory, ok := logBackends[name]
+	return factory, ok
+}
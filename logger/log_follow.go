@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"sync"
+)
+
+// the number of log chunks held for a slow follower before the new chunks are dropped
+const followerQueueSize = 256
+
+// LogBroadcaster sends the log written to it to all the followers, it can be shared by the
+// loggers of the successive runs of a program so the followers see the log across restarts
+type LogBroadcaster struct {
+	lock      sync.Mutex
+	followers map[chan []byte]struct{}
+}
+
+// NewLogBroadcaster creates LogBroadcaster object
+func NewLogBroadcaster() *LogBroadcaster {
+	return &LogBroadcaster{followers: make(map[chan []byte]struct{})}
+}
+
+// Follow returns the channel receiving the new log, Unfollow must be called with the channel
+// when the log is not needed anymore
+func (b *LogBroadcaster) Follow() chan []byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ch := make(chan []byte, followerQueueSize)
+	b.followers[ch] = struct{}{}
+	return ch
+}
+
+// Unfollow stops sending the log to the channel returned by Follow and closes it
+func (b *LogBroadcaster) Unfollow(ch chan []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.followers[ch]; ok {
+		delete(b.followers, ch)
+		close(ch)
+	}
+}
+
+// Write sends a copy of p to the followers without blocking, the log is dropped for the
+// follower whose queue is full
+func (b *LogBroadcaster) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.followers) == 0 {
+		return len(p), nil
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	for ch := range b.followers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// BroadcastLogger writes the program output to a LogBroadcaster
+type BroadcastLogger struct {
+	NullLogger
+	broadcaster *LogBroadcaster
+}
+
+// NewBroadcastLogger creates BroadcastLogger object writing to broadcaster
+func NewBroadcastLogger(broadcaster *LogBroadcaster) *BroadcastLogger {
+	return &BroadcastLogger{NullLogger: NullLogger{logEventEmitter: NewNullLogEventEmitter()},
+		broadcaster: broadcaster}
+}
+
+// Write log to the followers
+func (l *BroadcastLogger) Write(p []byte) (int, error) {
+	return l.broadcaster.Write(p)
+}
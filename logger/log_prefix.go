@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+)
+
+// the colors of the prefixes, a program always gets the same color by its name
+var prefixColors = []string{"\x1b[32m", "\x1b[33m", "\x1b[34m", "\x1b[35m", "\x1b[36m", "\x1b[92m", "\x1b[93m", "\x1b[94m", "\x1b[95m", "\x1b[96m"}
+
+// the lock shared by all the PrefixLoggers writing to supervisord stdout, so the lines of
+// the programs are not mixed
+var sharedStdoutLock sync.Mutex
+
+// PrefixLogger writes each line of the program output with a prefix like "[progname] " to
+// the supervisord stdout, the incomplete line is held until its end is written
+type PrefixLogger struct {
+	NullLogger
+	prefix string
+	writer io.Writer
+	locker sync.Locker
+	lock   sync.Mutex
+	buf    []byte
+}
+
+// NewSharedStdoutLogger creates PrefixLogger writing to supervisord stdout with prefix
+// "[programName] ", the prefix is colored if color is true
+func NewSharedStdoutLogger(programName string, color bool) *PrefixLogger {
+	return NewPrefixLogger(formatPrefix(programName, color), os.Stdout, &sharedStdoutLock)
+}
+
+// NewPrefixLogger creates PrefixLogger writing the lines with prefix to writer, the locker
+// is held when a line is written
+func NewPrefixLogger(prefix string, writer io.Writer, locker sync.Locker) *PrefixLogger {
+	return &PrefixLogger{NullLogger: NullLogger{logEventEmitter: NewNullLogEventEmitter()},
+		prefix: prefix,
+		writer: writer,
+		locker: locker}
+}
+
+func formatPrefix(programName string, color bool) string {
+	if !color {
+		return fmt.Sprintf("[%s] ", programName)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(programName))
+	return fmt.Sprintf("%s[%s]\x1b[0m ", prefixColors[h.Sum32()%uint32(len(prefixColors))], programName)
+}
+
+// Write the complete lines in p with prefix
+func (l *PrefixLogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		pos := bytes.IndexByte(l.buf, '\n')
+		if pos == -1 {
+			break
+		}
+		if err := l.writeLine(l.buf[0 : pos+1]); err != nil {
+			return len(p), err
+		}
+		l.buf = l.buf[pos+1:]
+	}
+	return len(p), nil
+}
+
+func (l *PrefixLogger) writeLine(line []byte) error {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	_, err := l.writer.Write(append([]byte(l.prefix), line...))
+	return err
+}
+
+// Close writes the incomplete line held in the logger
+func (l *PrefixLogger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.buf) == 0 {
+		return nil
+	}
+	line := append(l.buf, '\n')
+	l.buf = nil
+	return l.writeLine(line)
+}
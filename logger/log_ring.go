@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/ochinchina/supervisord/faults"
+)
+
+// RingBuffer keeps the last bytes of the log in memory. The offset of the log is counted
+// from the first byte written to the buffer like the offset in a log file, and the bytes
+// before the kept ones are not available anymore
+type RingBuffer struct {
+	lock    sync.Mutex
+	data    []byte
+	written int64
+}
+
+// NewRingBuffer creates RingBuffer object keeping the last maxBytes of log
+func NewRingBuffer(maxBytes int) *RingBuffer {
+	return &RingBuffer{data: make([]byte, maxBytes)}
+}
+
+// Write appends p to the buffer and drops the oldest bytes if the buffer is full
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	size := int64(len(rb.data))
+	b := p
+	if int64(len(b)) > size {
+		rb.written += int64(len(b)) - size
+		b = b[int64(len(b))-size:]
+	}
+	pos := rb.written % size
+	n := copy(rb.data[pos:], b)
+	copy(rb.data, b[n:])
+	rb.written += int64(len(b))
+	return len(p), nil
+}
+
+// Clear drops all the bytes in the buffer and resets the offset to 0
+func (rb *RingBuffer) Clear() {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+	rb.written = 0
+}
+
+// get the offset range [start, end) of the kept bytes, the lock must be held
+func (rb *RingBuffer) bounds() (int64, int64) {
+	start := rb.written - int64(len(rb.data))
+	if start < 0 {
+		start = 0
+	}
+	return start, rb.written
+}
+
+// read the bytes in [from, to) which must be in the bounds, the lock must be held
+func (rb *RingBuffer) read(from int64, to int64) string {
+	size := int64(len(rb.data))
+	result := make([]byte, 0, to-from)
+	for from < to {
+		pos := from % size
+		end := pos + to - from
+		if end > size {
+			end = size
+		}
+		result = append(result, rb.data[pos:end]...)
+		from += end - pos
+	}
+	return string(result)
+}
+
+// ReadLog reads the log like FileLogger.ReadLog, the offset before the kept bytes is moved
+// to the first kept byte
+func (rb *RingBuffer) ReadLog(offset int64, length int64) (string, error) {
+	if offset < 0 && length != 0 {
+		return "", faults.NewFault(faults.BadArguments, "BAD_ARGUMENTS")
+	}
+	if offset >= 0 && length < 0 {
+		return "", faults.NewFault(faults.BadArguments, "BAD_ARGUMENTS")
+	}
+
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	start, end := rb.bounds()
+	if offset < 0 {
+		offset = end + offset
+	} else if offset >= end {
+		return "", nil
+	}
+	if offset < start {
+		offset = start
+	}
+	if length == 0 || offset+length > end {
+		length = end - offset
+	}
+	return rb.read(offset, offset+length), nil
+}
+
+// ReadTailLog tails the log like FileLogger.ReadTailLog
+func (rb *RingBuffer) ReadTailLog(offset int64, length int64) (string, int64, bool, error) {
+	if offset < 0 {
+		return "", offset, false, faults.NewFault(faults.BadArguments, "BAD_ARGUMENTS")
+	}
+	if length < 0 {
+		return "", offset, false, faults.NewFault(faults.BadArguments, "BAD_ARGUMENTS")
+	}
+
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	start, end := rb.bounds()
+	if offset >= end {
+		return "", end, true, nil
+	}
+	if offset < start {
+		offset = start
+	}
+	if offset+length > end {
+		length = end - offset
+	}
+	return rb.read(offset, offset+length), offset + length, false, nil
+}
+
+// RingBufferLogger writes the program output to a RingBuffer, the buffer can be shared by
+// the loggers of the successive runs of a program so the output is kept across the restarts
+type RingBufferLogger struct {
+	NullLogger
+	buffer *RingBuffer
+}
+
+// NewRingBufferLogger creates RingBufferLogger object writing to buffer
+func NewRingBufferLogger(buffer *RingBuffer, logEventEmitter LogEventEmitter) *RingBufferLogger {
+	return &RingBufferLogger{NullLogger: NullLogger{logEventEmitter: logEventEmitter},
+		buffer: buffer}
+}
+
+// Write log to the buffer
+func (l *RingBufferLogger) Write(p []byte) (int, error) {
+	l.logEventEmitter.emitLogEvent(string(p))
+	return l.buffer.Write(p)
+}
+
+// ReadLog reads log from the buffer
+func (l *RingBufferLogger) ReadLog(offset int64, length int64) (string, error) {
+	return l.buffer.ReadLog(offset, length)
+}
+
+// ReadTailLog tails log from the buffer
+func (l *RingBufferLogger) ReadTailLog(offset int64, length int64) (string, int64, bool, error) {
+	return l.buffer.ReadTailLog(offset, length)
+}
+
+// ClearCurLogFile clears the buffer
+func (l *RingBufferLogger) ClearCurLogFile() error {
+	l.buffer.Clear()
+	return nil
+}
+
+// ClearAllLogFile clears the buffer
+func (l *RingBufferLogger) ClearAllLogFile() error {
+	l.buffer.Clear()
+	return nil
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// the max length of a partial line kept in RouteLogger before it is flushed
+const maxRouteLineLength = 64 * 1024
+
+// LogRoute sends the log lines matching the Pattern to the Logger
+type LogRoute struct {
+	Pattern *regexp.Regexp
+	Logger  Logger
+}
+
+// RouteLogger dispatches each log line to the logger of first matched route, the
+// lines not matching any route are written to the default logger
+type RouteLogger struct {
+	lock          sync.Mutex
+	defaultLogger Logger
+	routes        []LogRoute
+	// the last incomplete line
+	line []byte
+}
+
+// NewRouteLogger creates new RouteLogger object
+func NewRouteLogger(defaultLogger Logger, routes []LogRoute) *RouteLogger {
+	return &RouteLogger{defaultLogger: defaultLogger, routes: routes}
+}
+
+// Write the log data, only the complete lines are dispatched
+func (rl *RouteLogger) Write(p []byte) (int, error) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.line = append(rl.line, p...)
+	for {
+		pos := bytes.IndexByte(rl.line, '\n')
+		if pos == -1 {
+			break
+		}
+		rl.writeLine(rl.line[0 : pos+1])
+		rl.line = rl.line[pos+1:]
+	}
+	if len(rl.line) > maxRouteLineLength {
+		rl.writeLine(rl.line)
+		rl.line = nil
+	}
+	return len(p), nil
+}
+
+func (rl *RouteLogger) writeLine(line []byte) {
+	for _, route := range rl.routes {
+		if route.Pattern.Match(line) {
+			route.Logger.Write(line)
+			return
+		}
+	}
+	rl.defaultLogger.Write(line)
+}
+
+// Close flushes the incomplete line and closes all the loggers
+func (rl *RouteLogger) Close() error {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if len(rl.line) > 0 {
+		rl.writeLine(rl.line)
+		rl.line = nil
+	}
+	for _, route := range rl.routes {
+		route.Logger.Close()
+	}
+	return rl.defaultLogger.Close()
+}
+
+// SetPid sets pid of the program to all the loggers
+func (rl *RouteLogger) SetPid(pid int) {
+	for _, route := range rl.routes {
+		route.Logger.SetPid(pid)
+	}
+	rl.defaultLogger.SetPid(pid)
+}
+
+// ReadLog reads log from the default logger
+func (rl *RouteLogger) ReadLog(offset int64, length int64) (string, error) {
+	return rl.defaultLogger.ReadLog(offset, length)
+}
+
+// ReadTailLog tails log from the default logger
+func (rl *RouteLogger) ReadTailLog(offset int64, length int64) (string, int64, bool, error) {
+	return rl.defaultLogger.ReadTailLog(offset, length)
+}
+
+// ClearCurLogFile clears current log file of the default logger
+func (rl *RouteLogger) ClearCurLogFile() error {
+	return rl.defaultLogger.ClearCurLogFile()
+}
+
+// ClearAllLogFile clears all log files of all the loggers
+func (rl *RouteLogger) ClearAllLogFile() error {
+	for _, route := range rl.routes {
+		route.Logger.ClearAllLogFile()
+	}
+	return rl.defaultLogger.ClearAllLogFile()
+}
+
+// Reopen reopens the log files of all the loggers
+func (rl *RouteLogger) Reopen() error {
+	errs := make([]string, 0)
+	for _, route := range rl.routes {
+		if err := route.Logger.Reopen(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := rl.defaultLogger.Reopen(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogMatch one log line matched by SearchLogFile
+type LogMatch struct {
+	File string
+	Line string
+	// the offset of the first byte of line in the file
+	Offset int64
+	// the timestamp parsed from the beginning of line, zero if the line has no timestamp
+	Time time.Time
+}
+
+// the timestamp layouts recognized at the beginning of a log line
+var lineTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+}
+
+// GetLogFiles returns the current log file and its rotated backups, oldest first
+func GetLogFiles(logFile string, backups int) []string {
+	result := make([]string, 0)
+	for i := backups; i > 0; i-- {
+		name := fmt.Sprintf("%s.%d", logFile, i)
+		if _, err := os.Stat(name); err == nil {
+			result = append(result, name)
+		}
+	}
+	if _, err := os.Stat(logFile); err == nil {
+		result = append(result, logFile)
+	}
+	return result
+}
+
+// parse the timestamp at the beginning of the line
+func parseLineTime(line string) (time.Time, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	// some layouts contain one space, so try with the first one and two fields
+	candidates := []string{strings.Trim(fields[0], "[]")}
+	if len(fields) > 1 {
+		candidates = append(candidates, strings.Trim(fields[0]+" "+fields[1], "[]"))
+	}
+	for _, layout := range lineTimeLayouts {
+		for _, s := range candidates {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// SearchLogFile searches the log file and its rotated backups for lines matching the pattern.
+//
+// Only the lines in time window [since, until] are returned, a zero since or until means no bound.
+// Lines without timestamp are matched if their file was modified in the time window. At most
+// maxResults most recent matches are returned in chronological order with their offsets in the
+// files, truncated is true if some matches are discarded
+func SearchLogFile(logFile string, backups int, pattern *regexp.Regexp, since time.Time, until time.Time, maxResults int) (matches []LogMatch, truncated bool, err error) {
+	matches = make([]LogMatch, 0)
+	for _, name := range GetLogFiles(logFile, backups) {
+		fileInfo, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		// all the lines in the file are written before its modification time
+		if !since.IsZero() && fileInfo.ModTime().Before(since) {
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return matches, truncated, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		offset, lineOffset := int64(0), int64(0)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			if token != nil {
+				lineOffset = offset
+			}
+			offset += int64(advance)
+			return advance, token, err
+		})
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !pattern.MatchString(line) {
+				continue
+			}
+			lineTime, ok := parseLineTime(line)
+			if ok && (!since.IsZero() && lineTime.Before(since) || !until.IsZero() && lineTime.After(until)) {
+				continue
+			}
+			matches = append(matches, LogMatch{File: name, Line: line, Offset: lineOffset, Time: lineTime})
+			if len(matches) > maxResults {
+				matches = matches[1:]
+				truncated = true
+			}
+		}
+		f.Close()
+		// like a line is longer than the buffer of the scanner
+		if err := scanner.Err(); err != nil {
+			return matches, truncated, fmt.Errorf("fail to search %s: %v", name, err)
+		}
+	}
+	return matches, truncated, nil
+}
+
+// MergeLogMatches merges the matches of SearchLogFile on the different log files, like the stdout and stderr
+// logs of a program, into one list in chronological order. The matches of one log keep their order, and the
+// line without timestamp is ordered by the timestamp of the line matched before it in the same log
+func MergeLogMatches(lists ...[]LogMatch) []LogMatch {
+	result := make([]LogMatch, 0)
+	next := make([]int, len(lists))
+	lastTimes := make([]time.Time, len(lists))
+	for {
+		selected := -1
+		var selectedTime time.Time
+		for i, matches := range lists {
+			if next[i] >= len(matches) {
+				continue
+			}
+			t := matches[next[i]].Time
+			if t.IsZero() {
+				t = lastTimes[i]
+			}
+			if selected == -1 || t.Before(selectedTime) {
+				selected, selectedTime = i, t
+			}
+		}
+		if selected == -1 {
+			return result
+		}
+		result = append(result, lists[selected][next[selected]])
+		lastTimes[selected] = selectedTime
+		next[selected]++
+	}
+}
@@ -1,12 +1,19 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWriteSingleLog(t *testing.T) {
-	logger := NewFileLogger("test.log", int64(50), 2, NewNullLogEventEmitter(), NewNullLocker())
+	logger := NewFileLogger(filepath.Join(t.TempDir(), "test.log"), int64(50), 2, NewNullLogEventEmitter(), NewNullLocker())
 	for i := 0; i < 10; i++ {
 		logger.Write([]byte(fmt.Sprintf("this is a test %d\n", i)))
 	}
@@ -29,3 +36,169 @@ func TestSplitLogFile(t *testing.T) {
 	}
 
 }
+
+func TestSearchLogFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "search.log")
+	ioutil.WriteFile(logFile+".1", []byte("2022-01-01T10:00:00Z error: old\n"), 0644)
+	ioutil.WriteFile(logFile, []byte("2022-01-02T10:00:00Z error: first\ninfo: nothing\nerror: no time\n2022-01-03T10:00:00Z error: last\n"), 0644)
+
+	pattern := regexp.MustCompile("error")
+	matches, truncated, err := SearchLogFile(logFile, 2, pattern, time.Time{}, time.Time{}, 10)
+	if err != nil || truncated || len(matches) != 4 {
+		t.Fatalf("Fail to search all the log files, matches=%v", matches)
+	}
+	if matches[0].File != logFile+".1" || matches[3].Line != "2022-01-03T10:00:00Z error: last" {
+		t.Error("The matched lines are not in chronological order")
+	}
+	if matches[1].Offset != 0 || matches[2].Offset != 48 || matches[3].Offset != 63 {
+		t.Errorf("Fail to get the offsets of the matched lines, matches=%v", matches)
+	}
+
+	since := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2022, 1, 2, 23, 0, 0, 0, time.UTC)
+	matches, _, _ = SearchLogFile(logFile, 2, pattern, since, until, 10)
+	if len(matches) != 2 || matches[0].Line != "2022-01-02T10:00:00Z error: first" || matches[1].Line != "error: no time" {
+		t.Errorf("Fail to search the log in time window, matches=%v", matches)
+	}
+
+	matches, truncated, _ = SearchLogFile(logFile, 2, pattern, time.Time{}, time.Time{}, 1)
+	if !truncated || len(matches) != 1 || matches[0].Line != "2022-01-03T10:00:00Z error: last" {
+		t.Error("Fail to keep the most recent matched line")
+	}
+
+	ioutil.WriteFile(logFile, []byte("error: "+strings.Repeat("x", 2*1024*1024)+"\n"), 0644)
+	if _, _, err = SearchLogFile(logFile, 0, pattern, time.Time{}, time.Time{}, 10); err == nil {
+		t.Error("Fail to report the line too long to be searched")
+	}
+}
+
+func TestMergeLogMatches(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2022, 1, 1, hour, 0, 0, 0, time.UTC)
+	}
+	stdout := []LogMatch{{Line: "out 1", Time: at(1)}, {Line: "out no time"}, {Line: "out 4", Time: at(4)}}
+	stderr := []LogMatch{{Line: "err no time"}, {Line: "err 2", Time: at(2)}, {Line: "err 3", Time: at(3)}}
+	lines := make([]string, 0)
+	for _, match := range MergeLogMatches(stdout, stderr) {
+		lines = append(lines, match.Line)
+	}
+	expected := []string{"err no time", "out 1", "out no time", "err 2", "err 3", "out 4"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Fail to merge the matches in chronological order, got %v", lines)
+	}
+}
+
+func TestRouteLogger(t *testing.T) {
+	defaultCh := make(chan []byte, 10)
+	errorCh := make(chan []byte, 10)
+	logger := NewRouteLogger(NewChanLogger(defaultCh), []LogRoute{{Pattern: regexp.MustCompile("^ERROR"), Logger: NewChanLogger(errorCh)}})
+	logger.Write([]byte("ERROR fail\nINFO o"))
+	logger.Write([]byte("k\n"))
+	if s := string(<-errorCh); s != "ERROR fail\n" {
+		t.Errorf("Fail to route error line, got %q", s)
+	}
+	if s := string(<-defaultCh); s != "INFO ok\n" {
+		t.Errorf("Fail to write unmatched line to default logger, got %q", s)
+	}
+
+	missingFile := filepath.Join(t.TempDir(), "missing", "error.log")
+	logger = NewRouteLogger(NewChanLogger(defaultCh), []LogRoute{{Pattern: regexp.MustCompile("^ERROR"), Logger: NewFileLogger(missingFile, 0, 0, NewNullLogEventEmitter(), NewNullLocker())}})
+	if err := logger.Reopen(); err == nil || !strings.Contains(err.Error(), missingFile) {
+		t.Errorf("Fail to return the error of reopening the route log file, got %v", err)
+	}
+}
+
+func TestPrefixLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewPrefixLogger("[test] ", buf, NewNullLocker())
+	logger.Write([]byte("line 1\nli"))
+	logger.Write([]byte("ne 2\nline"))
+	if s := buf.String(); s != "[test] line 1\n[test] line 2\n" {
+		t.Errorf("Fail to write complete lines with prefix, got %q", s)
+	}
+	logger.Close()
+	if s := buf.String(); s != "[test] line 1\n[test] line 2\n[test] line\n" {
+		t.Errorf("Fail to write incomplete line on close, got %q", s)
+	}
+}
+
+func TestTimestampLogger(t *testing.T) {
+	ch := make(chan []byte, 10)
+	logger := NewTimestampLogger(NewChanLogger(ch))
+	logger.now = func() time.Time {
+		return time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	logger.Write([]byte("line 1\nli"))
+	logger.Write([]byte("ne 2\n"))
+	if s := string(<-ch) + string(<-ch); s != "2022-01-02T03:04:05Z line 1\n2022-01-02T03:04:05Z line 2\n" {
+		t.Errorf("Fail to prefix the lines with timestamp, got %q", s)
+	}
+}
+
+func TestStripANSILogger(t *testing.T) {
+	ch := make(chan []byte, 10)
+	logger := NewStripANSILogger(NewChanLogger(ch))
+	logger.Write([]byte("\x1b[1;31merror\x1b[0m: fail \x1b[3"))
+	logger.Write([]byte("2mok\x1b[0m\x1b]0;title\x07\n"))
+	if s := string(<-ch) + string(<-ch); s != "error: fail ok\n" {
+		t.Errorf("Fail to strip the ANSI escape sequences, got %q", s)
+	}
+}
+
+func TestRegisterLogBackend(t *testing.T) {
+	ch := make(chan []byte, 10)
+	RegisterLogBackend("test", func(config LogBackendConfig) Logger {
+		if config.ProgramName != "prog" || config.LogFile != "TEST://topic" {
+			t.Errorf("Fail to pass the config to the log backend, got %v", config)
+		}
+		return NewChanLogger(ch)
+	})
+	logger := NewLogger("prog", "TEST://topic", NewNullLocker(), 0, 0, nil, NewNullLogEventEmitter())
+	logger.Write([]byte("hello\n"))
+	if s := string(<-ch); s != "hello\n" {
+		t.Errorf("Fail to write to the log backend, got %q", s)
+	}
+	if _, ok := GetLogBackend("unknown://topic"); ok {
+		t.Error("Get the log backend of the unregistered scheme")
+	}
+	for logFile, name := range map[string]string{"": "/dev/null", "/dev/stdout": "/dev/stdout", "syslog": "syslog", "syslog @udp:localhost:514": "syslog@", "/var/log/app.log": "file"} {
+		if _, ok := GetLogBackend(logFile); !ok || getLogBackendName(logFile) != name {
+			t.Errorf("Fail to get the built-in log backend %s of %q", name, logFile)
+		}
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	buffer := NewRingBuffer(8)
+	buffer.Write([]byte("0123"))
+	buffer.Write([]byte("456789"))
+	if s, _ := buffer.ReadLog(0, 0); s != "23456789" {
+		t.Errorf("Fail to keep the last bytes, got %q", s)
+	}
+	if s, _ := buffer.ReadLog(-3, 0); s != "789" {
+		t.Errorf("Fail to read the tail, got %q", s)
+	}
+	s, offset, overflow, _ := buffer.ReadTailLog(6, 10)
+	if s != "6789" || offset != 10 || overflow {
+		t.Errorf("Fail to tail from offset, got %q %d %v", s, offset, overflow)
+	}
+	buffer.Write([]byte("abcdefghijk"))
+	if s, _ := buffer.ReadLog(0, 0); s != "defghijk" {
+		t.Errorf("Fail to write more bytes than the buffer, got %q", s)
+	}
+	if _, offset, overflow, _ = buffer.ReadTailLog(100, 10); offset != 21 || !overflow {
+		t.Errorf("Fail to return the end of log, got %d %v", offset, overflow)
+	}
+}
+
+func TestTeeLogger(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "tee.log")
+	logger := NewLogger("test", "/dev/null, "+logFile+", syslog@udp:127.0.0.1:1", NewNullLocker(), 1024, 1, map[string]string{}, NewNullLogEventEmitter())
+	if n, err := logger.Write([]byte("hello\n")); n != 6 || err != nil {
+		t.Errorf("Fail to write to the loggers, got %d %v", n, err)
+	}
+	if s, _ := logger.ReadLog(0, 0); s != "hello\n" {
+		t.Errorf("Fail to read log from the log file, got %q", s)
+	}
+	logger.Close()
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// TimestampLogger prefixes each line of the program output with the RFC3339 time when
+// the line is started, and writes it to the underlying logger. The incomplete line is
+// written without waiting for its end
+type TimestampLogger struct {
+	Logger
+	lock      sync.Mutex
+	lineStart bool
+	now       func() time.Time
+}
+
+// NewTimestampLogger creates TimestampLogger object writing to logger
+func NewTimestampLogger(logger Logger) *TimestampLogger {
+	return &TimestampLogger{Logger: logger, lineStart: true, now: time.Now}
+}
+
+// Write log with the timestamps of the lines started in p
+func (l *TimestampLogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	data := make([]byte, 0, len(p)+32)
+	for b := p; len(b) > 0; {
+		if l.lineStart {
+			data = append(data, l.now().Format(time.RFC3339)...)
+			data = append(data, ' ')
+			l.lineStart = false
+		}
+		pos := bytes.IndexByte(b, '\n')
+		if pos == -1 {
+			data = append(data, b...)
+			break
+		}
+		data = append(data, b[0:pos+1]...)
+		b = b[pos+1:]
+		l.lineStart = true
+	}
+	if _, err := l.Logger.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -2,16 +2,26 @@ package main
 
 import (
 	"fmt"
-	"github.com/ochinchina/supervisord/logger"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/ochinchina/supervisord/logger"
+	"github.com/ochinchina/supervisord/process"
+	log "github.com/sirupsen/logrus"
 )
 
+// the max bytes of log read at a time while the log is downloaded
+const logDownloadChunkSize = 64 * 1024
+
 // Logtail tails the process log through http interface
 type Logtail struct {
 	router     *mux.Router
 	supervisor *Supervisor
+	upgrader   websocket.Upgrader
 }
 
 // NewLogtail creates a Logtail object
@@ -19,10 +29,15 @@ func NewLogtail(supervisor *Supervisor) *Logtail {
 	return &Logtail{router: mux.NewRouter(), supervisor: supervisor}
 }
 
-// CreateHandler creates http handlers to process the program stdout and stderr through http interface
+// CreateHandler creates http handlers to stream the program stdout and stderr through http interface.
+// The tail of the log is sent first and then the new log until the client disconnects, over chunked
+// http response or WebSocket binary messages if the request asks to upgrade to WebSocket
 func (lt *Logtail) CreateHandler() http.Handler {
+	lt.router.HandleFunc("/logtail/{program}", lt.getStdoutLog).Methods("GET")
 	lt.router.HandleFunc("/logtail/{program}/stdout", lt.getStdoutLog).Methods("GET")
 	lt.router.HandleFunc("/logtail/{program}/stderr", lt.getStderrLog).Methods("GET")
+	lt.router.HandleFunc("/logtail/{program}/stdout/download", lt.downloadStdoutLog).Methods("GET")
+	lt.router.HandleFunc("/logtail/{program}/stderr/download", lt.downloadStderrLog).Methods("GET")
 	return lt.router
 }
 
@@ -35,60 +50,153 @@ func (lt *Logtail) getStderrLog(w http.ResponseWriter, req *http.Request) {
 }
 
 func (lt *Logtail) getLog(logType string, w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	program := vars["program"]
-	procMgr := lt.supervisor.GetManager()
-	proc := procMgr.Find(program)
-
+	proc := lt.supervisor.GetManager().Find(mux.Vars(req)["program"])
 	if proc == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	length, err := strconv.ParseInt(req.URL.Query().Get("length"), 10, 64)
+	if err != nil || length < 0 {
+		length = 10240
+	}
 
-	var ok bool = false
-	var compositeLogger *logger.CompositeLogger = nil
-	if logType == "stdout" {
-		compositeLogger, ok = proc.StdoutLog.(*logger.CompositeLogger)
+	// follow before reading the tail so no log is lost between them
+	ch, unfollow := proc.FollowLog(logType)
+	defer unfollow()
+	tail := readLogTail(proc, logType, length)
+
+	if websocket.IsWebSocketUpgrade(req) {
+		lt.streamWebSocket(w, req, tail, ch)
 	} else {
-		compositeLogger, ok = proc.StderrLog.(*logger.CompositeLogger)
+		streamChunked(w, req, tail, ch)
 	}
+}
 
-	if !ok {
+func (lt *Logtail) downloadStdoutLog(w http.ResponseWriter, req *http.Request) {
+	lt.downloadLog("stdout", w, req)
+}
+
+func (lt *Logtail) downloadStderrLog(w http.ResponseWriter, req *http.Request) {
+	lt.downloadLog("stderr", w, req)
+}
+
+// send the whole stdout or stderr log as an attachment named like "<program>-stdout.log"
+func (lt *Logtail) downloadLog(logType string, w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["program"]
+	proc := lt.supervisor.GetManager().Find(name)
+	if proc == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-
-	s, err := compositeLogger.ReadLog(0, 0)
+	procLogger := getProcessLogger(proc, logType)
+	if procLogger == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	// the logger returns the end of log if the offset exceeds it
+	_, end, _, err := procLogger.ReadTailLog(math.MaxInt64, 0)
 	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"-"+logType+".log"))
+	for offset := int64(0); offset < end; {
+		s, next, _, err := procLogger.ReadTailLog(offset, logDownloadChunkSize)
+		if err != nil || next <= offset {
+			return
+		}
+		if _, err = io.WriteString(w, s); err != nil {
+			return
+		}
+		offset = next
+	}
+}
+
+// get the stdout or stderr logger of the program, nil if the program is never started
+func getProcessLogger(proc *process.Process, logType string) logger.Logger {
+	if logType == "stderr" {
+		return proc.StderrLog
+	}
+	return proc.StdoutLog
+}
+
+// read the last length bytes of the stdout or stderr log
+func readLogTail(proc *process.Process, logType string, length int64) string {
+	procLogger := getProcessLogger(proc, logType)
+	if procLogger == nil || length == 0 {
+		return ""
+	}
+	// the logger returns the end of log if the offset exceeds it
+	_, end, _, err := procLogger.ReadTailLog(math.MaxInt64, 0)
+	if err != nil {
+		return ""
+	}
+	offset := end - length
+	if offset < 0 {
+		offset = 0
+	}
+	s, _, _, _ := procLogger.ReadTailLog(offset, end-offset)
+	return s
+}
+
+func streamChunked(w http.ResponseWriter, req *http.Request, tail string, ch chan []byte) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(tail))
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-req.Context().Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
 
-	w.Write([]byte(s))
-	//
-	//if ok {
-	//	w.Header().Set("Transfer-Encoding", "chunked")
-	//	w.WriteHeader(http.StatusOK)
-	//	flusher, _ := w.(http.Flusher)
-	//	ch := make(chan []byte, 100)
-	//	chanLogger := logger.NewChanLogger(ch)
-	//	compositeLogger.AddLogger(chanLogger)
-	//	for {
-	//		text, ok := <-ch
-	//		if !ok {
-	//			break
-	//		}
-	//		_, err := w.Write(text)
-	//		if err != nil {
-	//			break
-	//		}
-	//		flusher.Flush()
-	//	}
-	//	compositeLogger.RemoveLogger(chanLogger)
-	//	_ = chanLogger.Close()
-	//}
+func (lt *Logtail) streamWebSocket(w http.ResponseWriter, req *http.Request, tail string, ch chan []byte) {
+	conn, err := lt.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to upgrade to websocket")
+		return
+	}
+	defer conn.Close()
 
+	// the messages from the client are not expected, read them only to detect the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if len(tail) > 0 && conn.WriteMessage(websocket.BinaryMessage, []byte(tail)) != nil {
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if conn.WriteMessage(websocket.BinaryMessage, data) != nil {
+				return
+			}
+		}
+	}
 }
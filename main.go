@@ -23,6 +23,7 @@ type Options struct {
 	Configuration string `short:"c" long:"configuration" description:"the configuration file"`
 	Daemon        bool   `short:"d" long:"daemon" description:"run as daemon"`
 	EnvFile       string `long:"env-file" description:"the environment file"`
+	EnableChaos   bool   `long:"enable-chaos" description:"enable the chaos RPCs to inject failures, only for testing"`
 }
 
 func init() {
@@ -47,10 +48,11 @@ func initSignals(s *Supervisor) {
 	go func() {
 		sig := <-sigs
 		log.WithFields(log.Fields{"signal": sig}).Info("receive a signal to stop all process & exit")
-		s.procMgr.StopAllProcesses()
+		s.setSupervisorState(SupervisorShutdown)
+		s.stopAllProcesses()
 		os.Exit(-1)
 	}()
-
+	initReopenLogsSignal(s)
 }
 
 var options Options
@@ -134,6 +136,7 @@ func runServer() {
 			options.Configuration, _ = findSupervisordConf()
 		}
 		s := NewSupervisor(options.Configuration)
+		s.chaos = options.EnableChaos
 		initSignals(s)
 		if _, _, _, sErr := s.Reload(true); sErr != nil {
 			panic(sErr)
@@ -177,7 +180,14 @@ func main() {
 			}
 			os.Exit(0)
 		}
-		return command.Execute(args)
+		ctlCommand.applyProfile(parser.Active)
+		if ctlCommand.isFanout(parser.Active) {
+			ctlCommand.exit(ctlCommand.fanout(parser.Active.Active.Name))
+		}
+		err := command.Execute(args)
+		// print the results of the ctl subcommands kept for the json, yaml or tsv output
+		ctlCommand.flushOutput()
+		return err
 	}
 
 	if _, err := parser.Parse(); err != nil {
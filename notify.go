@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// the situations a notification is sent for
+const (
+	// the process enters the FATAL state
+	notifyOnFatal = "fatal"
+	// the process exits with an unexpected exit code
+	notifyOnExit = "exit"
+	// the process crashes again and again
+	notifyOnFlap = "flap"
+)
+
+// the events the Notifier handles
+var notifyEvents = []string{"PROCESS_STATE_FATAL", "PROCESS_STATE_EXITED", "PROCESS_STATE_BACKOFF"}
+
+// notifySender sends the message to a notification service
+type notifySender interface {
+	send(subject string, text string) error
+}
+
+type notification struct {
+	subject string
+	text    string
+}
+
+// Notifier sends the messages configured in the [notify:x] section to Slack, Telegram
+// or by email when the programs enter FATAL state, exit unexpectedly or flap
+type Notifier struct {
+	name     string
+	server   string
+	sender   notifySender
+	triggers map[string]bool
+	// the patterns of the program names, all the programs if it is empty
+	programs      []string
+	flapThreshold int
+	flapWindow    time.Duration
+	lock          sync.Mutex
+	// the times the program crashed in the flap window
+	crashes map[string][]time.Time
+	queue   chan notification
+	stop    chan struct{}
+}
+
+// NewNotifier creates Notifier object from the [notify:x] section and starts to send the messages.
+// The type of notification is the "type" setting or the section name if it is not set
+func NewNotifier(server string, entry *config.Entry) (*Notifier, error) {
+	name := entry.GetNotifyName()
+	sender, err := createNotifySender(entry.GetString("type", name), entry)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{name: name,
+		server:        server,
+		sender:        sender,
+		triggers:      make(map[string]bool),
+		programs:      trimStrings(entry.GetStringArray("programs", ",")),
+		flapThreshold: entry.GetInt("flap_threshold", 5),
+		flapWindow:    entry.GetDuration("flap_window", time.Minute),
+		crashes:       make(map[string][]time.Time),
+		queue:         make(chan notification, entry.GetInt("buffer_size", 100)),
+		stop:          make(chan struct{})}
+	for _, trigger := range trimStrings(entry.GetStringArray("notify_on", ",")) {
+		n.triggers[strings.ToLower(trigger)] = true
+	}
+	if len(n.triggers) == 0 {
+		n.triggers = map[string]bool{notifyOnFatal: true, notifyOnExit: true, notifyOnFlap: true}
+	}
+	go n.run()
+	return n, nil
+}
+
+func createNotifySender(notifyType string, entry *config.Entry) (notifySender, error) {
+	client := &http.Client{Timeout: entry.GetDuration("timeout", 10*time.Second)}
+	switch strings.ToLower(notifyType) {
+	case "slack":
+		webhookURL := entry.GetString("webhook_url", "")
+		if webhookURL == "" {
+			return nil, fmt.Errorf("the webhook_url of slack notification is not set")
+		}
+		return &slackSender{webhookURL: webhookURL, channel: entry.GetString("channel", ""), client: client}, nil
+	case "telegram":
+		botToken := entry.GetString("bot_token", "")
+		chatID := entry.GetString("chat_id", "")
+		if botToken == "" || chatID == "" {
+			return nil, fmt.Errorf("the bot_token or chat_id of telegram notification is not set")
+		}
+		return &telegramSender{apiURL: strings.TrimRight(entry.GetString("api_url", "https://api.telegram.org"), "/"),
+			botToken: botToken,
+			chatID:   chatID,
+			client:   client}, nil
+	case "email":
+		from := entry.GetString("from", "")
+		to := trimStrings(entry.GetStringArray("to", ","))
+		if from == "" || len(to) == 0 {
+			return nil, fmt.Errorf("the from or to of email notification is not set")
+		}
+		return &emailSender{smtpServer: entry.GetString("smtp_server", "localhost:25"),
+			username: entry.GetString("username", ""),
+			password: entry.GetString("password", ""),
+			from:     from,
+			to:       to}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification type %s", notifyType)
+	}
+}
+
+func trimStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// HandleEvent sends the notification if the process state event is one of the notify_on situations
+func (n *Notifier) HandleEvent(event events.Event) {
+	fields := events.ParseEventFields(event.GetBody())
+	program := fields["processname"]
+	if !n.isNotified(program) {
+		return
+	}
+	switch event.GetType() {
+	case "PROCESS_STATE_FATAL":
+		if n.triggers[notifyOnFatal] {
+			n.notify(fmt.Sprintf("program %s entered FATAL state", program),
+				fmt.Sprintf("program %s entered FATAL state from %s state", program, fields["from_state"]))
+		}
+	case "PROCESS_STATE_EXITED":
+		if fields["expected"] != "0" {
+			return
+		}
+		if n.triggers[notifyOnExit] {
+			n.notify(fmt.Sprintf("program %s exited unexpectedly", program),
+				fmt.Sprintf("program %s with pid %s exited unexpectedly from %s state", program, fields["pid"], fields["from_state"]))
+		}
+		n.checkFlapping(program)
+	case "PROCESS_STATE_BACKOFF":
+		n.checkFlapping(program)
+	}
+}
+
+func (n *Notifier) isNotified(program string) bool {
+	if program == "" {
+		return false
+	}
+	if len(n.programs) == 0 {
+		return true
+	}
+	for _, pattern := range n.programs {
+		if matched, err := filepath.Match(pattern, program); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// notify that the program is flapping if it crashes flap_threshold times in the flap_window
+func (n *Notifier) checkFlapping(program string) {
+	if !n.triggers[notifyOnFlap] || n.flapThreshold <= 0 {
+		return
+	}
+	n.lock.Lock()
+	now := time.Now()
+	crashes := []time.Time{now}
+	for _, t := range n.crashes[program] {
+		if now.Sub(t) < n.flapWindow {
+			crashes = append(crashes, t)
+		}
+	}
+	flapping := len(crashes) >= n.flapThreshold
+	if flapping {
+		// start counting again so the flapping is not notified for every crash
+		delete(n.crashes, program)
+	} else {
+		n.crashes[program] = crashes
+	}
+	n.lock.Unlock()
+
+	if flapping {
+		n.notify(fmt.Sprintf("program %s is flapping", program),
+			fmt.Sprintf("program %s is flapping, it crashed %d times in %v", program, len(crashes), n.flapWindow))
+	}
+}
+
+// queue the message to be sent, the message is discarded if the buffer is full
+func (n *Notifier) notify(subject string, text string) {
+	hostname, _ := os.Hostname()
+	msg := notification{subject: fmt.Sprintf("[%s@%s] %s", n.server, hostname, subject),
+		text: fmt.Sprintf("[%s@%s] %s", n.server, hostname, text)}
+	select {
+	case n.queue <- msg:
+	default:
+		log.WithFields(log.Fields{"notify": n.name, "subject": subject}).Error("notifications reach the buffer_size, discard the notification")
+	}
+}
+
+// Stop stops sending the messages, the queued messages are discarded
+func (n *Notifier) Stop() {
+	close(n.stop)
+}
+
+func (n *Notifier) run() {
+	for {
+		select {
+		case <-n.stop:
+			return
+		case msg := <-n.queue:
+			if err := n.sender.send(msg.subject, msg.text); err != nil {
+				log.WithFields(log.Fields{"notify": n.name, "subject": msg.subject, log.ErrorKey: err}).Error("fail to send the notification")
+			}
+		}
+	}
+}
+
+// slackSender posts the message to the Slack incoming webhook
+type slackSender struct {
+	webhookURL string
+	channel    string
+	client     *http.Client
+}
+
+func (s *slackSender) send(subject string, text string) error {
+	msg := map[string]string{"text": text}
+	if s.channel != "" {
+		msg["channel"] = s.channel
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack returns status %s", resp.Status)
+	}
+	return nil
+}
+
+// telegramSender sends the message to the chat by the sendMessage method of Telegram bot API
+type telegramSender struct {
+	apiURL   string
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+func (s *telegramSender) send(subject string, text string) error {
+	resp, err := s.client.PostForm(fmt.Sprintf("%s/bot%s/sendMessage", s.apiURL, s.botToken),
+		url.Values{"chat_id": {s.chatID}, "text": {text}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telegram returns status %s", resp.Status)
+	}
+	return nil
+}
+
+// emailSender sends the message by the SMTP server, the PLAIN authentication is used if username is set
+type emailSender struct {
+	smtpServer string
+	username   string
+	password   string
+	from       string
+	to         []string
+}
+
+func (s *emailSender) send(subject string, text string) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		host := s.smtpServer
+		if pos := strings.LastIndex(host, ":"); pos >= 0 {
+			host = host[0:pos]
+		}
+		auth = smtp.PlainAuth("", s.username, s.password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.from, strings.Join(s.to, ", "), subject, time.Now().Format(time.RFC1123Z), text)
+	return smtp.SendMail(s.smtpServer, auth, s.from, s.to, []byte(msg))
+}
@@ -0,0 +1,79 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// set the directory of the AUTO log files, the system temporary directory is used if dir is empty
+func (p *Process) setChildLogDir(dir string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.childLogDir = dir
+}
+
+// replace the special log file names in the comma separated logFile:
+//
+//	NONE - the log is discarded
+//	AUTO - the log is written to a uniquely named file in the childlogdir
+func (p *Process) resolveLogFile(stdType string, logFile string) string {
+	files := strings.Split(logFile, ",")
+	for i, f := range files {
+		switch strings.ToUpper(strings.TrimSpace(f)) {
+		case "NONE":
+			files[i] = "/dev/null"
+		case "AUTO":
+			files[i] = p.getAutoLogFile(stdType)
+		}
+	}
+	return strings.Join(files, ",")
+}
+
+// get the AUTO log file like <program>-stdout---<identifier>-<suffix>.log, the suffix is
+// generated when the process is created so the file name is kept in the lifetime of supervisord
+func (p *Process) getAutoLogFile(stdType string) string {
+	dir := p.childLogDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s---%s-%s.log", p.GetName(), stdType, p.supervisorID, p.autoLogSuffix))
+}
+
+// remove the AUTO log files and their backups of the program
+func (p *Process) removeAutoLogFiles() {
+	for _, stdType := range []string{"stdout", "stderr"} {
+		autoLogFile := p.getAutoLogFile(stdType)
+		if !strings.Contains(p.resolveLogFile(stdType, p.config.GetString(stdType+"_logfile", "")), autoLogFile) {
+			continue
+		}
+		files, _ := filepath.Glob(autoLogFile + "*")
+		for _, f := range files {
+			if err := os.Remove(f); err != nil {
+				log.WithFields(log.Fields{"program": p.GetName(), "file": f}).Warn("fail to remove the AUTO log file")
+			}
+		}
+	}
+}
+
+// SetChildLogDir sets the directory of the AUTO log files of the programs
+func (pm *Manager) SetChildLogDir(dir string) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.childLogDir = dir
+	for _, proc := range pm.procs {
+		proc.setChildLogDir(dir)
+	}
+}
+
+// remove the AUTO log files of all the programs, they are kept only in the lifetime of supervisord
+func (pm *Manager) removeAutoLogFiles() {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	for _, proc := range pm.procs {
+		proc.removeAutoLogFiles()
+	}
+}
@@ -0,0 +1,55 @@
+package process
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// failures injected to the process for chaos testing
+type chaosState struct {
+	// extra delay before the program is restarted automatically, in nanoseconds
+	restartDelay int64
+	// number of following start attempts failing with simulated spawn error
+	spawnErrors int32
+}
+
+// SetChaosRestartDelay delays the following automatic restarts of program for chaos testing
+func (p *Process) SetChaosRestartDelay(delay time.Duration) {
+	atomic.StoreInt64(&p.chaos.restartDelay, int64(delay))
+}
+
+// SetChaosSpawnErrors makes the following n start attempts of program fail for chaos testing
+func (p *Process) SetChaosSpawnErrors(n int) {
+	atomic.StoreInt32(&p.chaos.spawnErrors, int32(n))
+}
+
+// ClearChaos removes all the failures injected to the process
+func (p *Process) ClearChaos() {
+	p.SetChaosRestartDelay(0)
+	p.SetChaosSpawnErrors(0)
+}
+
+// wait for the injected restart delay
+func (p *Process) waitChaosRestartDelay() {
+	delay := time.Duration(atomic.LoadInt64(&p.chaos.restartDelay))
+	if delay > 0 {
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("chaos: delay the restart for ", delay)
+		time.Sleep(delay)
+	}
+}
+
+// returns a simulated spawn error if it is injected
+func (p *Process) takeChaosSpawnError() error {
+	for {
+		n := atomic.LoadInt32(&p.chaos.spawnErrors)
+		if n <= 0 {
+			return nil
+		}
+		if atomic.CompareAndSwapInt32(&p.chaos.spawnErrors, n, n-1) {
+			return fmt.Errorf("chaos: simulated spawn error")
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package process
+
+import (
+	"os"
+	"runtime"
+)
+
+// the PATH passed to the program which doesn't copy the environment of supervisord
+const minimalPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// get the minimal environment for the program with copy_env=false
+func minimalEnv() []string {
+	if runtime.GOOS != "windows" {
+		return []string{"PATH=" + minimalPath}
+	}
+	// most windows programs can't start without SystemRoot
+	env := make([]string, 0)
+	for _, name := range []string{"SystemRoot", "PATH"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
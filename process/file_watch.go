@@ -0,0 +1,108 @@
+package process
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// start watching the files matching the watch_files patterns, the program is restarted
+// or the reload_signal is sent to it when any of the files changes
+func (p *Process) startFileWatch() {
+	patterns := make([]string, 0)
+	for _, pattern := range strings.Split(p.config.GetStringExpression("watch_files", ""), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if absPattern, err := filepath.Abs(pattern); err == nil {
+			pattern = absPattern
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error("fail to create file watcher: ", err)
+		return
+	}
+	dirs := make(map[string]bool)
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "dir": dir}).Error("fail to watch directory: ", err)
+		}
+	}
+	p.fileWatcher = watcher
+	go p.watchFiles(watcher, patterns)
+}
+
+// stop watching the files of watch_files
+func (p *Process) stopFileWatch() {
+	if p.fileWatcher != nil {
+		p.fileWatcher.Close()
+		p.fileWatcher = nil
+	}
+}
+
+func (p *Process) watchFiles(watcher *fsnotify.Watcher, patterns []string) {
+	// the editors may change a file with several operations, so handle the changes
+	// after no more change in the delay
+	delay := p.config.GetDuration("watch_files_delay", time.Second)
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if matchAnyPattern(patterns, event.Name) {
+				log.WithFields(log.Fields{"program": p.GetName(), "file": event.Name, "op": event.Op.String()}).Debug("watched file is changed")
+				timer.Reset(delay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithFields(log.Fields{"program": p.GetName()}).Error("file watcher error: ", err)
+		case <-timer.C:
+			p.onWatchedFilesChanged()
+		}
+	}
+}
+
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// restart the program or send the reload_signal to it if it is running
+func (p *Process) onWatchedFilesChanged() {
+	p.lock.RLock()
+	running := p.state == Running
+	p.lock.RUnlock()
+	if !running {
+		return
+	}
+	if s := p.config.GetString("reload_signal", ""); s != "" {
+		log.WithFields(log.Fields{"program": p.GetName(), "signal": s}).Info("watched files are changed, send reload signal to program")
+		p.sendSignals(strings.Fields(s), true)
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("watched files are changed, restart program")
+	p.Restart(true, nil)
+}
@@ -0,0 +1,170 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Health the result of the program health check
+type Health string
+
+const (
+	// HealthUnknown no health check is configured or the first check is not finished
+	HealthUnknown Health = ""
+
+	// Healthy the health check passes
+	Healthy Health = "HEALTHY"
+
+	// Unhealthy the health check fails more than healthcheck_retries times in sequence
+	Unhealthy Health = "UNHEALTHY"
+)
+
+// HealthChecker checks if the program is healthy
+type HealthChecker interface {
+	// Check returns nil if the program is healthy
+	Check(timeout time.Duration) error
+}
+
+// HTTPHealthChecker checks the program health by HTTP GET, any 2xx/3xx status is healthy
+type HTTPHealthChecker struct {
+	url string
+	// if true, only status 200 is healthy
+	strict bool
+}
+
+// Check sends HTTP GET request to the url
+func (hc *HTTPHealthChecker) Check(timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(hc.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if hc.strict && resp.StatusCode != http.StatusOK || resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected http status %s", resp.Status)
+	}
+	return nil
+}
+
+// TCPHealthChecker checks the program health by connecting to the TCP address
+type TCPHealthChecker struct {
+	addr string
+}
+
+// Check connects to the TCP address
+func (tc *TCPHealthChecker) Check(timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", tc.addr, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// CmdHealthChecker checks the program health by running a command, exit code 0 is healthy
+type CmdHealthChecker struct {
+	command string
+}
+
+// Check runs the command
+func (cc *CmdHealthChecker) Check(timeout time.Duration) error {
+	args, err := parseCommand(cc.command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty health check command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// NewHealthChecker creates HealthChecker from the healthcheck_url, healthcheck_tcp or
+// healthcheck_cmd of program, returns nil if none of them is configured
+func NewHealthChecker(entry *config.Entry) HealthChecker {
+	if url := entry.GetStringExpression("healthcheck_url", ""); url != "" {
+		return &HTTPHealthChecker{url: url}
+	}
+	if addr := entry.GetStringExpression("healthcheck_tcp", ""); addr != "" {
+		// only port is given, check it on local host
+		if !strings.Contains(addr, ":") {
+			addr = net.JoinHostPort("127.0.0.1", addr)
+		}
+		return &TCPHealthChecker{addr: addr}
+	}
+	if command := entry.GetStringExpression("healthcheck_cmd", ""); command != "" {
+		return &CmdHealthChecker{command: command}
+	}
+	return nil
+}
+
+// GetHealth returns the latest health check result of the process
+func (p *Process) GetHealth() Health {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.health
+}
+
+func (p *Process) setHealth(health Health) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.health = health
+}
+
+// monitor the health of the started program until it exits
+func (p *Process) monitorHealth(cmd *exec.Cmd) {
+	checker := NewHealthChecker(p.config)
+	if checker == nil {
+		return
+	}
+	interval := p.config.GetDuration("healthcheck_interval", 10*time.Second)
+	timeout := p.config.GetDuration("healthcheck_timeout", 5*time.Second)
+	retries := p.config.GetInt("healthcheck_retries", 3)
+	restart := p.config.GetBool("healthcheck_restart", false)
+	failures := 0
+
+	for {
+		time.Sleep(interval)
+		p.lock.RLock()
+		running := p.cmd == cmd && p.state == Running
+		p.lock.RUnlock()
+		if !running {
+			return
+		}
+		err := checker.Check(timeout)
+		if err == nil {
+			failures = 0
+			if p.GetHealth() != Healthy {
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("program is healthy")
+				p.setHealth(Healthy)
+			}
+			continue
+		}
+		failures++
+		log.WithFields(log.Fields{"program": p.GetName(), "failures": failures}).Warn("health check failed: ", err)
+		if failures < retries {
+			continue
+		}
+		if p.GetHealth() != Unhealthy {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("program is unhealthy")
+			p.setHealth(Unhealthy)
+		}
+		if restart {
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("restart the unhealthy program")
+			p.Restart(false, nil)
+			return
+		}
+	}
+}
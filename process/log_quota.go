@@ -0,0 +1,153 @@
+package process
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ochinchina/supervisord/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// the interval to check the disk usage of log files against the quotas
+const logQuotaCheckInterval = 10 * time.Second
+
+// logFileInfo the size and modification time of a log file
+type logFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	// true if it is a rotated backup which can be removed
+	backup bool
+	proc   *Process
+}
+
+// get the current log files and their rotated backups of program
+func (p *Process) getLogFileInfos() []logFileInfo {
+	result := make([]logFileInfo, 0)
+	for _, logFile := range p.GetLogFiles() {
+		for _, name := range logger.GetLogFiles(logFile, p.GetLogFileBackups(logFile)) {
+			fileInfo, err := os.Stat(name)
+			if err != nil {
+				continue
+			}
+			result = append(result, logFileInfo{name: name,
+				size:    fileInfo.Size(),
+				modTime: fileInfo.ModTime(),
+				backup:  name != logFile,
+				proc:    p})
+		}
+	}
+	return result
+}
+
+// GetLogUsage returns the log files with their rotated backups of program and the total bytes of them
+func (p *Process) GetLogUsage() ([]string, int64) {
+	files := make([]string, 0)
+	total := int64(0)
+	for _, info := range p.getLogFileInfos() {
+		files = append(files, info.name)
+		total += info.size
+	}
+	return files, total
+}
+
+// GetLogQuota returns the max bytes of the log files with their rotated backups of program, 0 for no limit
+func (p *Process) GetLogQuota() int64 {
+	return int64(p.config.GetBytes("logfile_quota", 0))
+}
+
+// remove the oldest rotated backups in files until the total bytes of files does not exceed
+// the quota, the current log files are never removed. Returns the total bytes after removing
+func removeOldestLogBackups(files []logFileInfo, quota int64) int64 {
+	total := int64(0)
+	for _, info := range files {
+		total += info.size
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, info := range files {
+		if total <= quota {
+			break
+		}
+		if !info.backup {
+			continue
+		}
+		if err := os.Remove(info.name); err != nil {
+			log.WithFields(log.Fields{"program": info.proc.GetName(), "file": info.name, log.ErrorKey: err}).Error("fail to remove the log backup exceeding quota")
+			continue
+		}
+		log.WithFields(log.Fields{"program": info.proc.GetName(), "file": info.name}).Warn("remove the log backup because the log quota is exceeded")
+		total -= info.size
+	}
+	return total
+}
+
+// SetLogQuota sets the max bytes of the log files of all the programs, 0 for no limit. The
+// quotas are checked periodically if the global quota or the logfile_quota of any program is set
+func (pm *Manager) SetLogQuota(quota int64) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.logQuota = quota
+	pm.startLogQuotaMonitor()
+}
+
+// check if the global quota or the logfile_quota of any program is set, the pm.lock must be held
+func (pm *Manager) hasLogQuota() bool {
+	if pm.logQuota > 0 {
+		return true
+	}
+	for _, proc := range pm.procs {
+		if proc.GetLogQuota() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// start the monitor of the quotas if it is not started and any quota is set, the pm.lock must be held
+func (pm *Manager) startLogQuotaMonitor() {
+	if pm.logQuotaMonitored || !pm.hasLogQuota() {
+		return
+	}
+	pm.logQuotaMonitored = true
+	go pm.monitorLogQuota()
+}
+
+// check the quotas periodically until no quota is set
+func (pm *Manager) monitorLogQuota() {
+	for {
+		time.Sleep(logQuotaCheckInterval)
+		pm.lock.Lock()
+		if !pm.hasLogQuota() {
+			pm.logQuotaMonitored = false
+			pm.lock.Unlock()
+			return
+		}
+		pm.lock.Unlock()
+		pm.enforceLogQuota()
+	}
+}
+
+// remove the oldest log backups of the programs exceeding their logfile_quota, and then the
+// oldest log backups of all the programs if the total exceeds the global quota
+func (pm *Manager) enforceLogQuota() {
+	pm.lock.Lock()
+	procs := pm.getAllProcess()
+	globalQuota := pm.logQuota
+	pm.lock.Unlock()
+
+	all := make([]logFileInfo, 0)
+	for _, proc := range procs {
+		files := proc.getLogFileInfos()
+		if quota := proc.GetLogQuota(); quota > 0 {
+			removeOldestLogBackups(files, quota)
+			files = proc.getLogFileInfos()
+		}
+		all = append(all, files...)
+	}
+	if globalQuota > 0 {
+		removeOldestLogBackups(all, globalQuota)
+	}
+}
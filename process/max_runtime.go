@@ -0,0 +1,43 @@
+package process
+
+import (
+	"os/exec"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the interval to check if the program exceeds its max_runtime
+const maxRuntimeCheckInterval = time.Second
+
+// monitor the run time of started program, stop it gracefully after max_runtime and
+// start it again if its autorestart allows it like the program exits by itself
+func (p *Process) monitorRuntime(cmd *exec.Cmd) {
+	maxRuntime := p.config.GetDuration("max_runtime", 0)
+	if maxRuntime <= 0 {
+		return
+	}
+	for {
+		p.lock.RLock()
+		running := p.cmd == cmd && p.state == Running
+		uptime := p.getUptime()
+		p.lock.RUnlock()
+		if !running {
+			return
+		}
+		if uptime < maxRuntime {
+			wait := maxRuntime - uptime
+			if wait > maxRuntimeCheckInterval {
+				wait = maxRuntimeCheckInterval
+			}
+			time.Sleep(wait)
+			continue
+		}
+		log.WithFields(log.Fields{"program": p.GetName(), "max_runtime": maxRuntime}).Info("stop the program because it runs longer than max_runtime")
+		p.Stop(true)
+		if p.isAutoRestart() {
+			p.startAfterStopped(false, nil)
+		}
+		return
+	}
+}
@@ -0,0 +1,50 @@
+package process
+
+import (
+	"os/exec"
+	"time"
+
+	"github.com/ochinchina/supervisord/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// monitor the memory of started program and restart it if its RSS exceeds max_memory
+// in max_memory_samples sequential samples
+func (p *Process) monitorMemory(cmd *exec.Cmd) {
+	maxMemory := p.config.GetBytes("max_memory", 0)
+	if maxMemory <= 0 {
+		return
+	}
+	interval := p.config.GetDuration("max_memory_interval", 10*time.Second)
+	samples := p.config.GetInt("max_memory_samples", 3)
+	exceeded := 0
+
+	for {
+		time.Sleep(interval)
+		p.lock.RLock()
+		running := p.cmd == cmd && p.state == Running
+		p.lock.RUnlock()
+		if !running {
+			return
+		}
+		pid := cmd.Process.Pid
+		rss, err := getProcessRSS(pid)
+		if err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Error("stop monitoring memory, fail to get memory of program: ", err)
+			return
+		}
+		if rss <= maxMemory {
+			exceeded = 0
+			continue
+		}
+		exceeded++
+		log.WithFields(log.Fields{"program": p.GetName(), "rss": rss, "max_memory": maxMemory}).Warn("memory of program exceeds max_memory")
+		if exceeded < samples {
+			continue
+		}
+		events.EmitEvent(events.CreateProcessMemoryExceededEvent(p.GetName(), p.GetGroup(), pid, rss, maxMemory))
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("restart the program because its memory exceeds max_memory")
+		p.Restart(false, nil)
+		return
+	}
+}
@@ -6,5 +6,7 @@ import (
 	"syscall"
 )
 
-func setDeathsig(_ *syscall.SysProcAttr) {
+// start the program in a new process group, so CTRL_BREAK can be sent to it for graceful stop
+func setDeathsig(sysProcAttr *syscall.SysProcAttr) {
+	sysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
 }
@@ -0,0 +1,136 @@
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the clock ticks per second used by the /proc/<pid>/stat, it is 100 on almost all the linux systems
+const clockTicks = 100
+
+// get the resident set size in bytes of the process from /proc/<pid>/statm
+func getProcessRSS(pid int) (int, error) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid statm of process %d", pid)
+	}
+	pages, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, err
+	}
+	return pages * os.Getpagesize(), nil
+}
+
+// read the fields of /proc/<pid>/stat from the third one, the state of the process
+func readProcessStat(pid int) ([]string, error) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	// the command name in the second field may contain spaces, so parse the fields after it
+	s := string(b)
+	pos := strings.LastIndex(s, ")")
+	if pos == -1 {
+		return nil, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(s[pos+1:])
+	// num_threads is the 20th field, the last one used
+	if len(fields) < 18 {
+		return nil, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return fields, nil
+}
+
+// get the user and system CPU time consumed by the process from /proc/<pid>/stat
+func getProcessCPUTime(pid int) (time.Duration, error) {
+	fields, err := readProcessStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	// utime and stime are the 14th and 15th fields of stat
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicks, nil
+}
+
+// get the number of threads of the process from /proc/<pid>/stat
+func getProcessThreadCount(pid int) (int, error) {
+	fields, err := readProcessStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(fields[17])
+}
+
+// get the number of open file descriptors of the process from /proc/<pid>/fd
+func getProcessFDCount(pid int) (int, error) {
+	entries, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// the numbers of the direct children of the processes by their pids, built from /proc/<pid>/stat of all
+// the processes once in a resourceSampleInterval and shared by the samples of all the programs
+var childCounts = struct {
+	sync.Mutex
+	counts map[int]int
+	time   time.Time
+}{}
+
+// count the direct children of all the processes by the parent pid in /proc/<pid>/stat
+func readChildCounts() (map[int]int, error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int)
+	for _, stat := range stats {
+		child, err := strconv.Atoi(filepath.Base(filepath.Dir(stat)))
+		if err != nil {
+			continue
+		}
+		// the process may exit after it is listed
+		if fields, err := readProcessStat(child); err == nil {
+			if ppid, err := strconv.Atoi(fields[1]); err == nil {
+				counts[ppid]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// get the number of the direct children of the process, the counts of all the processes are read
+// again if they are read before the last resourceSampleInterval
+func getProcessChildCount(pid int) (int, error) {
+	childCounts.Lock()
+	defer childCounts.Unlock()
+	if childCounts.counts == nil || time.Since(childCounts.time) >= resourceSampleInterval {
+		counts, err := readChildCounts()
+		if err != nil {
+			return 0, err
+		}
+		childCounts.counts = counts
+		childCounts.time = time.Now()
+	}
+	return childCounts.counts[pid], nil
+}
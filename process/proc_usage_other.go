@@ -0,0 +1,34 @@
+// +build !linux
+
+package process
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// get the resident set size in bytes of the process
+func getProcessRSS(pid int) (int, error) {
+	return 0, fmt.Errorf("memory usage of process is not supported on %s", runtime.GOOS)
+}
+
+// get the user and system CPU time consumed by the process
+func getProcessCPUTime(pid int) (time.Duration, error) {
+	return 0, fmt.Errorf("cpu usage of process is not supported on %s", runtime.GOOS)
+}
+
+// get the number of threads of the process
+func getProcessThreadCount(pid int) (int, error) {
+	return 0, fmt.Errorf("thread count of process is not supported on %s", runtime.GOOS)
+}
+
+// get the number of open file descriptors of the process
+func getProcessFDCount(pid int) (int, error) {
+	return 0, fmt.Errorf("fd count of process is not supported on %s", runtime.GOOS)
+}
+
+// get the number of the direct children of the process
+func getProcessChildCount(pid int) (int, error) {
+	return 0, fmt.Errorf("child count of process is not supported on %s", runtime.GOOS)
+}
@@ -7,7 +7,9 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/ochinchina/filechangemonitor"
 	"github.com/ochinchina/supervisord/config"
 	"github.com/ochinchina/supervisord/events"
@@ -55,6 +58,9 @@ const (
 
 var scheduler *cron.Cron = nil
 
+// the max bytes written to the stdin of the program at once
+const stdinChunkSize = 64 * 1024
+
 func init() {
 	scheduler = cron.New(cron.WithSeconds())
 	scheduler.Start()
@@ -92,13 +98,55 @@ type Process struct {
 	state        State
 	// true if process is starting
 	inStart bool
+	// closed when the loop starting and restarting the program ends
+	startDone chan struct{}
 	// true if the process is stopped by user
 	stopByUser bool
 	retryTimes *int32
+	health     Health
 	lock       sync.RWMutex
 	stdin      io.WriteCloser
-	StdoutLog  logger.Logger
-	StderrLog  logger.Logger
+	// serialize the writes to stdin so the data sent by a call is not interleaved with the others
+	stdinLock sync.Mutex
+	StdoutLog logger.Logger
+	StderrLog logger.Logger
+	// the command evaluated again when the configuration is reloaded, empty if not reloaded
+	reloadedCommand string
+	// environment overrides of current run, in KEY=VALUE format
+	envOverrides []string
+	// readiness checker of current run
+	readyChecker HealthChecker
+	// failures injected for chaos testing
+	chaos chaosState
+	// the watcher of watch_files
+	fileWatcher *fsnotify.Watcher
+	// the latest state changes
+	stateHistory []StateChange
+	// the latest resource usage samples
+	resourceSamples []ResourceSample
+	// the scheduler entry of restart_cron
+	restartCronID cron.EntryID
+	// the scheduler entry of cron
+	cronID cron.EntryID
+	// the number of the times the program is restarted automatically after it exits
+	restarts int32
+	// called with the p.lock held after the state is changed, it must not block
+	stateListener func(p *Process, state State)
+	// true if the standby program is started because its primary is in FATAL state
+	standbyActive bool
+	// the Job Object of the process tree on windows
+	job processJob
+	// the error of the latest failed spawn, empty if the program is spawned
+	spawnErr string
+	// the directory and the unique suffix of the AUTO log files
+	childLogDir   string
+	autoLogSuffix string
+	// the last output kept in memory if no log file is set, they are kept across the restarts
+	stdoutBuffer *logger.RingBuffer
+	stderrBuffer *logger.RingBuffer
+	// send the new output to the followers of the logs
+	stdoutBroadcaster *logger.LogBroadcaster
+	stderrBroadcaster *logger.LogBroadcaster
 }
 
 // NewProcess creates new Process object
@@ -112,9 +160,14 @@ func NewProcess(supervisorID string, config *config.Entry) *Process {
 		inStart:    false,
 		stopByUser: false,
 		retryTimes: new(int32)}
+	proc.autoLogSuffix = fmt.Sprintf("%x", time.Now().UnixNano())
+	proc.stdoutBroadcaster = logger.NewLogBroadcaster()
+	proc.stderrBroadcaster = logger.NewLogBroadcaster()
 	proc.config = config
 	proc.cmd = nil
 	proc.addToCron()
+	proc.addRestartCron()
+	proc.startFileWatch()
 	return proc
 }
 
@@ -122,13 +175,22 @@ func (p *Process) GetConfig() *config.Entry {
 	return p.config
 }
 
+// update the command of process when supervisord reloads its configuration, the host
+// expressions like %(host_node_name)s in it are evaluated again and the new command
+// takes effect from next start of the program
+func (p *Process) updateCommand(config *config.Entry) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reloadedCommand = config.GetStringExpression("command", "")
+}
+
 // add this process to crontab
 func (p *Process) addToCron() {
 	s := p.config.GetString("cron", "")
 
 	if s != "" {
 		log.WithFields(log.Fields{"program": p.GetName()}).Info("try to create cron program with cron expression:", s)
-		scheduler.AddFunc(s, func() {
+		p.cronID, _ = scheduler.AddFunc(s, func() {
 			log.WithFields(log.Fields{"program": p.GetName()}).Info("start cron program")
 			if !p.isRunning() {
 				p.Start(false)
@@ -140,8 +202,20 @@ func (p *Process) addToCron() {
 
 // Start process
 // Args:
-//  wait - true, wait the program started or failed
+//
+//	wait - true, wait the program started or failed
 func (p *Process) Start(wait bool) {
+	p.StartWithEnv(wait, nil)
+}
+
+// StartWithEnv starts process with environment overrides in KEY=VALUE format. The
+// overrides are only applied to this run and are not saved to the configuration
+//
+// Args:
+//
+//	wait - true, wait the program started or failed
+//	envOverrides - the environment variables overriding the configured ones
+func (p *Process) StartWithEnv(wait bool, envOverrides []string) {
 	log.WithFields(log.Fields{"program": p.GetName()}).Info("try to start program")
 	p.lock.Lock()
 	if p.inStart {
@@ -151,7 +225,9 @@ func (p *Process) Start(wait bool) {
 	}
 
 	p.inStart = true
+	p.startDone = make(chan struct{})
 	p.stopByUser = false
+	p.envOverrides = envOverrides
 	p.lock.Unlock()
 
 	var runCond *sync.Cond
@@ -170,8 +246,12 @@ func (p *Process) Start(wait bool) {
 					runCond.L.Unlock()
 				}
 			})
+			if p.stopByUser {
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("Stopped by user, don't start it again")
+				break
+			}
 			// avoid print too many logs if fail to start program too quickly
-			if time.Now().Unix()-p.startTime.Unix() < 2 {
+			if time.Since(p.startTime) < 2*time.Second {
 				time.Sleep(5 * time.Second)
 			}
 			if p.stopByUser {
@@ -182,9 +262,12 @@ func (p *Process) Start(wait bool) {
 				log.WithFields(log.Fields{"program": p.GetName()}).Info("Don't start the stopped program because its autorestart flag is false")
 				break
 			}
+			p.waitChaosRestartDelay()
+			atomic.AddInt32(&p.restarts, 1)
 		}
 		p.lock.Lock()
 		p.inStart = false
+		close(p.startDone)
 		p.lock.Unlock()
 	}()
 
@@ -194,6 +277,28 @@ func (p *Process) Start(wait bool) {
 	}
 }
 
+// Restart stops the program and waits for it to exit in stopwaitsecs, then starts it again with
+// the environment overrides. The program is started after the loop restarting it ends, so it is
+// not skipped as already started
+func (p *Process) Restart(wait bool, envOverrides []string) {
+	p.Stop(true)
+	p.startAfterStopped(wait, envOverrides)
+}
+
+// start the stopped program after the loop restarting it ends
+func (p *Process) startAfterStopped(wait bool, envOverrides []string) {
+	for {
+		p.lock.RLock()
+		inStart, startDone := p.inStart, p.startDone
+		p.lock.RUnlock()
+		if !inStart {
+			break
+		}
+		<-startDone
+	}
+	p.StartWithEnv(wait, envOverrides)
+}
+
 // GetName returns name of program or event listener
 func (p *Process) GetName() string {
 	if p.config.IsProgram() {
@@ -215,20 +320,76 @@ func (p *Process) GetDescription() string {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 	if p.state == Running {
-		seconds := int(time.Now().Sub(p.startTime).Seconds())
+		seconds := int(p.getUptime().Seconds())
 		minutes := seconds / 60
 		hours := minutes / 60
 		days := hours / 24
 		if days > 0 {
-			return fmt.Sprintf("pid %d, uptime %d days, %d:%02d:%02d", p.cmd.Process.Pid, days, hours%24, minutes%60, seconds%60)
+			return fmt.Sprintf("pid %d, uptime %d days, %d:%02d:%02d%s", p.cmd.Process.Pid, days, hours%24, minutes%60, seconds%60, p.envOverridesDescription())
 		}
-		return fmt.Sprintf("pid %d, uptime %d:%02d:%02d", p.cmd.Process.Pid, hours%24, minutes%60, seconds%60)
+		return fmt.Sprintf("pid %d, uptime %d:%02d:%02d%s", p.cmd.Process.Pid, hours%24, minutes%60, seconds%60, p.envOverridesDescription())
+	} else if (p.state == Backoff || p.state == Fatal) && p.spawnErr != "" {
+		return p.spawnErr
 	} else if p.state != Stopped {
 		return p.stopTime.String()
 	}
 	return ""
 }
 
+// GetSpawnErr returns the error of the latest failed spawn, empty if the program is spawned
+func (p *Process) GetSpawnErr() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.spawnErr
+}
+
+// GetEnvOverrides returns names of the environment variables overridden in current run
+func (p *Process) GetEnvOverrides() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return EnvNames(p.envOverrides)
+}
+
+// GetEnv returns the environment variables in KEY=VALUE format sorted by name the process is started with, the
+// variable set later overrides the earlier one like exec does. Nil if the process is never started
+func (p *Process) GetEnv() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.cmd == nil {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, e := range p.cmd.Env {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+	result := make([]string, 0, len(values))
+	for name, value := range values {
+		result = append(result, name+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// EnvNames returns the names of environment variables in KEY=VALUE format
+func EnvNames(env []string) []string {
+	names := make([]string, 0)
+	for _, e := range env {
+		names = append(names, strings.SplitN(e, "=", 2)[0])
+	}
+	return names
+}
+
+// the values are not shown because they may contain secrets
+func (p *Process) envOverridesDescription() string {
+	if len(p.envOverrides) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", env override: %s", strings.Join(EnvNames(p.envOverrides), ","))
+}
+
 // GetExitstatus returns exit status of the process if the program exit
 func (p *Process) GetExitstatus() int {
 	p.lock.RLock()
@@ -281,9 +442,25 @@ func (p *Process) GetStopTime() time.Time {
 	}
 }
 
+// GetUptime returns how long the process is in running state. It is measured by the
+// monotonic clock, so it is not affected by the wall clock changes
+func (p *Process) GetUptime() time.Duration {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.getUptime()
+}
+
+func (p *Process) getUptime() time.Duration {
+	if p.state != Running {
+		return 0
+	}
+	// time.Since uses the monotonic clock reading of startTime
+	return time.Since(p.startTime)
+}
+
 // GetStdoutLogfile returns program stdout log filename
 func (p *Process) GetStdoutLogfile() string {
-	fileName := p.config.GetStringExpression("stdout_logfile", "/dev/null")
+	fileName := p.resolveLogFile("stdout", p.config.GetStringExpression("stdout_logfile", "/dev/null"))
 	expandFile, err := PathExpand(fileName)
 	if err != nil {
 		return fileName
@@ -293,7 +470,7 @@ func (p *Process) GetStdoutLogfile() string {
 
 // GetStderrLogfile returns program stderr log filename
 func (p *Process) GetStderrLogfile() string {
-	fileName := p.config.GetStringExpression("stderr_logfile", "/dev/null")
+	fileName := p.resolveLogFile("stderr", p.config.GetStringExpression("stderr_logfile", "/dev/null"))
 	expandFile, err := PathExpand(fileName)
 	if err != nil {
 		return fileName
@@ -301,6 +478,81 @@ func (p *Process) GetStderrLogfile() string {
 	return expandFile
 }
 
+// GetLogFiles returns the regular files the stdout and stderr of program are written to
+func (p *Process) GetLogFiles() []string {
+	result := make([]string, 0)
+	for _, logFile := range append(p.getStdLogFiles("stdout"), p.getStdLogFiles("stderr")...) {
+		found := false
+		for _, f := range result {
+			found = found || f == logFile
+		}
+		if !found {
+			result = append(result, logFile)
+		}
+	}
+	return result
+}
+
+// GetLogFileBackups returns the number of rotated backups kept for the log file of program
+func (p *Process) GetLogFileBackups(logFile string) int {
+	for _, f := range p.getStdLogFiles("stdout") {
+		if f == logFile {
+			return p.config.GetInt("stdout_logfile_backups", 10)
+		}
+	}
+	return p.config.GetInt("stderr_logfile_backups", 10)
+}
+
+// get the regular log files of stdout or stderr including the files of log routes
+func (p *Process) getStdLogFiles(stdType string) []string {
+	var result []string
+	if stdType == "stdout" {
+		result = splitLogFiles(p.GetStdoutLogfile())
+	} else {
+		result = splitLogFiles(p.GetStderrLogfile())
+	}
+	for _, route := range p.getLogRouteConfigs(stdType) {
+		result = append(result, splitLogFiles(route.logFile)...)
+	}
+	return result
+}
+
+// ReopenLogs closes and opens the stdout and stderr log files of the running program again,
+// the log files of a stopped program are opened in its next start
+func (p *Process) ReopenLogs() error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if !p.isRunning() {
+		return nil
+	}
+	var err error
+	if p.StdoutLog != nil {
+		err = p.StdoutLog.Reopen()
+	}
+	if p.StderrLog != nil && p.StderrLog != p.StdoutLog {
+		if stderrErr := p.StderrLog.Reopen(); err == nil {
+			err = stderrErr
+		}
+	}
+	return err
+}
+
+// split the configured log destinations and keep only the regular files
+func splitLogFiles(logFile string) []string {
+	result := make([]string, 0)
+	for _, f := range strings.Split(logFile, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || strings.HasPrefix(f, "/dev/") || strings.HasPrefix(f, "syslog") {
+			continue
+		}
+		if _, ok := logger.GetLogBackend(f); ok {
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
 func (p *Process) getStartSeconds() int64 {
 	return int64(p.config.GetInt("startsecs", 1))
 }
@@ -314,6 +566,10 @@ func (p *Process) getStartRetries() int32 {
 }
 
 func (p *Process) isAutoStart() bool {
+	// the standby program is only started when its primary fails
+	if p.getStandbyFor() != "" {
+		return false
+	}
 	return p.config.GetString("autostart", "true") == "true"
 }
 
@@ -328,8 +584,39 @@ func (p *Process) getNumberProcs() int {
 
 // SendProcessStdin sends data to process stdin
 func (p *Process) SendProcessStdin(chars string) error {
+	return p.SendProcessStdinBytes([]byte(chars))
+}
+
+// SendProcessStdinBytes sends the binary data to process stdin in chunks, the data is written after the data
+// of the previous calls is written
+func (p *Process) SendProcessStdinBytes(data []byte) error {
+	p.stdinLock.Lock()
+	defer p.stdinLock.Unlock()
+	stdin := p.stdin
+	if stdin == nil {
+		return fmt.Errorf("NO_FILE")
+	}
+	for len(data) > 0 {
+		n := len(data)
+		if n > stdinChunkSize {
+			n = stdinChunkSize
+		}
+		if _, err := stdin.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// CloseProcessStdin closes the process stdin, so the program reading stdin gets end of input
+func (p *Process) CloseProcessStdin() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	if p.stdin != nil {
-		_, err := p.stdin.Write([]byte(chars))
+		err := p.stdin.Close()
+		p.stdin = nil
 		return err
 	}
 	return fmt.Errorf("NO_FILE")
@@ -392,7 +679,6 @@ func (p *Process) getExitCodes() []int {
 }
 
 // check if the process is running or not
-//
 func (p *Process) isRunning() bool {
 	if p.cmd != nil && p.cmd.Process != nil {
 		if runtime.GOOS == "windows" {
@@ -406,7 +692,11 @@ func (p *Process) isRunning() bool {
 
 // create Command object for the program
 func (p *Process) createProgramCommand() error {
-	args, err := parseCommand(p.config.GetStringExpression("command", ""))
+	command := p.reloadedCommand
+	if command == "" {
+		command = p.config.GetStringExpression("command", "")
+	}
+	args, err := parseCommand(command)
 
 	if err != nil {
 		return err
@@ -415,21 +705,41 @@ func (p *Process) createProgramCommand() error {
 	if err != nil {
 		return err
 	}
-	if p.setUser() != nil {
+	if err = p.setUser(); err != nil {
 		log.WithFields(log.Fields{"user": p.config.GetString("user", "")}).Error("fail to run as user")
-		return fmt.Errorf("fail to set user")
+		return fmt.Errorf("fail to set user %s: %v", p.config.GetString("user", ""), err)
 	}
 	p.setProgramRestartChangeMonitor(args[0])
 	setDeathsig(p.cmd.SysProcAttr)
 	p.setEnv()
 	p.setDir()
 	p.setLog()
+	p.setReadyChecker()
 
 	p.stdin, _ = p.cmd.StdinPipe()
 	return nil
 
 }
 
+// create the readiness checker for this run, the output of program is also written
+// to the checker if it checks the log lines
+func (p *Process) setReadyChecker() {
+	p.readyChecker = nil
+	if !p.config.IsProgram() {
+		return
+	}
+	p.readyChecker = NewReadyChecker(p.config)
+	if logChecker, ok := p.readyChecker.(*LogReadyChecker); ok {
+		if p.cmd.Stdout == p.cmd.Stderr {
+			p.cmd.Stdout = io.MultiWriter(p.cmd.Stdout, logChecker)
+			p.cmd.Stderr = p.cmd.Stdout
+		} else {
+			p.cmd.Stdout = io.MultiWriter(p.cmd.Stdout, logChecker)
+			p.cmd.Stderr = io.MultiWriter(p.cmd.Stderr, logChecker)
+		}
+	}
+}
+
 func (p *Process) setProgramRestartChangeMonitor(programPath string) {
 	if p.config.GetBool("restart_when_binary_changed", false) {
 		absPath, err := filepath.Abs(programPath)
@@ -498,6 +808,12 @@ func (p *Process) waitForExit(startSecs int64) {
 	p.stopTime = time.Now()
 }
 
+// record the error of failed spawn and emit the spawn error event, the p.lock must be held
+func (p *Process) setSpawnErr(err error) {
+	p.spawnErr = err.Error()
+	events.EmitEvent(events.CreateProcessSpawnErrorEvent(p.GetName(), p.GetGroup(), p.spawnErr))
+}
+
 // fail to start the program
 func (p *Process) failToStartProgram(reason string, finishCb func()) {
 	log.WithFields(log.Fields{"program": p.GetName()}).Errorf(reason)
@@ -505,13 +821,19 @@ func (p *Process) failToStartProgram(reason string, finishCb func()) {
 	finishCb()
 }
 
-// monitor if the program is in running before endTime
-//
-func (p *Process) monitorProgramIsRunning(endTime time.Time, monitorExited *int32, programExited *int32) {
+// monitor if the program is in running before endTime, and then wait for its readiness
+// if readyChecker is not nil
+func (p *Process) monitorProgramIsRunning(endTime time.Time, readyChecker HealthChecker, monitorExited *int32, programExited *int32) {
 	// if time is not expired
 	for time.Now().Before(endTime) && atomic.LoadInt32(programExited) == 0 {
 		time.Sleep(time.Duration(100) * time.Millisecond)
 	}
+	if readyChecker != nil && atomic.LoadInt32(programExited) == 0 && !p.waitForReady(readyChecker, programExited) {
+		if atomic.LoadInt32(programExited) == 0 {
+			log.WithFields(log.Fields{"program": p.GetName()}).Error("kill the program because it is not ready")
+			p.Signal(syscall.SIGKILL, true)
+		}
+	}
 	atomic.StoreInt32(monitorExited, 1)
 
 	p.lock.Lock()
@@ -557,15 +879,20 @@ func (p *Process) run(finishCb func()) {
 		p.changeStateTo(Starting)
 		atomic.AddInt32(p.retryTimes, 1)
 
-		err := p.createProgramCommand()
-		if err != nil {
-			p.failToStartProgram("fail to create program", finishCbWrapper)
-			break
+		// the simulated spawn error is taken before the command is created, so no stdin pipe or log is left open
+		err := p.takeChaosSpawnError()
+		if err == nil {
+			if err = p.createProgramCommand(); err != nil {
+				// retry does not help if the command or user is misconfigured
+				p.setSpawnErr(err)
+				p.failToStartProgram(fmt.Sprintf("fail to create program with error:%v", err), finishCbWrapper)
+				break
+			}
+			err = p.cmd.Start()
 		}
 
-		err = p.cmd.Start()
-
 		if err != nil {
+			p.setSpawnErr(err)
 			if atomic.LoadInt32(p.retryTimes) >= p.getStartRetries() {
 				p.failToStartProgram(fmt.Sprintf("fail to start program with error:%v", err), finishCbWrapper)
 				break
@@ -575,12 +902,14 @@ func (p *Process) run(finishCb func()) {
 				continue
 			}
 		}
+		p.spawnErr = ""
 		if p.StdoutLog != nil {
 			p.StdoutLog.SetPid(p.cmd.Process.Pid)
 		}
 		if p.StderrLog != nil {
 			p.StderrLog.SetPid(p.cmd.Process.Pid)
 		}
+		p.attachJob()
 
 		// logger.CompositeLogger is not `os.File`, so `cmd.Wait()` will wait for the logger to close
 		// if parent process passes its FD to child process, the logger will not close even when parent process exits
@@ -607,13 +936,14 @@ func (p *Process) run(finishCb func()) {
 		programExited := int32(0)
 		// Set startsec to 0 to indicate that the program needn't stay
 		// running for any particular amount of time.
-		if startSecs <= 0 {
+		readyChecker := p.readyChecker
+		if startSecs <= 0 && readyChecker == nil {
 			log.WithFields(log.Fields{"program": p.GetName()}).Info("success to start program")
 			p.changeStateTo(Running)
 			go finishCbWrapper()
 		} else {
 			go func() {
-				p.monitorProgramIsRunning(endTime, &monitorExited, &programExited)
+				p.monitorProgramIsRunning(endTime, readyChecker, &monitorExited, &programExited)
 				finishCbWrapper()
 			}()
 		}
@@ -646,7 +976,14 @@ func (p *Process) run(finishCb func()) {
 		}
 
 		p.lock.Lock()
+		p.closeJob()
 
+		// the program is stopped by user
+		if p.state == Stopping {
+			p.changeStateTo(Stopped)
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("program stopped")
+			break
+		}
 		// if the program still in running after startSecs
 		if p.state == Running {
 			p.changeStateTo(Exited)
@@ -667,42 +1004,68 @@ func (p *Process) run(finishCb func()) {
 
 }
 
+// change the state of program or event listener and emit the PROCESS_STATE_* event of the
+// new state, the p.lock must be held
 func (p *Process) changeStateTo(procState State) {
+	p.emitStateEvent(procState)
 	if p.config.IsProgram() {
-		progName := p.config.GetProgramName()
-		groupName := p.config.GetGroupName()
 		if procState == Starting {
-			events.EmitEvent(events.CreateProcessStartingEvent(progName, groupName, p.state.String(), int(atomic.LoadInt32(p.retryTimes))))
+			p.health = HealthUnknown
 		} else if procState == Running {
-			events.EmitEvent(events.CreateProcessRunningEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
-		} else if procState == Backoff {
-			events.EmitEvent(events.CreateProcessBackoffEvent(progName, groupName, p.state.String(), int(atomic.LoadInt32(p.retryTimes))))
-		} else if procState == Stopping {
-			events.EmitEvent(events.CreateProcessStoppingEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
-		} else if procState == Exited {
-			exitCode, err := p.getExitCode()
-			expected := 0
-			if err == nil && p.inExitCodes(exitCode) {
-				expected = 1
-			}
-			events.EmitEvent(events.CreateProcessExitedEvent(progName, groupName, p.state.String(), expected, p.cmd.Process.Pid))
-		} else if procState == Fatal {
-			events.EmitEvent(events.CreateProcessFatalEvent(progName, groupName, p.state.String()))
-		} else if procState == Stopped {
-			events.EmitEvent(events.CreateProcessStoppedEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
-		} else if procState == Unknown {
-			events.EmitEvent(events.CreateProcessUnknownEvent(progName, groupName, p.state.String()))
+			go p.monitorHealth(p.cmd)
+			go p.monitorMemory(p.cmd)
+			go p.monitorRuntime(p.cmd)
+			go p.sampleResources(p.cmd)
 		}
 	}
+	p.recordStateChange(p.state, procState)
 	p.state = procState
+	if p.stateListener != nil {
+		p.stateListener(p, procState)
+	}
+}
+
+// emit the event of changing from the current state to procState with the header fields
+// like supervisor, the from_state is in upper case like "STARTING"
+func (p *Process) emitStateEvent(procState State) {
+	name := p.GetName()
+	group := p.GetGroup()
+	fromState := strings.ToUpper(p.state.String())
+	pid := 0
+	if p.cmd != nil && p.cmd.Process != nil {
+		pid = p.cmd.Process.Pid
+	}
+	switch procState {
+	case Starting:
+		events.EmitEvent(events.CreateProcessStartingEvent(name, group, fromState, int(atomic.LoadInt32(p.retryTimes))))
+	case Running:
+		events.EmitEvent(events.CreateProcessRunningEvent(name, group, fromState, pid))
+	case Backoff:
+		events.EmitEvent(events.CreateProcessBackoffEvent(name, group, fromState, int(atomic.LoadInt32(p.retryTimes))))
+	case Stopping:
+		events.EmitEvent(events.CreateProcessStoppingEvent(name, group, fromState, pid))
+	case Exited:
+		exitCode, err := p.getExitCode()
+		expected := 0
+		if err == nil && p.inExitCodes(exitCode) {
+			expected = 1
+		}
+		events.EmitEvent(events.CreateProcessExitedEvent(name, group, fromState, expected, pid))
+	case Fatal:
+		events.EmitEvent(events.CreateProcessFatalEvent(name, group, fromState))
+	case Stopped:
+		events.EmitEvent(events.CreateProcessStoppedEvent(name, group, fromState, pid))
+	default:
+		events.EmitEvent(events.CreateProcessUnknownEvent(name, group, fromState))
+	}
 }
 
 // Signal sends signal to the process
 //
 // Args:
-//   sig - the signal to the process
-//   sigChildren - if true, sends the same signal to the process and its children
 //
+//	sig - the signal to the process
+//	sigChildren - if true, sends the same signal to the process and its children
 func (p *Process) Signal(sig os.Signal, sigChildren bool) error {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -727,12 +1090,16 @@ func (p *Process) sendSignals(sigs []string, sigChildren bool) {
 // send signal to the process
 //
 // Args:
-//    sig - the signal to be sent
-//    sigChildren - if true, the signal also will be sent to children processes too
 //
+//	sig - the signal to be sent
+//	sigChildren - if true, the signal also will be sent to children processes too
 func (p *Process) sendSignal(sig os.Signal, sigChildren bool) error {
 	if p.cmd != nil && p.cmd.Process != nil {
 		log.WithFields(log.Fields{"program": p.GetName(), "signal": sig}).Info("Send signal to program")
+		// kill the whole process tree in the Job Object on windows
+		if sig == syscall.SIGKILL && p.terminateJob() {
+			return nil
+		}
 		err := signals.Kill(p.cmd.Process, sig, sigChildren)
 		return err
 	}
@@ -741,11 +1108,16 @@ func (p *Process) sendSignal(sig os.Signal, sigChildren bool) error {
 
 func (p *Process) setEnv() {
 	envFromFiles := p.config.GetEnvFromFiles("envFiles")
-	env := p.config.GetEnv("environment")
+	env := append(p.config.GetEnv("environment"), p.envOverrides...)
+	baseEnv := os.Environ()
+	if !p.config.GetBool("copy_env", true) {
+		// don't leak the environment of supervisord to the program
+		baseEnv = minimalEnv()
+	}
 	if len(env)+len(envFromFiles) != 0 {
-		p.cmd.Env = append(append(os.Environ(), envFromFiles...), env...)
+		p.cmd.Env = append(append(baseEnv, envFromFiles...), env...)
 	} else {
-		p.cmd.Env = os.Environ()
+		p.cmd.Env = baseEnv
 	}
 }
 
@@ -781,7 +1153,7 @@ func (p *Process) setLog() {
 
 		if captureBytes > 0 {
 			log.WithFields(log.Fields{"program": p.config.GetProgramName()}).Info("capture stderr process communication")
-			p.StderrLog = logger.NewLogCaptureLogger(p.StdoutLog,
+			p.StderrLog = logger.NewLogCaptureLogger(p.StderrLog,
 				captureBytes,
 				"PROCESS_COMMUNICATION_STDERR",
 				p.GetName(),
@@ -816,7 +1188,7 @@ func (p *Process) setLog() {
 
 func (p *Process) createStdoutLogEventEmitter() logger.LogEventEmitter {
 	if p.config.GetBytes("stdout_capture_maxbytes", 0) <= 0 && p.config.GetBool("stdout_events_enabled", false) {
-		return logger.NewStdoutLogEventEmitter(p.config.GetProgramName(), p.config.GetGroupName(), func() int {
+		return logger.NewStdoutLogEventEmitter(p.config.GetProgramName(), p.GetGroup(), func() int {
 			return p.GetPid()
 		})
 	}
@@ -825,7 +1197,7 @@ func (p *Process) createStdoutLogEventEmitter() logger.LogEventEmitter {
 
 func (p *Process) createStderrLogEventEmitter() logger.LogEventEmitter {
 	if p.config.GetBytes("stderr_capture_maxbytes", 0) <= 0 && p.config.GetBool("stderr_events_enabled", false) {
-		return logger.NewStdoutLogEventEmitter(p.config.GetProgramName(), p.config.GetGroupName(), func() int {
+		return logger.NewStderrLogEventEmitter(p.config.GetProgramName(), p.GetGroup(), func() int {
 			return p.GetPid()
 		})
 	}
@@ -841,7 +1213,11 @@ func (p *Process) registerEventListener(eventListenerName string,
 		stdin,
 		stdout,
 		p.config.GetInt("buffer_size", 100))
-	events.RegisterEventListener(eventListenerName, _events, eventListener)
+	filter, err := events.NewEventFilter(p.config.GetString("filter", ""))
+	if err != nil {
+		log.WithFields(log.Fields{"eventListener": eventListenerName, log.ErrorKey: err}).Error("invalid event filter, the events are not filtered")
+	}
+	events.RegisterFilteredEventHandler(eventListenerName, _events, filter, eventListener)
 }
 
 func (p *Process) unregisterEventListener(eventListenerName string) {
@@ -868,7 +1244,7 @@ func (p *Process) createStdoutLogger() logger.Logger {
 		props["syslog_priority"] = syslog_priority
 	}
 
-	return logger.NewLogger(p.GetName(), logFile, logger.NewNullLocker(), maxBytes, backups, props, logEventEmitter)
+	return p.followLogs("stdout", p.shareStdout(p.stripANSI(p.routeLogs("stdout", p.timestampLogs("stdout", p.createLogger("stdout", logFile, maxBytes, backups, props, logEventEmitter)), maxBytes, backups, props))))
 }
 
 func (p *Process) createStderrLogger() logger.Logger {
@@ -891,7 +1267,118 @@ func (p *Process) createStderrLogger() logger.Logger {
 		props["syslog_priority"] = syslog_priority
 	}
 
-	return logger.NewLogger(p.GetName(), logFile, logger.NewNullLocker(), maxBytes, backups, props, logEventEmitter)
+	return p.followLogs("stderr", p.shareStdout(p.stripANSI(p.routeLogs("stderr", p.timestampLogs("stderr", p.createLogger("stderr", logFile, maxBytes, backups, props, logEventEmitter)), maxBytes, backups, props))))
+}
+
+// create the logger of stdout or stderr, the output is kept in a ring buffer of
+// <stdType>_ringbuffer_maxbytes if the log file is not set
+func (p *Process) createLogger(stdType string, logFile string, maxBytes int64, backups int, props map[string]string, logEventEmitter logger.LogEventEmitter) logger.Logger {
+	bufferBytes := p.config.GetBytes(stdType+"_ringbuffer_maxbytes", 64*1024)
+	if p.config.GetString(stdType+"_logfile", "") != "" || bufferBytes <= 0 {
+		return logger.NewLogger(p.GetName(), logFile, logger.NewNullLocker(), maxBytes, backups, props, logEventEmitter)
+	}
+	buffer := &p.stdoutBuffer
+	if stdType == "stderr" {
+		buffer = &p.stderrBuffer
+	}
+	if *buffer == nil {
+		*buffer = logger.NewRingBuffer(bufferBytes)
+	}
+	return logger.NewRingBufferLogger(*buffer, logEventEmitter)
+}
+
+// send the output to the followers of the stdout or stderr log as well
+func (p *Process) followLogs(stdType string, l logger.Logger) logger.Logger {
+	broadcaster := p.stdoutBroadcaster
+	if stdType == "stderr" {
+		broadcaster = p.stderrBroadcaster
+	}
+	return logger.NewCompositeLogger([]logger.Logger{l, logger.NewBroadcastLogger(broadcaster)})
+}
+
+// FollowLog returns the channel receiving the new stdout or stderr output of the program across
+// its restarts, the returned function must be called to stop following
+func (p *Process) FollowLog(stdType string) (chan []byte, func()) {
+	broadcaster := p.stdoutBroadcaster
+	if stdType == "stderr" {
+		broadcaster = p.stderrBroadcaster
+	}
+	ch := broadcaster.Follow()
+	return ch, func() {
+		broadcaster.Unfollow(ch)
+	}
+}
+
+// prefix each line written to the log with the RFC3339 timestamp if <stdType>_logfile_prefix_timestamp is true
+func (p *Process) timestampLogs(stdType string, l logger.Logger) logger.Logger {
+	if !p.config.GetBool(stdType+"_logfile_prefix_timestamp", false) {
+		return l
+	}
+	return logger.NewTimestampLogger(l)
+}
+
+// remove the ANSI escape sequences like colors from the output written to the log if strip_ansi
+// is true, the output written to supervisord stdout by shared_stdout is kept colored
+func (p *Process) stripANSI(l logger.Logger) logger.Logger {
+	if !p.config.GetBool("strip_ansi", false) {
+		return l
+	}
+	return logger.NewStripANSILogger(l)
+}
+
+// write the output to supervisord stdout with the "[progname] " prefix as well if shared_stdout
+// is true, so the output of all the programs can be read from supervisord stdout like by "docker logs"
+func (p *Process) shareStdout(l logger.Logger) logger.Logger {
+	if !p.config.GetBool("shared_stdout", false) {
+		return l
+	}
+	color := p.config.GetBool("shared_stdout_color", runtime.GOOS != "windows")
+	return logger.NewCompositeLogger([]logger.Logger{l, logger.NewSharedStdoutLogger(p.GetName(), color)})
+}
+
+// logRouteConfig the log lines matching pattern are written to logFile instead of the stdout/stderr logfile
+type logRouteConfig struct {
+	logFile string
+	pattern *regexp.Regexp
+}
+
+// get the log routes of stdout or stderr configured like:
+//
+//	stdout_log_route_<name> = <logfile> <regular expression>
+func (p *Process) getLogRouteConfigs(stdType string) []logRouteConfig {
+	result := make([]logRouteConfig, 0)
+	for _, key := range p.config.GetKeysWithPrefix(stdType + "_log_route_") {
+		fields := strings.SplitN(p.config.GetStringExpression(key, ""), " ", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[1]) == "" {
+			log.WithFields(log.Fields{"program": p.GetName(), "key": key}).Error("invalid log route, it should be: <logfile> <regular expression>")
+			continue
+		}
+		pattern, err := regexp.Compile(strings.TrimSpace(fields[1]))
+		if err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "key": key}).Error("invalid regular expression of log route: ", err)
+			continue
+		}
+		logFile := fields[0]
+		if expandFile, err := PathExpand(logFile); err == nil {
+			logFile = expandFile
+		}
+		result = append(result, logRouteConfig{logFile: logFile, pattern: pattern})
+	}
+	return result
+}
+
+// dispatch the lines matching log routes of stdout or stderr to their own log files
+func (p *Process) routeLogs(stdType string, defaultLogger logger.Logger, maxBytes int64, backups int, props map[string]string) logger.Logger {
+	routeConfigs := p.getLogRouteConfigs(stdType)
+	if len(routeConfigs) == 0 {
+		return defaultLogger
+	}
+	routes := make([]logger.LogRoute, 0)
+	for _, route := range routeConfigs {
+		routes = append(routes, logger.LogRoute{Pattern: route.pattern,
+			Logger: p.timestampLogs(stdType, logger.NewLogger(p.GetName(), route.logFile, logger.NewNullLocker(), maxBytes, backups, props, logger.NewNullLogEventEmitter()))})
+	}
+	return logger.NewRouteLogger(defaultLogger, routes)
 }
 
 func (p *Process) setUser() error {
@@ -938,6 +1425,12 @@ func (p *Process) Stop(wait bool) {
 	p.lock.Lock()
 	p.stopByUser = true
 	isRunning := p.isRunning()
+	if isRunning && (p.state == Starting || p.state == Running) {
+		p.changeStateTo(Stopping)
+	} else if !isRunning && p.state == Backoff {
+		// the program is waiting to be started again
+		p.changeStateTo(Stopped)
+	}
 	p.lock.Unlock()
 	if !isRunning {
 		log.WithFields(log.Fields{"program": p.GetName()}).Info("program is not running")
@@ -0,0 +1,137 @@
+package process
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// the max number of state changes kept for a process
+	maxStateHistory = 50
+
+	// the max number of resource samples kept for a process
+	maxResourceSamples = 60
+
+	// the interval of resource sampling
+	resourceSampleInterval = 5 * time.Second
+)
+
+// StateChange one state transition of the process
+type StateChange struct {
+	Time time.Time
+	From State
+	To   State
+}
+
+// ResourceSample the resource usage of the process sampled at Time
+type ResourceSample struct {
+	Time time.Time
+	// resident memory in bytes
+	RSS int
+	// CPU usage in percent of one core since last sample
+	CPU float64
+	// the number of open file descriptors
+	FDs int
+	// the number of threads
+	Threads int
+	// the number of the direct child processes
+	Children int
+}
+
+// GetStateHistory returns the latest state changes of the process, oldest first
+func (p *Process) GetStateHistory() []StateChange {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return append([]StateChange(nil), p.stateHistory...)
+}
+
+// GetResourceSamples returns the latest resource usage samples of the process, oldest first
+func (p *Process) GetResourceSamples() []ResourceSample {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return append([]ResourceSample(nil), p.resourceSamples...)
+}
+
+// GetResourceUsage returns the latest resource usage sample of the running program, false if
+// the program is not running or it is not sampled yet
+func (p *Process) GetResourceUsage() (ResourceSample, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.state != Running || len(p.resourceSamples) == 0 || p.resourceSamples[len(p.resourceSamples)-1].Time.Before(p.startTime) {
+		return ResourceSample{}, false
+	}
+	return p.resourceSamples[len(p.resourceSamples)-1], true
+}
+
+// GetResourceThresholds returns the CPU usage in percent of one core and the resident memory in bytes over
+// which the resource usage of the program is flagged, 0 if not configured. The memory defaults to max_memory
+func (p *Process) GetResourceThresholds() (cpu int, rss int) {
+	return p.config.GetInt("cpu_threshold", 0), p.config.GetBytes("memory_threshold", p.config.GetBytes("max_memory", 0))
+}
+
+// GetLabels returns the labels of program configured like "labels=team=payments,tier=web",
+// each label is in key=value format
+func (p *Process) GetLabels() []string {
+	labels := make([]string, 0)
+	for _, label := range strings.Split(p.config.GetString("labels", ""), ",") {
+		label = strings.TrimSpace(label)
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// record the state change, the p.lock must be held
+func (p *Process) recordStateChange(from State, to State) {
+	p.stateHistory = append(p.stateHistory, StateChange{Time: time.Now(), From: from, To: to})
+	if len(p.stateHistory) > maxStateHistory {
+		p.stateHistory = p.stateHistory[len(p.stateHistory)-maxStateHistory:]
+	}
+}
+
+// sample the resource usage of the started program until it exits
+func (p *Process) sampleResources(cmd *exec.Cmd) {
+	pid := cmd.Process.Pid
+	lastTime := time.Now()
+	lastCPU, err := getProcessCPUTime(pid)
+	if err != nil {
+		return
+	}
+	for {
+		time.Sleep(resourceSampleInterval)
+		p.lock.RLock()
+		running := p.cmd == cmd && p.state == Running
+		p.lock.RUnlock()
+		if !running {
+			return
+		}
+		rss, err := getProcessRSS(pid)
+		if err != nil {
+			return
+		}
+		cpu, err := getProcessCPUTime(pid)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		sample := ResourceSample{Time: now,
+			RSS: rss,
+			CPU: float64(cpu-lastCPU) * 100 / float64(now.Sub(lastTime))}
+		// the counts are left 0 if they can't be read, like the fds of the program run as another user
+		sample.FDs, _ = getProcessFDCount(pid)
+		sample.Threads, _ = getProcessThreadCount(pid)
+		sample.Children, _ = getProcessChildCount(pid)
+		lastTime, lastCPU = now, cpu
+
+		p.lock.Lock()
+		p.resourceSamples = append(p.resourceSamples, sample)
+		if len(p.resourceSamples) > maxResourceSamples {
+			p.resourceSamples = p.resourceSamples[len(p.resourceSamples)-maxResourceSamples:]
+		}
+		p.lock.Unlock()
+	}
+}
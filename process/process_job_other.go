@@ -0,0 +1,16 @@
+// +build !windows
+
+package process
+
+// processJob the process tree is managed by the process group on non-windows system
+type processJob struct{}
+
+func (p *Process) attachJob() {
+}
+
+func (p *Process) terminateJob() bool {
+	return false
+}
+
+func (p *Process) closeJob() {
+}
@@ -0,0 +1,65 @@
+// +build windows
+
+package process
+
+import (
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+)
+
+// processJob the Job Object holding the program and all its children
+type processJob windows.Handle
+
+// put the started program into a new Job Object, the children created by the program
+// are also in the job so the whole process tree can be terminated. The children
+// created before the program is assigned to the job are not in it
+func (p *Process) attachJob() {
+	p.closeJob()
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to create job object: ", err)
+		return
+	}
+	// kill the process tree if supervisord exits and the job handle is closed
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if _, err = windows.SetInformationJobObject(job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info))); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to set job object limit: ", err)
+	}
+	handle, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(job, handle)
+		windows.CloseHandle(handle)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to assign program to job object: ", err)
+		windows.CloseHandle(job)
+		return
+	}
+	p.job = processJob(job)
+}
+
+// terminate all the processes in the Job Object, returns false if the program is not in a job
+func (p *Process) terminateJob() bool {
+	if p.job == 0 {
+		return false
+	}
+	if err := windows.TerminateJobObject(windows.Handle(p.job), 1); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to terminate job object: ", err)
+		return false
+	}
+	return true
+}
+
+// close the Job Object handle after the program exits
+func (p *Process) closeJob() {
+	if p.job != 0 {
+		windows.CloseHandle(windows.Handle(p.job))
+		p.job = 0
+	}
+}
@@ -2,6 +2,7 @@ package process
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -14,6 +15,16 @@ type Manager struct {
 	procs          map[string]*Process
 	eventListeners map[string]*Process
 	lock           sync.Mutex
+	concurrency    int
+	childLogDir    string
+	logQuota       int64
+	// true if the goroutine checking the log quotas is running
+	logQuotaMonitored bool
+	// the state changes of the programs not handled yet, they are handled in order by one goroutine
+	stateChanges     []processStateChange
+	stateChangesLock sync.Mutex
+	// true if the goroutine handling the state changes is running
+	stateChangesHandled bool
 }
 
 // NewManager creates new Manager object
@@ -23,6 +34,14 @@ func NewManager() *Manager {
 	}
 }
 
+// SetConcurrency sets the max number of processes started or stopped at the same time
+// in the mass start/stop, 0 for no limit
+func (pm *Manager) SetConcurrency(concurrency int) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.concurrency = concurrency
+}
+
 // CreateProcess creates process (program or event listener) and adds to Manager object
 func (pm *Manager) CreateProcess(supervisorID string, config *config.Entry) *Process {
 	pm.lock.Lock()
@@ -38,11 +57,79 @@ func (pm *Manager) CreateProcess(supervisorID string, config *config.Entry) *Pro
 
 // StartAutoStartPrograms starts all programs that set as should be autostarted
 func (pm *Manager) StartAutoStartPrograms() {
+	pm.startAutoStartPrograms(func(proc *Process) bool {
+		return true
+	})
+}
+
+// StartAutoStartGroup starts the programs of the group that set as should be autostarted
+func (pm *Manager) StartAutoStartGroup(group string) {
+	pm.startAutoStartPrograms(func(proc *Process) bool {
+		return proc.GetGroup() == group
+	})
+}
+
+// StartAutoStartProcesses starts the given programs that set as should be autostarted
+func (pm *Manager) StartAutoStartProcesses(names []string) {
+	pm.startAutoStartPrograms(func(proc *Process) bool {
+		for _, name := range names {
+			if proc.GetName() == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (pm *Manager) startAutoStartPrograms(filter func(proc *Process) bool) {
+	procs := make([]*Process, 0)
 	pm.ForEachProcess(func(proc *Process) {
-		if proc.isAutoStart() {
-			proc.Start(false)
+		if !filter(proc) || !proc.isAutoStart() {
+			return
+		}
+		dependencies := pm.getReadyCheckDependencies(proc)
+		if len(dependencies) == 0 {
+			procs = append(procs, proc)
+			return
 		}
+		// hold the program until the programs it depends on are ready
+		go func() {
+			for _, dependency := range dependencies {
+				log.WithFields(log.Fields{"program": proc.GetName(), "depends_on": dependency.GetName()}).Info("wait for the program ready")
+				if err := dependency.waitUntilReady(); err != nil {
+					proc.failDependency(fmt.Errorf("depends_on program is not ready: %v", err))
+					return
+				}
+			}
+			proc.Start(false)
+		}()
 	})
+
+	pm.lock.Lock()
+	concurrency := pm.concurrency
+	pm.lock.Unlock()
+	if concurrency <= 0 {
+		for _, proc := range procs {
+			proc.Start(false)
+		}
+		return
+	}
+	// wait for each program started or failed to limit the programs in starting
+	runConcurrently(procs, concurrency, func(proc *Process) {
+		proc.Start(true)
+	}, nil)
+}
+
+// get the programs which the proc depends on and have readiness probe, the pm.lock must be held
+func (pm *Manager) getReadyCheckDependencies(proc *Process) []*Process {
+	result := make([]*Process, 0)
+	for _, name := range strings.Split(proc.config.GetString("depends_on", ""), ",") {
+		name = strings.TrimSpace(name)
+		if dependency, ok := pm.procs[name]; ok && dependency.hasReadyCheck() {
+			result = append(result, dependency)
+		}
+	}
+	return result
 }
 
 func (pm *Manager) createProgram(supervisorID string, config *config.Entry) *Process {
@@ -52,7 +139,13 @@ func (pm *Manager) createProgram(supervisorID string, config *config.Entry) *Pro
 
 	if !ok {
 		proc = NewProcess(supervisorID, config)
+		proc.stateListener = pm.queueStateChange
+		proc.childLogDir = pm.childLogDir
 		pm.procs[procName] = proc
+		pm.startLogQuotaMonitor()
+	} else {
+		// the expressions like %(host_node_name)s are evaluated again in the reloaded command
+		proc.updateCommand(config)
 	}
 	log.Info("create process:", procName)
 	return proc
@@ -90,44 +183,72 @@ func (pm *Manager) Remove(name string) *Process {
 	defer pm.lock.Unlock()
 	proc, _ := pm.procs[name]
 	delete(pm.procs, name)
+	if proc != nil {
+		proc.stopFileWatch()
+		proc.removeRestartCron()
+	}
 	log.Info("remove process:", name)
 	return proc
 }
 
 // Find process by program name. Returns process or nil if process is not listed in Manager object
 func (pm *Manager) Find(name string) *Process {
-	procs := pm.FindMatch(name)
-	if len(procs) == 1 {
-		if procs[0].GetName() == name || name == fmt.Sprintf("%s:%s", procs[0].GetGroup(), procs[0].GetName()) {
-			return procs[0]
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	if proc, ok := pm.procs[name]; ok {
+		return proc
+	}
+	if pos := strings.Index(name, ":"); pos != -1 {
+		if proc, ok := pm.procs[name[pos+1:]]; ok && proc.GetGroup() == name[0:pos] {
+			return proc
 		}
 	}
 	return nil
 }
 
-// FindMatch lookup program with one of following format:
+// FindMatch lookup program with one of following format, or the comma separated list of them:
 // - group:program
-// - group:*
+// - group:* or group:
 // - program
+// The group and program can be the shell patterns like "worker-*", the processes matching each
+// item are returned in their priority order, a process is returned only once
 func (pm *Manager) FindMatch(name string) []*Process {
 	result := make([]*Process, 0)
-	if pos := strings.Index(name, ":"); pos != -1 {
-		groupName := name[0:pos]
-		programName := name[pos+1:]
+	found := make(map[*Process]bool)
+	for _, item := range strings.Split(name, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		groupPattern, programPattern := "", item
+		if pos := strings.Index(item, ":"); pos != -1 {
+			groupPattern = item[0:pos]
+			programPattern = item[pos+1:]
+			if programPattern == "" {
+				programPattern = "*"
+			}
+		}
+		// the program without wildcards is looked up by its name
+		if !hasWildcard(programPattern) {
+			pm.lock.Lock()
+			p, ok := pm.procs[programPattern]
+			pm.lock.Unlock()
+			if ok && !found[p] && (groupPattern == "" || matchName(groupPattern, p.GetGroup())) {
+				found[p] = true
+				result = append(result, p)
+			}
+			continue
+		}
 		pm.ForEachProcess(func(p *Process) {
-			if p.GetGroup() == groupName {
-				if programName == "*" || programName == p.GetName() {
-					result = append(result, p)
-				}
+			if found[p] || !matchName(programPattern, p.GetName()) {
+				return
 			}
+			if groupPattern != "" && !matchName(groupPattern, p.GetGroup()) {
+				return
+			}
+			found[p] = true
+			result = append(result, p)
 		})
-	} else {
-		pm.lock.Lock()
-		defer pm.lock.Unlock()
-		proc, ok := pm.procs[name]
-		if ok {
-			result = append(result, proc)
-		}
 	}
 	if len(result) <= 0 {
 		log.Info("fail to find process:", name)
@@ -135,6 +256,20 @@ func (pm *Manager) FindMatch(name string) []*Process {
 	return result
 }
 
+// check if the name has the wildcards of the shell pattern
+func hasWildcard(name string) bool {
+	return strings.ContainsAny(name, "*?[\\")
+}
+
+// check if the name is same as the pattern or matches the shell pattern
+func matchName(pattern string, name string) bool {
+	if pattern == name {
+		return true
+	}
+	matched, err := filepath.Match(pattern, name)
+	return err == nil && matched
+}
+
 // Clear all the processes from Manager object
 func (pm *Manager) Clear() {
 	pm.lock.Lock()
@@ -163,16 +298,39 @@ func (pm *Manager) AsyncForEachProcess(procFunc func(p *Process), done chan *Pro
 	defer pm.lock.Unlock()
 
 	procs := pm.getAllProcess()
+	runConcurrently(procs, pm.concurrency, procFunc, done)
+	return len(procs)
+}
 
+// run the action on the processes in their priority order without waiting, at most
+// concurrency (0 for no limit) processes are handled at the same time. The process
+// is sent to done after it is handled if done is not nil
+func runConcurrently(procs []*Process, concurrency int, action func(p *Process), done chan *Process) {
+	if concurrency <= 0 || concurrency >= len(procs) {
+		for _, proc := range procs {
+			go forOneProcess(proc, action, done)
+		}
+		return
+	}
+	queue := make(chan *Process, len(procs))
 	for _, proc := range procs {
-		go forOneProcess(proc, procFunc, done)
+		queue <- proc
+	}
+	close(queue)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for proc := range queue {
+				forOneProcess(proc, action, done)
+			}
+		}()
 	}
-	return len(procs)
 }
 
 func forOneProcess(proc *Process, action func(p *Process), done chan *Process) {
 	action(proc)
-	done <- proc
+	if done != nil {
+		done <- proc
+	}
 }
 
 func (pm *Manager) getAllProcess() []*Process {
@@ -187,17 +345,18 @@ func (pm *Manager) getAllProcess() []*Process {
 func (pm *Manager) StopAllProcesses() {
 	var wg sync.WaitGroup
 
-	pm.ForEachProcess(func(proc *Process) {
-		wg.Add(1)
-
-		go func(wg *sync.WaitGroup) {
-			defer wg.Done()
+	pm.lock.Lock()
+	procs := pm.getAllProcess()
+	wg.Add(len(procs))
+	runConcurrently(procs, pm.concurrency, func(proc *Process) {
+		defer wg.Done()
 
-			proc.Stop(true)
-		}(&wg)
-	})
+		proc.Stop(true)
+	}, nil)
+	pm.lock.Unlock()
 
 	wg.Wait()
+	pm.removeAutoLogFiles()
 }
 
 func sortProcess(procs []*Process) []*Process {
@@ -1,7 +1,7 @@
 package process
 
 import (
-	"supervisord/config"
+	"github.com/ochinchina/supervisord/config"
 	"testing"
 )
 
@@ -31,3 +31,16 @@ func TestProcMgrRemove(t *testing.T) {
 		t.Error("fail to remove process")
 	}
 }
+
+func TestProcMgrFindMatch(t *testing.T) {
+	procs.Clear()
+	for _, name := range []string{"web-1", "web-2", "api"} {
+		procs.Add(name, NewProcess("supervisord", &config.Entry{ConfigDir: ".", Group: "test", Name: "program:" + name}))
+	}
+	if procs.Find("api") == nil || procs.Find("test:api") == nil || procs.Find("other:api") != nil || procs.Find("web-*") != nil {
+		t.Error("fail to find process by the exact name")
+	}
+	if len(procs.FindMatch("web-*")) != 2 || len(procs.FindMatch("test:")) != 3 || len(procs.FindMatch("api,test:api")) != 1 {
+		t.Error("fail to find processes by the pattern")
+	}
+}
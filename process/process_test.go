@@ -0,0 +1,103 @@
+package process
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+)
+
+// stdinRecorder records the writes to the process stdin
+type stdinRecorder struct {
+	lock   sync.Mutex
+	writes [][]byte
+}
+
+func (r *stdinRecorder) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.writes = append(r.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (r *stdinRecorder) Close() error {
+	return nil
+}
+
+func TestSendProcessStdinBytes(t *testing.T) {
+	proc := &Process{}
+	if err := proc.SendProcessStdinBytes([]byte("data")); err == nil || err.Error() != "NO_FILE" {
+		t.Errorf("the data should not be sent without stdin, but get %v", err)
+	}
+
+	recorder := &stdinRecorder{}
+	proc.stdin = recorder
+	first := bytes.Repeat([]byte{'a'}, stdinChunkSize*2+1)
+	second := bytes.Repeat([]byte{'b'}, stdinChunkSize*2+1)
+	var wg sync.WaitGroup
+	for _, data := range [][]byte{first, second} {
+		wg.Add(1)
+		go func(data []byte) {
+			defer wg.Done()
+			if err := proc.SendProcessStdinBytes(data); err != nil {
+				t.Error(err)
+			}
+		}(data)
+	}
+	wg.Wait()
+
+	if len(recorder.writes) != 6 {
+		t.Fatalf("the data should be written in 6 chunks, but get %d", len(recorder.writes))
+	}
+	for i, chunk := range recorder.writes {
+		if len(chunk) > stdinChunkSize {
+			t.Errorf("the chunk %d should be at most %d bytes, but get %d", i, stdinChunkSize, len(chunk))
+		}
+	}
+	written := bytes.Join(recorder.writes, nil)
+	if !bytes.Equal(written, append(append([]byte(nil), first...), second...)) && !bytes.Equal(written, append(append([]byte(nil), second...), first...)) {
+		t.Error("the data of a call should not be interleaved with the data of the other")
+	}
+}
+
+func TestStartAfterStoppedWaitsForStartLoop(t *testing.T) {
+	confFile := filepath.Join(t.TempDir(), "supervisord.conf")
+	ioutil.WriteFile(confFile, []byte("[program:web]\ncommand=sleep 30\nautostart=false\nstartsecs=0\nstopwaitsecs=1\n"), 0644)
+	myconfig := config.NewConfig(confFile)
+	if _, err := myconfig.Load(); err != nil {
+		t.Fatal(err)
+	}
+	proc := NewProcess("supervisord", myconfig.GetProgram("web"))
+	defer proc.Stop(true)
+
+	// the loop of the previous start has not ended yet
+	proc.inStart = true
+	proc.startDone = make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		proc.startAfterStopped(true, nil)
+		close(started)
+	}()
+	select {
+	case <-started:
+		t.Fatal("the program should not be started before the previous start loop ends")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	proc.lock.Lock()
+	proc.inStart = false
+	close(proc.startDone)
+	proc.lock.Unlock()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the program should be started after the previous start loop ends")
+	}
+	if proc.GetState() != Running {
+		t.Errorf("the program should be running, but get %v", proc.GetState())
+	}
+}
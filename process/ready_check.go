@@ -0,0 +1,152 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// the max length of a partial output line kept by LogReadyChecker
+const maxReadyLineLength = 64 * 1024
+
+// LogReadyChecker checks the program readiness by matching its output lines with a regular expression
+type LogReadyChecker struct {
+	pattern *regexp.Regexp
+	lock    sync.Mutex
+	line    []byte
+	ready   bool
+}
+
+// NewLogReadyChecker creates LogReadyChecker object
+func NewLogReadyChecker(pattern *regexp.Regexp) *LogReadyChecker {
+	return &LogReadyChecker{pattern: pattern}
+}
+
+// Write the program output to the checker
+func (lc *LogReadyChecker) Write(b []byte) (int, error) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	if lc.ready {
+		return len(b), nil
+	}
+	lc.line = append(lc.line, b...)
+	for {
+		pos := bytes.IndexByte(lc.line, '\n')
+		if pos == -1 {
+			break
+		}
+		if lc.pattern.Match(lc.line[0:pos]) {
+			lc.ready = true
+			lc.line = nil
+			return len(b), nil
+		}
+		lc.line = lc.line[pos+1:]
+	}
+	if len(lc.line) > maxReadyLineLength {
+		lc.line = lc.line[len(lc.line)-maxReadyLineLength:]
+	}
+	return len(b), nil
+}
+
+// Check returns nil if a line matching the pattern was written
+func (lc *LogReadyChecker) Check(timeout time.Duration) error {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	if lc.ready || lc.pattern.Match(lc.line) {
+		return nil
+	}
+	return fmt.Errorf("no output line matches %s", lc.pattern.String())
+}
+
+// NewReadyChecker creates the readiness checker from the ready_url, ready_tcp or
+// ready_log_pattern of program, returns nil if none of them is configured
+func NewReadyChecker(entry *config.Entry) HealthChecker {
+	if url := entry.GetStringExpression("ready_url", ""); url != "" {
+		return &HTTPHealthChecker{url: url, strict: true}
+	}
+	if addr := entry.GetStringExpression("ready_tcp", ""); addr != "" {
+		if !strings.Contains(addr, ":") {
+			addr = net.JoinHostPort("127.0.0.1", addr)
+		}
+		return &TCPHealthChecker{addr: addr}
+	}
+	if pattern := entry.GetString("ready_log_pattern", ""); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.WithFields(log.Fields{"program": entry.GetProgramName()}).Error("invalid ready_log_pattern: ", err)
+			return nil
+		}
+		return NewLogReadyChecker(re)
+	}
+	return nil
+}
+
+// wait until the program is ready or the ready_timeout is expired
+//
+// Returns: true if the program is ready
+func (p *Process) waitForReady(checker HealthChecker, programExited *int32) bool {
+	timeout := p.config.GetDuration("ready_timeout", 60*time.Second)
+	interval := p.config.GetDuration("ready_interval", time.Second)
+	endTime := time.Now().Add(timeout)
+	for {
+		err := checker.Check(interval)
+		if err == nil {
+			return true
+		}
+		if time.Now().After(endTime) {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("program is not ready before timeout: ", err)
+			return false
+		}
+		if atomic.LoadInt32(programExited) != 0 {
+			return false
+		}
+		time.Sleep(interval)
+	}
+}
+
+// check if any readiness probe is configured for the program
+func (p *Process) hasReadyCheck() bool {
+	return p.config.HasParameter("ready_url") || p.config.HasParameter("ready_tcp") || p.config.HasParameter("ready_log_pattern")
+}
+
+// wait until the program is ready, it is failed to start or its ready_timeout is expired
+//
+// Returns: nil if the program is ready, or the error why it is not ready
+func (p *Process) waitUntilReady() error {
+	timeout := p.config.GetDuration("ready_timeout", 60*time.Second) + time.Duration(p.getStartSeconds())*time.Second
+	endTime := time.Now().Add(timeout)
+	for time.Now().Before(endTime) {
+		switch state := p.GetState(); state {
+		case Running:
+			return nil
+		case Fatal:
+			return fmt.Errorf("program %s is failed to start", p.GetName())
+		case Starting, Backoff:
+		default:
+			// the program not started automatically may never be started
+			if !p.isAutoStart() {
+				return fmt.Errorf("program %s is %s and not started automatically", p.GetName(), state.String())
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("program %s is not ready in %v", p.GetName(), timeout)
+}
+
+// give up starting the program because a program it depends on is not ready, the program is FATAL with
+// the reason in its description
+func (p *Process) failDependency(err error) {
+	log.WithFields(log.Fields{"program": p.GetName()}).Error("program is not started: ", err)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.setSpawnErr(err)
+	p.changeStateTo(Fatal)
+}
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ochinchina/supervisord/config"
+)
+
+func TestWaitUntilReady(t *testing.T) {
+	confFile := filepath.Join(t.TempDir(), "supervisord.conf")
+	ioutil.WriteFile(confFile, []byte("[program:db]\ncommand=db\nautostart=false\nready_tcp=5432\nready_timeout=1\n\n[program:web]\ncommand=web\nready_tcp=8080\nready_timeout=1\n"), 0644)
+	myconfig := config.NewConfig(confFile)
+	if _, err := myconfig.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewProcess("supervisord", myconfig.GetProgram("db"))
+	if err := db.waitUntilReady(); err == nil {
+		t.Error("the stopped program not started automatically should not be waited for")
+	}
+	db.state = Running
+	if err := db.waitUntilReady(); err != nil {
+		t.Errorf("the running program should be ready, but get %v", err)
+	}
+	db.state = Fatal
+	if err := db.waitUntilReady(); err == nil {
+		t.Error("the FATAL program should not be ready")
+	}
+
+	web := NewProcess("supervisord", myconfig.GetProgram("web"))
+	web.state = Starting
+	if err := web.waitUntilReady(); err == nil {
+		t.Error("the program not ready before timeout should not be ready")
+	}
+	web.failDependency(fmt.Errorf("depends_on program is not ready"))
+	if web.GetState() != Fatal || web.GetDescription() != "depends_on program is not ready" {
+		t.Errorf("the program should be FATAL with the reason, but get %v %s", web.GetState(), web.GetDescription())
+	}
+}
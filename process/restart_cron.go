@@ -0,0 +1,53 @@
+package process
+
+import (
+	"github.com/ochinchina/supervisord/events"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// the parser of restart_cron, the seconds field is optional so both the standard cron
+// expression like "0 4 * * *" and the one with seconds like the cron parameter are accepted
+var restartCronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// schedule the restart of program by its restart_cron
+func (p *Process) addRestartCron() {
+	spec := p.config.GetString("restart_cron", "")
+	if spec == "" {
+		return
+	}
+	schedule, err := restartCronParser.Parse(spec)
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName(), "restart_cron": spec}).Error("invalid restart_cron expression: ", err)
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "restart_cron": spec}).Info("schedule the restart of program")
+	p.restartCronID = scheduler.Schedule(schedule, cron.FuncJob(p.scheduledRestart))
+}
+
+// remove the scheduled restart of program
+func (p *Process) removeRestartCron() {
+	if p.restartCronID != 0 {
+		scheduler.Remove(p.restartCronID)
+		p.restartCronID = 0
+	}
+}
+
+// restart the program if it is running, the program stopped by user or not started yet
+// is left as it is
+func (p *Process) scheduledRestart() {
+	p.lock.RLock()
+	running := p.state == Running
+	pid := 0
+	if running {
+		pid = p.cmd.Process.Pid
+	}
+	p.lock.RUnlock()
+	if !running {
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("skip the scheduled restart, program is not running")
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "pid": pid}).Info("scheduled restart of program")
+	events.EmitEvent(events.CreateProcessScheduledRestartEvent(p.GetName(), p.GetGroup(), pid))
+	p.Restart(false, nil)
+}
@@ -0,0 +1,86 @@
+package process
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Snapshot the state of a program exported for the backup and audit tools, the times are
+// the seconds since epoch and 0 if not set
+type Snapshot struct {
+	Name      string `json:"name"`
+	Group     string `json:"group"`
+	State     string `json:"state"`
+	Pid       int    `json:"pid"`
+	StartTime int64  `json:"start_time"`
+	StopTime  int64  `json:"stop_time"`
+	// the seconds the program is running
+	Uptime int64 `json:"uptime"`
+	// the number of the times the program is restarted automatically after it exits
+	Restarts int `json:"restarts"`
+	// the spawn attempts of the current start
+	RetryTimes int  `json:"retry_times"`
+	StopByUser bool `json:"stop_by_user"`
+	// the environment overrides of the current run in KEY=VALUE format
+	EnvOverrides []string `json:"env_overrides,omitempty"`
+	// the next time the program is started by cron and restarted by restart_cron
+	NextCron        int64 `json:"next_cron,omitempty"`
+	NextRestartCron int64 `json:"next_restart_cron,omitempty"`
+}
+
+// GetSnapshot get the state of program
+func (p *Process) GetSnapshot() Snapshot {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	snapshot := Snapshot{Name: p.GetName(),
+		Group:           p.GetGroup(),
+		State:           p.state.String(),
+		StartTime:       unixTime(p.startTime),
+		StopTime:        unixTime(p.stopTime),
+		Uptime:          int64(p.getUptime().Seconds()),
+		Restarts:        int(atomic.LoadInt32(&p.restarts)),
+		RetryTimes:      int(atomic.LoadInt32(p.retryTimes)),
+		StopByUser:      p.stopByUser,
+		EnvOverrides:    append([]string(nil), p.envOverrides...),
+		NextCron:        nextScheduleTime(p.cronID),
+		NextRestartCron: nextScheduleTime(p.restartCronID)}
+	if p.cmd != nil && p.cmd.Process != nil {
+		snapshot.Pid = p.cmd.Process.Pid
+	}
+	return snapshot
+}
+
+// RestoreSnapshot restore the restart count of program from the snapshot and start or stop it
+// to reach the state in the snapshot, the overrides are only applied if the program is started
+func (p *Process) RestoreSnapshot(snapshot Snapshot, envOverrides []string) {
+	atomic.StoreInt32(&p.restarts, int32(snapshot.Restarts))
+	switch strings.ToUpper(snapshot.State) {
+	case "RUNNING", "STARTING", "BACKOFF":
+		if !p.isRunning() {
+			p.StartWithEnv(false, envOverrides)
+		}
+	case "STOPPED", "STOPPING":
+		if p.isRunning() && snapshot.StopByUser {
+			p.Stop(false)
+		}
+	}
+}
+
+// the seconds since epoch of t, 0 if t is not set
+func unixTime(t time.Time) int64 {
+	if t.Unix() <= 0 {
+		return 0
+	}
+	return t.Unix()
+}
+
+// get the next time of the scheduler entry, 0 if it is not scheduled
+func nextScheduleTime(id cron.EntryID) int64 {
+	if id == 0 {
+		return 0
+	}
+	return unixTime(scheduler.Entry(id).Next)
+}
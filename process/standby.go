@@ -0,0 +1,93 @@
+package process
+
+import (
+	"strings"
+
+	"github.com/ochinchina/supervisord/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// get the primary program name of the standby program
+func (p *Process) getStandbyFor() string {
+	return strings.TrimSpace(p.config.GetString("standby_for", ""))
+}
+
+// get the standby programs of the primary program
+func (pm *Manager) getStandbyPrograms(primary string) []*Process {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	result := make([]*Process, 0)
+	for _, proc := range pm.procs {
+		if proc.getStandbyFor() == primary {
+			result = append(result, proc)
+		}
+	}
+	return result
+}
+
+// processStateChange the state a program is changed to
+type processStateChange struct {
+	proc  *Process
+	state State
+}
+
+// queue the state change of the program, the changes are handled one by one in the order they are
+// made so the standby programs see FATAL and RUNNING of their primary in order
+func (pm *Manager) queueStateChange(proc *Process, state State) {
+	pm.stateChangesLock.Lock()
+	defer pm.stateChangesLock.Unlock()
+	pm.stateChanges = append(pm.stateChanges, processStateChange{proc: proc, state: state})
+	if !pm.stateChangesHandled {
+		pm.stateChangesHandled = true
+		go pm.handleStateChanges()
+	}
+}
+
+// handle the queued state changes until the queue is empty
+func (pm *Manager) handleStateChanges() {
+	for {
+		pm.stateChangesLock.Lock()
+		if len(pm.stateChanges) == 0 {
+			pm.stateChangesHandled = false
+			pm.stateChangesLock.Unlock()
+			return
+		}
+		change := pm.stateChanges[0]
+		pm.stateChanges = pm.stateChanges[1:]
+		pm.stateChangesLock.Unlock()
+		pm.onProcessStateChange(change.proc, change.state)
+	}
+}
+
+// start the standby programs when the primary enters FATAL state, and stop them when
+// the primary is running again
+func (pm *Manager) onProcessStateChange(primary *Process, state State) {
+	if state != Fatal && state != Running {
+		return
+	}
+	for _, standby := range pm.getStandbyPrograms(primary.GetName()) {
+		standby.lock.Lock()
+		active := standby.standbyActive
+		if state == Fatal && !active {
+			standby.standbyActive = true
+		} else if state == Running && active {
+			standby.standbyActive = false
+		} else {
+			standby.lock.Unlock()
+			continue
+		}
+		standby.lock.Unlock()
+
+		fields := log.Fields{"program": standby.GetName(), "primary": primary.GetName()}
+		if state == Fatal {
+			log.WithFields(fields).Warn("primary program is in FATAL state, start the standby program")
+			events.EmitEvent(events.CreateProcessStandbyActivatedEvent(standby.GetName(), standby.GetGroup(), primary.GetName()))
+			standby.Start(false)
+		} else {
+			log.WithFields(fields).Info("primary program is running again, stop the standby program")
+			events.EmitEvent(events.CreateProcessStandbyDeactivatedEvent(standby.GetName(), standby.GetGroup(), primary.GetName()))
+			standby.Stop(false)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package process
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/events"
+)
+
+func TestStandbyFollowsPrimaryInOrder(t *testing.T) {
+	confFile := filepath.Join(t.TempDir(), "supervisord.conf")
+	ioutil.WriteFile(confFile, []byte("[program:db]\ncommand=sleep 30\nautostart=false\n\n[program:db-standby]\ncommand=sleep 30\nautostart=false\nstartsecs=0\nstandby_for=db\n"), 0644)
+	myconfig := config.NewConfig(confFile)
+	if _, err := myconfig.Load(); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewManager()
+	primary := pm.CreateProcess("supervisord", myconfig.GetProgram("db"))
+	standby := pm.CreateProcess("supervisord", myconfig.GetProgram("db-standby"))
+	defer standby.Stop(true)
+	ch := events.Subscribe("PROCESS_STANDBY_ACTIVATED", "PROCESS_STANDBY_DEACTIVATED")
+	defer events.Unsubscribe(ch)
+
+	// the primary flaps between FATAL and RUNNING faster than the standby is started and stopped
+	primary.lock.Lock()
+	for _, state := range []State{Fatal, Running, Fatal, Running} {
+		primary.stateListener(primary, state)
+	}
+	primary.lock.Unlock()
+
+	expected := []string{"PROCESS_STANDBY_ACTIVATED", "PROCESS_STANDBY_DEACTIVATED", "PROCESS_STANDBY_ACTIVATED", "PROCESS_STANDBY_DEACTIVATED"}
+	for i, eventType := range expected {
+		select {
+		case event := <-ch:
+			if event.GetType() != eventType {
+				t.Fatalf("the event %d should be %s, but get %s", i, eventType, event.GetType())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the event %d %s is not emitted", i, eventType)
+		}
+	}
+	standby.lock.Lock()
+	active := standby.standbyActive
+	standby.lock.Unlock()
+	if active {
+		t.Error("the standby program should not be active after the primary is running again")
+	}
+}
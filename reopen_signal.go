@@ -0,0 +1,23 @@
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reopen the log files on SIGUSR2, like after they are moved by logrotate
+func initReopenLogsSignal(s *Supervisor) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR2)
+	go func() {
+		for sig := range sigs {
+			log.WithFields(log.Fields{"signal": sig}).Info("receive a signal to reopen the log files")
+			s.ReopenLogs(nil, &struct{}{}, &struct{ Ret bool }{})
+		}
+	}()
+}
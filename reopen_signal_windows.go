@@ -0,0 +1,7 @@
+// +build windows
+
+package main
+
+// SIGUSR2 is not available on windows, the log files are reopened by the reopenLogs RPC only
+func initReopenLogsSignal(s *Supervisor) {
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/ochinchina/supervisord/process"
 	"github.com/ochinchina/supervisord/types"
 )
 
@@ -25,9 +29,18 @@ func (sr *SupervisorRestful) CreateProgramHandler() http.Handler {
 	sr.router.HandleFunc("/program/list", sr.ListProgram).Methods("GET")
 	sr.router.HandleFunc("/program/start/{name}", sr.StartProgram).Methods("POST", "PUT")
 	sr.router.HandleFunc("/program/stop/{name}", sr.StopProgram).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/restart/{name}", sr.RestartProgram).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/clearlog/{name}", sr.ClearProgramLog).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/info/{name}", sr.ProgramDetail).Methods("GET")
+	sr.router.HandleFunc("/program/resources", sr.ListResources).Methods("GET")
 	sr.router.HandleFunc("/program/log/{name}/stdout", sr.ReadStdoutLog).Methods("GET")
+	sr.router.HandleFunc("/program/log/{name}/stderr", sr.ReadStderrLog).Methods("GET")
 	sr.router.HandleFunc("/program/startPrograms", sr.StartPrograms).Methods("POST", "PUT")
 	sr.router.HandleFunc("/program/stopPrograms", sr.StopPrograms).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/restartPrograms", sr.RestartPrograms).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/startGroup/{group}", sr.StartGroup).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/stopGroup/{group}", sr.StopGroup).Methods("POST", "PUT")
+	sr.router.HandleFunc("/program/restartGroup/{group}", sr.RestartGroup).Methods("POST", "PUT")
 	return sr.router
 }
 
@@ -133,8 +146,180 @@ func (sr *SupervisorRestful) StopPrograms(w http.ResponseWriter, req *http.Reque
 
 }
 
+// RestartProgram restart a program through the restful interface
+func (sr *SupervisorRestful) RestartProgram(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	restartArgs := StartProcessArgs{Name: mux.Vars(req)["name"], Wait: true}
+	result := struct{ Success bool }{false}
+	err := sr.supervisor.RestartProcess(nil, &restartArgs, &result)
+	r := map[string]bool{"success": err == nil && result.Success}
+	json.NewEncoder(w).Encode(&r)
+}
+
+// RestartPrograms restart programs through the restful interface, all of them are stopped before any of
+// them is started
+func (sr *SupervisorRestful) RestartPrograms(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var programs []string
+	var b []byte
+	var err error
+	if b, err = ioutil.ReadAll(req.Body); err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("not a valid request"))
+		return
+	}
+
+	if err := json.Unmarshal(b, &programs); err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("not a valid request"))
+	} else {
+		procs := make([]*process.Process, 0)
+		for _, program := range programs {
+			if proc := sr.supervisor.GetManager().Find(program); proc != nil {
+				procs = append(procs, proc)
+			}
+		}
+		restartProcesses(procs, true, nil)
+		w.Write([]byte("Success to restart the programs"))
+	}
+}
+
+// StartGroup start the programs in a group through the restful interface
+func (sr *SupervisorRestful) StartGroup(w http.ResponseWriter, req *http.Request) {
+	sr.changeGroup(sr.supervisor.StartProcessGroup, w, req)
+}
+
+// StopGroup stop the programs in a group through the restful interface
+func (sr *SupervisorRestful) StopGroup(w http.ResponseWriter, req *http.Request) {
+	sr.changeGroup(sr.supervisor.StopProcessGroup, w, req)
+}
+
+// RestartGroup restart the programs in a group through the restful interface
+func (sr *SupervisorRestful) RestartGroup(w http.ResponseWriter, req *http.Request) {
+	sr.changeGroup(sr.supervisor.RestartProcessGroup, w, req)
+}
+
+// start, stop or restart the programs in the group by change, it fails if the group has no program
+func (sr *SupervisorRestful) changeGroup(change func(*http.Request, *StartProcessArgs, *struct{ AllProcessInfo []types.ProcessInfo }) error, w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	args := StartProcessArgs{Name: mux.Vars(req)["group"], Wait: true}
+	result := struct{ AllProcessInfo []types.ProcessInfo }{}
+	err := change(nil, &args, &result)
+	r := map[string]bool{"success": err == nil && len(result.AllProcessInfo) > 0}
+	json.NewEncoder(w).Encode(&r)
+}
+
+// ClearProgramLog clear the stdout and stderr logs of a program through the restful interface
+func (sr *SupervisorRestful) ClearProgramLog(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	args := struct{ Name string }{Name: mux.Vars(req)["name"]}
+	result := struct{ Success bool }{false}
+	err := sr.supervisor.ClearProcessLogs(nil, &args, &result)
+	r := map[string]bool{"success": err == nil && result.Success}
+	json.NewEncoder(w).Encode(&r)
+}
+
+// ProgramDetail returns the program information with its state history and resource usage
+func (sr *SupervisorRestful) ProgramDetail(w http.ResponseWriter, req *http.Request) {
+	proc := sr.supervisor.GetManager().Find(mux.Vars(req)["name"])
+	if proc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	detail := types.ProcessDetail{Info: *getProcessInfo(proc),
+		StateHistory: make([]types.ProcessStateChange, 0),
+		Resources:    getResourceSamples(proc)}
+	for _, change := range proc.GetStateHistory() {
+		detail.StateHistory = append(detail.StateHistory, types.ProcessStateChange{Time: int(change.Time.Unix()),
+			From: change.From.String(),
+			To:   change.To.String()})
+	}
+	json.NewEncoder(w).Encode(&detail)
+}
+
+// ListResources returns the recent resource usage samples of all the programs with their thresholds
+func (sr *SupervisorRestful) ListResources(w http.ResponseWriter, req *http.Request) {
+	result := make([]types.ProcessResourceHistory, 0)
+	sr.supervisor.GetManager().ForEachProcess(func(proc *process.Process) {
+		history := types.ProcessResourceHistory{Name: proc.GetName(), Group: proc.GetGroup(), Samples: getResourceSamples(proc)}
+		history.CPUThreshold, history.RSSThreshold = proc.GetResourceThresholds()
+		if usage, ok := proc.GetResourceUsage(); ok {
+			history.CPUExceeded = history.CPUThreshold > 0 && usage.CPU > float64(history.CPUThreshold)
+			history.RSSExceeded = history.RSSThreshold > 0 && usage.RSS > history.RSSThreshold
+		}
+		result = append(result, history)
+	})
+	json.NewEncoder(w).Encode(result)
+}
+
+// get the recent resource usage samples of the program, oldest first
+func getResourceSamples(proc *process.Process) []types.ProcessResourceSample {
+	samples := make([]types.ProcessResourceSample, 0)
+	for _, sample := range proc.GetResourceSamples() {
+		samples = append(samples, types.ProcessResourceSample{Time: int(sample.Time.Unix()),
+			RSS:      sample.RSS,
+			CPU:      sample.CPU,
+			FDs:      sample.FDs,
+			Threads:  sample.Threads,
+			Children: sample.Children})
+	}
+	return samples
+}
+
 // ReadStdoutLog read the stdout of given program
 func (sr *SupervisorRestful) ReadStdoutLog(w http.ResponseWriter, req *http.Request) {
+	writeProcessLog(sr.supervisor, mux.Vars(req)["name"], "stdout", w, req)
+}
+
+// ReadStderrLog read the stderr of given program
+func (sr *SupervisorRestful) ReadStderrLog(w http.ResponseWriter, req *http.Request) {
+	writeProcessLog(sr.supervisor, mux.Vars(req)["name"], "stderr", w, req)
+}
+
+// write the log of program from the "offset" in query, or the tail of log if no "offset" is
+// given. At most "length" (default 10240) bytes are returned with the offset for next read
+func writeProcessLog(s *Supervisor, name string, logType string, w http.ResponseWriter, req *http.Request) {
+	proc := s.GetManager().Find(name)
+	if proc == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no process named %s", name))
+		return
+	}
+	procLogger := proc.StdoutLog
+	if logType == "stderr" {
+		procLogger = proc.StderrLog
+	}
+	if procLogger == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no %s log of %s", logType, name))
+		return
+	}
+	length, err := strconv.ParseInt(req.URL.Query().Get("length"), 10, 64)
+	if err != nil || length <= 0 {
+		length = 10240
+	}
+	offset, err := strconv.ParseInt(req.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		// the logger returns the end of log if the offset exceeds it
+		_, end, _, _ := procLogger.ReadTailLog(math.MaxInt64, 0)
+		offset = end - length
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	result := struct {
+		Log      string `json:"log"`
+		Offset   int64  `json:"offset"`
+		Overflow bool   `json:"overflow"`
+	}{}
+	result.Log, result.Offset, result.Overflow, err = procLogger.ReadTailLog(offset, length)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &result)
 }
 
 // Shutdown the supervisor itself
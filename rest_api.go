@@ -0,0 +1,242 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/mux"
+	xmlrpc "github.com/ochinchina/gorilla-xmlrpc/xml"
+	"github.com/ochinchina/supervisord/faults"
+	"github.com/ochinchina/supervisord/signals"
+	"github.com/ochinchina/supervisord/types"
+)
+
+// the OpenAPI 3 document of the JSON API, it must be updated with the routes in CreateHandler
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// the Swagger UI page loading its scripts from unpkg to browse the OpenAPI document
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>supervisord JSON API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// SupervisorRestAPI the JSON API under /api/v1/ to manage the programs without XML-RPC. The
+// errors are returned as {"error": "..."} with the HTTP status code
+type SupervisorRestAPI struct {
+	router     *mux.Router
+	supervisor *Supervisor
+}
+
+// NewSupervisorRestAPI create a new SupervisorRestAPI object
+func NewSupervisorRestAPI(supervisor *Supervisor) *SupervisorRestAPI {
+	return &SupervisorRestAPI{router: mux.NewRouter(), supervisor: supervisor}
+}
+
+// CreateHandler create http handler to process the /api/v1/ requests
+func (api *SupervisorRestAPI) CreateHandler() http.Handler {
+	r := api.router.PathPrefix("/api/v1").Subrouter()
+	r.HandleFunc("/state", api.GetState).Methods("GET")
+	r.HandleFunc("/processes", api.ListProcesses).Methods("GET")
+	r.HandleFunc("/processes/{name}", api.GetProcess).Methods("GET")
+	r.HandleFunc("/processes/{name}/start", api.StartProcess).Methods("POST")
+	r.HandleFunc("/processes/{name}/stop", api.StopProcess).Methods("POST")
+	r.HandleFunc("/processes/{name}/restart", api.RestartProcess).Methods("POST")
+	r.HandleFunc("/processes/{name}/signal", api.SignalProcess).Methods("POST")
+	r.HandleFunc("/logs/{name}", api.ReadStdoutLog).Methods("GET")
+	r.HandleFunc("/logs/{name}/stdout", api.ReadStdoutLog).Methods("GET")
+	r.HandleFunc("/logs/{name}/stderr", api.ReadStderrLog).Methods("GET")
+	r.HandleFunc("/openapi.json", api.GetOpenAPISpec).Methods("GET")
+	r.HandleFunc("/docs", api.GetDocs).Methods("GET")
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no API %s", req.URL.Path))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed for %s", req.Method, req.URL.Path))
+	})
+	return api.router
+}
+
+// GetState returns the {statecode, statename} of supervisord
+func (api *SupervisorRestAPI) GetState(w http.ResponseWriter, req *http.Request) {
+	reply := struct{ StateInfo StateInfo }{}
+	api.supervisor.GetState(req, nil, &reply)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"statecode": reply.StateInfo.Statecode, "statename": reply.StateInfo.Statename})
+}
+
+// ListProcesses returns the information of all the programs
+func (api *SupervisorRestAPI) ListProcesses(w http.ResponseWriter, req *http.Request) {
+	reply := struct{ AllProcessInfo []types.ProcessInfo }{make([]types.ProcessInfo, 0)}
+	if err := api.supervisor.GetAllProcessInfo(req, nil, &reply); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reply.AllProcessInfo)
+}
+
+// GetProcess returns the information of the program
+func (api *SupervisorRestAPI) GetProcess(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	proc := api.supervisor.GetManager().Find(name)
+	if proc == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no process named %s", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, getProcessInfo(proc))
+}
+
+// StartProcess starts the programs matching the name, like "web", "web:*" or "worker-*", and
+// waits for them to be started unless the "wait" query is false
+func (api *SupervisorRestAPI) StartProcess(w http.ResponseWriter, req *http.Request) {
+	api.changeProcess(w, req, func(args *StartProcessArgs, reply *struct{ Success bool }) error {
+		return api.supervisor.StartProcess(req, args, reply)
+	})
+}
+
+// StopProcess stops the programs matching the name like StartProcess
+func (api *SupervisorRestAPI) StopProcess(w http.ResponseWriter, req *http.Request) {
+	api.changeProcess(w, req, func(args *StartProcessArgs, reply *struct{ Success bool }) error {
+		return api.supervisor.StopProcess(req, args, reply)
+	})
+}
+
+// RestartProcess restarts the programs matching the name like StartProcess
+func (api *SupervisorRestAPI) RestartProcess(w http.ResponseWriter, req *http.Request) {
+	api.changeProcess(w, req, func(args *StartProcessArgs, reply *struct{ Success bool }) error {
+		return api.supervisor.RestartProcess(req, args, reply)
+	})
+}
+
+// SignalProcess sends the signal in the "signal" query or the {"signal": "HUP"} body to the
+// programs matching the name
+func (api *SupervisorRestAPI) SignalProcess(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	body := struct {
+		Signal string `json:"signal"`
+	}{Signal: req.URL.Query().Get("signal")}
+	if body.Signal == "" && req.Body != nil {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+	}
+	signal, err := parseSignalName(body.Signal)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(api.supervisor.GetManager().FindMatch(name)) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no process named %s", name))
+		return
+	}
+	reply := struct{ Success bool }{}
+	if err := api.supervisor.SignalProcess(req, &types.ProcessSignal{Name: name, Signal: signal}, &reply); err != nil {
+		writeJSONError(w, faultStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"success": reply.Success})
+}
+
+// call the start, stop or restart with the name and the "wait" query
+func (api *SupervisorRestAPI) changeProcess(w http.ResponseWriter, req *http.Request, change func(args *StartProcessArgs, reply *struct{ Success bool }) error) {
+	name := mux.Vars(req)["name"]
+	wait := true
+	if value := req.URL.Query().Get("wait"); value != "" {
+		var err error
+		if wait, err = strconv.ParseBool(value); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid wait %s", value))
+			return
+		}
+	}
+	if len(api.supervisor.GetManager().FindMatch(name)) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("no process named %s", name))
+		return
+	}
+	reply := struct{ Success bool }{}
+	if err := change(&StartProcessArgs{Name: name, Wait: wait}, &reply); err != nil {
+		writeJSONError(w, faultStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"success": reply.Success})
+}
+
+// ReadStdoutLog reads the stdout log of the program from the "offset" query, or the tail of the
+// log if no offset is given, at most "length" (default 10240) bytes are returned
+func (api *SupervisorRestAPI) ReadStdoutLog(w http.ResponseWriter, req *http.Request) {
+	writeProcessLog(api.supervisor, mux.Vars(req)["name"], "stdout", w, req)
+}
+
+// ReadStderrLog reads the stderr log of the program like ReadStdoutLog
+func (api *SupervisorRestAPI) ReadStderrLog(w http.ResponseWriter, req *http.Request) {
+	writeProcessLog(api.supervisor, mux.Vars(req)["name"], "stderr", w, req)
+}
+
+// returns the upper case signal name if it is a known signal like "hup" or "SIGUSR1"
+func parseSignalName(name string) (string, error) {
+	// the unknown signal name is converted to SIGTERM
+	name = strings.ToUpper(name)
+	sig, err := signals.ToSignal(name)
+	if err != nil || (sig == syscall.SIGTERM && strings.TrimPrefix(name, "SIG") != "TERM") {
+		return "", fmt.Errorf("invalid signal %s", name)
+	}
+	return name, nil
+}
+
+// GetOpenAPISpec returns the OpenAPI 3 document describing this API
+func (api *SupervisorRestAPI) GetOpenAPISpec(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// GetDocs returns the Swagger UI page to browse the OpenAPI document
+func (api *SupervisorRestAPI) GetDocs(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, swaggerUIPage)
+}
+
+// the HTTP status of the error returned by the RPC
+func faultStatus(err error) int {
+	var fault *xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return http.StatusInternalServerError
+	}
+	switch fault.Code {
+	case faults.BadName, faults.NoFile:
+		return http.StatusNotFound
+	case faults.IncorrectParameters, faults.BadArguments, faults.BadSignal:
+		return http.StatusBadRequest
+	case faults.AlreadyStated, faults.NotRunning, faults.AlreadyAdded, faults.StillRunning:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	spec := struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	api := NewSupervisorRestAPI(nil)
+	api.CreateHandler()
+	routes := 0
+	api.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || path == "/api/v1" {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		routes++
+		operations, ok := spec.Paths[strings.TrimPrefix(path, "/api/v1")]
+		if !ok {
+			t.Errorf("the route %s is not in the OpenAPI document", path)
+			return nil
+		}
+		for _, method := range methods {
+			if _, ok := operations[strings.ToLower(method)]; !ok {
+				t.Errorf("the route %s %s is not in the OpenAPI document", method, path)
+			}
+		}
+		return nil
+	})
+	if routes != len(spec.Paths) {
+		t.Errorf("the OpenAPI document has %d paths, but there are %d routes", len(spec.Paths), routes)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochinchina/supervisord/config"
+	"github.com/ochinchina/supervisord/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// the max number of the clients whose request rates are tracked before the idle ones are dropped
+const maxRateBuckets = 10000
+
+// rpcAudit writes the state-changing RPCs to the audit log and limits the request rate of each client
+type rpcAudit struct {
+	lock sync.Mutex
+	// the audit log, nil if audit_logfile is not set
+	writer  logger.Logger
+	logFile string
+	// the requests per second of each client, 0 for no limit
+	rate  float64
+	burst int
+	// the token buckets by client
+	buckets map[string]*rateBucket
+	// the patterns of environment_mask, the values of the matched environment variables in the arguments are masked
+	envMask []string
+}
+
+// rpcAuditRecord a line of the audit log
+type rpcAuditRecord struct {
+	Time     string `json:"time"`
+	Client   string `json:"client"`
+	Addr     string `json:"addr"`
+	Protocol string `json:"protocol"`
+	Method   string `json:"method"`
+	Args     string `json:"args,omitempty"`
+	Result   string `json:"result"`
+}
+
+// rateBucket the tokens left to a client, a request takes a token and the tokens are refilled by the rate
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRPCAudit() *rpcAudit {
+	return &rpcAudit{buckets: make(map[string]*rateBucket)}
+}
+
+// open the audit log file with the rotation like the supervisord log and set the request rate limit of
+// each client, the log file is kept open if it is not changed
+func (a *rpcAudit) configure(logFile string, maxBytes int64, backups int, rate float64, burst int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if logFile != a.logFile {
+		if a.writer != nil {
+			a.writer.Close()
+			a.writer = nil
+		}
+		if logFile != "" {
+			a.writer = logger.NewLogger("audit", logFile, &sync.Mutex{}, maxBytes, backups, make(map[string]string), logger.NewNullLogEventEmitter())
+		}
+		a.logFile = logFile
+	}
+	if rate < 0 {
+		rate = 0
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	if rate != a.rate || burst != a.burst {
+		a.buckets = make(map[string]*rateBucket)
+	}
+	a.rate = rate
+	a.burst = burst
+}
+
+// set the patterns of environment_mask to mask the environment variables in the arguments
+func (a *rpcAudit) setEnvironmentMask(patterns []string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.envMask = patterns
+}
+
+func (a *rpcAudit) environmentMask() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.envMask
+}
+
+// reopen the audit log file, like after it is moved by logrotate
+func (a *rpcAudit) reopen() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.writer == nil {
+		return nil
+	}
+	return a.writer.Reopen()
+}
+
+// check if the client can make one more request now, the client is the authenticated user or the address
+func (a *rpcAudit) allow(client string, now time.Time) bool {
+	if a == nil {
+		return true
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.rate == 0 {
+		return true
+	}
+	bucket, ok := a.buckets[client]
+	if !ok {
+		if len(a.buckets) >= maxRateBuckets {
+			a.dropIdleBuckets(now)
+		}
+		bucket = &rateBucket{tokens: float64(a.burst), last: now}
+		a.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(float64(a.burst), bucket.tokens+now.Sub(bucket.last).Seconds()*a.rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// drop the buckets which are refilled, they are the same as the new ones
+func (a *rpcAudit) dropIdleBuckets(now time.Time) {
+	for client, bucket := range a.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*a.rate >= float64(a.burst) {
+			delete(a.buckets, client)
+		}
+	}
+}
+
+// write a state-changing RPC to the audit log
+func (a *rpcAudit) record(client string, addr string, protocol string, method string, args string, result string) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.writer == nil {
+		return
+	}
+	if client == "" {
+		client = "-"
+	}
+	b, err := json.Marshal(rpcAuditRecord{Time: time.Now().Format(time.RFC3339),
+		Client:   client,
+		Addr:     addr,
+		Protocol: protocol,
+		Method:   method,
+		Args:     args,
+		Result:   result})
+	if err != nil {
+		return
+	}
+	if _, err = a.writer.Write(append(b, '\n')); err != nil {
+		log.WithFields(log.Fields{"file": a.logFile, log.ErrorKey: err}).Error("fail to write the audit log")
+	}
+}
+
+// get the key of the client in the rate limit, the authenticated user if it is known or the IP address
+func rateLimitKey(client string, addr string) string {
+	if client != "" {
+		return "user:" + client
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if addr == "" || addr == "@" {
+		addr = "unix"
+	}
+	return "addr:" + addr
+}
+
+// the key of the name of the authenticated client in the request context
+type clientContextKey struct{}
+
+// get the name of the client authenticated by the request, empty if it is anonymous
+func getRequestClient(ctx context.Context) string {
+	client, _ := ctx.Value(clientContextKey{}).(string)
+	return client
+}
+
+// get the name of the client, the common name of the verified certificate, the name of the bearer token
+// or the username of the basic authentication, empty if the client is anonymous
+func (h *httpBasicAuth) clientName(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	if name, ok := h.tokens.getName(r); ok {
+		return "token:" + name
+	}
+	if username, _, ok := r.BasicAuth(); ok {
+		return username
+	}
+	return ""
+}
+
+// statusRecorder records the status code written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// the result of the XML-RPC response written to the audit log, the fault string or "ok"
+func xmlrpcAuditResult(status int, body []byte) string {
+	if status != http.StatusOK {
+		return http.StatusText(status)
+	}
+	if !strings.Contains(string(body), "<fault>") {
+		return "ok"
+	}
+	var response xmlrpcMethodResponse
+	if err := xml.Unmarshal(body, &response); err == nil && response.Fault != nil {
+		for _, member := range response.Fault.Struct {
+			if member.Name == "faultString" {
+				return member.Value.text()
+			}
+		}
+	}
+	return "fault"
+}
+
+// the max length of an argument written to the audit log, the longer ones are summarized
+const maxAuditArgLength = 256
+
+// the indexes of the arguments of the XML-RPC methods which are the program sections or the state snapshots,
+// they are summarized in the audit log because they may have the secrets
+var xmlrpcAuditBodyArgs = map[string]int{"supervisor.addProgram": 1, "supervisor.importState": 0}
+
+// the arguments of the XML-RPC call written to the audit log, the strings and numbers are kept as they are
+// except the bodies of xmlrpcAuditBodyArgs and the long ones, which are summarized by their length. The
+// values of the KEY=VALUE variables in the arrays matching the envMask patterns are masked
+func xmlrpcAuditArgs(method string, params []xmlrpcValue, envMask []string) string {
+	args := make([]string, 0, len(params))
+	for i, param := range params {
+		text := param.text()
+		if text == "" && strings.Contains(param.Inner, "<") {
+			text = strings.TrimSpace(param.Inner)
+		}
+		if index, ok := xmlrpcAuditBodyArgs[method]; (ok && index == i) || len(text) > maxAuditArgLength {
+			args = append(args, fmt.Sprintf("(%d bytes)", len(text)))
+		} else if len(param.Array) > 0 {
+			values := make([]string, 0, len(param.Array))
+			for _, value := range param.Array {
+				values = append(values, value.text())
+			}
+			b, _ := json.Marshal(maskEnv(values, envMask))
+			args = append(args, string(b))
+		} else {
+			args = append(args, text)
+		}
+	}
+	b, _ := json.Marshal(args)
+	return string(b)
+}
+
+// set the audit_logfile and the rpc_rate_limit of supervisord section
+func (s *Supervisor) setRPCAudit() {
+	logFile, maxBytes, backups, rate, burst := "", 50*1024*1024, 10, 0.0, 0
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		env := config.NewStringExpression("here", s.config.GetConfigFileDir())
+		var err error
+		if logFile, err = env.Eval(supervisordConf.GetString("audit_logfile", "")); err != nil {
+			log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to parse the audit_logfile")
+			logFile = ""
+		}
+		maxBytes = supervisordConf.GetBytes("audit_logfile_maxbytes", maxBytes)
+		backups = supervisordConf.GetInt("audit_logfile_backups", backups)
+		if value := supervisordConf.GetString("rpc_rate_limit", ""); value != "" {
+			if rate, err = strconv.ParseFloat(value, 64); err != nil {
+				log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to parse the rpc_rate_limit")
+				rate = 0
+			}
+		}
+		burst = supervisordConf.GetInt("rpc_rate_burst", burst)
+	}
+	s.audit.configure(logFile, int64(maxBytes), backups, rate, burst)
+	s.audit.setEnvironmentMask(s.getEnvironmentMask())
+}
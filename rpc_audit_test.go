@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRPCAuditRateLimit(t *testing.T) {
+	audit := newRPCAudit()
+	now := time.Now()
+	if !audit.allow("addr:127.0.0.1", now) {
+		t.Error("the requests should not be limited without rpc_rate_limit")
+	}
+
+	audit.configure("", 0, 0, 1, 2)
+	for i, expected := range []bool{true, true, false} {
+		if audit.allow("user:ci", now) != expected {
+			t.Errorf("the request %d of the burst should be allowed: %v", i, expected)
+		}
+	}
+	if !audit.allow("user:ops", now) {
+		t.Error("the requests of the other clients should not be limited")
+	}
+	if !audit.allow("user:ci", now.Add(time.Second)) || audit.allow("user:ci", now.Add(time.Second)) {
+		t.Error("one request should be allowed after a second")
+	}
+
+	if key := rateLimitKey("", "10.0.0.1:5000"); key != "addr:10.0.0.1" {
+		t.Errorf("the anonymous client should be limited by IP, but the key is %s", key)
+	}
+}
+
+func TestRPCAuditRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logFile := filepath.Join(dir, "audit.log")
+
+	audit := newRPCAudit()
+	audit.configure(logFile, 1024*1024, 1, 0, 0)
+	audit.record("admin", "127.0.0.1:5000", "xmlrpc", "supervisor.stopProcess", `["web","true"]`, "ok")
+	audit.configure("", 0, 0, 0, 0)
+
+	b, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record rpcAuditRecord
+	if err := json.Unmarshal(b, &record); err != nil {
+		t.Fatalf("fail to parse the audit log %s: %v", string(b), err)
+	}
+	if record.Client != "admin" || record.Method != "supervisor.stopProcess" || record.Result != "ok" {
+		t.Errorf("unexpected audit record %+v", record)
+	}
+}
+
+func TestXMLRPCAuditArgs(t *testing.T) {
+	var call xmlrpcMethodCall
+	xml.Unmarshal([]byte(`<methodCall><methodName>supervisor.restartProcessWithEnv</methodName><params>
+<param><value><string>web</string></value></param>
+<param><value><string>true</string></value></param>
+<param><value><array><data><value><string>DB_PASSWORD=secret</string></value><value><string>MODE=debug</string></value></data></array></value></param>
+</params></methodCall>`), &call)
+	mask := strings.Split(defaultEnvironmentMask, ",")
+	if args := xmlrpcAuditArgs(call.Method, call.Params, mask); args != `["web","true","[\"DB_PASSWORD=******\",\"MODE=debug\"]"]` {
+		t.Errorf("the secret environment variable should be masked, but get %s", args)
+	}
+
+	if args := xmlrpcAuditArgs("supervisor.importState", []xmlrpcValue{{Inner: `{"version":1}`}}, mask); args != `["(13 bytes)"]` {
+		t.Errorf("the state snapshot should be summarized, but get %s", args)
+	}
+	section := xmlrpcValue{Inner: "[program:web]\ncommand=ls\nenvironment=TOKEN=secret\n"}
+	if args := xmlrpcAuditArgs("supervisor.addProgram", []xmlrpcValue{{Inner: "web"}, section}, mask); args != `["web","(50 bytes)"]` {
+		t.Errorf("the program section should be summarized, but get %s", args)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/ochinchina/supervisord/signals
 
 go 1.16
 
-require github.com/sirupsen/logrus v1.8.1
+require (
+	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8
+)
@@ -9,6 +9,8 @@ import (
 	"os"
 	"os/exec"
 	"syscall"
+
+	"golang.org/x/sys/windows"
 )
 
 //convert a signal name to signal
@@ -41,6 +43,13 @@ func ToSignal(signalName string) (os.Signal, error) {
 //    sigChildren - ignore in windows system
 //
 func Kill(process *os.Process, sig os.Signal, sigChilren bool) error {
+	// stop the program gracefully by CTRL_BREAK, the program is started in a new process
+	// group whose id is the pid of program. It fails if supervisord has no console
+	if sig != syscall.SIGKILL {
+		if err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(process.Pid)); err == nil {
+			return nil
+		}
+	}
 	//Signal command can't kill children processes, call  taskkill command to kill them
 	cmd := exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", process.Pid))
 	err := cmd.Start()
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ochinchina/supervisord/faults"
+	"github.com/ochinchina/supervisord/process"
+	log "github.com/sirupsen/logrus"
+)
+
+// the version of the state snapshot format
+const stateSnapshotVersion = 1
+
+// stateSnapshot the state of supervisord and all its programs exported as JSON
+type stateSnapshot struct {
+	Version           int                `json:"version"`
+	SupervisorVersion string             `json:"supervisor_version"`
+	Identification    string             `json:"identification"`
+	Pid               int                `json:"pid"`
+	State             string             `json:"state"`
+	Time              int64              `json:"time"`
+	Processes         []process.Snapshot `json:"processes"`
+}
+
+// ExportState export the states, restart counts, uptimes and next schedules of all the programs as JSON,
+// the values of the secret environment overrides are masked
+func (s *Supervisor) ExportState(r *http.Request, args *struct{}, reply *struct{ State string }) error {
+	snapshot := stateSnapshot{Version: stateSnapshotVersion,
+		SupervisorVersion: VERSION,
+		Identification:    s.GetSupervisorID(),
+		Pid:               os.Getpid(),
+		State:             s.GetSupervisorState().String(),
+		Time:              time.Now().Unix(),
+		Processes:         make([]process.Snapshot, 0)}
+	mask := s.getEnvironmentMask()
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		procSnapshot := proc.GetSnapshot()
+		if len(procSnapshot.EnvOverrides) > 0 {
+			procSnapshot.EnvOverrides = maskEnv(procSnapshot.EnvOverrides, mask)
+		}
+		snapshot.Processes = append(snapshot.Processes, procSnapshot)
+	})
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return faults.NewFault(faults.Failed, fmt.Sprintf("FAILED: fail to export the state: %v", err))
+	}
+	reply.State = string(b)
+	return nil
+}
+
+// ImportState import the state exported by ExportState, the restart counts are restored and the programs
+// are started or stopped to reach their exported states. The programs not in the configuration are skipped
+// and the masked environment overrides are dropped
+func (s *Supervisor) ImportState(r *http.Request, args *struct{ State string }, reply *struct{ Success bool }) error {
+	var snapshot stateSnapshot
+	if err := json.Unmarshal([]byte(args.State), &snapshot); err != nil {
+		return faults.NewFault(faults.IncorrectParameters, fmt.Sprintf("INCORRECT_PARAMETERS: invalid state snapshot: %v", err))
+	}
+	if snapshot.Version != stateSnapshotVersion {
+		return faults.NewFault(faults.IncorrectParameters, fmt.Sprintf("INCORRECT_PARAMETERS: unsupported state snapshot version %d", snapshot.Version))
+	}
+	for _, procSnapshot := range snapshot.Processes {
+		proc := s.procMgr.Find(procSnapshot.Name)
+		if proc == nil {
+			log.WithFields(log.Fields{"program": procSnapshot.Name}).Warn("skip the program of the state snapshot, it is not in the configuration")
+			continue
+		}
+		envOverrides := make([]string, 0, len(procSnapshot.EnvOverrides))
+		for _, env := range procSnapshot.EnvOverrides {
+			if !strings.HasSuffix(env, "="+maskedEnvValue) {
+				envOverrides = append(envOverrides, env)
+			}
+		}
+		log.WithFields(log.Fields{"program": proc.GetName(), "state": procSnapshot.State, "restarts": procSnapshot.Restarts}).Info("restore the state of program")
+		proc.RestoreSnapshot(procSnapshot, envOverrides)
+	}
+	reply.Success = true
+	return nil
+}
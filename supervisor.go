@@ -1,11 +1,18 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ochinchina/supervisord/config"
@@ -23,8 +30,42 @@ import (
 const (
 	// SupervisorVersion the version of supervisor
 	SupervisorVersion = "3.0"
+
+	// the upper limit of lines returned by one log search
+	maxLogSearchResults = 1000
+
+	// the max time to wait for the requests being handled when the http server is restarted on reload
+	httpServerStopTimeout = 10 * time.Second
 )
 
+// SupervisorState the state of supervisord returned by supervisor.getState
+type SupervisorState int32
+
+const (
+	// SupervisorFatal the configuration fails to be loaded
+	SupervisorFatal SupervisorState = 2
+	// SupervisorRunning the configuration is loaded and the programs are managed
+	SupervisorRunning SupervisorState = 1
+	// SupervisorRestarting the configuration is being loaded or supervisord is restarting
+	SupervisorRestarting SupervisorState = 0
+	// SupervisorShutdown supervisord is stopping all the programs to exit
+	SupervisorShutdown SupervisorState = -1
+)
+
+func (s SupervisorState) String() string {
+	switch s {
+	case SupervisorFatal:
+		return "FATAL"
+	case SupervisorRunning:
+		return "RUNNING"
+	case SupervisorRestarting:
+		return "RESTARTING"
+	case SupervisorShutdown:
+		return "SHUTDOWN"
+	}
+	return "UNKNOWN"
+}
+
 // Supervisor manage all the processes defined in the supervisor configuration file.
 // All the supervisor public interface is defined in this class
 type Supervisor struct {
@@ -33,13 +74,38 @@ type Supervisor struct {
 	xmlRPC     *XMLRPC          // XMLRPC interface
 	logger     logger.Logger    // logger manager
 	lock       sync.Mutex
-	restarting bool // if supervisor is in restarting state
+	restarting bool  // if supervisor is in restarting state
+	state      int32 // the SupervisorState, accessed atomically
+	chaos      bool  // if the chaos testing RPCs are enabled
+	// the event webhooks by name
+	eventWebhooks map[string]*EventWebhook
+	// the event sinks by name
+	eventSinks map[string]*EventSink
+	// the crash notifiers by name
+	notifiers map[string]*Notifier
+	// the bearer tokens accepted in addition to the username and password
+	tokens *apiTokens
+	// the audit log and the rate limit of the RPCs
+	audit *rpcAudit
+	// the settings of the started http servers and gRPC server by protocol
+	httpServerConfigs map[string]string
+	// serialize the restarts of the http servers on reload
+	httpServerLock sync.Mutex
 }
 
 // StartProcessArgs arguments for starting a process
 type StartProcessArgs struct {
 	Name string // program name
 	Wait bool   `default:"true"` // Wait the program starting finished
+	// optional environment variables in KEY=VALUE format merged over the configured ones only for this run
+	Env []string
+}
+
+// WaitForStateArgs arguments for waiting for a process to reach a state
+type WaitForStateArgs struct {
+	Name    string // program name
+	State   string // state name like RUNNING, case insensitive
+	Timeout int    // the max seconds to wait
 }
 
 // ProcessStdin  process stdin from client
@@ -48,6 +114,12 @@ type ProcessStdin struct {
 	Chars string // inputs from client
 }
 
+// ProcessStdinBytes the binary data sent to the process stdin from client
+type ProcessStdinBytes struct {
+	Name string // program name
+	Data []byte // the data in XML-RPC base64
+}
+
 // RemoteCommEvent remove communication event from client side
 type RemoteCommEvent struct {
 	Type string // the event type
@@ -84,16 +156,45 @@ type ProcessLogReadInfo struct {
 // ProcessTailLog the output of tail the program log
 type ProcessTailLog struct {
 	LogData  string
-	Offset   int64
+	Offset   int
 	Overflow bool
 }
 
+// ProcessLogSearchArgs the input argument to search the log of program
+type ProcessLogSearchArgs struct {
+	Name       string // the program name
+	Pattern    string // the regular expression to search
+	MaxResults int    // the max number of matched lines returned
+	Since      int    // the start of time window in unix seconds, 0 for no bound
+	Until      int    // the end of time window in unix seconds, 0 for no bound
+}
+
+// EventHistoryArgs arguments for getting the event history
+type EventHistoryArgs struct {
+	Filter string // the comma separated event names and "key:value" body fields, empty for all
+	Since  int    // the unix seconds the events emitted before are skipped, 0 for no bound
+}
+
 // NewSupervisor create a Supervisor object with supervisor configuration file
 func NewSupervisor(configFile string) *Supervisor {
 	return &Supervisor{config: config.NewConfig(configFile),
 		procMgr:    process.NewManager(),
 		xmlRPC:     NewXMLRPC(),
-		restarting: false}
+		tokens:     newAPITokens(),
+		audit:      newRPCAudit(),
+		restarting: false,
+		state:      int32(SupervisorRestarting)}
+}
+
+// GetSupervisorState get the current state of supervisord
+func (s *Supervisor) GetSupervisorState() SupervisorState {
+	return SupervisorState(atomic.LoadInt32(&s.state))
+}
+
+func (s *Supervisor) setSupervisorState(state SupervisorState) {
+	if prev := SupervisorState(atomic.SwapInt32(&s.state, int32(state))); prev != state {
+		log.WithFields(log.Fields{"from": prev.String(), "to": state.String()}).Info("supervisord state changed")
+	}
 }
 
 // GetConfig get the loaded supervisor configuration
@@ -137,8 +238,9 @@ func (s *Supervisor) GetState(r *http.Request, args *struct{}, reply *struct{ St
 	// 0            RESTARTING
 	// -1           SHUTDOWN
 	log.Debug("Get state")
-	reply.StateInfo.Statecode = 1
-	reply.StateInfo.Statename = "RUNNING"
+	state := s.GetSupervisorState()
+	reply.StateInfo.Statecode = int(state)
+	reply.StateInfo.Statename = state.String()
 	return nil
 }
 
@@ -162,6 +264,18 @@ func (s *Supervisor) ReadLog(r *http.Request, args *LogReadInfo, reply *struct{
 	return err
 }
 
+// TailLog tails the supervisor log like TailProcessStdoutLog
+func (s *Supervisor) TailLog(r *http.Request, args *LogReadInfo, reply *ProcessTailLog) error {
+	if s.logger == nil {
+		return faults.NewFault(faults.NoFile, "NO_FILE the supervisord log is not written to a file")
+	}
+	var err error
+	var offset int64
+	reply.LogData, offset, reply.Overflow, err = s.logger.ReadTailLog(int64(args.Offset), int64(args.Length))
+	reply.Offset = int(offset)
+	return err
+}
+
 // ClearLog clear the supervisor log
 func (s *Supervisor) ClearLog(r *http.Request, args *struct{}, reply *struct{ Ret bool }) error {
 	err := s.logger.ClearAllLogFile()
@@ -169,11 +283,38 @@ func (s *Supervisor) ClearLog(r *http.Request, args *struct{}, reply *struct{ Re
 	return err
 }
 
+// ReopenLogs closes and opens the supervisord log and the log files of all the running
+// programs again, so the log files moved by logrotate are created again
+func (s *Supervisor) ReopenLogs(r *http.Request, args *struct{}, reply *struct{ Ret bool }) error {
+	log.Info("reopen the log files")
+	var result error
+	if s.logger != nil {
+		result = s.logger.Reopen()
+	}
+	if err := s.audit.reopen(); err != nil {
+		log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to reopen the audit log")
+		if result == nil {
+			result = err
+		}
+	}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if err := proc.ReopenLogs(); err != nil {
+			log.WithFields(log.Fields{"program": proc.GetName(), log.ErrorKey: err}).Error("fail to reopen the log files")
+			if result == nil {
+				result = err
+			}
+		}
+	})
+	reply.Ret = result == nil
+	return result
+}
+
 // Shutdown the supervisor
 func (s *Supervisor) Shutdown(r *http.Request, args *struct{}, reply *struct{ Ret bool }) error {
 	reply.Ret = true
 	log.Info("received rpc request to stop all processes & exit")
-	s.procMgr.StopAllProcesses()
+	s.setSupervisorState(SupervisorShutdown)
+	s.stopAllProcesses()
 	go func() {
 		time.Sleep(1 * time.Second)
 		os.Exit(0)
@@ -184,6 +325,7 @@ func (s *Supervisor) Shutdown(r *http.Request, args *struct{}, reply *struct{ Re
 // Restart the supervisor
 func (s *Supervisor) Restart(r *http.Request, args *struct{}, reply *struct{ Ret bool }) error {
 	log.Info("Receive instruction to restart")
+	s.setSupervisorState(SupervisorRestarting)
 	s.restarting = true
 	reply.Ret = true
 	return nil
@@ -195,20 +337,32 @@ func (s *Supervisor) IsRestarting() bool {
 }
 
 func getProcessInfo(proc *process.Process) *types.ProcessInfo {
+	now := time.Now()
+	uptime := proc.GetUptime()
+	start := proc.GetStartTime()
+	// derive the start time from the monotonic uptime, so Now - Start is the real uptime
+	// even if the wall clock is changed after the program started
+	if uptime > 0 {
+		start = now.Add(-uptime)
+	}
 	return &types.ProcessInfo{Name: proc.GetName(),
 		Group:         proc.GetGroup(),
 		Description:   proc.GetDescription(),
-		Start:         int(proc.GetStartTime().Unix()),
+		Start:         int(start.Unix()),
 		Stop:          int(proc.GetStopTime().Unix()),
-		Now:           int(time.Now().Unix()),
+		Now:           int(now.Unix()),
 		State:         int(proc.GetState()),
 		Statename:     proc.GetState().String(),
-		Spawnerr:      "",
+		Spawnerr:      proc.GetSpawnErr(),
 		Exitstatus:    proc.GetExitstatus(),
 		Logfile:       proc.GetStdoutLogfile(),
 		StdoutLogfile: proc.GetStdoutLogfile(),
 		StderrLogfile: proc.GetStderrLogfile(),
-		Pid:           proc.GetPid()}
+		Pid:           proc.GetPid(),
+		Health:        string(proc.GetHealth()),
+		EnvOverride:   strings.Join(proc.GetEnvOverrides(), ","),
+		Uptime:        int(uptime.Seconds()),
+		Labels:        strings.Join(proc.GetLabels(), ",")}
 
 }
 
@@ -223,6 +377,19 @@ func (s *Supervisor) GetAllProcessInfo(r *http.Request, args *struct{}, reply *s
 	return nil
 }
 
+// GetAllConfigInfo get all the programs in the configuration, including the ones not added to supervisord
+func (s *Supervisor) GetAllConfigInfo(r *http.Request, args *struct{}, reply *struct{ AllConfigInfo []types.ConfigInfo }) error {
+	reply.AllConfigInfo = make([]types.ConfigInfo, 0)
+	for _, entry := range s.config.GetPrograms() {
+		reply.AllConfigInfo = append(reply.AllConfigInfo, types.ConfigInfo{Name: entry.GetProgramName(),
+			Group:     entry.Group,
+			Inuse:     s.procMgr.Find(entry.GetProgramName()) != nil,
+			Autostart: entry.GetBool("autostart", true),
+			Priority:  entry.GetInt("priority", 999)})
+	}
+	return nil
+}
+
 // GetProcessInfo get the process information of one program
 func (s *Supervisor) GetProcessInfo(r *http.Request, args *struct{ Name string }, reply *struct{ ProcInfo types.ProcessInfo }) error {
 	log.Info("Get process info of: ", args.Name)
@@ -235,6 +402,174 @@ func (s *Supervisor) GetProcessInfo(r *http.Request, args *struct{ Name string }
 	return nil
 }
 
+// GetProcessLogUsage get the disk usage of the log files with their rotated backups of one program
+func (s *Supervisor) GetProcessLogUsage(r *http.Request, args *struct{ Name string }, reply *struct{ Usage types.ProcessLogUsage }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: no process named %s", args.Name))
+	}
+	reply.Usage = getProcessLogUsage(proc)
+	return nil
+}
+
+// GetAllProcessLogUsage get the disk usage of the log files with their rotated backups of all the programs
+func (s *Supervisor) GetAllProcessLogUsage(r *http.Request, args *struct{}, reply *struct{ AllUsage []types.ProcessLogUsage }) error {
+	reply.AllUsage = make([]types.ProcessLogUsage, 0)
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		reply.AllUsage = append(reply.AllUsage, getProcessLogUsage(proc))
+	})
+	return nil
+}
+
+func getProcessLogUsage(proc *process.Process) types.ProcessLogUsage {
+	files, bytes := proc.GetLogUsage()
+	return types.ProcessLogUsage{Name: proc.GetName(),
+		Group: proc.GetGroup(),
+		Bytes: strconv.FormatInt(bytes, 10),
+		Quota: strconv.FormatInt(proc.GetLogQuota(), 10),
+		Files: files}
+}
+
+// GetProcessResourceUsage get the latest CPU, memory, fd, thread and child process usage of one
+// program sampled in the background
+func (s *Supervisor) GetProcessResourceUsage(r *http.Request, args *struct{ Name string }, reply *struct{ Usage types.ProcessResourceUsage }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: no process named %s", args.Name))
+	}
+	reply.Usage = getProcessResourceUsage(proc)
+	return nil
+}
+
+// GetAllProcessResourceUsage get the latest resource usage of all the programs
+func (s *Supervisor) GetAllProcessResourceUsage(r *http.Request, args *struct{}, reply *struct{ AllUsage []types.ProcessResourceUsage }) error {
+	reply.AllUsage = make([]types.ProcessResourceUsage, 0)
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		reply.AllUsage = append(reply.AllUsage, getProcessResourceUsage(proc))
+	})
+	return nil
+}
+
+// the default environment_mask, the values of the variables whose names match the patterns are masked
+const defaultEnvironmentMask = "*PASSWORD*,*PASSWD*,*SECRET*,*TOKEN*,*KEY*,*CREDENTIAL*"
+
+// the masked value of the secret environment variables
+const maskedEnvValue = "******"
+
+// GetProcessEnvironment get the environment variables the program is started with, the values of the
+// variables whose names match the environment_mask patterns are masked
+func (s *Supervisor) GetProcessEnvironment(r *http.Request, args *struct{ Name string }, reply *struct{ Env types.ProcessEnvironment }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: no process named %s", args.Name))
+	}
+	env := proc.GetEnv()
+	if env == nil {
+		return faults.NewFault(faults.NotRunning, fmt.Sprintf("NOT_RUNNING: %s is never started", args.Name))
+	}
+	reply.Env = types.ProcessEnvironment{Name: proc.GetName(),
+		Group:     proc.GetGroup(),
+		Pid:       proc.GetPid(),
+		Env:       maskEnv(env, s.getEnvironmentMask()),
+		Overrides: strings.Join(proc.GetEnvOverrides(), ",")}
+	return nil
+}
+
+// get the patterns of the environment_mask
+func (s *Supervisor) getEnvironmentMask() []string {
+	mask := defaultEnvironmentMask
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		mask = supervisordConf.GetString("environment_mask", mask)
+	}
+	return strings.Split(mask, ",")
+}
+
+// replace the values of the KEY=VALUE variables whose names match the case insensitive shell patterns with "******"
+func maskEnv(env []string, patterns []string) []string {
+	result := make([]string, 0, len(env))
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		for _, pattern := range patterns {
+			pattern = strings.TrimSpace(pattern)
+			if matched, err := filepath.Match(strings.ToUpper(pattern), strings.ToUpper(kv[0])); err == nil && matched && pattern != "" {
+				e = kv[0] + "=" + maskedEnvValue
+				break
+			}
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func getProcessResourceUsage(proc *process.Process) types.ProcessResourceUsage {
+	usage := types.ProcessResourceUsage{Name: proc.GetName(), Group: proc.GetGroup()}
+	if sample, ok := proc.GetResourceUsage(); ok {
+		usage.Pid = proc.GetPid()
+		usage.Time = int(sample.Time.Unix())
+		usage.Rss = sample.RSS
+		usage.Cpu = sample.CPU
+		usage.Fds = sample.FDs
+		usage.Threads = sample.Threads
+		usage.Children = sample.Children
+	}
+	return usage
+}
+
+// WaitForState waits until the program reaches the state or the timeout in seconds expires, the
+// reply is false if it times out
+func (s *Supervisor) WaitForState(r *http.Request, args *WaitForStateArgs, reply *struct{ Success bool }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: no process named %s", args.Name))
+	}
+	state, ok := parseProcessState(args.State)
+	if !ok {
+		return faults.NewFault(faults.BadArguments, fmt.Sprintf("BAD_ARGUMENTS: unknown process state %s", args.State))
+	}
+
+	// subscribe before checking the current state so no state change is lost between them
+	ch := events.Subscribe("PROCESS_STATE")
+	defer events.Unsubscribe(ch)
+	if proc.GetState() == state {
+		reply.Success = true
+		return nil
+	}
+	var cancelled <-chan struct{}
+	if r != nil {
+		cancelled = r.Context().Done()
+	}
+	timer := time.NewTimer(time.Duration(args.Timeout) * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if e, ok := event.(*events.ProcessStateEvent); ok && e.GetProcessName() == proc.GetName() &&
+				strings.EqualFold(strings.TrimPrefix(e.GetType(), "PROCESS_STATE_"), state.String()) {
+				reply.Success = true
+				return nil
+			}
+		case <-timer.C:
+			return nil
+		case <-cancelled:
+			return nil
+		}
+	}
+}
+
+// get the process state by its name like RUNNING, case insensitive
+func parseProcessState(name string) (process.State, bool) {
+	for _, state := range []process.State{process.Stopped, process.Starting, process.Running, process.Backoff,
+		process.Stopping, process.Exited, process.Fatal} {
+		if strings.EqualFold(state.String(), name) {
+			return state, true
+		}
+	}
+	return process.Unknown, false
+}
+
 // StartProcess start the given program
 func (s *Supervisor) StartProcess(r *http.Request, args *StartProcessArgs, reply *struct{ Success bool }) error {
 	procs := s.procMgr.FindMatch(args.Name)
@@ -242,13 +577,108 @@ func (s *Supervisor) StartProcess(r *http.Request, args *StartProcessArgs, reply
 	if len(procs) <= 0 {
 		return fmt.Errorf("fail to find process %s", args.Name)
 	}
+	if err := checkEnvOverrides(args.Env); err != nil {
+		return err
+	}
 	for _, proc := range procs {
-		proc.Start(args.Wait)
+		if len(args.Env) > 0 {
+			log.WithFields(log.Fields{"program": proc.GetName(), "env": strings.Join(process.EnvNames(args.Env), ",")}).Info("start program with environment overrides")
+		}
+		proc.StartWithEnv(args.Wait, args.Env)
+	}
+	reply.Success = true
+	return nil
+}
+
+// check if the environment overrides are all in KEY=VALUE format
+func checkEnvOverrides(envs []string) error {
+	for _, env := range envs {
+		if !strings.Contains(env, "=") || strings.HasPrefix(env, "=") {
+			return faults.NewFault(faults.BadArguments, fmt.Sprintf("invalid environment variable %s, it should be KEY=VALUE", env))
+		}
+	}
+	return nil
+}
+
+// RestartProcessWithEnv restart the program with environment overrides only for this run like
+// RestartProcess, the overrides are not saved to the configuration and are dropped on next start
+func (s *Supervisor) RestartProcessWithEnv(r *http.Request, args *StartProcessArgs, reply *struct{ Success bool }) error {
+	return s.RestartProcess(r, args, reply)
+}
+
+// RestartProcess stops the program and waits for it to exit, then starts it again and waits for
+// it to be running if Wait is true. SPAWN_ERROR fault is returned if the program is not running
+// after the start is waited
+func (s *Supervisor) RestartProcess(r *http.Request, args *StartProcessArgs, reply *struct{ Success bool }) error {
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: %s", args.Name))
+	}
+	if err := checkEnvOverrides(args.Env); err != nil {
+		return err
+	}
+	if len(args.Env) > 0 {
+		log.WithFields(log.Fields{"program": args.Name, "env": strings.Join(process.EnvNames(args.Env), ",")}).Info("restart process with environment overrides")
+	} else {
+		log.WithFields(log.Fields{"program": args.Name}).Info("restart process")
+	}
+	if err := restartProcesses(procs, args.Wait, args.Env); err != nil {
+		return err
 	}
 	reply.Success = true
 	return nil
 }
 
+// RestartProcessGroup stops all the processes in the group and waits for them to exit, then
+// starts them again like RestartProcess
+func (s *Supervisor) RestartProcessGroup(r *http.Request, args *StartProcessArgs, reply *struct{ AllProcessInfo []types.ProcessInfo }) error {
+	procs := make([]*process.Process, 0)
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if proc.GetGroup() == args.Name {
+			procs = append(procs, proc)
+		}
+	})
+	if len(procs) == 0 {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: %s", args.Name))
+	}
+	log.WithFields(log.Fields{"group": args.Name}).Info("restart process group")
+	err := restartProcesses(procs, args.Wait, nil)
+	for _, proc := range procs {
+		reply.AllProcessInfo = append(reply.AllProcessInfo, *getProcessInfo(proc))
+	}
+	return err
+}
+
+// restart the processes concurrently, all of them are stopped before any of them is started
+func restartProcesses(procs []*process.Process, wait bool, envOverrides []string) error {
+	var wg sync.WaitGroup
+	wg.Add(len(procs))
+	for _, proc := range procs {
+		go func(proc *process.Process) {
+			defer wg.Done()
+			proc.Stop(true)
+		}(proc)
+	}
+	wg.Wait()
+	wg.Add(len(procs))
+	for _, proc := range procs {
+		go func(proc *process.Process) {
+			defer wg.Done()
+			proc.Restart(wait, envOverrides)
+		}(proc)
+	}
+	wg.Wait()
+	if !wait {
+		return nil
+	}
+	for _, proc := range procs {
+		if proc.GetState() != process.Running {
+			return faults.NewFault(faults.SpawnError, fmt.Sprintf("SPAWN_ERROR: %s", proc.GetName()))
+		}
+	}
+	return nil
+}
+
 // StartAllProcesses start all the programs
 func (s *Supervisor) StartAllProcesses(r *http.Request, args *struct {
 	Wait bool `default:"true"`
@@ -361,24 +791,35 @@ func (s *Supervisor) SignalProcess(r *http.Request, args *types.ProcessSignal, r
 		reply.Success = false
 		return fmt.Errorf("No process named %s", args.Name)
 	}
-	sig, err := signals.ToSignal(args.Signal)
-	if err == nil {
-		for _, proc := range procs {
-			proc.Signal(sig, false)
-		}
+	sig, err := toSignal(args.Signal)
+	if err != nil {
+		return err
+	}
+	for _, proc := range procs {
+		proc.Signal(sig, false)
 	}
 	reply.Success = true
 	return nil
 }
 
+// get the signal by the name like "HUP" or "SIGUSR1", BAD_SIGNAL fault is returned if the name is unknown
+func toSignal(name string) (os.Signal, error) {
+	name, err := parseSignalName(name)
+	if err != nil {
+		return nil, faults.NewFault(faults.BadSignal, fmt.Sprintf("BAD_SIGNAL: %v", err))
+	}
+	return signals.ToSignal(name)
+}
+
 // SignalProcessGroup send signal to all processes in one group
 func (s *Supervisor) SignalProcessGroup(r *http.Request, args *types.ProcessSignal, reply *struct{ AllProcessInfo []types.ProcessInfo }) error {
+	sig, err := toSignal(args.Signal)
+	if err != nil {
+		return err
+	}
 	s.procMgr.ForEachProcess(func(proc *process.Process) {
 		if proc.GetGroup() == args.Name {
-			sig, err := signals.ToSignal(args.Signal)
-			if err == nil {
-				proc.Signal(sig, false)
-			}
+			proc.Signal(sig, false)
 		}
 	})
 
@@ -392,11 +833,12 @@ func (s *Supervisor) SignalProcessGroup(r *http.Request, args *types.ProcessSign
 
 // SignalAllProcesses send signal to all the processes in the supervisor
 func (s *Supervisor) SignalAllProcesses(r *http.Request, args *types.ProcessSignal, reply *struct{ AllProcessInfo []types.ProcessInfo }) error {
+	sig, err := toSignal(args.Signal)
+	if err != nil {
+		return err
+	}
 	s.procMgr.ForEachProcess(func(proc *process.Process) {
-		sig, err := signals.ToSignal(args.Signal)
-		if err == nil {
-			proc.Signal(sig, false)
-		}
+		proc.Signal(sig, false)
 	})
 	s.procMgr.ForEachProcess(func(proc *process.Process) {
 		reply.AllProcessInfo = append(reply.AllProcessInfo, *getProcessInfo(proc))
@@ -424,6 +866,39 @@ func (s *Supervisor) SendProcessStdin(r *http.Request, args *ProcessStdin, reply
 	return err
 }
 
+// SendProcessStdinBase64 sends the binary data in XML-RPC base64 to program through stdin, so the bytes which
+// are not valid in XML survive the round trip
+func (s *Supervisor) SendProcessStdinBase64(r *http.Request, args *ProcessStdinBytes, reply *struct{ Success bool }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		log.WithFields(log.Fields{"program": args.Name}).Error("program does not exist")
+		return fmt.Errorf("NOT_RUNNING")
+	}
+	if proc.GetState() != process.Running {
+		log.WithFields(log.Fields{"program": args.Name}).Error("program does not run")
+		return fmt.Errorf("NOT_RUNNING")
+	}
+	err := proc.SendProcessStdinBytes(args.Data)
+	reply.Success = err == nil
+	return err
+}
+
+// CloseProcessStdin closes the stdin of program to signal the end of input
+func (s *Supervisor) CloseProcessStdin(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		log.WithFields(log.Fields{"program": args.Name}).Error("program does not exist")
+		return fmt.Errorf("NOT_RUNNING")
+	}
+	if proc.GetState() != process.Running {
+		log.WithFields(log.Fields{"program": args.Name}).Error("program does not run")
+		return fmt.Errorf("NOT_RUNNING")
+	}
+	err := proc.CloseProcessStdin()
+	reply.Success = err == nil
+	return err
+}
+
 // SendRemoteCommEvent emit a remote communication event
 func (s *Supervisor) SendRemoteCommEvent(r *http.Request, args *RemoteCommEvent, reply *struct{ Success bool }) error {
 	events.EmitEvent(events.NewRemoteCommunicationEvent(args.Type, args.Data))
@@ -439,22 +914,39 @@ func (s *Supervisor) Reload(restart bool) (addedGroup []string, changedGroup []s
 	prevPrograms := s.config.GetProgramNames()
 	prevProgGroup := s.config.ProgramGroup.Clone()
 
+	s.setSupervisorState(SupervisorRestarting)
 	loadedPrograms, err := s.config.Load()
+	if err != nil {
+		// keep the programs of the previous configuration
+		log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to load the configuration")
+		s.setSupervisorState(SupervisorFatal)
+		return nil, nil, nil, err
+	}
 
 	if checkErr := s.checkRequiredResources(); checkErr != nil {
 		log.Error(checkErr)
 		os.Exit(1)
 
 	}
-	if err == nil {
-		s.setSupervisordInfo()
-		s.startEventListeners()
-		s.createPrograms(prevPrograms)
-		if restart {
-			s.startHTTPServer()
-		}
-		s.startAutoStartPrograms()
+	s.setSupervisordInfo()
+	s.setHostRefreshInterval()
+	s.setProcessConcurrency()
+	s.setChildLogDir()
+	s.setLogQuota()
+	s.setEventHistory()
+	s.setAPITokens()
+	s.setRPCAudit()
+	s.startEventListeners()
+	s.startEventWebhooks()
+	s.startEventSinks()
+	s.startNotifiers()
+	s.createPrograms(prevPrograms)
+	if restart {
+		s.startHTTPServer()
+	} else {
+		s.reloadHTTPServer()
 	}
+	s.startAutoStartPrograms()
 	removedPrograms := util.Sub(prevPrograms, loadedPrograms)
 	for _, removedProg := range removedPrograms {
 		log.WithFields(log.Fields{"program": removedProg}).Info("the program is removed and will be stopped")
@@ -466,15 +958,31 @@ func (s *Supervisor) Reload(restart bool) (addedGroup []string, changedGroup []s
 
 	}
 	addedGroup, changedGroup, removedGroup = s.config.ProgramGroup.Sub(prevProgGroup)
-	return addedGroup, changedGroup, removedGroup, err
+	for _, group := range removedGroup {
+		events.EmitEvent(events.CreateProcessGroupRemovedEvent(group))
+	}
+	for _, group := range addedGroup {
+		events.EmitEvent(events.CreateProcessGroupAddedEvent(group))
+	}
+	s.setSupervisorState(SupervisorRunning)
+	if restart {
+		events.EmitEvent(events.CreateSupervisorStateChangeRunning())
+	}
+	return addedGroup, changedGroup, removedGroup, nil
 
 }
 
+// emit SUPERVISOR_STATE_CHANGE_STOPPING event and stop all the processes before supervisord exits or restarts
+func (s *Supervisor) stopAllProcesses() {
+	events.EmitEvent(events.CreateSupervisorStateChangeStopping())
+	s.procMgr.StopAllProcesses()
+}
+
 // WaitForExit waits for supervisord to exit
 func (s *Supervisor) WaitForExit() {
 	for {
 		if s.IsRestarting() {
-			s.procMgr.StopAllProcesses()
+			s.stopAllProcesses()
 			break
 		}
 		time.Sleep(10 * time.Second)
@@ -509,49 +1017,267 @@ func (s *Supervisor) startEventListeners() {
 	}
 }
 
+// create the webhooks of [eventwebhook:x] sections again, the old webhooks are stopped
+func (s *Supervisor) startEventWebhooks() {
+	for name, webhook := range s.eventWebhooks {
+		events.UnregisterEventHandler("eventwebhook:" + name)
+		webhook.Stop()
+	}
+	s.eventWebhooks = make(map[string]*EventWebhook)
+	for _, entry := range s.config.GetEventWebhooks() {
+		name := entry.GetEventWebhookName()
+		if entry.GetString("url", "") == "" {
+			log.WithFields(log.Fields{"eventWebhook": name}).Error("the url of event webhook is not set")
+			continue
+		}
+		eventTypes := entry.GetStringArray("events", ",")
+		for i, eventType := range eventTypes {
+			eventTypes[i] = strings.TrimSpace(eventType)
+		}
+		webhook := NewEventWebhook(s.GetSupervisorID(), entry)
+		s.eventWebhooks[name] = webhook
+		events.RegisterFilteredEventHandler("eventwebhook:"+name, eventTypes, getEventFilter(entry), webhook)
+	}
+}
+
+// parse the filter expression of the section, the events are not filtered if it is invalid
+func getEventFilter(entry *config.Entry) *events.EventFilter {
+	filter, err := events.NewEventFilter(entry.GetString("filter", ""))
+	if err != nil {
+		log.WithFields(log.Fields{"section": entry.Name, log.ErrorKey: err}).Error("invalid event filter, the events are not filtered")
+	}
+	return filter
+}
+
+// create the event sinks of [eventsink:x] sections again, the old sinks are stopped
+func (s *Supervisor) startEventSinks() {
+	for name, sink := range s.eventSinks {
+		events.UnregisterEventHandler("eventsink:" + name)
+		sink.Stop()
+	}
+	s.eventSinks = make(map[string]*EventSink)
+	for _, entry := range s.config.GetEventSinks() {
+		name := entry.GetEventSinkName()
+		sink, err := NewEventSink(s.GetSupervisorID(), entry)
+		if err != nil {
+			log.WithFields(log.Fields{"eventSink": name, log.ErrorKey: err}).Error("fail to create the event sink")
+			continue
+		}
+		eventTypes := []string{"PROCESS_STATE"}
+		if entry.GetString("events", "") != "" {
+			eventTypes = trimStrings(entry.GetStringArray("events", ","))
+		}
+		s.eventSinks[name] = sink
+		events.RegisterFilteredEventHandler("eventsink:"+name, eventTypes, getEventFilter(entry), sink)
+	}
+}
+
+// create the notifiers of [notify:x] sections again, the old notifiers are stopped
+func (s *Supervisor) startNotifiers() {
+	for name, notifier := range s.notifiers {
+		events.UnregisterEventHandler("notify:" + name)
+		notifier.Stop()
+	}
+	s.notifiers = make(map[string]*Notifier)
+	for _, entry := range s.config.GetNotifies() {
+		name := entry.GetNotifyName()
+		notifier, err := NewNotifier(s.GetSupervisorID(), entry)
+		if err != nil {
+			log.WithFields(log.Fields{"notify": name, log.ErrorKey: err}).Error("fail to create the notification")
+			continue
+		}
+		s.notifiers[name] = notifier
+		events.RegisterFilteredEventHandler("notify:"+name, notifyEvents, getEventFilter(entry), notifier)
+	}
+}
+
 func (s *Supervisor) startHTTPServer() {
-	httpServerConfig, ok := s.config.GetInetHTTPServer()
+	s.httpServerLock.Lock()
+	defer s.httpServerLock.Unlock()
 	s.xmlRPC.Stop()
-	if ok {
-		addr := httpServerConfig.GetString("port", "")
-		if addr != "" {
-			cond := sync.NewCond(&sync.Mutex{})
-			cond.L.Lock()
-			defer cond.L.Unlock()
-			go s.xmlRPC.StartInetHTTPServer(httpServerConfig.GetString("username", ""),
-				httpServerConfig.GetString("password", ""),
-				addr,
-				s,
-				func() {
-					cond.L.Lock()
-					cond.Signal()
-					cond.L.Unlock()
-				})
-			cond.Wait()
-		}
-	}
-
-	httpServerConfig, ok = s.config.GetUnixHTTPServer()
-	if ok {
+	s.httpServerConfigs = s.getHTTPServerConfigs()
+	protocols := make([]string, 0)
+	for protocol := range s.httpServerConfigs {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	for _, protocol := range protocols {
+		serve, err := s.prepareHTTPServer(protocol)
+		if err != nil {
+			log.WithFields(log.Fields{log.ErrorKey: err, "protocol": protocol}).Fatal("fail to start the server")
+		}
+		serveHTTPServer(serve)
+	}
+}
+
+// gracefully restart the http servers and the gRPC server whose settings are changed by the reload, the others
+// keep serving. The new server listens before the running one is stopped, and the running one is kept if the
+// new one can't be started. It is done in background because the reload may be requested through the server
+// to restart
+func (s *Supervisor) reloadHTTPServer() {
+	go s.restartChangedHTTPServers(s.getHTTPServerConfigs())
+}
+
+// restart the servers whose settings are not the same as the configs by protocol
+func (s *Supervisor) restartChangedHTTPServers(configs map[string]string) {
+	s.httpServerLock.Lock()
+	defer s.httpServerLock.Unlock()
+	changed := make([]string, 0)
+	for protocol, settings := range configs {
+		if settings != s.httpServerConfigs[protocol] {
+			changed = append(changed, protocol)
+		}
+	}
+	for protocol := range s.httpServerConfigs {
+		if _, ok := configs[protocol]; !ok {
+			changed = append(changed, protocol)
+		}
+	}
+	sort.Strings(changed)
+	servers := make(map[string]func(startedCb func()))
+	for _, protocol := range changed {
+		serve, err := s.prepareHTTPServer(protocol)
+		if err != nil {
+			log.WithFields(log.Fields{log.ErrorKey: err, "protocol": protocol}).Error("fail to restart the server with the new settings, keep the running one")
+			// the server is restarted by the next reload
+			if settings, ok := s.httpServerConfigs[protocol]; ok {
+				configs[protocol] = settings
+			} else {
+				delete(configs, protocol)
+			}
+			continue
+		}
+		servers[protocol] = serve
+	}
+	s.httpServerConfigs = configs
+	for _, protocol := range changed {
+		serve, ok := servers[protocol]
+		if !ok {
+			continue
+		}
+		log.WithFields(log.Fields{"protocol": protocol}).Info("the settings of the server are changed, restart it")
+		s.xmlRPC.StopServer(protocol, httpServerStopTimeout)
+		serveHTTPServer(serve)
+	}
+}
+
+// get the settings of the http servers and the gRPC server by protocol, the gRPC server without port shares
+// the inet http server. The tokens are not included because they are updated without restart
+func (s *Supervisor) getHTTPServerConfigs() map[string]string {
+	settings := func(entry *config.Entry) string {
+		var b strings.Builder
+		for _, key := range entry.GetKeysWithPrefix("") {
+			if key != "tokens" {
+				fmt.Fprintf(&b, "%s=%s\n", key, entry.GetString(key, ""))
+			}
+		}
+		return b.String()
+	}
+	result := make(map[string]string)
+	grpcServerSettings := ""
+	if entry, ok := s.config.GetGRPCServer(); ok {
+		grpcServerSettings = settings(entry)
+		result["grpc"] = grpcServerSettings
+	}
+	for _, entry := range s.config.GetInetHTTPServers() {
+		result[inetHTTPServerProtocol(entry)] = settings(entry) + "[grpc_server]\n" + grpcServerSettings
+	}
+	if entry, ok := s.config.GetUnixHTTPServer(); ok {
+		result["unix"] = settings(entry)
+	}
+	return result
+}
+
+// prepare the http server or the gRPC server on the protocol like "tcp", "tcp:<name>", "unix" or "grpc" with
+// the current configuration. The settings are checked and the address is listened on, and the returned function
+// serves on it. The returned function is nil if there is no such server
+func (s *Supervisor) prepareHTTPServer(protocol string) (func(startedCb func()), error) {
+	switch protocol {
+	case "unix":
+		entry, ok := s.config.GetUnixHTTPServer()
+		if !ok {
+			return nil, nil
+		}
 		env := config.NewStringExpression("here", s.config.GetConfigFileDir())
-		sockFile, err := env.Eval(httpServerConfig.GetString("file", "/tmp/supervisord.sock"))
-		if err == nil {
-			cond := sync.NewCond(&sync.Mutex{})
-			cond.L.Lock()
-			defer cond.L.Unlock()
-			go s.xmlRPC.StartUnixHTTPServer(httpServerConfig.GetString("username", ""),
-				httpServerConfig.GetString("password", ""),
-				sockFile,
-				s,
-				func() {
-					cond.L.Lock()
-					cond.Signal()
-					cond.L.Unlock()
-				})
-			cond.Wait()
+		sockFile, err := env.Eval(entry.GetString("file", "/tmp/supervisord.sock"))
+		if err != nil {
+			return nil, err
+		}
+		perm, err := parseUnixSocketPermission(entry)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse the chmod and chown of unix_http_server: %v", err)
 		}
+		listener, err := s.xmlRPC.listen(protocol, "unix", sockFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to listen on %s: %v", sockFile, err)
+		}
+		if perm != nil {
+			if err := perm.apply(sockFile); err != nil {
+				listener.Close()
+				return nil, fmt.Errorf("fail to set the mode and owner of the unix socket %s: %v", sockFile, err)
+			}
+		}
+		return func(startedCb func()) {
+			s.xmlRPC.StartUnixHTTPServer(entry.GetString("username", ""), entry.GetString("password", ""), listener, s, startedCb)
+		}, nil
+	case "grpc":
+		// the gRPC server without port shares the port of inet_http_server
+		entry, ok := s.config.GetGRPCServer()
+		if !ok || entry.GetString("port", "") == "" {
+			return nil, nil
+		}
+		listener, tlsConfig, certRoles, err := s.listenInetServer(protocol, entry)
+		if err != nil {
+			return nil, err
+		}
+		return func(startedCb func()) {
+			s.xmlRPC.StartGRPCServer(entry.GetString("username", ""), entry.GetString("password", ""), listener, tlsConfig, certRoles, s, startedCb)
+		}, nil
+	}
+	for _, entry := range s.config.GetInetHTTPServers() {
+		if inetHTTPServerProtocol(entry) != protocol || entry.GetString("port", "") == "" {
+			continue
+		}
+		if _, err := getHTTPEndpoints(entry); err != nil {
+			return nil, fmt.Errorf("fail to get the endpoints of %s: %v", entry.Name, err)
+		}
+		listener, tlsConfig, certRoles, err := s.listenInetServer(protocol, entry)
+		if err != nil {
+			return nil, err
+		}
+		return func(startedCb func()) {
+			s.xmlRPC.StartInetHTTPServer(entry, entry.GetString("username", ""), entry.GetString("password", ""), listener, tlsConfig, certRoles, s, startedCb)
+		}, nil
 	}
+	return nil, nil
+}
+
+// load the certificate and the client roles of the inet_http_server or grpc_server entry and listen on its port
+func (s *Supervisor) listenInetServer(protocol string, entry *config.Entry) (net.Listener, *tls.Config, *clientCertRoles, error) {
+	tlsConfig, err := loadServerTLSConfig(entry)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fail to load the certificate of %s: %v", entry.Name, err)
+	}
+	certRoles, err := loadClientCertRoles(entry)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fail to load the client roles of %s: %v", entry.Name, err)
+	}
+	addr := entry.GetString("port", "")
+	listener, err := s.xmlRPC.listen(protocol, "tcp", addr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fail to listen on %s: %v", addr, err)
+	}
+	return listener, tlsConfig, certRoles, nil
+}
 
+// serve the prepared server and wait until it is started
+func serveHTTPServer(serve func(startedCb func())) {
+	if serve == nil {
+		return
+	}
+	started := make(chan struct{})
+	go serve(func() { close(started) })
+	<-started
 }
 
 func (s *Supervisor) setSupervisordInfo() {
@@ -591,6 +1317,58 @@ func (s *Supervisor) setSupervisordInfo() {
 	}
 }
 
+// limit the programs started or stopped at the same time by start_concurrency
+func (s *Supervisor) setProcessConcurrency() {
+	concurrency := 0
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		concurrency = supervisordConf.GetInt("start_concurrency", 0)
+	}
+	s.procMgr.SetConcurrency(concurrency)
+}
+
+// set the directory of the stdout_logfile=AUTO and stderr_logfile=AUTO log files
+func (s *Supervisor) setChildLogDir() {
+	dir := ""
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		dir = supervisordConf.GetStringExpression("childlogdir", "")
+	}
+	if expandDir, err := process.PathExpand(dir); err == nil {
+		dir = expandDir
+	}
+	s.procMgr.SetChildLogDir(dir)
+}
+
+// limit the total bytes of the log files of all the programs by logfile_quota
+func (s *Supervisor) setLogQuota() {
+	quota := 0
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		quota = supervisordConf.GetBytes("logfile_quota", 0)
+	}
+	s.procMgr.SetLogQuota(int64(quota))
+}
+
+// keep the last event_history_size events and append them to event_journal if it is set
+func (s *Supervisor) setEventHistory() {
+	size, journal, journalMaxBytes := 1000, "", 10*1024*1024
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		size = supervisordConf.GetInt("event_history_size", size)
+		journal = supervisordConf.GetString("event_journal", "")
+		journalMaxBytes = supervisordConf.GetBytes("event_journal_maxbytes", journalMaxBytes)
+	}
+	if err := events.SetEventHistory(size, journal, int64(journalMaxBytes)); err != nil {
+		log.WithFields(log.Fields{"file": journal, log.ErrorKey: err}).Error("fail to open the event journal")
+	}
+}
+
+// refresh the host variables like %(host_ip)s periodically if host_refresh_interval is set
+func (s *Supervisor) setHostRefreshInterval() {
+	interval := time.Duration(0)
+	if supervisordConf, ok := s.config.GetSupervisord(); ok {
+		interval = supervisordConf.GetDuration("host_refresh_interval", 0)
+	}
+	config.SetHostRefreshInterval(interval)
+}
+
 func toLogLevel(level string) log.Level {
 	switch strings.ToLower(level) {
 	case "critical":
@@ -627,18 +1405,211 @@ func (s *Supervisor) ReloadConfig(r *http.Request, args *struct{}, reply *types.
 	return err
 }
 
-// AddProcessGroup adds a process group to the supervisor
+// GetConfigDiff rereads the configuration file and gets the programs which would be added, changed and
+// removed by ReloadConfig with their changed keys, nothing is changed
+func (s *Supervisor) GetConfigDiff(r *http.Request, args *struct{}, reply *struct{ Changes []types.ProgramConfigChange }) error {
+	reread, err := s.config.Reread()
+	if err != nil {
+		return fmt.Errorf("CANT_REREAD %v", err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	reply.Changes = diffProgramConfigs(s.config.GetPrograms(), reread.GetPrograms())
+	return nil
+}
+
+// AddProcessGroup adds the programs of the group in the configuration which are not added yet, like the
+// group removed by RemoveProcessGroup, and starts the autostart programs of them
 func (s *Supervisor) AddProcessGroup(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
-	reply.Success = false
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	found := false
+	added := 0
+	for _, entry := range s.config.GetPrograms() {
+		if entry.Group != args.Name {
+			continue
+		}
+		found = true
+		if s.procMgr.Find(entry.GetProgramName()) == nil {
+			s.procMgr.CreateProcess(s.GetSupervisorID(), entry)
+			added++
+		}
+	}
+	if !found {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: %s", args.Name))
+	}
+	if added == 0 {
+		return faults.NewFault(faults.AlreadyAdded, fmt.Sprintf("ALREADY_ADDED: %s", args.Name))
+	}
+	log.WithFields(log.Fields{"group": args.Name}).Info("add process group")
+	events.EmitEvent(events.CreateProcessGroupAddedEvent(args.Name))
+	s.procMgr.StartAutoStartGroup(args.Name)
+	reply.Success = true
 	return nil
 }
 
-// RemoveProcessGroup removes a process group from the supervisor
+// RemoveProcessGroup stops the programs of the group and removes them from the supervisor, the group
+// can be added again by AddProcessGroup
 func (s *Supervisor) RemoveProcessGroup(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
-	reply.Success = false
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	procs := make([]*process.Process, 0)
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if proc.GetGroup() == args.Name {
+			procs = append(procs, proc)
+		}
+	})
+	if len(procs) == 0 {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: %s", args.Name))
+	}
+	log.WithFields(log.Fields{"group": args.Name}).Info("remove process group")
+	var wg sync.WaitGroup
+	wg.Add(len(procs))
+	for _, proc := range procs {
+		go func(proc *process.Process) {
+			defer wg.Done()
+			proc.Stop(true)
+		}(proc)
+	}
+	wg.Wait()
+	for _, proc := range procs {
+		s.procMgr.Remove(proc.GetName())
+	}
+	events.EmitEvent(events.CreateProcessGroupRemovedEvent(args.Name))
+	reply.Success = true
+	return nil
+}
+
+// AddProgram adds the [program:name] section given as its "key=value" lines or a JSON object at runtime,
+// and starts the autostart programs of it
+func (s *Supervisor) AddProgram(r *http.Request, args *struct {
+	Name   string
+	Config string
+}, reply *struct{ Success bool }) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.config.GetProgramSection(args.Name)) > 0 {
+		return faults.NewFault(faults.AlreadyAdded, fmt.Sprintf("ALREADY_ADDED: %s", args.Name))
+	}
+	programs, err := s.config.AddProgramSection(args.Name, args.Config)
+	if err != nil {
+		return faults.NewFault(faults.IncorrectParameters, fmt.Sprintf("INCORRECT_PARAMETERS: %v", err))
+	}
+	log.WithFields(log.Fields{"program": args.Name, "processes": strings.Join(programs, ",")}).Info("add program")
+	groups := make(map[string]bool)
+	entries := s.config.GetProgramSection(args.Name)
+	for _, entry := range entries {
+		groups[entry.Group] = true
+	}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		delete(groups, proc.GetGroup())
+	})
+	for _, entry := range entries {
+		s.procMgr.CreateProcess(s.GetSupervisorID(), entry)
+	}
+	for group := range groups {
+		events.EmitEvent(events.CreateProcessGroupAddedEvent(group))
+	}
+	s.procMgr.StartAutoStartProcesses(programs)
+	reply.Success = true
+	return nil
+}
+
+// RemoveProgram stops the programs of the [program:name] section and removes them with the section,
+// the programs added by AddProgram are removed from program_conf_dir too
+func (s *Supervisor) RemoveProgram(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries := s.config.GetProgramSection(args.Name)
+	if len(entries) == 0 {
+		return faults.NewFault(faults.BadName, fmt.Sprintf("BAD_NAME: %s", args.Name))
+	}
+	procs := make([]*process.Process, 0)
+	for _, entry := range entries {
+		if proc := s.procMgr.Find(entry.GetProgramName()); proc != nil {
+			procs = append(procs, proc)
+		}
+	}
+	log.WithFields(log.Fields{"program": args.Name}).Info("remove program")
+	var wg sync.WaitGroup
+	wg.Add(len(procs))
+	for _, proc := range procs {
+		go func(proc *process.Process) {
+			defer wg.Done()
+			proc.Stop(true)
+		}(proc)
+	}
+	wg.Wait()
+	if _, err := s.config.RemoveProgramSection(args.Name); err != nil {
+		return faults.NewFault(faults.Failed, fmt.Sprintf("FAILED: %v", err))
+	}
+	groups := make(map[string]bool)
+	for _, proc := range procs {
+		s.procMgr.Remove(proc.GetName())
+		groups[proc.GetGroup()] = true
+	}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		delete(groups, proc.GetGroup())
+	})
+	for group := range groups {
+		events.EmitEvent(events.CreateProcessGroupRemovedEvent(group))
+	}
+	reply.Success = true
 	return nil
 }
 
+// UpdateProgramSection writes the text of the [program:name] section to its configuration file and reloads
+// only this section. If the programs of the section are changed, they are stopped and created again from the
+// new section and the autostart ones are started, the other programs are not touched. The file is not written
+// if the configuration with the new section can't be loaded.
+//
+// Return the changes of the programs of the section
+func (s *Supervisor) UpdateProgramSection(name string, text string) ([]types.ProgramConfigChange, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	oldEntries := s.config.GetProgramSection(name)
+	if len(oldEntries) == 0 {
+		return nil, fmt.Errorf("no program %s", name)
+	}
+	programs, err := s.config.UpdateProgramSection(name, text)
+	if err != nil {
+		return nil, err
+	}
+	changes := diffProgramConfigs(oldEntries, s.config.GetProgramSection(name))
+	if len(changes) == 0 {
+		return changes, nil
+	}
+	log.WithFields(log.Fields{"program": name, "processes": strings.Join(programs, ",")}).Info("reload program")
+	procs := make([]*process.Process, 0)
+	for _, entry := range oldEntries {
+		if proc := s.procMgr.Find(entry.GetProgramName()); proc != nil {
+			procs = append(procs, proc)
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(procs))
+	for _, proc := range procs {
+		go func(proc *process.Process) {
+			defer wg.Done()
+			proc.Stop(true)
+		}(proc)
+	}
+	wg.Wait()
+	for _, proc := range procs {
+		s.procMgr.Remove(proc.GetName())
+	}
+	for _, entry := range s.config.GetProgramSection(name) {
+		s.procMgr.CreateProcess(s.GetSupervisorID(), entry)
+	}
+	s.procMgr.StartAutoStartProcesses(programs)
+	return changes, nil
+}
+
 // ReadProcessStdoutLog reads stdout of given program
 func (s *Supervisor) ReadProcessStdoutLog(r *http.Request, args *ProcessLogReadInfo, reply *struct{ LogData string }) error {
 	proc := s.procMgr.Find(args.Name)
@@ -668,7 +1639,9 @@ func (s *Supervisor) TailProcessStdoutLog(r *http.Request, args *ProcessLogReadI
 		return fmt.Errorf("No such process %s", args.Name)
 	}
 	var err error
-	reply.LogData, reply.Offset, reply.Overflow, err = proc.StdoutLog.ReadTailLog(int64(args.Offset), int64(args.Length))
+	var offset int64
+	reply.LogData, offset, reply.Overflow, err = proc.StdoutLog.ReadTailLog(int64(args.Offset), int64(args.Length))
+	reply.Offset = int(offset)
 	return err
 }
 
@@ -679,19 +1652,98 @@ func (s *Supervisor) TailProcessStderrLog(r *http.Request, args *ProcessLogReadI
 		return fmt.Errorf("No such process %s", args.Name)
 	}
 	var err error
-	reply.LogData, reply.Offset, reply.Overflow, err = proc.StderrLog.ReadTailLog(int64(args.Offset), int64(args.Length))
+	var offset int64
+	reply.LogData, offset, reply.Overflow, err = proc.StderrLog.ReadTailLog(int64(args.Offset), int64(args.Length))
+	reply.Offset = int(offset)
 	return err
 }
 
+// GetEventHistory gets the events kept in the event history matching the filter
+func (s *Supervisor) GetEventHistory(r *http.Request, args *EventHistoryArgs, reply *struct{ Events []types.EventInfo }) error {
+	var since time.Time
+	if args.Since > 0 {
+		since = time.Unix(int64(args.Since), 0)
+	}
+	reply.Events = make([]types.EventInfo, 0)
+	for _, record := range events.GetEventHistory(args.Filter, since) {
+		reply.Events = append(reply.Events, types.EventInfo{Serial: int(record.Serial),
+			EventName: record.EventName,
+			Time:      int(record.Time.Unix()),
+			Body:      record.Body})
+	}
+	return nil
+}
+
+// SearchProcessLog searches the current and rotated logs of the program for lines matching a pattern
+func (s *Supervisor) SearchProcessLog(r *http.Request, args *ProcessLogSearchArgs, reply *struct{ Result types.LogSearchResult }) error {
+	proc := s.procMgr.Find(args.Name)
+	if proc == nil {
+		return fmt.Errorf("No such process %s", args.Name)
+	}
+	pattern, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return faults.NewFault(faults.BadArguments, fmt.Sprintf("BAD_ARGUMENTS invalid pattern: %v", err))
+	}
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 100
+	} else if maxResults > maxLogSearchResults {
+		maxResults = maxLogSearchResults
+	}
+	var since, until time.Time
+	if args.Since > 0 {
+		since = time.Unix(int64(args.Since), 0)
+	}
+	if args.Until > 0 {
+		until = time.Unix(int64(args.Until), 0)
+	}
+
+	lists := make([][]logger.LogMatch, 0)
+	for _, logFile := range proc.GetLogFiles() {
+		matches, truncated, err := logger.SearchLogFile(logFile, proc.GetLogFileBackups(logFile), pattern, since, until, maxResults)
+		if err != nil {
+			return err
+		}
+		reply.Result.Truncated = reply.Result.Truncated || truncated
+		lists = append(lists, matches)
+	}
+	// the most recent matches of the stdout and stderr logs together
+	matches := logger.MergeLogMatches(lists...)
+	if len(matches) > maxResults {
+		matches = matches[len(matches)-maxResults:]
+		reply.Result.Truncated = true
+	}
+	reply.Result.Matches = make([]types.LogSearchMatch, 0)
+	for _, match := range matches {
+		lineTime := 0
+		if !match.Time.IsZero() {
+			lineTime = int(match.Time.Unix())
+		}
+		reply.Result.Matches = append(reply.Result.Matches, types.LogSearchMatch{File: match.File, Line: match.Line, Offset: int(match.Offset), Time: lineTime})
+	}
+	return nil
+}
+
 // ClearProcessLogs clears log of given program
 func (s *Supervisor) ClearProcessLogs(r *http.Request, args *struct{ Name string }, reply *struct{ Success bool }) error {
 	proc := s.procMgr.Find(args.Name)
 	if proc == nil {
 		return fmt.Errorf("No such process %s", args.Name)
 	}
-	err1 := proc.StdoutLog.ClearAllLogFile()
-	err2 := proc.StderrLog.ClearAllLogFile()
-	reply.Success = err1 == nil && err2 == nil
+	err := clearProcessLogFiles(proc)
+	reply.Success = err == nil
+	return err
+}
+
+// clear the stdout and stderr logs of the program, the program never started has no logs
+func clearProcessLogFiles(proc *process.Process) error {
+	var err1, err2 error
+	if proc.StdoutLog != nil {
+		err1 = proc.StdoutLog.ClearAllLogFile()
+	}
+	if proc.StderrLog != nil {
+		err2 = proc.StderrLog.ClearAllLogFile()
+	}
 	if err1 != nil {
 		return err1
 	}
@@ -702,8 +1754,7 @@ func (s *Supervisor) ClearProcessLogs(r *http.Request, args *struct{ Name string
 func (s *Supervisor) ClearAllProcessLogs(r *http.Request, args *struct{}, reply *struct{ RPCTaskResults []RPCTaskResult }) error {
 
 	s.procMgr.ForEachProcess(func(proc *process.Process) {
-		proc.StdoutLog.ClearAllLogFile()
-		proc.StderrLog.ClearAllLogFile()
+		clearProcessLogFiles(proc)
 		procInfo := getProcessInfo(proc)
 		reply.RPCTaskResults = append(reply.RPCTaskResults, RPCTaskResult{
 			Name:        procInfo.Name,
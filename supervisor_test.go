@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaskEnv(t *testing.T) {
+	env := []string{"DB_PASSWORD=x", "api_token=y", "HOME=/root", "EMPTY="}
+	masked := maskEnv(env, strings.Split(defaultEnvironmentMask, ","))
+	expected := []string{"DB_PASSWORD=******", "api_token=******", "HOME=/root", "EMPTY="}
+	if !reflect.DeepEqual(masked, expected) {
+		t.Errorf("the secrets should be masked, but got %v", masked)
+	}
+}
+
+func TestExportImportState(t *testing.T) {
+	s := NewSupervisor("")
+	exported := struct{ State string }{}
+	if err := s.ExportState(nil, &struct{}{}, &exported); err != nil {
+		t.Fatal(err)
+	}
+	imported := struct{ Success bool }{}
+	if err := s.ImportState(nil, &struct{ State string }{exported.State}, &imported); err != nil || !imported.Success {
+		t.Errorf("the exported state %s should be imported: %v", exported.State, err)
+	}
+	if err := s.ImportState(nil, &struct{ State string }{`{"version": 0}`}, &imported); err == nil {
+		t.Error("the unsupported snapshot version should be rejected")
+	}
+}
+
+func TestReloadHTTPServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	confFile := filepath.Join(t.TempDir(), "supervisord.conf")
+	writeConf := func(extra string) {
+		ioutil.WriteFile(confFile, []byte(fmt.Sprintf("[inet_http_server]\nport=%s\nusername=admin\n%s", addr, extra)), 0644)
+	}
+	status := func(password string) int {
+		r, _ := http.NewRequest("GET", "http://"+addr+"/api/v1/state", nil)
+		r.SetBasicAuth("admin", password)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	writeConf("password=old\n")
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.startHTTPServer()
+	defer s.xmlRPC.Stop()
+	if status("old") != http.StatusOK {
+		t.Fatal("the server should be started")
+	}
+
+	// the socket of the running server is shared by the new one on the same port
+	writeConf("password=new\n")
+	s.config.Load()
+	s.restartChangedHTTPServers(s.getHTTPServerConfigs())
+	if status("new") != http.StatusOK || status("old") != http.StatusUnauthorized {
+		t.Error("the server should be restarted with the new password on the same port")
+	}
+
+	// the running server is kept if the new one can't be started
+	writeConf("password=broken\ncertfile=/nonexistent.crt\nkeyfile=/nonexistent.key\n")
+	s.config.Load()
+	s.restartChangedHTTPServers(s.getHTTPServerConfigs())
+	if status("new") != http.StatusOK {
+		t.Error("the running server should be kept if the new one fails to start")
+	}
+	if !strings.Contains(s.httpServerConfigs["tcp"], "password=new\n") {
+		t.Error("the failed server should be restarted by the next reload")
+	}
+}
+
+func TestWebguiLogRoutesRequireAuth(t *testing.T) {
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "supervisord.conf")
+	ioutil.WriteFile(filepath.Join(dir, "web.log"), []byte("secret output\n"), 0644)
+	ioutil.WriteFile(confFile, []byte(fmt.Sprintf("[program:web]\ncommand=/bin/true\nstdout_logfile=%s\n", filepath.Join(dir, "web.log"))), 0644)
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewXMLRPC().handleWebgui(mux, "admin", "secret", s.tokens.forServer("tcp"), nil, s)
+	for _, path := range []string{"/log?name=web", "/confFile", "/log/web/web.log"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s should require the authentication, but get %d", path, w.Code)
+		}
+		r := httptest.NewRequest("GET", path, nil)
+		r.SetBasicAuth("admin", "secret")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s should be served to the authenticated client, but get %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// +build !windows
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the first file descriptor passed by the systemd socket activation
+const listenFdsStart = 3
+
+var (
+	activatedSocketsOnce sync.Once
+	// the sockets passed by the systemd socket activation, they are kept open so the listeners can be
+	// created again when the http servers are restarted
+	activatedSockets []*os.File
+)
+
+// get the sockets passed by systemd with the LISTEN_PID and LISTEN_FDS environment variables, the variables
+// are unset and the sockets are closed on exec so the programs don't inherit them
+func getActivatedSockets() []*os.File {
+	activatedSocketsOnce.Do(func() {
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			return
+		}
+		n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || n <= 0 {
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+		for i := 0; i < n; i++ {
+			fd := listenFdsStart + i
+			name := "LISTEN_FD_" + strconv.Itoa(fd)
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			syscall.CloseOnExec(fd)
+			activatedSockets = append(activatedSockets, os.NewFile(uintptr(fd), name))
+		}
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+		log.WithFields(log.Fields{"count": n}).Info("get the sockets passed by systemd")
+	})
+	return activatedSockets
+}
+
+// listen on the address, the socket passed by systemd on the same address is used if there is one
+func listenSocket(network string, address string) (net.Listener, error) {
+	for _, f := range getActivatedSockets() {
+		listener, err := net.FileListener(f)
+		if err != nil {
+			continue
+		}
+		if isSameAddress(listener.Addr(), network, address) {
+			log.WithFields(log.Fields{"addr": address, "socket": f.Name()}).Info("use the socket passed by systemd")
+			return listener, nil
+		}
+		listener.Close()
+	}
+	return net.Listen(network, address)
+}
+
+// check if systemd passes a socket on the address
+func isActivatedSocket(network string, address string) bool {
+	for _, f := range getActivatedSockets() {
+		listener, err := net.FileListener(f)
+		if err != nil {
+			continue
+		}
+		found := isSameAddress(listener.Addr(), network, address)
+		listener.Close()
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// check if the address of the socket is the address to listen on, the socket on the unspecified IP like
+// 0.0.0.0 matches any host with the same port, the address without host matches any socket on the port
+func isSameAddress(addr net.Addr, network string, address string) bool {
+	if addr.Network() != network {
+		return false
+	}
+	if network == "unix" {
+		return filepath.Clean(addr.String()) == filepath.Clean(address)
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	socketHost, socketPort, err := net.SplitHostPort(addr.String())
+	if err != nil || port != socketPort {
+		return false
+	}
+	if host == "" || host == socketHost {
+		return true
+	}
+	socketIP := net.ParseIP(socketHost)
+	ip := net.ParseIP(host)
+	return socketIP != nil && (socketIP.IsUnspecified() || socketIP.Equal(ip))
+}
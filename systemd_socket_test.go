@@ -0,0 +1,30 @@
+// +build !windows
+
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsSameAddress(t *testing.T) {
+	for _, c := range []struct {
+		socket   string
+		address  string
+		expected bool
+	}{
+		{"0.0.0.0:9001", ":9001", true},
+		{"0.0.0.0:9001", "127.0.0.1:9001", true},
+		{"127.0.0.1:9001", "127.0.0.1:9001", true},
+		{"127.0.0.1:9001", "10.0.0.1:9001", false},
+		{"0.0.0.0:9002", ":9001", false},
+	} {
+		addr, _ := net.ResolveTCPAddr("tcp", c.socket)
+		if isSameAddress(addr, "tcp", c.address) != c.expected {
+			t.Errorf("the socket %s should be used for %s: %v", c.socket, c.address, c.expected)
+		}
+	}
+	if !isSameAddress(&net.UnixAddr{Name: "/run/supervisord.sock", Net: "unix"}, "unix", "/run//supervisord.sock") {
+		t.Error("the unix socket on the same path should be used")
+	}
+}
@@ -0,0 +1,16 @@
+// +build windows
+
+package main
+
+import (
+	"net"
+)
+
+// the systemd socket activation is not available on windows
+func listenSocket(network string, address string) (net.Listener, error) {
+	return net.Listen(network, address)
+}
+
+func isActivatedSocket(network string, address string) bool {
+	return false
+}
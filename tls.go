@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ochinchina/supervisord/config"
+)
+
+const (
+	// roleAdmin can call all the methods
+	roleAdmin = "admin"
+	// roleReadOnly can only get the information and read the logs
+	roleReadOnly = "readonly"
+)
+
+// the key of the role of the authenticated client in the request context
+type roleContextKey struct{}
+
+// load the TLS configuration from the certfile and keyfile of the http server section, nil if
+// neither of them is set. The "h2" protocol is negotiated for the gRPC calls sharing the port.
+// The client certificates signed by the ca_certfile are required if it is set
+func loadServerTLSConfig(entry *config.Entry) (*tls.Config, error) {
+	certFile := entry.GetString("certfile", "")
+	keyFile := entry.GetString("keyfile", "")
+	caFile := entry.GetString("ca_certfile", "")
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both certfile and keyfile are required for TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert},
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"}}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", caFile)
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// load the TLS configuration of the client connecting to a server, like the event sink connecting to the broker.
+// The server is verified by the ca_certfile or the system CA certificates, unless insecure is true, and the
+// certfile and keyfile are presented to the server requiring the client certificate
+func loadClientTLSConfig(entry *config.Entry) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: entry.GetBool("insecure", false)}
+	if caFile := entry.GetString("ca_certfile", ""); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", caFile)
+		}
+	}
+	certFile := entry.GetString("certfile", "")
+	keyFile := entry.GetString("keyfile", "")
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both certfile and keyfile are required for the client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// clientCertRoles the roles of the clients authenticated by the certificates signed by ca_certfile
+type clientCertRoles struct {
+	// the role by the common name or the subject alternative name, "*" for the other clients
+	roles map[string]string
+}
+
+// load the client_roles of the http server section like "name:role,...", nil if ca_certfile is not set.
+// All the clients are admin if client_roles is not set
+func loadClientCertRoles(entry *config.Entry) (*clientCertRoles, error) {
+	if entry.GetString("ca_certfile", "") == "" {
+		return nil, nil
+	}
+	certRoles := &clientCertRoles{roles: make(map[string]string)}
+	for _, item := range strings.FieldsFunc(entry.GetString("client_roles", ""), func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		pos := strings.LastIndex(item, ":")
+		if pos <= 0 {
+			return nil, fmt.Errorf("invalid client role %s, it should be name:role", item)
+		}
+		role := item[pos+1:]
+		if role != roleAdmin && role != roleReadOnly {
+			return nil, fmt.Errorf("invalid role %s of client %s, it should be admin or readonly", role, item[:pos])
+		}
+		certRoles.roles[item[:pos]] = role
+	}
+	return certRoles, nil
+}
+
+// get the role of the client by the verified certificate of the request, false if the client has no
+// verified certificate or its names are not in the client_roles
+func (c *clientCertRoles) getRole(r *http.Request) (string, bool) {
+	if c == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	if len(c.roles) == 0 {
+		return roleAdmin, true
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if role, ok := c.roles[name]; ok {
+			return role, true
+		}
+	}
+	role, ok := c.roles["*"]
+	return role, ok
+}
+
+// get the role of the client authenticated by the request, admin if it is not set
+func getRequestRole(ctx context.Context) string {
+	if role, ok := ctx.Value(roleContextKey{}).(string); ok {
+		return role
+	}
+	return roleAdmin
+}
+
+// check if the XML-RPC method only gets the information or reads the logs
+func isReadOnlyMethod(method string) bool {
+	if strings.HasPrefix(method, "system.") {
+		return true
+	}
+	if !strings.HasPrefix(method, "supervisor.") {
+		return false
+	}
+	name := strings.TrimPrefix(method, "supervisor.")
+	for _, prefix := range []string{"get", "read", "tail", "list", "search", "waitFor"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"testing"
+)
+
+func TestClientCertRoles(t *testing.T) {
+	certRoles := &clientCertRoles{roles: map[string]string{"ops": roleAdmin, "monitor.example.com": roleReadOnly}}
+	request := func(cn string, dnsNames ...string) *http.Request {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}, DNSNames: dnsNames}
+		return &http.Request{TLS: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	}
+
+	if role, ok := certRoles.getRole(request("ops")); !ok || role != roleAdmin {
+		t.Errorf("the role of ops should be admin, but it is %s", role)
+	}
+	if role, ok := certRoles.getRole(request("monitor", "monitor.example.com")); !ok || role != roleReadOnly {
+		t.Errorf("the role of monitor.example.com should be readonly, but it is %s", role)
+	}
+	if _, ok := certRoles.getRole(request("stranger")); ok {
+		t.Error("the client not in client_roles should not get a role")
+	}
+	if _, ok := certRoles.getRole(&http.Request{}); ok {
+		t.Error("the client without certificate should not get a role")
+	}
+	certRoles.roles["*"] = roleReadOnly
+	if role, ok := certRoles.getRole(request("stranger")); !ok || role != roleReadOnly {
+		t.Errorf("the role of the other clients should be readonly, but it is %s", role)
+	}
+}
+
+func TestReadOnlyMethod(t *testing.T) {
+	for method, expected := range map[string]bool{
+		"supervisor.getAllProcessInfo":       true,
+		"supervisor.tailProcessStdoutLog":    true,
+		"system.multicall":                   true,
+		"supervisor.stopProcess":             false,
+		"supervisor.createToken":             false,
+		"chaos.clear":                        false,
+		"/supervisord.v1.Supervisor/TailLog": false,
+	} {
+		if isReadOnlyMethod(method) != expected {
+			t.Errorf("%s should be readonly: %v", method, expected)
+		}
+	}
+	if !isReadOnlyGRPCMethod("/supervisord.v1.Supervisor/TailLog") || isReadOnlyGRPCMethod("/supervisord.v1.Supervisor/StopProcess") {
+		t.Error("fail to check the readonly gRPC methods")
+	}
+}
@@ -20,6 +20,138 @@ type ProcessInfo struct {
 	StdoutLogfile string `xml:"stdout_logfile" json:"stdout_logfile"`
 	StderrLogfile string `xml:"stderr_logfile" json:"stderr_logfile"`
 	Pid           int    `xml:"pid" json:"pid"`
+	Health        string `xml:"health" json:"health"`
+	EnvOverride   string `xml:"env_override" json:"env_override"`
+	Uptime        int    `xml:"uptime" json:"uptime"`
+	Labels        string `xml:"labels" json:"labels"`
+}
+
+// ConfigInfo a program in the configuration, Inuse is true if the program is added to supervisord
+type ConfigInfo struct {
+	Name      string `xml:"name" json:"name"`
+	Group     string `xml:"group" json:"group"`
+	Inuse     bool   `xml:"inuse" json:"inuse"`
+	Autostart bool   `xml:"autostart" json:"autostart"`
+	Priority  int    `xml:"priority" json:"priority"`
+}
+
+// ProcessStateChange one state transition of process
+type ProcessStateChange struct {
+	Time int    `xml:"time" json:"time"`
+	From string `xml:"from" json:"from"`
+	To   string `xml:"to" json:"to"`
+}
+
+// ProcessResourceSample the resource usage of process sampled at Time
+type ProcessResourceSample struct {
+	Time     int     `xml:"time" json:"time"`
+	RSS      int     `xml:"rss" json:"rss"`
+	CPU      float64 `xml:"cpu" json:"cpu"`
+	FDs      int     `xml:"fds" json:"fds"`
+	Threads  int     `xml:"threads" json:"threads"`
+	Children int     `xml:"children" json:"children"`
+}
+
+// ProcessResourceHistory the recent resource usage samples of process, oldest first, with the thresholds over
+// which its usage is flagged, 0 if not configured. The exceeded flags are set if the latest sample of the running
+// process is over the thresholds
+type ProcessResourceHistory struct {
+	Name         string                  `xml:"name" json:"name"`
+	Group        string                  `xml:"group" json:"group"`
+	CPUThreshold int                     `xml:"cpu_threshold" json:"cpu_threshold"`
+	RSSThreshold int                     `xml:"rss_threshold" json:"rss_threshold"`
+	CPUExceeded  bool                    `xml:"cpu_exceeded" json:"cpu_exceeded"`
+	RSSExceeded  bool                    `xml:"rss_exceeded" json:"rss_exceeded"`
+	Samples      []ProcessResourceSample `xml:"samples" json:"samples"`
+}
+
+// ProcessResourceUsage the latest resource usage of process sampled at Time, the Time is 0 if the
+// process is not running or not sampled yet. The fields are named like the XML-RPC members with
+// the first letter in upper case to be decoded by the client
+type ProcessResourceUsage struct {
+	Name     string  `xml:"name" json:"name"`
+	Group    string  `xml:"group" json:"group"`
+	Pid      int     `xml:"pid" json:"pid"`
+	Time     int     `xml:"time" json:"time"`
+	Rss      int     `xml:"rss" json:"rss"`
+	Cpu      float64 `xml:"cpu" json:"cpu"`
+	Fds      int     `xml:"fds" json:"fds"`
+	Threads  int     `xml:"threads" json:"threads"`
+	Children int     `xml:"children" json:"children"`
+}
+
+// ProcessEnvironment the environment variables in KEY=VALUE format the process is started with, the values
+// of the secrets are masked. The Overrides are the comma separated names of the variables overridden only for
+// the current run
+type ProcessEnvironment struct {
+	Name      string   `xml:"name" json:"name"`
+	Group     string   `xml:"group" json:"group"`
+	Pid       int      `xml:"pid" json:"pid"`
+	Env       []string `xml:"env" json:"env"`
+	Overrides string   `xml:"overrides" json:"overrides"`
+}
+
+// ProcessDetail the process information with its state history and resource usage
+type ProcessDetail struct {
+	Info         ProcessInfo             `xml:"info" json:"info"`
+	StateHistory []ProcessStateChange    `xml:"state_history" json:"state_history"`
+	Resources    []ProcessResourceSample `xml:"resources" json:"resources"`
+}
+
+// ProcessLogUsage the disk usage of the log files with their rotated backups of process, the Bytes
+// and Quota are decimal strings because they may exceed the 32 bits integer of XML-RPC
+type ProcessLogUsage struct {
+	Name  string   `xml:"name" json:"name"`
+	Group string   `xml:"group" json:"group"`
+	Bytes string   `xml:"bytes" json:"bytes"`
+	Quota string   `xml:"quota" json:"quota"`
+	Files []string `xml:"files" json:"files"`
+}
+
+// APIToken a bearer token without the token itself, the Expires is the unix seconds it expires at, or
+// 0 if it never expires, the Source is "config" or "rpc"
+type APIToken struct {
+	Name    string `xml:"name" json:"name"`
+	Expires int    `xml:"expires" json:"expires"`
+	Source  string `xml:"source" json:"source"`
+	// the protocol of the server accepting the token like "tcp" or "tcp:metrics", empty for all the servers
+	Server string `xml:"server" json:"server"`
+	// the role of the client authenticated by the token, admin or readonly
+	Role string `xml:"role" json:"role"`
+}
+
+// ConfigKeyChange a key of the program changed in the configuration file, Old is empty if the key is
+// added and New is empty if it is removed
+type ConfigKeyChange struct {
+	Key string `xml:"key" json:"key"`
+	Old string `xml:"old" json:"old"`
+	New string `xml:"new" json:"new"`
+}
+
+// ProgramConfigChange a program added, changed or removed in the configuration file since it is loaded,
+// the Change is "added", "changed" or "removed"
+type ProgramConfigChange struct {
+	Name   string            `xml:"name" json:"name"`
+	Change string            `xml:"change" json:"change"`
+	Keys   []ConfigKeyChange `xml:"keys" json:"keys"`
+}
+
+// ProgramSection the [program:x] section in the configuration file, the Text is the lines of the section.
+// The section is not Editable if it is in the main configuration file
+type ProgramSection struct {
+	Name     string `xml:"name" json:"name"`
+	File     string `xml:"file" json:"file"`
+	Editable bool   `xml:"editable" json:"editable"`
+	Text     string `xml:"text,omitempty" json:"text,omitempty"`
+}
+
+// ProgramSectionResult the result of validating or saving the [program:x] section. The section is rejected
+// if there are Errors, the Warnings are the parameters not known. The Changes are the programs changed by
+// the saved section
+type ProgramSectionResult struct {
+	Errors   []string              `xml:"errors" json:"errors"`
+	Warnings []string              `xml:"warnings" json:"warnings"`
+	Changes  []ProgramConfigChange `xml:"changes" json:"changes"`
 }
 
 // ReloadConfigResult the result of supervisor configuration reloading
@@ -47,3 +179,26 @@ func (pi ProcessInfo) GetFullName() string {
 	}
 	return pi.Name
 }
+
+// LogSearchMatch one log line matched by the log search
+type LogSearchMatch struct {
+	File   string `xml:"file" json:"file"`
+	Line   string `xml:"line" json:"line"`
+	Offset int    `xml:"offset" json:"offset"`
+	Time   int    `xml:"time" json:"time"`
+}
+
+// LogSearchResult the result of searching the program logs
+type LogSearchResult struct {
+	Matches   []LogSearchMatch `xml:"matches" json:"matches"`
+	Truncated bool             `xml:"truncated" json:"truncated"`
+}
+
+// EventInfo an event kept in the event history of supervisord
+type EventInfo struct {
+	Serial    int    `xml:"serial" json:"serial"`
+	EventName string `xml:"eventname" json:"eventname"`
+	// the time the event is emitted in unix seconds
+	Time int    `xml:"time" json:"time"`
+	Body string `xml:"body" json:"body"`
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/ochinchina/supervisord/config"
+)
+
+// unixSocketPermission the mode and owner set to the unix socket file after it is bound
+type unixSocketPermission struct {
+	// 0 to keep the mode
+	mode os.FileMode
+	// -1 to keep the owner
+	uid int
+	gid int
+}
+
+// parse the chmod like 0700 and the chown like "user" or "user:group" of the unix_http_server section,
+// nil if neither of them is set
+func parseUnixSocketPermission(entry *config.Entry) (*unixSocketPermission, error) {
+	chmod := entry.GetString("chmod", "")
+	chown := entry.GetString("chown", "")
+	if chmod == "" && chown == "" {
+		return nil, nil
+	}
+	perm := &unixSocketPermission{uid: -1, gid: -1}
+	if chmod != "" {
+		mode, err := strconv.ParseUint(chmod, 8, 32)
+		if err != nil || mode == 0 || mode > 0777 {
+			return nil, fmt.Errorf("invalid chmod %s, it should be an octal mode like 0700", chmod)
+		}
+		perm.mode = os.FileMode(mode)
+	}
+	if chown != "" {
+		userName := chown
+		groupName := ""
+		if pos := strings.Index(chown, ":"); pos != -1 {
+			userName = chown[0:pos]
+			groupName = chown[pos+1:]
+		}
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return nil, err
+		}
+		if perm.uid, err = strconv.Atoi(u.Uid); err != nil {
+			return nil, err
+		}
+		gid := u.Gid
+		if groupName != "" {
+			g, err := user.LookupGroup(groupName)
+			if err != nil {
+				return nil, err
+			}
+			gid = g.Gid
+		}
+		if perm.gid, err = strconv.Atoi(gid); err != nil {
+			return nil, err
+		}
+	}
+	return perm, nil
+}
+
+// set the mode and owner of the socket file
+func (p *unixSocketPermission) apply(path string) error {
+	if p.uid != -1 || p.gid != -1 {
+		if err := os.Chown(path, p.uid, p.gid); err != nil {
+			return err
+		}
+	}
+	if p.mode != 0 {
+		return os.Chmod(path, p.mode)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -20,6 +21,25 @@ func NewSupervisorWebgui(supervisor *Supervisor) *SupervisorWebgui {
 
 // CreateHandler create a http handler to process the request from WEBGUI
 func (sw *SupervisorWebgui) CreateHandler() http.Handler {
+	sw.router.PathPrefix("/ui/").HandlerFunc(sw.serveUI)
 	sw.router.PathPrefix("/").Handler(http.FileServer(HTTP))
 	return sw.router
 }
+
+// serveUI serves the single page of group and process views for the deep links like
+// /ui/group/<group> and /ui/process/<name>, the page renders the view by its path
+func (sw *SupervisorWebgui) serveUI(w http.ResponseWriter, req *http.Request) {
+	serveWebguiPage(w, "ui.html")
+}
+
+// serve the html page of the web GUI
+func serveWebguiPage(w http.ResponseWriter, name string) {
+	f, err := HTTP.Open(name)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.Copy(w, f)
+}
@@ -9,4 +9,35 @@ function getUrlVars()
         vars[hash[0]] = hash[1];
     }
     return vars;
-}
\ No newline at end of file
+}
+
+function formatBytes( n ) {
+    var units = ["B", "KB", "MB", "GB", "TB"];
+    var i = 0;
+    while( n >= 1024 && i < units.length - 1 ) {
+        n = n / 1024;
+        i++;
+    }
+    return n.toFixed( 1 ) + units[i];
+}
+
+// draw the values as a SVG polyline of width x height, a dashed line is drawn at the threshold if it is above 0
+function sparkline( values, width, height, threshold ) {
+    if( values.length < 2 ) {
+        return '<span class="text-muted">no samples</span>';
+    }
+    var max = Math.max( Math.max.apply( null, values ), threshold || 0 ) || 1;
+    var y = function( value ) {
+        return ( height - 2 - value * ( height - 4 ) / max ).toFixed( 1 );
+    };
+    var points = [];
+    for( var i = 0; i < values.length; i++ ) {
+        points.push( ( i * width / ( values.length - 1 ) ).toFixed( 1 ) + "," + y( values[i] ) );
+    }
+    var svg = '<svg class="sparkline" width="' + width + '" height="' + height + '" viewBox="0 0 ' + width + ' ' + height + '">';
+    svg += '<polyline points="' + points.join( " " ) + '"/>';
+    if( threshold > 0 ) {
+        svg += '<line class="threshold" x1="0" y1="' + y( threshold ) + '" x2="' + width + '" y2="' + y( threshold ) + '"/>';
+    }
+    return svg + '</svg>';
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/ochinchina/supervisord/events"
+	"github.com/ochinchina/supervisord/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// SupervisorWebSocket pushes the process state changes and optionally the program logs to the
+// WebSocket clients connected to /ws, so the UIs don't have to poll the process information
+type SupervisorWebSocket struct {
+	router     *mux.Router
+	supervisor *Supervisor
+	upgrader   websocket.Upgrader
+}
+
+// the JSON message pushed to the WebSocket clients, the fields are set by the message type:
+//
+//	processes: the information of all the programs, sent first after the client connects
+//	state: the program name changes from the state to the state, with its information
+//	supervisor: the state of supervisord changes to the statename
+//	log: the data is written to the stdout or stderr log of the program name
+type wsMessage struct {
+	Type      string              `json:"type"`
+	Name      string              `json:"name,omitempty"`
+	From      string              `json:"from,omitempty"`
+	To        string              `json:"to,omitempty"`
+	Statename string              `json:"statename,omitempty"`
+	Channel   string              `json:"channel,omitempty"`
+	Data      string              `json:"data,omitempty"`
+	Process   *types.ProcessInfo  `json:"process,omitempty"`
+	Processes []types.ProcessInfo `json:"processes,omitempty"`
+}
+
+// NewSupervisorWebSocket create a new SupervisorWebSocket object
+func NewSupervisorWebSocket(supervisor *Supervisor) *SupervisorWebSocket {
+	return &SupervisorWebSocket{router: mux.NewRouter(), supervisor: supervisor}
+}
+
+// CreateHandler create http handler to accept the WebSocket connections on /ws. The logs of the
+// programs matching the "logs" query, like "web" or "worker-*", are pushed besides the states
+func (sw *SupervisorWebSocket) CreateHandler() http.Handler {
+	sw.router.HandleFunc("/ws", sw.push).Methods("GET")
+	return sw.router
+}
+
+func (sw *SupervisorWebSocket) push(w http.ResponseWriter, req *http.Request) {
+	conn, err := sw.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.WithFields(log.Fields{log.ErrorKey: err}).Error("fail to upgrade to websocket")
+		return
+	}
+	defer conn.Close()
+
+	// the messages from the client are not expected, read them only to detect the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// subscribe before sending the process information so no state change is lost between them
+	ch := events.Subscribe("PROCESS_STATE", "SUPERVISOR_STATE_CHANGE")
+	defer events.Unsubscribe(ch)
+	logs := make(chan *wsMessage)
+	if pattern := req.URL.Query().Get("logs"); pattern != "" {
+		for _, proc := range sw.supervisor.GetManager().FindMatch(pattern) {
+			for _, channel := range []string{"stdout", "stderr"} {
+				logCh, unfollow := proc.FollowLog(channel)
+				defer unfollow()
+				go forwardLog(proc.GetName(), channel, logCh, logs, closed)
+			}
+		}
+	}
+
+	reply := struct{ AllProcessInfo []types.ProcessInfo }{make([]types.ProcessInfo, 0)}
+	sw.supervisor.GetAllProcessInfo(nil, nil, &reply)
+	if conn.WriteJSON(&wsMessage{Type: "processes", Processes: reply.AllProcessInfo}) != nil {
+		return
+	}
+	for {
+		var msg *wsMessage
+		select {
+		case <-closed:
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			msg = sw.toMessage(event)
+		case msg = <-logs:
+		}
+		if msg != nil && conn.WriteJSON(msg) != nil {
+			return
+		}
+	}
+}
+
+// convert the process state or supervisord state event to the message
+func (sw *SupervisorWebSocket) toMessage(event events.Event) *wsMessage {
+	switch e := event.(type) {
+	case *events.ProcessStateEvent:
+		msg := &wsMessage{Type: "state",
+			Name: e.GetProcessName(),
+			From: e.GetFromState(),
+			To:   strings.TrimPrefix(e.GetType(), "PROCESS_STATE_")}
+		if proc := sw.supervisor.GetManager().Find(e.GetProcessName()); proc != nil {
+			msg.Process = getProcessInfo(proc)
+		}
+		return msg
+	case *events.SupervisorStateChangeEvent:
+		return &wsMessage{Type: "supervisor", Statename: strings.TrimPrefix(e.GetType(), "SUPERVISOR_STATE_CHANGE_")}
+	}
+	return nil
+}
+
+// forward the log of program name to the logs until the client is closed
+func forwardLog(name string, channel string, logCh chan []byte, logs chan *wsMessage, closed chan struct{}) {
+	for {
+		select {
+		case <-closed:
+			return
+		case data, ok := <-logCh:
+			if !ok {
+				return
+			}
+			select {
+			case logs <- &wsMessage{Type: "log", Name: name, Channel: channel, Data: string(data)}:
+			case <-closed:
+				return
+			}
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1" //nolint:gosec
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -11,41 +13,112 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/rpc"
 	"github.com/ochinchina/gorilla-xmlrpc/xml"
+	"github.com/ochinchina/supervisord/config"
 	"github.com/ochinchina/supervisord/process"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"github.com/soheilhy/cmux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // XMLRPC mange the XML RPC servers
 // start XML RPC servers to accept the XML RPC request from client side
 type XMLRPC struct {
-	// all the listeners to accept the XML RPC request
+	lock sync.Mutex
+	// all the listeners to accept the XML RPC request by protocol
 	listeners map[string]net.Listener
+	// the http servers on the listeners by protocol
+	servers map[string]*http.Server
+	// the gRPC servers by protocol, the one sharing the port of the inet http server is on "tcp"
+	grpcServers map[string]*grpc.Server
 }
 
 type httpBasicAuth struct {
 	user     string
 	password string
-	handler  http.Handler
+	// the bearer tokens accepted by the server in addition to the username and password
+	tokens *serverTokens
+	// the roles of the clients authenticated by the certificates, nil if ca_certfile is not set
+	certRoles *clientCertRoles
+	// the audit log and the rate limit of the requests
+	audit   *rpcAudit
+	handler http.Handler
 }
 
-// create a new HttpBasicAuth object with username, password and the http request handler
-func newHTTPBasicAuth(user string, password string, handler http.Handler) *httpBasicAuth {
+// create a new HttpBasicAuth object with username, password, the bearer tokens, the client certificate roles,
+// the audit log and the http request handler
+func newHTTPBasicAuth(user string, password string, tokens *serverTokens, certRoles *clientCertRoles, audit *rpcAudit, handler http.Handler) *httpBasicAuth {
 	if user != "" && password != "" {
 		log.Debug("require authentication")
 	}
-	return &httpBasicAuth{user: user, password: password, handler: handler}
+	return &httpBasicAuth{user: user, password: password, tokens: tokens, certRoles: certRoles, audit: audit, handler: handler}
 }
 
 func (h *httpBasicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	role, ok := h.authenticate(r)
+	client := ""
+	if ok {
+		client = h.clientName(r)
+	}
+	if !h.audit.allow(rateLimitKey(client, r.RemoteAddr), time.Now()) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"supervisor\"")
+		w.Header().Add("WWW-Authenticate", "Bearer realm=\"supervisor\"")
+		w.WriteHeader(401)
+		return
+	}
+	// the XML-RPC methods are checked by the xmlrpcSystemHandler
+	if role == roleReadOnly && r.Method != "GET" && r.Method != "HEAD" && r.URL.Path != "/RPC2" {
+		http.Error(w, "the readonly client can't change anything", http.StatusForbidden)
+		return
+	}
+	ctx := context.WithValue(context.WithValue(r.Context(), roleContextKey{}, role), clientContextKey{}, client)
+	// the XML-RPC methods are audited by the xmlrpcSystemHandler
+	if r.Method == "GET" || r.Method == "HEAD" || r.URL.Path == "/RPC2" {
+		h.handler.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.handler.ServeHTTP(recorder, r.WithContext(ctx))
+	h.audit.record(client, r.RemoteAddr, "http", r.Method+" "+r.URL.Path, r.URL.RawQuery, http.StatusText(recorder.status))
+}
+
+// check the client certificate, the basic authentication or the bearer token of the request
+func (h *httpBasicAuth) authenticated(r *http.Request) bool {
+	_, ok := h.authenticate(r)
+	return ok
+}
+
+// authenticate the request and return the role of the client. The client with the verified certificate gets
+// its role in client_roles, the other clients are admin if the password or the bearer token is correct, the
+// password may be the hex SHA1 prefixed by {SHA}. The server with only the tokens set refuses the clients
+// without a token
+func (h *httpBasicAuth) authenticate(r *http.Request) (string, bool) {
+	if role, ok := h.certRoles.getRole(r); ok {
+		log.Debug("auth with client certificate")
+		return role, true
+	}
+	if role, ok := h.tokens.authenticate(r); ok {
+		return role, true
+	}
 	if h.user == "" || h.password == "" {
+		// the server is only open if neither the username and password nor the tokens are set
+		if h.certRoles != nil || h.tokens.configured() {
+			return "", false
+		}
 		log.Debug("no auth required")
-		h.handler.ServeHTTP(w, r)
-		return
+		return roleAdmin, true
 	}
 	username, password, ok := r.BasicAuth()
 	if ok && username == h.user {
@@ -53,52 +126,185 @@ func (h *httpBasicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			log.Debug("auth with SHA")
 			hash := sha1.New() //nolint:gosec
 			io.WriteString(hash, password)
-			if hex.EncodeToString(hash.Sum(nil)) == h.password[5:] {
-				h.handler.ServeHTTP(w, r)
-				return
-			}
+			return roleAdmin, hex.EncodeToString(hash.Sum(nil)) == h.password[5:]
 		} else if password == h.password {
 			log.Debug("Auth with normal password")
-			h.handler.ServeHTTP(w, r)
-			return
+			return roleAdmin, true
 		}
 	}
-	w.Header().Set("WWW-Authenticate", "Basic realm=\"supervisor\"")
-	w.WriteHeader(401)
+	return "", false
 }
 
 // NewXMLRPC create a new XML RPC object
 func NewXMLRPC() *XMLRPC {
-	return &XMLRPC{listeners: make(map[string]net.Listener)}
+	return &XMLRPC{listeners: make(map[string]net.Listener),
+		servers:     make(map[string]*http.Server),
+		grpcServers: make(map[string]*grpc.Server)}
 }
 
 // Stop network listening
 func (p *XMLRPC) Stop() {
 	log.Info("stop listening")
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	for _, listener := range p.listeners {
 		listener.Close()
 	}
+	for _, server := range p.grpcServers {
+		server.Stop()
+	}
 	p.listeners = make(map[string]net.Listener)
+	p.servers = make(map[string]*http.Server)
+	p.grpcServers = make(map[string]*grpc.Server)
+}
+
+// StopServer gracefully stops the server on the protocol like "tcp", "unix" or "grpc". It stops listening at
+// once and waits for the requests being handled to be finished, the connections are closed after timeout
+func (p *XMLRPC) StopServer(protocol string, timeout time.Duration) {
+	p.lock.Lock()
+	listener, ok := p.listeners[protocol]
+	server := p.servers[protocol]
+	grpcServer := p.grpcServers[protocol]
+	delete(p.listeners, protocol)
+	delete(p.servers, protocol)
+	delete(p.grpcServers, protocol)
+	p.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	log.WithFields(log.Fields{"addr": listener.Addr().String(), "protocol": protocol}).Info("stop listening")
+	listener.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if server != nil && server.Shutdown(ctx) != nil {
+		server.Close()
+	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
 }
 
-// StartUnixHTTPServer start http server on unix domain socket with path listenAddr. If both user and password are not empty, the user
-// must provide user and password for basic authentication when making an XML RPC request.
-func (p *XMLRPC) StartUnixHTTPServer(user string, password string, listenAddr string, s *Supervisor, startedCb func()) {
-	os.Remove(listenAddr)
-	p.startHTTPServer(user, password, "unix", listenAddr, s, startedCb)
+// StartUnixHTTPServer start http server on the unix domain socket listener. If both user and password are not
+// empty, the user must provide user and password for basic authentication when making an XML RPC request.
+func (p *XMLRPC) StartUnixHTTPServer(user string, password string, listener net.Listener, s *Supervisor, startedCb func()) {
+	p.startHTTPServer(nil, user, password, "unix", listener, nil, nil, s, startedCb)
 }
 
-// StartInetHTTPServer start http server on tcp with path listenAddr. If both user and password are not empty, the user
-// must provide user and password for basic authentication when making an XML RPC request.
-func (p *XMLRPC) StartInetHTTPServer(user string, password string, listenAddr string, s *Supervisor, startedCb func()) {
-	p.startHTTPServer(user, password, "tcp", listenAddr, s, startedCb)
+// StartInetHTTPServer start http server of the inet_http_server entry on the tcp listener. If both user and
+// password are not empty, the user must provide user and password for basic authentication when making an XML RPC
+// request. The https is served if tlsConfig is not nil, and the clients with the certificates verified by it are
+// authenticated by the certRoles.
+func (p *XMLRPC) StartInetHTTPServer(entry *config.Entry, user string, password string, listener net.Listener, tlsConfig *tls.Config, certRoles *clientCertRoles, s *Supervisor, startedCb func()) {
+	p.startHTTPServer(entry, user, password, inetHTTPServerProtocol(entry), listener, tlsConfig, certRoles, s, startedCb)
+}
+
+// get the protocol of the inet_http_server entry, "tcp" or "tcp:<name>" for the named one
+func inetHTTPServerProtocol(entry *config.Entry) string {
+	if name := entry.GetInetHTTPServerName(); name != "" {
+		return "tcp:" + name
+	}
+	return "tcp"
+}
+
+// StartGRPCServer start the gRPC server on the tcp listener. If both user and password are not empty, the user
+// must provide user and password in the "authorization" metadata like the http basic authentication. The TLS is
+// served if tlsConfig is not nil, and the clients with the certificates verified by it are authenticated by the
+// certRoles.
+func (p *XMLRPC) StartGRPCServer(user string, password string, listener net.Listener, tlsConfig *tls.Config, certRoles *clientCertRoles, s *Supervisor, startedCb func()) {
+	if p.isHTTPServerStartedOnProtocol("grpc") {
+		listener.Close()
+		startedCb()
+		return
+	}
+	log.WithFields(log.Fields{"addr": listener.Addr().String(), "protocol": "grpc"}).Info("success to listen on address")
+	opts := make([]grpc.ServerOption, 0)
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := NewSupervisorGRPC(s, user, password, s.tokens.forServer("grpc"), certRoles).CreateServer(opts...)
+	p.lock.Lock()
+	p.listeners["grpc"] = listener
+	p.grpcServers["grpc"] = server
+	p.lock.Unlock()
+	startedCb()
+	server.Serve(listener)
+}
+
+// listen on the address for the server on the protocol. If the running server on the protocol listens on the
+// same address, its socket is shared so the new server listens before the running one is stopped
+func (p *XMLRPC) listen(protocol string, network string, listenAddr string) (net.Listener, error) {
+	p.lock.Lock()
+	running := p.listeners[protocol]
+	p.lock.Unlock()
+	if running != nil && isListeningOn(running.Addr(), network, listenAddr) {
+		fileListener, ok := running.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("can't share the socket of %s", listenAddr)
+		}
+		f, err := fileListener.File()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if unixListener, ok := running.(*net.UnixListener); ok {
+			// the socket file is kept for the new server when the running server is stopped
+			unixListener.SetUnlinkOnClose(false)
+		}
+		return net.FileListener(f)
+	}
+	// the socket file passed by systemd is kept
+	if network == "unix" && !isActivatedSocket("unix", listenAddr) {
+		os.Remove(listenAddr)
+	}
+	return listenSocket(network, listenAddr)
+}
+
+// check if the socket address is exactly the address to listen on, the unspecified host only matches the
+// socket listening on all the interfaces
+func isListeningOn(addr net.Addr, network string, listenAddr string) bool {
+	if network == "unix" {
+		return addr.Network() == network && filepath.Clean(addr.String()) == filepath.Clean(listenAddr)
+	}
+	socketAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	tcpAddr, err := net.ResolveTCPAddr(network, listenAddr)
+	if err != nil || tcpAddr.Port != socketAddr.Port {
+		return false
+	}
+	if tcpAddr.IP == nil || tcpAddr.IP.IsUnspecified() {
+		return socketAddr.IP == nil || socketAddr.IP.IsUnspecified()
+	}
+	return tcpAddr.IP.Equal(socketAddr.IP)
 }
 
 func (p *XMLRPC) isHTTPServerStartedOnProtocol(protocol string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	_, ok := p.listeners[protocol]
 	return ok
 }
 
+// serve the http requests on the listener until the server on the protocol is stopped
+func (p *XMLRPC) serveHTTP(protocol string, listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+	p.lock.Lock()
+	p.servers[protocol] = server
+	p.lock.Unlock()
+	server.Serve(listener)
+}
+
 func readFile(path string) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -124,57 +330,140 @@ func getProgramConfigPath(programName string, s *Supervisor) string {
 }
 
 func readLogHtml(writer http.ResponseWriter, request *http.Request) {
-	b, err := readFile("webgui/log.html")
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		return
-	}
+	serveWebguiPage(writer, "log.html")
+}
 
-	writer.WriteHeader(http.StatusOK)
-	writer.Write(b)
+// the endpoints which can be enabled by the endpoints option of inet_http_server, all of them are enabled by default
+var httpEndpoints = []string{"xmlrpc", "rest", "api", "ws", "webgui", "metrics", "grpc"}
+
+// get the enabled endpoints of the http server section, all the endpoints are enabled if entry is nil or
+// the endpoints option is not set
+func getHTTPEndpoints(entry *config.Entry) (map[string]bool, error) {
+	result := make(map[string]bool)
+	value := ""
+	if entry != nil {
+		value = entry.GetString("endpoints", "")
+	}
+	names := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+	if len(names) == 0 {
+		names = httpEndpoints
+	}
+	for _, name := range names {
+		found := false
+		for _, endpoint := range httpEndpoints {
+			found = found || endpoint == name
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown endpoint %s, it should be one of %s", name, strings.Join(httpEndpoints, ","))
+		}
+		result[name] = true
+	}
+	return result, nil
 }
 
-func (p *XMLRPC) startHTTPServer(user string, password string, protocol string, listenAddr string, s *Supervisor, startedCb func()) {
+// start the http server on the protocol like "tcp" or "unix", the protocol of the named inet_http_server is
+// followed by its name like "tcp:metrics". The endpoints and the allowed origins are set by the entry
+func (p *XMLRPC) startHTTPServer(entry *config.Entry, user string, password string, protocol string, listener net.Listener, tlsConfig *tls.Config, certRoles *clientCertRoles, s *Supervisor, startedCb func()) {
 	if p.isHTTPServerStartedOnProtocol(protocol) {
+		listener.Close()
+		startedCb()
+		return
+	}
+	network := strings.SplitN(protocol, ":", 2)[0]
+	endpoints, err := getHTTPEndpoints(entry)
+	if err != nil {
+		listener.Close()
 		startedCb()
+		log.WithFields(log.Fields{log.ErrorKey: err, "addr": listener.Addr().String(), "protocol": protocol}).Error("fail to get the endpoints of the http server")
 		return
 	}
-	procCollector := process.NewProcCollector(s.procMgr)
-	prometheus.Register(procCollector)
+	tokens := s.tokens.forServer(protocol)
 	mux := http.NewServeMux()
-	mux.Handle("/RPC2", newHTTPBasicAuth(user, password, p.createRPCServer(s)))
+	if endpoints["xmlrpc"] {
+		mux.Handle("/RPC2", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, newXMLRPCSystemHandler(p.createRPCServer(s), s.audit)))
+	}
+
+	if endpoints["rest"] {
+		progRestHandler := NewSupervisorRestful(s).CreateProgramHandler()
+		mux.Handle("/program/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, progRestHandler))
+
+		supervisorRestHandler := NewSupervisorRestful(s).CreateSupervisorHandler()
+		mux.Handle("/supervisor/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, supervisorRestHandler))
+	}
+
+	if endpoints["api"] {
+		apiHandler := NewSupervisorRestAPI(s).CreateHandler()
+		mux.Handle("/api/v1/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, apiHandler))
+	}
+
+	if endpoints["ws"] {
+		wsHandler := NewSupervisorWebSocket(s).CreateHandler()
+		mux.Handle("/ws", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, wsHandler))
+	}
 
-	progRestHandler := NewSupervisorRestful(s).CreateProgramHandler()
-	mux.Handle("/program/", newHTTPBasicAuth(user, password, progRestHandler))
+	if endpoints["webgui"] {
+		p.handleWebgui(mux, user, password, tokens, certRoles, s)
+	}
 
-	supervisorRestHandler := NewSupervisorRestful(s).CreateSupervisorHandler()
-	mux.Handle("/supervisor/", newHTTPBasicAuth(user, password, supervisorRestHandler))
+	if endpoints["metrics"] {
+		procCollector := process.NewProcCollector(s.procMgr)
+		prometheus.Register(procCollector)
+		mux.Handle("/metrics", promhttp.Handler())
+	}
 
+	handler := http.Handler(mux)
+	if entry != nil && network == "tcp" {
+		handler = newCORSHandler(entry.GetString("allowed_origins", ""), mux)
+	}
+
+	log.WithFields(log.Fields{"addr": listener.Addr().String(), "protocol": protocol}).Info("success to listen on address")
+	p.lock.Lock()
+	p.listeners[protocol] = listener
+	p.lock.Unlock()
+	var grpcServer *grpc.Server
+	if network == "tcp" && endpoints["grpc"] {
+		grpcServer = p.createSharedGRPCServer(protocol, user, password, certRoles, s)
+	}
+	startedCb()
+	if tlsConfig != nil {
+		// the gRPC calls can't be told from the other requests before the TLS handshake
+		listener = tls.NewListener(listener, tlsConfig)
+		if grpcServer != nil {
+			p.serveHTTP(protocol, listener, newGRPCHTTPHandler(grpcServer, handler))
+			return
+		}
+	}
+	if grpcServer == nil {
+		p.serveHTTP(protocol, listener, handler)
+		return
+	}
+	// the gRPC calls are the HTTP/2 requests with content-type application/grpc
+	m := cmux.New(listener)
+	grpcListener := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
+	httpListener := m.Match(cmux.Any())
+	go grpcServer.Serve(grpcListener)
+	go m.Serve()
+	p.serveHTTP(protocol, httpListener, handler)
+}
+
+// handle the web GUI, the configuration files and the log files on the mux
+func (p *XMLRPC) handleWebgui(mux *http.ServeMux, user string, password string, tokens *serverTokens, certRoles *clientCertRoles, s *Supervisor) {
 	// 有bug已弃用
 	logtailHandler := NewLogtail(s).CreateHandler()
-	mux.Handle("/logtail/", newHTTPBasicAuth(user, password, logtailHandler))
+	mux.Handle("/logtail/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, logtailHandler))
 
 	webguiHandler := NewSupervisorWebgui(s).CreateHandler()
-	mux.Handle("/", newHTTPBasicAuth(user, password, webguiHandler))
+	mux.Handle("/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, webguiHandler))
 
 	// conf 文件
 	confHandler := NewConfApi(s).CreateHandler()
-	mux.Handle("/conf/", newHTTPBasicAuth(user, password, confHandler))
-	mux.HandleFunc("/confFile", func(writer http.ResponseWriter, request *http.Request) {
-		b, err := readFile("webgui/conf.html")
-		if err != nil {
-			writer.WriteHeader(http.StatusNotFound)
-			return
-		}
-
-		writer.WriteHeader(http.StatusOK)
-		writer.Write(b)
-	})
+	mux.Handle("/conf/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, confHandler))
+	mux.Handle("/confFile", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		serveWebguiPage(writer, "conf.html")
+	})))
 
 	// 读log.html文件
-	mux.HandleFunc("/log", readLogHtml)
-
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/log", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, http.HandlerFunc(readLogHtml)))
 
 	// 注册日志路由,可以查看日志目录
 	entryList := s.config.GetPrograms()
@@ -189,59 +478,121 @@ func (p *XMLRPC) startHTTPServer(user string, password string, protocol string,
 			continue
 		}
 		dir := filepath.Dir(filePath)
-		fmt.Println(dir)
-		mux.Handle("/log/"+realName+"/", http.StripPrefix("/log/"+realName+"/", http.FileServer(http.Dir(dir))))
+		mux.Handle("/log/"+realName+"/", newHTTPBasicAuth(user, password, tokens, certRoles, s.audit, http.StripPrefix("/log/"+realName+"/", http.FileServer(http.Dir(dir)))))
 	}
+}
 
-	listener, err := net.Listen(protocol, listenAddr)
-	if err == nil {
-		log.WithFields(log.Fields{"addr": listenAddr, "protocol": protocol}).Info("success to listen on address")
-		p.listeners[protocol] = listener
-		startedCb()
-		http.Serve(listener, mux)
-	} else {
-		startedCb()
-		log.WithFields(log.Fields{"addr": listenAddr, "protocol": protocol}).Fatal("fail to listen on address")
-	}
+// serve the gRPC calls, the HTTP/2 requests with content-type application/grpc, by the gRPC server and
+// the other requests by the handler
+func newGRPCHTTPHandler(grpcServer *grpc.Server, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
 
+// create the gRPC server sharing the inet http server port if the grpc_server section has no port
+func (p *XMLRPC) createSharedGRPCServer(protocol string, user string, password string, certRoles *clientCertRoles, s *Supervisor) *grpc.Server {
+	grpcServerConfig, ok := s.config.GetGRPCServer()
+	if !ok || grpcServerConfig.GetString("port", "") != "" {
+		return nil
+	}
+	log.Info("serve gRPC on the inet http server port")
+	server := NewSupervisorGRPC(s,
+		grpcServerConfig.GetString("username", user),
+		grpcServerConfig.GetString("password", password),
+		s.tokens.forServer(protocol),
+		certRoles).CreateServer()
+	p.lock.Lock()
+	p.grpcServers[protocol] = server
+	p.lock.Unlock()
+	return server
 }
+
 func (p *XMLRPC) createRPCServer(s *Supervisor) *rpc.Server {
 	RPC := rpc.NewServer()
 	xmlrpcCodec := xml.NewCodec()
 	RPC.RegisterCodec(xmlrpcCodec, "text/xml")
 	RPC.RegisterService(s, "")
 
-	xmlrpcCodec.RegisterAlias("supervisor.getVersion", "Supervisor.GetVersion")
-	xmlrpcCodec.RegisterAlias("supervisor.getAPIVersion", "Supervisor.GetVersion")
-	xmlrpcCodec.RegisterAlias("supervisor.getIdentification", "Supervisor.GetIdentification")
-	xmlrpcCodec.RegisterAlias("supervisor.getState", "Supervisor.GetState")
-	xmlrpcCodec.RegisterAlias("supervisor.getPID", "Supervisor.GetPID")
-	xmlrpcCodec.RegisterAlias("supervisor.readLog", "Supervisor.ReadLog")
-	xmlrpcCodec.RegisterAlias("supervisor.clearLog", "Supervisor.ClearLog")
-	xmlrpcCodec.RegisterAlias("supervisor.shutdown", "Supervisor.Shutdown")
-	xmlrpcCodec.RegisterAlias("supervisor.restart", "Supervisor.Restart")
-	xmlrpcCodec.RegisterAlias("supervisor.getProcessInfo", "Supervisor.GetProcessInfo")
-	xmlrpcCodec.RegisterAlias("supervisor.getSupervisorVersion", "Supervisor.GetVersion")
-	xmlrpcCodec.RegisterAlias("supervisor.getAllProcessInfo", "Supervisor.GetAllProcessInfo")
-	xmlrpcCodec.RegisterAlias("supervisor.startProcess", "Supervisor.StartProcess")
-	xmlrpcCodec.RegisterAlias("supervisor.startAllProcesses", "Supervisor.StartAllProcesses")
-	xmlrpcCodec.RegisterAlias("supervisor.startProcessGroup", "Supervisor.StartProcessGroup")
-	xmlrpcCodec.RegisterAlias("supervisor.stopProcess", "Supervisor.StopProcess")
-	xmlrpcCodec.RegisterAlias("supervisor.stopProcessGroup", "Supervisor.StopProcessGroup")
-	xmlrpcCodec.RegisterAlias("supervisor.stopAllProcesses", "Supervisor.StopAllProcesses")
-	xmlrpcCodec.RegisterAlias("supervisor.signalProcess", "Supervisor.SignalProcess")
-	xmlrpcCodec.RegisterAlias("supervisor.signalProcessGroup", "Supervisor.SignalProcessGroup")
-	xmlrpcCodec.RegisterAlias("supervisor.signalAllProcesses", "Supervisor.SignalAllProcesses")
-	xmlrpcCodec.RegisterAlias("supervisor.sendProcessStdin", "Supervisor.SendProcessStdin")
-	xmlrpcCodec.RegisterAlias("supervisor.sendRemoteCommEvent", "Supervisor.SendRemoteCommEvent")
-	xmlrpcCodec.RegisterAlias("supervisor.reloadConfig", "Supervisor.ReloadConfig")
-	xmlrpcCodec.RegisterAlias("supervisor.addProcessGroup", "Supervisor.AddProcessGroup")
-	xmlrpcCodec.RegisterAlias("supervisor.removeProcessGroup", "Supervisor.RemoveProcessGroup")
-	xmlrpcCodec.RegisterAlias("supervisor.readProcessStdoutLog", "Supervisor.ReadProcessStdoutLog")
-	xmlrpcCodec.RegisterAlias("supervisor.readProcessStderrLog", "Supervisor.ReadProcessStderrLog")
-	xmlrpcCodec.RegisterAlias("supervisor.tailProcessStdoutLog", "Supervisor.TailProcessStdoutLog")
-	xmlrpcCodec.RegisterAlias("supervisor.tailProcessStderrLog", "Supervisor.TailProcessStderrLog")
-	xmlrpcCodec.RegisterAlias("supervisor.clearProcessLogs", "Supervisor.ClearProcessLogs")
-	xmlrpcCodec.RegisterAlias("supervisor.clearAllProcessLogs", "Supervisor.ClearAllProcessLogs")
+	for _, m := range xmlrpcMethods {
+		xmlrpcCodec.RegisterAlias(m.name, m.method)
+	}
 	return RPC
 }
+
+// xmlrpcMethod the XML-RPC method bound to the method of Supervisor, the signature and help are
+// returned by system.methodSignature and system.methodHelp
+type xmlrpcMethod struct {
+	name   string
+	method string
+	// the return type followed by the types of the parameters
+	signature []string
+	help      string
+}
+
+var xmlrpcMethods = []xmlrpcMethod{
+	{"supervisor.getVersion", "Supervisor.GetVersion", []string{"string"}, "Return the version of the RPC API used by supervisord"},
+	{"supervisor.getAPIVersion", "Supervisor.GetVersion", []string{"string"}, "Return the version of the RPC API used by supervisord"},
+	{"supervisor.getSupervisorVersion", "Supervisor.GetVersion", []string{"string"}, "Return the version of supervisord"},
+	{"supervisor.getIdentification", "Supervisor.GetIdentification", []string{"string"}, "Return the identifier of supervisord set by the identifier option"},
+	{"supervisor.getState", "Supervisor.GetState", []string{"struct"}, "Return the current state of supervisord as a {statecode, statename} struct"},
+	{"supervisor.getPID", "Supervisor.GetPID", []string{"int"}, "Return the PID of supervisord"},
+	{"supervisor.readLog", "Supervisor.ReadLog", []string{"string", "int", "int"}, "Read length bytes from the supervisord log starting at offset"},
+	{"supervisor.tailLog", "Supervisor.TailLog", []string{"array", "int", "int"}, "Tail the supervisord log from offset, return the log, the offset of the next read and the overflow flag"},
+	{"supervisor.clearLog", "Supervisor.ClearLog", []string{"boolean"}, "Clear the supervisord log"},
+	{"supervisor.reopenLogs", "Supervisor.ReopenLogs", []string{"boolean"}, "Reopen the supervisord log and the log files of the running programs"},
+	{"supervisor.getProcessLogUsage", "Supervisor.GetProcessLogUsage", []string{"struct", "string"}, "Return the log files and the disk usage of the program name"},
+	{"supervisor.getAllProcessLogUsage", "Supervisor.GetAllProcessLogUsage", []string{"array"}, "Return the log files and the disk usage of all the programs"},
+	{"supervisor.getProcessResourceUsage", "Supervisor.GetProcessResourceUsage", []string{"struct", "string"}, "Return the CPU percent, RSS, open fd count, thread count and child count of the program name sampled in the background"},
+	{"supervisor.getProcessEnvironment", "Supervisor.GetProcessEnvironment", []string{"struct", "string"}, "Return the environment variables in KEY=VALUE format the program name is started with, the values of the secrets are masked"},
+	{"supervisor.exportState", "Supervisor.ExportState", []string{"string"}, "Return the states, restart counts, uptimes and next schedules of all the programs as JSON"},
+	{"supervisor.importState", "Supervisor.ImportState", []string{"boolean", "string"}, "Restore the restart counts of the programs from the JSON exported by exportState and start or stop them to reach the exported states"},
+	{"supervisor.getAllProcessResourceUsage", "Supervisor.GetAllProcessResourceUsage", []string{"array"}, "Return the CPU percent, RSS, open fd count, thread count and child count of all the programs sampled in the background"},
+	{"supervisor.getEventHistory", "Supervisor.GetEventHistory", []string{"array", "string", "int"}, "Return the kept events matching the filter which are emitted since the unix seconds"},
+	{"supervisor.createToken", "Supervisor.CreateToken", []string{"string", "string", "int"}, "Create a bearer token name which expires after ttl seconds, or never if ttl is 0, and return the token"},
+	{"supervisor.revokeToken", "Supervisor.RevokeToken", []string{"boolean", "string"}, "Revoke the bearer token name"},
+	{"supervisor.listTokens", "Supervisor.ListTokens", []string{"array"}, "Return the name, expiry and source of the bearer tokens"},
+	{"supervisor.shutdown", "Supervisor.Shutdown", []string{"boolean"}, "Stop all the programs and shut down supervisord"},
+	{"supervisor.restart", "Supervisor.Restart", []string{"boolean"}, "Restart supervisord"},
+	{"supervisor.getProcessInfo", "Supervisor.GetProcessInfo", []string{"struct", "string"}, "Return the information of the program name"},
+	{"supervisor.getAllProcessInfo", "Supervisor.GetAllProcessInfo", []string{"array"}, "Return the information of all the programs"},
+	{"supervisor.getConfigDiff", "Supervisor.GetConfigDiff", []string{"array"}, "Reread the configuration file and return the programs which would be added, changed and removed by reloadConfig with their changed keys"},
+	{"supervisor.getAllConfigInfo", "Supervisor.GetAllConfigInfo", []string{"array"}, "Return the programs in the configuration with whether they are in use, their autostart and priority"},
+	{"supervisor.waitForState", "Supervisor.WaitForState", []string{"boolean", "string", "string", "int"}, "Wait until the program name reaches the state like RUNNING, return false if it does not in timeout seconds"},
+	{"supervisor.startProcess", "Supervisor.StartProcess", []string{"boolean", "string", "boolean", "array"}, "Start the program name, wait for it to be started if wait is true, the optional array of KEY=VALUE environment variables overrides the configured ones for this run"},
+	{"supervisor.restartProcessWithEnv", "Supervisor.RestartProcessWithEnv", []string{"boolean", "string", "boolean", "array"}, "Restart the program name like restartProcess with the array of KEY=VALUE environment variables merged over the configured ones for this run"},
+	{"supervisor.restartProcess", "Supervisor.RestartProcess", []string{"boolean", "string", "boolean", "array"}, "Stop the program name and wait for it to exit, then start it again and wait for it to be running if wait is true, the optional array of KEY=VALUE environment variables overrides the configured ones for this run"},
+	{"supervisor.restartProcessGroup", "Supervisor.RestartProcessGroup", []string{"array", "string", "boolean"}, "Stop all the programs in the group name and wait for them to exit, then start them again and wait for them to be running if wait is true"},
+	{"supervisor.startAllProcesses", "Supervisor.StartAllProcesses", []string{"array", "boolean"}, "Start all the programs, wait for them to be started if wait is true"},
+	{"supervisor.startProcessGroup", "Supervisor.StartProcessGroup", []string{"array", "string", "boolean"}, "Start all the programs in the group name, wait for them to be started if wait is true"},
+	{"supervisor.stopProcess", "Supervisor.StopProcess", []string{"boolean", "string", "boolean"}, "Stop the program name, wait for it to be stopped if wait is true"},
+	{"supervisor.stopProcessGroup", "Supervisor.StopProcessGroup", []string{"array", "string", "boolean"}, "Stop all the programs in the group name, wait for them to be stopped if wait is true"},
+	{"supervisor.stopAllProcesses", "Supervisor.StopAllProcesses", []string{"array", "boolean"}, "Stop all the programs, wait for them to be stopped if wait is true"},
+	{"supervisor.signalProcess", "Supervisor.SignalProcess", []string{"boolean", "string", "string"}, "Send the signal like HUP to the program name"},
+	{"supervisor.signalProcessGroup", "Supervisor.SignalProcessGroup", []string{"array", "string", "string"}, "Send the signal like HUP to all the programs in the group name"},
+	{"supervisor.signalAllProcesses", "Supervisor.SignalAllProcesses", []string{"array", "string", "string"}, "Send the signal like HUP to all the programs, the name is ignored"},
+	{"supervisor.sendProcessStdin", "Supervisor.SendProcessStdin", []string{"boolean", "string", "string"}, "Send the chars to the stdin of the program name"},
+	{"supervisor.sendProcessStdinBase64", "Supervisor.SendProcessStdinBase64", []string{"boolean", "string", "base64"}, "Send the binary data to the stdin of the program name, the data of a call is written before the data of the following calls"},
+	{"supervisor.closeProcessStdin", "Supervisor.CloseProcessStdin", []string{"boolean", "string"}, "Close the stdin of the program name"},
+	{"supervisor.sendRemoteCommEvent", "Supervisor.SendRemoteCommEvent", []string{"boolean", "string", "string"}, "Emit the REMOTE_COMMUNICATION event with the type and data"},
+	{"supervisor.reloadConfig", "Supervisor.ReloadConfig", []string{"array"}, "Reload the configuration, return the added, changed and removed groups"},
+	{"supervisor.addProcessGroup", "Supervisor.AddProcessGroup", []string{"boolean", "string"}, "Add the programs of the group name in the configuration which are not added"},
+	{"supervisor.removeProcessGroup", "Supervisor.RemoveProcessGroup", []string{"boolean", "string"}, "Stop and remove the programs of the group name"},
+	{"supervisor.addProgram", "Supervisor.AddProgram", []string{"boolean", "string", "string"}, "Add the program name defined by the ini lines or JSON object of its section and start it if autostart"},
+	{"supervisor.removeProgram", "Supervisor.RemoveProgram", []string{"boolean", "string"}, "Stop and remove the programs of the program section name"},
+	{"supervisor.readProcessStdoutLog", "Supervisor.ReadProcessStdoutLog", []string{"string", "string", "int", "int"}, "Read length bytes from the stdout log of the program name starting at offset"},
+	{"supervisor.readProcessStderrLog", "Supervisor.ReadProcessStderrLog", []string{"string", "string", "int", "int"}, "Read length bytes from the stderr log of the program name starting at offset"},
+	{"supervisor.tailProcessStdoutLog", "Supervisor.TailProcessStdoutLog", []string{"array", "string", "int", "int"}, "Tail the stdout log of the program name from offset, return the log, the offset of the next read and the overflow flag"},
+	{"supervisor.tailProcessStderrLog", "Supervisor.TailProcessStderrLog", []string{"array", "string", "int", "int"}, "Tail the stderr log of the program name from offset, return the log, the offset of the next read and the overflow flag"},
+	{"supervisor.searchProcessLog", "Supervisor.SearchProcessLog", []string{"struct", "string", "string", "int", "int", "int"}, "Search the logs of the program name for the lines matching the pattern, at most maxResults lines between the since and until unix seconds are returned"},
+	{"supervisor.clearProcessLogs", "Supervisor.ClearProcessLogs", []string{"boolean", "string"}, "Clear the stdout and stderr logs of the program name"},
+	{"supervisor.clearAllProcessLogs", "Supervisor.ClearAllProcessLogs", []string{"array"}, "Clear the stdout and stderr logs of all the programs"},
+	{"chaos.killRandomProcess", "Supervisor.ChaosKillRandomProcess", []string{"string", "string"}, "Kill a random running program in the group with SIGKILL, return the killed program"},
+	{"chaos.delayRestart", "Supervisor.ChaosDelayRestart", []string{"boolean", "string", "int"}, "Delay the automatic restarts of the program name by the seconds, 0 to remove the delay"},
+	{"chaos.simulateSpawnError", "Supervisor.ChaosSimulateSpawnError", []string{"boolean", "string", "int"}, "Fail the following count start attempts of the program name"},
+	{"chaos.clear", "Supervisor.ChaosClear", []string{"boolean", "string"}, "Remove the injected failures of the program name"},
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ochinchina/supervisord/faults"
+)
+
+// xmlrpcSystemHandler handles the system.* XML-RPC methods which are not bound to the Supervisor
+// service, like system.multicall and the introspection methods, other requests are handled by the
+// rpc server
+type xmlrpcSystemHandler struct {
+	rpc http.Handler
+	// all the methods by name
+	methods map[string]xmlrpcMethod
+	// the state-changing methods are written to the audit log
+	audit *rpcAudit
+}
+
+var xmlrpcSystemMethods = []xmlrpcMethod{
+	{"system.listMethods", "", []string{"array"}, "Return an array of the names of all the methods"},
+	{"system.methodHelp", "", []string{"string", "string"}, "Return the help of the method name"},
+	{"system.methodSignature", "", []string{"array", "string"}, "Return an array of the signatures of the method name, a signature is an array of the return type followed by the types of the parameters"},
+	{"system.multicall", "", []string{"array", "array"}, "Call the methods in the array of {methodName, params} structs in order, return an array with the result of each call in a single element array, or a {faultCode, faultString} struct if the call fails"},
+}
+
+// the value in the XML-RPC request or response, the raw XML in it is kept to be passed through
+type xmlrpcValue struct {
+	Inner  string         `xml:",innerxml"`
+	String *string        `xml:"string"`
+	Array  []xmlrpcValue  `xml:"array>data>value"`
+	Struct []xmlrpcMember `xml:"struct>member"`
+}
+
+type xmlrpcMember struct {
+	Name  string      `xml:"name"`
+	Value xmlrpcValue `xml:"value"`
+}
+
+type xmlrpcMethodCall struct {
+	XMLName xml.Name      `xml:"methodCall"`
+	Method  string        `xml:"methodName"`
+	Params  []xmlrpcValue `xml:"params>param>value"`
+}
+
+type xmlrpcMethodResponse struct {
+	XMLName xml.Name      `xml:"methodResponse"`
+	Params  []xmlrpcValue `xml:"params>param>value"`
+	Fault   *xmlrpcValue  `xml:"fault>value"`
+}
+
+// the response written by the rpc server for a call in system.multicall
+type xmlrpcResponseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *xmlrpcResponseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *xmlrpcResponseBuffer) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *xmlrpcResponseBuffer) WriteHeader(status int) {
+	b.status = status
+}
+
+func newXMLRPCSystemHandler(rpc http.Handler, audit *rpcAudit) *xmlrpcSystemHandler {
+	h := &xmlrpcSystemHandler{rpc: rpc, methods: make(map[string]xmlrpcMethod), audit: audit}
+	for _, m := range append(xmlrpcSystemMethods, xmlrpcMethods...) {
+		h.methods[m.name] = m
+	}
+	return h
+}
+
+func (h *xmlrpcSystemHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.rpc.ServeHTTP(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var call xmlrpcMethodCall
+	err = xml.Unmarshal(body, &call)
+	if getRequestRole(r.Context()) == roleReadOnly && (err != nil || !isReadOnlyMethod(call.Method)) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(xmlrpcFault(faults.Failed, "FAILED: the readonly client can't call "+call.Method)))
+		return
+	}
+	if err == nil {
+		switch call.Method {
+		case "system.multicall":
+			h.multicall(w, r, call)
+			return
+		case "system.listMethods", "system.methodHelp", "system.methodSignature":
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			w.Write([]byte(h.introspect(call)))
+			return
+		}
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil || isReadOnlyMethod(call.Method) {
+		h.rpc.ServeHTTP(w, r)
+		return
+	}
+	resp := &xmlrpcResponseBuffer{header: w.Header(), status: http.StatusOK}
+	h.rpc.ServeHTTP(resp, r)
+	w.WriteHeader(resp.status)
+	w.Write(resp.body.Bytes())
+	h.audit.record(getRequestClient(r.Context()), r.RemoteAddr, "xmlrpc", call.Method, xmlrpcAuditArgs(call.Method, call.Params, h.audit.environmentMask()), xmlrpcAuditResult(resp.status, resp.body.Bytes()))
+}
+
+// the response of the introspection methods
+func (h *xmlrpcSystemHandler) introspect(call xmlrpcMethodCall) string {
+	if call.Method == "system.listMethods" {
+		names := make([]string, 0, len(h.methods))
+		for name := range h.methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return xmlrpcResponse(xmlrpcStringArray(names))
+	}
+	if len(call.Params) != 1 {
+		return xmlrpcFault(faults.IncorrectParameters, "INCORRECT_PARAMETERS: the method name is required")
+	}
+	m, ok := h.methods[call.Params[0].text()]
+	if !ok {
+		return xmlrpcFault(faults.SignatureUnsupported, "SIGNATURE_UNSUPPORTED: no method named "+call.Params[0].text())
+	}
+	if call.Method == "system.methodHelp" {
+		return xmlrpcResponse(xmlrpcString(m.help))
+	}
+	return xmlrpcResponse("<array><data><value>" + xmlrpcStringArray(m.signature) + "</value></data></array>")
+}
+
+// call the methods in the array of {methodName, params} structs one by one, the result of each
+// method is returned as an array with the result, or a {faultCode, faultString} struct if it fails
+func (h *xmlrpcSystemHandler) multicall(w http.ResponseWriter, r *http.Request, call xmlrpcMethodCall) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	if len(call.Params) != 1 || !strings.Contains(call.Params[0].Inner, "<array") {
+		w.Write([]byte(xmlrpcFault(faults.IncorrectParameters, "INCORRECT_PARAMETERS: system.multicall requires an array of calls")))
+		return
+	}
+	result := bytes.NewBufferString("<methodResponse><params><param><value><array><data>")
+	for _, c := range call.Params[0].Array {
+		result.WriteString("<value>")
+		result.WriteString(h.callInMulticall(r, c))
+		result.WriteString("</value>")
+	}
+	result.WriteString("</data></array></value></param></params></methodResponse>")
+	w.Write(result.Bytes())
+}
+
+// call a method of system.multicall, the XML in the <value> of the result is returned
+func (h *xmlrpcSystemHandler) callInMulticall(r *http.Request, c xmlrpcValue) string {
+	method := ""
+	params := make([]xmlrpcValue, 0)
+	for _, member := range c.Struct {
+		switch member.Name {
+		case "methodName":
+			method = member.Value.text()
+		case "params":
+			params = member.Value.Array
+		}
+	}
+	if method == "" {
+		return xmlrpcFaultValue(faults.IncorrectParameters, "INCORRECT_PARAMETERS: the methodName is missing")
+	}
+	if method == "system.multicall" {
+		return xmlrpcFaultValue(faults.IncorrectParameters, "INCORRECT_PARAMETERS: recursive system.multicall forbidden")
+	}
+
+	request := bytes.NewBufferString("<?xml version=\"1.0\"?><methodCall><methodName>")
+	xml.EscapeText(request, []byte(method))
+	request.WriteString("</methodName><params>")
+	for _, param := range params {
+		request.WriteString("<param><value>")
+		request.WriteString(param.Inner)
+		request.WriteString("</value></param>")
+	}
+	request.WriteString("</params></methodCall>")
+	req, err := http.NewRequest("POST", r.URL.String(), request)
+	if err != nil {
+		return xmlrpcFaultValue(faults.Failed, fmt.Sprintf("FAILED: %v", err))
+	}
+	req = req.WithContext(r.Context())
+	req.Header.Set("Content-Type", "text/xml")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	req.RemoteAddr = r.RemoteAddr
+
+	resp := &xmlrpcResponseBuffer{header: make(http.Header), status: http.StatusOK}
+	h.ServeHTTP(resp, req)
+	var response xmlrpcMethodResponse
+	if resp.status != http.StatusOK || xml.Unmarshal(resp.body.Bytes(), &response) != nil {
+		// the rpc server replies the error in plain text if the method is not found
+		return xmlrpcFaultValue(faults.UnknownMethod, "UNKNOWN_METHOD: "+strings.TrimSpace(resp.body.String()))
+	}
+	if response.Fault != nil {
+		return response.Fault.Inner
+	}
+	result := bytes.NewBufferString("<array><data>")
+	for _, param := range response.Params {
+		result.WriteString("<value>")
+		result.WriteString(param.Inner)
+		result.WriteString("</value>")
+	}
+	result.WriteString("</data></array>")
+	return result.String()
+}
+
+// get the string in <value><string>...</string></value> or <value>...</value>
+func (v xmlrpcValue) text() string {
+	if v.String != nil {
+		return *v.String
+	}
+	if strings.Contains(v.Inner, "<") {
+		return ""
+	}
+	var s string
+	xml.Unmarshal([]byte("<s>"+v.Inner+"</s>"), &s)
+	return s
+}
+
+// the XML in <value> of the string
+func xmlrpcString(s string) string {
+	buf := bytes.NewBufferString("<string>")
+	xml.EscapeText(buf, []byte(s))
+	buf.WriteString("</string>")
+	return buf.String()
+}
+
+// the XML in <value> of the array of strings
+func xmlrpcStringArray(values []string) string {
+	buf := bytes.NewBufferString("<array><data>")
+	for _, s := range values {
+		buf.WriteString("<value>" + xmlrpcString(s) + "</value>")
+	}
+	buf.WriteString("</data></array>")
+	return buf.String()
+}
+
+// the XML in <value> of the {faultCode, faultString} struct
+func xmlrpcFaultValue(code int, message string) string {
+	buf := bytes.NewBufferString(fmt.Sprintf("<struct><member><name>faultCode</name><value><int>%d</int></value></member>", code))
+	buf.WriteString("<member><name>faultString</name><value>" + xmlrpcString(message) + "</value></member></struct>")
+	return buf.String()
+}
+
+// the response of the method with the XML in <value> of the result
+func xmlrpcResponse(value string) string {
+	return "<methodResponse><params><param><value>" + value + "</value></param></params></methodResponse>"
+}
+
+// the fault response of the method
+func xmlrpcFault(code int, message string) string {
+	return "<methodResponse><fault><value>" + xmlrpcFaultValue(code, message) + "</value></fault></methodResponse>"
+}
@@ -5,5 +5,8 @@ go 1.16
 require (
 	github.com/gorilla/rpc v1.2.0 // indirect
 	github.com/ochinchina/gorilla-xmlrpc v0.0.0-20171012055324-ecf2fe693a2c
+	github.com/ochinchina/supervisord/types v0.0.0-20220520055329-8fdf6b62a44f
 	github.com/rogpeppe/go-charset v0.0.0-20190617161244-0dc95cdf6f31 // indirect
 )
+
+replace github.com/ochinchina/supervisord/types => ../types
@@ -0,0 +1,238 @@
+package xmlrpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ochinchina/supervisord/types"
+
+	"github.com/ochinchina/gorilla-xmlrpc/xml"
+)
+
+const (
+	// the max bytes of log read by one tail request in Follow
+	followChunkSize = 10240
+
+	// the interval to poll the log if no new log is available
+	followPollInterval = time.Second
+)
+
+// ErrStreamNotAvailable the log streaming endpoint of supervisord is not available, like it is disabled by
+// the endpoints of the http server
+var ErrStreamNotAvailable = errors.New("the log streaming endpoint is not available")
+
+// TailLogReply the log tailed from the program
+type TailLogReply struct {
+	// the log data read from the offset
+	LogData string
+	// the offset for the next read
+	Offset int
+	// true if the requested offset is at or beyond the end of log
+	Overflow bool
+}
+
+// TailProcessStdoutLog reads at most length bytes of the program stdout log from offset
+func (r *XMLRPCClient) TailProcessStdoutLog(ctx context.Context, name string, offset int, length int) (reply TailLogReply, err error) {
+	return r.tailProcessLog(ctx, "supervisor.tailProcessStdoutLog", name, offset, length)
+}
+
+// TailProcessStderrLog reads at most length bytes of the program stderr log from offset
+func (r *XMLRPCClient) TailProcessStderrLog(ctx context.Context, name string, offset int, length int) (reply TailLogReply, err error) {
+	return r.tailProcessLog(ctx, "supervisor.tailProcessStderrLog", name, offset, length)
+}
+
+func (r *XMLRPCClient) tailProcessLog(ctx context.Context, method string, name string, offset int, length int) (reply TailLogReply, err error) {
+	ins := struct {
+		Name   string
+		Offset int
+		Length int
+	}{
+		Name:   name,
+		Offset: offset,
+		Length: length,
+	}
+	return r.tailLog(ctx, method, &ins, fmt.Errorf("Fail to tail log of program %s", name))
+}
+
+// TailLog reads at most length bytes of the supervisord log from offset
+func (r *XMLRPCClient) TailLog(ctx context.Context, offset int, length int) (reply TailLogReply, err error) {
+	ins := struct {
+		Offset int
+		Length int
+	}{
+		Offset: offset,
+		Length: length,
+	}
+	return r.tailLog(ctx, "supervisor.tailLog", &ins, fmt.Errorf("Fail to tail log of supervisord"))
+}
+
+// ReadProcessStdoutLog reads length bytes of the program stdout log from offset, the offset and length
+// follow supervisor.readProcessStdoutLog, a negative offset reads the last -offset bytes with length 0
+func (r *XMLRPCClient) ReadProcessStdoutLog(ctx context.Context, name string, offset int, length int) (string, error) {
+	return r.readProcessLog(ctx, "supervisor.readProcessStdoutLog", name, offset, length)
+}
+
+// ReadProcessStderrLog reads length bytes of the program stderr log from offset like ReadProcessStdoutLog
+func (r *XMLRPCClient) ReadProcessStderrLog(ctx context.Context, name string, offset int, length int) (string, error) {
+	return r.readProcessLog(ctx, "supervisor.readProcessStderrLog", name, offset, length)
+}
+
+func (r *XMLRPCClient) readProcessLog(ctx context.Context, method string, name string, offset int, length int) (string, error) {
+	ins := struct {
+		Name   string
+		Offset int
+		Length int
+	}{
+		Name:   name,
+		Offset: offset,
+		Length: length,
+	}
+	return r.readLog(ctx, method, &ins)
+}
+
+// ReadLog reads length bytes of the supervisord log from offset like ReadProcessStdoutLog
+func (r *XMLRPCClient) ReadLog(ctx context.Context, offset int, length int) (string, error) {
+	ins := struct {
+		Offset int
+		Length int
+	}{
+		Offset: offset,
+		Length: length,
+	}
+	return r.readLog(ctx, "supervisor.readLog", &ins)
+}
+
+// call the read method with the arguments ins and get the log data
+func (r *XMLRPCClient) readLog(ctx context.Context, method string, ins interface{}) (data string, err error) {
+	reply := struct{ LogData string }{}
+	r.post(ctx, method, ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	// empty string is decoded as raw xml
+	if reply.LogData == "<string></string>" {
+		reply.LogData = ""
+	}
+	return reply.LogData, err
+}
+
+// ClearLog clears the supervisord log
+func (r *XMLRPCClient) ClearLog(ctx context.Context) (reply types.BooleanReply, err error) {
+	ins := struct{}{}
+	r.post(ctx, "supervisor.clearLog", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// ClearAllProcessLogs clears the stdout and stderr logs of all the programs
+func (r *XMLRPCClient) ClearAllProcessLogs(ctx context.Context) (reply AllProcStatusInfoReply, err error) {
+	ins := struct{}{}
+	r.post(ctx, "supervisor.clearAllProcessLogs", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// call the tail method with the arguments ins, connectErr is returned if fail to connect supervisord
+func (r *XMLRPCClient) tailLog(ctx context.Context, method string, ins interface{}, connectErr error) (reply TailLogReply, err error) {
+	// the body is not processed if fail to connect supervisord
+	err = connectErr
+	r.post(ctx, method, ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	// empty string is decoded as raw xml
+	if reply.LogData == "<string></string>" {
+		reply.LogData = ""
+	}
+	return
+}
+
+// Follow writes the tail of program stdout log and then the new log to writer until
+// error occurs, like "tail -f". The log is read from beginning again if it is rotated
+// or cleared
+func (r *XMLRPCClient) Follow(ctx context.Context, name string, writer io.Writer) error {
+	return r.FollowProcessLog(ctx, name, "stdout", followChunkSize, writer)
+}
+
+// FollowStderr works like Follow on the program stderr log
+func (r *XMLRPCClient) FollowStderr(ctx context.Context, name string, writer io.Writer) error {
+	return r.FollowProcessLog(ctx, name, "stderr", followChunkSize, writer)
+}
+
+// FollowProcessLog writes the last length bytes of the program stdout or stderr log and then the new log to
+// writer until error occurs, like TailFollow
+func (r *XMLRPCClient) FollowProcessLog(ctx context.Context, name string, stdType string, length int, writer io.Writer) error {
+	return r.TailFollow(ctx, name, stdType, length, func(chunk string) error {
+		_, err := io.WriteString(writer, chunk)
+		return err
+	})
+}
+
+// StreamProcessLog writes the last length bytes of the program stdout or stderr log and then the new log
+// streamed by the /logtail endpoint of supervisord to writer until the stream ends or error occurs.
+// ErrStreamNotAvailable is returned if supervisord does not serve the endpoint
+func (r *XMLRPCClient) StreamProcessLog(ctx context.Context, name string, stdType string, length int, writer io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.requestURL(fmt.Sprintf("/logtail/%s/%s?length=%d", url.PathEscape(name), stdType, length)), nil)
+	if err != nil {
+		return err
+	}
+	r.setAuthorization(req)
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return ErrStreamNotAvailable
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("No program %s", name)
+	case resp.StatusCode/100 != 2:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+// FollowLog writes the last length bytes of the supervisord log and then the new log to
+// writer until error occurs, like Follow
+func (r *XMLRPCClient) FollowLog(ctx context.Context, length int, writer io.Writer) error {
+	// supervisord returns the end of log if the offset exceeds it
+	reply, err := r.TailLog(ctx, math.MaxInt32, 0)
+	if err != nil {
+		return err
+	}
+	offset := reply.Offset - length
+	if offset < 0 {
+		offset = 0
+	}
+	err = pollLog(ctx, func(offset int, length int) (TailLogReply, error) {
+		return r.TailLog(ctx, offset, length)
+	}, &offset, func(chunk string) error {
+		_, err := io.WriteString(writer, chunk)
+		return err
+	})
+	var handlerErr *tailHandlerError
+	if errors.As(err, &handlerErr) {
+		return handlerErr.err
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package xmlrpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy the policy to retry the failed calls, like the connection refused while supervisord is restarting
+type RetryPolicy struct {
+	// the max number of the retries after the first attempt, 0 for no retry
+	MaxRetries int
+	// the delay before the first retry, it is doubled for every next retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// check if the call failed by the error can be retried, IsRetryableError is used if nil
+	Retryable func(err error) bool
+}
+
+// StatusError the http response of supervisord is not successful
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Bad response with status code %d", e.StatusCode)
+}
+
+// SetRetryPolicy sets the policy to retry the failed calls, the calls are not retried by default
+func (r *XMLRPCClient) SetRetryPolicy(policy RetryPolicy) {
+	r.retryPolicy = policy
+}
+
+// IsRetryableError checks if the call failed by the error is not processed by supervisord and can be retried
+// safely: the connection is refused or the unix socket does not exist while supervisord is restarting, or
+// supervisord answers 429 Too Many Requests or 503 Service Unavailable
+func IsRetryableError(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+}
+
+// post the request like send and retry it by the retry policy if it fails by the retryable error, the
+// last error is passed to processBody if all the attempts fail or ctx is done
+func (r *XMLRPCClient) post(ctx context.Context, method string, data interface{}, processBody func(io.ReadCloser, error)) {
+	policy := r.retryPolicy
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		var retryErr error
+		r.send(ctx, method, data, func(body io.ReadCloser, err error) {
+			if err != nil && attempt < policy.MaxRetries && ctx.Err() == nil && retryable(err) {
+				retryErr = err
+				return
+			}
+			processBody(body, err)
+		})
+		if retryErr == nil {
+			return
+		}
+		if r.verbose {
+			fmt.Printf("Retry %s in %v after error: %v\n", method, backoff, retryErr)
+		}
+		select {
+		case <-ctx.Done():
+			processBody(emptyReader, ctx.Err())
+			return
+		case <-time.After(backoff):
+		}
+		backoff = policy.nextBackoff(backoff)
+	}
+}
+
+// get the delay before the next retry, the delay of the last retry doubled up to MaxBackoff
+func (policy RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return backoff
+}
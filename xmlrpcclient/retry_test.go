@@ -0,0 +1,110 @@
+package xmlrpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const versionResponse = `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>4.0</string></value></param></params></methodResponse>`
+
+func TestRetryRefusedConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	rpcc := NewXMLRPCClient("http://"+addr, false)
+	if _, err := rpcc.GetVersion(context.Background()); err == nil || !IsRetryableError(err) {
+		t.Fatalf("the refused connection should fail with the retryable error without the retry policy, but get %v", err)
+	}
+
+	// supervisord is listening again after the first attempts are refused
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, versionResponse)
+	}))
+	started := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err == nil {
+			server.Listener = l
+			server.Start()
+		}
+		started <- err
+	}()
+	rpcc.SetRetryPolicy(RetryPolicy{MaxRetries: 10, Backoff: 50 * time.Millisecond})
+	reply, err := rpcc.GetVersion(context.Background())
+	if err := <-started; err != nil {
+		t.Skipf("fail to listen on %s again: %v", addr, err)
+	}
+	defer server.Close()
+	if err != nil || reply.Value != "4.0" {
+		t.Errorf("the call should succeed after the retries, but get %v, %v", reply, err)
+	}
+}
+
+func TestRetryStatusCodes(t *testing.T) {
+	for status, attempts := range map[int]int32{http.StatusServiceUnavailable: 3, http.StatusTooManyRequests: 3, http.StatusInternalServerError: 1} {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(status)
+		}))
+		rpcc := NewXMLRPCClient(server.URL, false)
+		rpcc.SetRetryPolicy(RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+		_, err := rpcc.GetVersion(context.Background())
+		server.Close()
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Errorf("the call should fail with the status %d, but get %v", status, err)
+		}
+		if calls != attempts {
+			t.Errorf("the call with the status %d should be sent %d times, but get %d", status, attempts, calls)
+		}
+	}
+}
+
+func TestRetryBackoffCapped(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	backoff := policy.Backoff
+	for _, expected := range []time.Duration{200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond} {
+		backoff = policy.nextBackoff(backoff)
+		if backoff != expected {
+			t.Errorf("the backoff should be %v, but get %v", expected, backoff)
+		}
+	}
+	if backoff := (RetryPolicy{}).nextBackoff(time.Second); backoff != 2*time.Second {
+		t.Errorf("the backoff without MaxBackoff should be doubled, but get %v", backoff)
+	}
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	rpcc := NewXMLRPCClient(server.URL, false)
+	rpcc.SetRetryPolicy(RetryPolicy{MaxRetries: 5, Backoff: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := rpcc.GetVersion(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the call cancelled in the backoff should return the error of ctx, but get %v", err)
+	}
+	if time.Since(start) > 10*time.Second || calls != 1 {
+		t.Errorf("the call should return once ctx is done without retrying, but get %d calls", calls)
+	}
+}
@@ -0,0 +1,159 @@
+package xmlrpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"syscall"
+	"time"
+)
+
+// TailHandler is called by TailFollow with every chunk of the log in order, TailFollow stops and returns the
+// error if it returns error
+type TailHandler func(chunk string) error
+
+// TailFollow calls handler with the last length bytes of the program stdout or stderr log given by stream and
+// then with every new chunk of the log until ctx is done or error occurs. The log is streamed by the /logtail
+// endpoint of supervisord if it is available, otherwise it is polled by the tail RPC. If the connection is lost
+// after the log is read, like supervisord is restarting, it is connected again after the backoff of the retry
+// policy and the log is resumed from where it was, or from the beginning if it is rotated or cleared
+func (r *XMLRPCClient) TailFollow(ctx context.Context, name string, stream string, length int, handler TailHandler) error {
+	tail := r.TailProcessStdoutLog
+	switch stream {
+	case "stdout":
+	case "stderr":
+		tail = r.TailProcessStderrLog
+	default:
+		return fmt.Errorf("Unknown log stream %s", stream)
+	}
+	retryable := r.retryPolicy.Retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+	backoff := r.retryPolicy.Backoff
+	if backoff <= 0 {
+		backoff = followPollInterval
+	}
+
+	// the offset of the log passed to handler, -1 before the log is read
+	offset := -1
+	streamAvailable := true
+	for {
+		err := r.tailFollowOnce(ctx, name, stream, tail, length, &offset, &streamAvailable, handler)
+		var handlerErr *tailHandlerError
+		switch {
+		case errors.As(err, &handlerErr):
+			return handlerErr.err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case offset == -1:
+			// not connected yet, the failed calls are retried by the retry policy already
+			return err
+		case err != nil && !retryable(err) && !isConnectionLost(err):
+			return err
+		}
+		if r.verbose {
+			fmt.Printf("Reconnect to follow the log of %s in %v after error: %v\n", name, backoff, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if r.retryPolicy.MaxBackoff > 0 && backoff*2 <= r.retryPolicy.MaxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// check if the error is caused by the connection closed by supervisord while the log is streamed
+func isConnectionLost(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// the error returned by the TailHandler
+type tailHandlerError struct {
+	err error
+}
+
+func (e *tailHandlerError) Error() string {
+	return e.err.Error()
+}
+
+// follow the log from offset, or the last length bytes if offset is -1, until the connection is lost. The
+// offset is moved forward by the log passed to handler
+func (r *XMLRPCClient) tailFollowOnce(ctx context.Context, name string, stream string, tail func(context.Context, string, int, int) (TailLogReply, error), length int, offset *int, streamAvailable *bool, handler TailHandler) error {
+	// supervisord returns the end of log if the offset exceeds it
+	reply, err := tail(ctx, name, math.MaxInt32, 0)
+	if err != nil {
+		return err
+	}
+	end := reply.Offset
+	start := *offset
+	if start == -1 {
+		start = end - length
+		if start < 0 {
+			start = 0
+		}
+	} else if end < start {
+		// the log is rotated or cleared
+		start = 0
+	}
+	*offset = start
+
+	if *streamAvailable {
+		err = r.StreamProcessLog(ctx, name, stream, end-start, &tailHandlerWriter{handler: handler, offset: offset})
+		if err != ErrStreamNotAvailable {
+			return err
+		}
+		*streamAvailable = false
+	}
+	return pollLog(ctx, func(offset int, length int) (TailLogReply, error) {
+		return tail(ctx, name, offset, length)
+	}, offset, handler)
+}
+
+// poll the log from offset by the tail function and call handler with the new log until ctx is done or
+// error occurs. The offset is moved forward by the log passed to handler, and the log is read from the
+// beginning again if it is rotated or cleared
+func pollLog(ctx context.Context, tail func(offset int, length int) (TailLogReply, error), offset *int, handler TailHandler) error {
+	for {
+		reply, err := tail(*offset, followChunkSize)
+		if err != nil {
+			return err
+		}
+		if reply.Offset < *offset {
+			*offset = 0
+			continue
+		}
+		if len(reply.LogData) > 0 {
+			if err = handler(reply.LogData); err != nil {
+				return &tailHandlerError{err: err}
+			}
+		}
+		*offset = reply.Offset
+		if len(reply.LogData) < followChunkSize {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(followPollInterval):
+			}
+		}
+	}
+}
+
+// tailHandlerWriter passes the streamed log to the TailHandler and moves the offset forward by it
+type tailHandlerWriter struct {
+	handler TailHandler
+	offset  *int
+}
+
+func (w *tailHandlerWriter) Write(p []byte) (int, error) {
+	if err := w.handler(string(p)); err != nil {
+		return 0, &tailHandlerError{err: err}
+	}
+	*w.offset += len(p)
+	return len(p), nil
+}
@@ -0,0 +1,198 @@
+package xmlrpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errStopTail = errors.New("stop")
+
+// fakeLog serves the log like supervisord tails it, the offset beyond the end of log returns the end
+type fakeLog struct {
+	lock sync.Mutex
+	data string
+}
+
+func (l *fakeLog) tail(offset int, length int) TailLogReply {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if offset >= len(l.data) {
+		return TailLogReply{Offset: len(l.data), Overflow: true}
+	}
+	end := offset + length
+	if end > len(l.data) {
+		end = len(l.data)
+	}
+	return TailLogReply{LogData: l.data[offset:end], Offset: end}
+}
+
+func (l *fakeLog) set(data string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.data = data
+}
+
+var intParam = regexp.MustCompile(`<(?:int|i4)>(-?\d+)</(?:int|i4)>`)
+
+// serve the tail RPC of the log, drop is called before every tail request and the connection is closed
+// without the response if it returns true. The log is not streamed by /logtail
+func tailRPCHandler(t *testing.T, log *fakeLog, drop func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/RPC2" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if drop() {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		params := intParam.FindAllStringSubmatch(string(body), -1)
+		if len(params) != 2 {
+			t.Errorf("the tail request should have the offset and length, but get %s", body)
+			return
+		}
+		offset, _ := strconv.Atoi(params[0][1])
+		length, _ := strconv.Atoi(params[1][1])
+		reply := log.tail(offset, length)
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>%s</string></value></param><param><value><int>%d</int></value></param><param><value><boolean>%d</boolean></value></param></params></methodResponse>`,
+			html.EscapeString(reply.LogData), reply.Offset, map[bool]int{false: 0, true: 1}[reply.Overflow])
+	}
+}
+
+func TestPollLogResetsOffsetAfterRotation(t *testing.T) {
+	log := &fakeLog{data: "hello\n"}
+	var chunks []string
+	offset := 0
+	rotated := false
+	err := pollLog(context.Background(), func(offset int, length int) (TailLogReply, error) {
+		if offset == len("hello\n") && !rotated {
+			// the log is rotated after the first read
+			rotated = true
+			log.set("new\n")
+		}
+		return log.tail(offset, length), nil
+	}, &offset, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		if len(chunks) == 2 {
+			return errStopTail
+		}
+		return nil
+	})
+	var handlerErr *tailHandlerError
+	if !errors.As(err, &handlerErr) || handlerErr.err != errStopTail {
+		t.Errorf("the error of handler should be returned, but get %v", err)
+	}
+	if strings.Join(chunks, "") != "hello\nnew\n" {
+		t.Errorf("the log should be read from the beginning after rotation, but get %q", chunks)
+	}
+	if offset != 0 {
+		t.Errorf("the offset should not be moved by the chunk failed by handler, but get %d", offset)
+	}
+}
+
+func TestPollLogTailError(t *testing.T) {
+	offset := 3
+	tailErr := errors.New("tail failed")
+	err := pollLog(context.Background(), func(offset int, length int) (TailLogReply, error) {
+		return TailLogReply{}, tailErr
+	}, &offset, func(chunk string) error {
+		t.Errorf("the handler should not be called, but get %q", chunk)
+		return nil
+	})
+	if err != tailErr || offset != 3 {
+		t.Errorf("the error of tail should be returned with the offset unchanged, but get %v, %d", err, offset)
+	}
+}
+
+func TestTailFollowReconnectsAfterConnectionDropped(t *testing.T) {
+	log := &fakeLog{data: "hello\n"}
+	var lock sync.Mutex
+	var chunks []string
+	dropped := false
+	server := httptest.NewServer(tailRPCHandler(t, log, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		// drop the connection once after the log is read and then truncate the log
+		if dropped || len(chunks) == 0 {
+			return false
+		}
+		dropped = true
+		log.set("new\n")
+		return true
+	}))
+	defer server.Close()
+
+	rpcc := NewXMLRPCClient(server.URL, false)
+	rpcc.SetRetryPolicy(RetryPolicy{Backoff: 10 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := rpcc.TailFollow(ctx, "web", "stdout", 1024, func(chunk string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		chunks = append(chunks, chunk)
+		if dropped {
+			return errStopTail
+		}
+		return nil
+	})
+	if err != errStopTail {
+		t.Errorf("the error of handler should be returned, but get %v", err)
+	}
+	if !dropped {
+		t.Error("the connection should be dropped")
+	}
+	if got := strings.Join(chunks, ""); got != "hello\nnew\n" {
+		t.Errorf("the log should be resumed from the beginning after reconnect, but get %q", got)
+	}
+}
+
+func TestTailFollowStreamHandlerError(t *testing.T) {
+	// the end of log is got by the tail RPC before the log is streamed
+	tailRPC := tailRPCHandler(t, &fakeLog{data: "hello\n"}, func() bool { return false })
+	streamed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logtail/web/stderr" {
+			tailRPC(w, r)
+			return
+		}
+		streamed = true
+		fmt.Fprint(w, "hello\n")
+	}))
+	defer server.Close()
+
+	rpcc := NewXMLRPCClient(server.URL, false)
+	err := rpcc.TailFollow(context.Background(), "web", "stderr", 1024, func(chunk string) error {
+		return errStopTail
+	})
+	if err != errStopTail {
+		t.Errorf("the error of handler should be returned, but get %v", err)
+	}
+	if !streamed {
+		t.Error("the log should be streamed by /logtail")
+	}
+}
+
+func TestTailFollowUnknownStream(t *testing.T) {
+	rpcc := NewXMLRPCClient("http://127.0.0.1:9001", false)
+	if err := rpcc.TailFollow(context.Background(), "web", "stdin", 1024, func(chunk string) error { return nil }); err == nil {
+		t.Error("the unknown log stream should fail")
+	}
+}
@@ -1,15 +1,17 @@
 package xmlrpcclient
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ochinchina/supervisord/types"
@@ -22,8 +24,14 @@ type XMLRPCClient struct {
 	serverurl string
 	user      string
 	password  string
-	timeout   time.Duration
-	verbose   bool
+	token     string
+	// the http client keeping the connections alive between the calls, with the TLS configuration set by
+	// SetTLSConfig and the proxy set by SetProxy
+	httpClient  *http.Client
+	tlsConfig   *tls.Config
+	proxy       *url.URL
+	retryPolicy RetryPolicy
+	verbose     bool
 }
 
 // VersionReply the version reply message from supervisor
@@ -36,6 +44,12 @@ type StartStopReply struct {
 	Value bool
 }
 
+// StateReply the state of supervisord, the statename is one of FATAL, RUNNING, RESTARTING and SHUTDOWN
+type StateReply struct {
+	Statecode int    `xml:"statecode"`
+	Statename string `xml:"statename"`
+}
+
 // ShutdownReply the program shutdown reply message
 type ShutdownReply StartStopReply
 
@@ -53,7 +67,9 @@ func init() {
 
 // NewXMLRPCClient creates XMLRPCClient object
 func NewXMLRPCClient(serverurl string, verbose bool) *XMLRPCClient {
-	return &XMLRPCClient{serverurl: serverurl, timeout: 0, verbose: verbose}
+	r := &XMLRPCClient{serverurl: serverurl, verbose: verbose}
+	r.updateHTTPClient()
+	return r
 }
 
 // SetUser sets username for basic http auth
@@ -66,9 +82,117 @@ func (r *XMLRPCClient) SetPassword(password string) {
 	r.password = password
 }
 
-// SetTimeout sets http request timeout
-func (r *XMLRPCClient) SetTimeout(timeout time.Duration) {
-	r.timeout = timeout
+// SetToken sets the bearer token used instead of the username and password
+func (r *XMLRPCClient) SetToken(token string) {
+	r.token = token
+}
+
+// SetTLSConfig sets the TLS configuration of the https connections, like the CA certificates to verify the server
+func (r *XMLRPCClient) SetTLSConfig(tlsConfig *tls.Config) {
+	r.tlsConfig = tlsConfig
+	r.updateHTTPClient()
+}
+
+// SetProxy sets the URL of the proxy to connect to the http or https server, like http://bastion:3128 or
+// socks5://bastion:1080, instead of the proxies of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables. The empty URL restores the environment proxies
+func (r *XMLRPCClient) SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		r.proxy = nil
+		r.updateHTTPClient()
+		return nil
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q in %s", proxy.Scheme, proxyURL)
+	}
+	if proxy.Host == "" {
+		return fmt.Errorf("no proxy host in %s", proxyURL)
+	}
+	r.proxy = proxy
+	r.updateHTTPClient()
+	return nil
+}
+
+// the max number of the idle connections kept alive to supervisord
+const maxIdleConns = 4
+
+// create the http client by the TLS configuration and the proxy, the connections to the unix socket of the
+// unix URL are pooled like the tcp connections
+func (r *XMLRPCClient) updateHTTPClient() {
+	if r.httpClient != nil {
+		r.httpClient.CloseIdleConnections()
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     r.tlsConfig,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConns,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if r.proxy != nil {
+		transport.Proxy = http.ProxyURL(r.proxy)
+	}
+	if myurl, err := url.Parse(r.serverurl); err == nil && myurl.Scheme == "unix" {
+		path := myurl.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+	r.httpClient = &http.Client{Transport: transport}
+}
+
+// CloseIdleConnections closes the connections kept alive to supervisord which are not used by any call
+func (r *XMLRPCClient) CloseIdleConnections() {
+	r.httpClient.CloseIdleConnections()
+}
+
+// TLSOptions the files and the verification of the https connections
+type TLSOptions struct {
+	// the PEM bundle of the CA certificates to verify the server instead of the system ones
+	CAFile string
+	// the PEM files of the client certificate and its private key for the server requiring the client certificate
+	CertFile string
+	KeyFile  string
+	// skip the verification of the server certificate, only for testing
+	InsecureSkipVerify bool
+}
+
+// SetTLSOptions loads the CA bundle and the client certificate in the options and sets the TLS configuration
+// of the https connections by them
+func (r *XMLRPCClient) SetTLSOptions(options TLSOptions) error {
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+	if options.CAFile != "" {
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate is found in %s", options.CAFile)
+		}
+	}
+	if options.CertFile != "" {
+		keyFile := options.KeyFile
+		if keyFile == "" {
+			// the private key may be in the same file as the certificate
+			keyFile = options.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(options.CertFile, keyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	r.SetTLSConfig(tlsConfig)
+	return nil
 }
 
 // URL returns RPC url
@@ -76,6 +200,15 @@ func (r *XMLRPCClient) URL() string {
 	return fmt.Sprintf("%s/RPC2", r.serverurl)
 }
 
+// get the URL of the path on the server, the host of the unix URL is replaced as the connection is dialed
+// to the socket path
+func (r *XMLRPCClient) requestURL(path string) string {
+	if strings.HasPrefix(r.serverurl, "unix:") {
+		return "http://unix" + path
+	}
+	return r.serverurl + path
+}
+
 func (r *XMLRPCClient) createHTTPRequest(method string, url string, data interface{}) (*http.Request, error) {
 	buf, _ := xml.EncodeClientRequest(method, data)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(buf))
@@ -86,115 +219,78 @@ func (r *XMLRPCClient) createHTTPRequest(method string, url string, data interfa
 		return nil, err
 	}
 
-	if len(r.user) > 0 && len(r.password) > 0 {
-		req.SetBasicAuth(r.user, r.password)
-	}
-
+	r.setAuthorization(req)
 	req.Header.Set("Content-Type", "text/xml")
 
 	return req, nil
 }
 
+// set the bearer token or the basic authentication of the request
+func (r *XMLRPCClient) setAuthorization(req *http.Request) {
+	if len(r.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	} else if len(r.user) > 0 && len(r.password) > 0 {
+		req.SetBasicAuth(r.user, r.password)
+	}
+}
+
 func (r *XMLRPCClient) processResponse(resp *http.Response, processBody func(io.ReadCloser, error)) {
 	defer resp.Body.Close()
+	// the connection is reused only if the body is read to the end
+	defer io.Copy(ioutil.Discard, resp.Body)
 
 	if resp.StatusCode/100 != 2 {
 		if r.verbose {
 			fmt.Println("Bad Response:", resp.Status)
 		}
-		processBody(emptyReader, fmt.Errorf("Bad response with status code %d", resp.StatusCode))
+		processBody(emptyReader, &StatusError{StatusCode: resp.StatusCode})
 	} else {
 		processBody(resp.Body, nil)
 	}
 }
 
-func (r *XMLRPCClient) postInetHTTP(method string, url string, data interface{}, processBody func(io.ReadCloser, error)) {
+func (r *XMLRPCClient) postHTTP(ctx context.Context, method string, url string, data interface{}, processBody func(io.ReadCloser, error)) {
 	req, err := r.createHTTPRequest(method, url, data)
 	if err != nil {
+		processBody(emptyReader, err)
 		return
 	}
+	req = req.WithContext(ctx)
 
-	if r.timeout > 0 {
-		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
-		defer cancel()
-		req = req.WithContext(ctx)
-	}
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		if r.verbose {
 			fmt.Println("Fail to send request to supervisord:", err)
 		}
+		processBody(emptyReader, err)
 		return
 	}
 	r.processResponse(resp, processBody)
 
 }
 
-func (r *XMLRPCClient) postUnixHTTP(method string, path string, data interface{}, processBody func(io.ReadCloser, error)) {
-	var conn net.Conn
-	var err error
-	if r.timeout > 0 {
-		conn, err = net.DialTimeout("unix", path, r.timeout)
-	} else {
-		conn, err = net.Dial("unix", path)
-	}
-	if err != nil {
-		if r.verbose {
-			fmt.Printf("Fail to connect unix socket path: %s\n", r.serverurl)
-		}
-		return
-	}
-	defer conn.Close()
-
-	if r.timeout > 0 {
-		if err := conn.SetDeadline(time.Now().Add(r.timeout)); err != nil {
-			return
-		}
-	}
-	req, err := r.createHTTPRequest(method, "/RPC2", data)
-
-	if err != nil {
-		return
-	}
-	err = req.Write(conn)
-	if err != nil {
-		if r.verbose {
-			fmt.Printf("Fail to write to unix socket %s\n", r.serverurl)
-		}
-		return
-	}
-	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
-	if err != nil {
-		if r.verbose {
-			fmt.Printf("Fail to read response %s\n", err)
-		}
-		return
-	}
-	r.processResponse(resp, processBody)
-
-}
-
-func (r *XMLRPCClient) post(method string, data interface{}, processBody func(io.ReadCloser, error)) {
+// send the XML-RPC request of the method until the response is processed or ctx is done, the error to send the
+// request or read the response is passed to processBody with the empty body
+func (r *XMLRPCClient) send(ctx context.Context, method string, data interface{}, processBody func(io.ReadCloser, error)) {
 	myurl, err := url.Parse(r.serverurl)
 	if err != nil {
 		fmt.Printf("Malform url:%s\n", myurl)
+		processBody(emptyReader, err)
 		return
 	}
-	if myurl.Scheme == "http" || myurl.Scheme == "https" {
-		r.postInetHTTP(method, r.URL(), data, processBody)
-	} else if myurl.Scheme == "unix" {
-		r.postUnixHTTP(method, myurl.Path, data, processBody)
+	if myurl.Scheme == "http" || myurl.Scheme == "https" || myurl.Scheme == "unix" {
+		r.postHTTP(ctx, method, r.requestURL("/RPC2"), data, processBody)
 	} else {
 		fmt.Printf("Unsupported URL scheme:%s\n", myurl.Scheme)
+		processBody(emptyReader, fmt.Errorf("Unsupported URL scheme %s", myurl.Scheme))
 	}
 
 }
 
 // GetVersion sends http request to acquire software version of supervisord
-func (r *XMLRPCClient) GetVersion() (reply VersionReply, err error) {
+func (r *XMLRPCClient) GetVersion(ctx context.Context) (reply VersionReply, err error) {
 	ins := struct{}{}
-	r.post("supervisor.getVersion", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.getVersion", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -204,9 +300,9 @@ func (r *XMLRPCClient) GetVersion() (reply VersionReply, err error) {
 }
 
 // GetAllProcessInfo requests all info about supervised processes
-func (r *XMLRPCClient) GetAllProcessInfo() (reply AllProcessInfoReply, err error) {
+func (r *XMLRPCClient) GetAllProcessInfo(ctx context.Context) (reply AllProcessInfoReply, err error) {
 	ins := struct{}{}
-	r.post("supervisor.getAllProcessInfo", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.getAllProcessInfo", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -216,15 +312,115 @@ func (r *XMLRPCClient) GetAllProcessInfo() (reply AllProcessInfoReply, err error
 	return
 }
 
+// GetPID gets the pid of supervisord
+func (r *XMLRPCClient) GetPID(ctx context.Context) (pid int, err error) {
+	ins := struct{}{}
+	result := struct{ Pid int }{}
+	r.post(ctx, "supervisor.getPID", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				pid = result.Pid
+			}
+		}
+	})
+
+	return
+}
+
+// GetIdentification gets the identifier of supervisord set by the identifier option
+func (r *XMLRPCClient) GetIdentification(ctx context.Context) (id string, err error) {
+	ins := struct{}{}
+	result := struct{ ID string }{}
+	r.post(ctx, "supervisor.getIdentification", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				id = result.ID
+			}
+		}
+	})
+
+	return
+}
+
+// GetState gets the state of supervisord
+func (r *XMLRPCClient) GetState(ctx context.Context) (reply StateReply, err error) {
+	ins := struct{}{}
+	result := struct{ StateInfo StateReply }{}
+	r.post(ctx, "supervisor.getState", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.StateInfo
+			}
+		}
+	})
+
+	return
+}
+
+// GetAllConfigInfo gets the programs in the configuration, including the ones not added to supervisord
+func (r *XMLRPCClient) GetAllConfigInfo(ctx context.Context) (reply []types.ConfigInfo, err error) {
+	ins := struct{}{}
+	result := struct{ AllConfigInfo []types.ConfigInfo }{}
+	r.post(ctx, "supervisor.getAllConfigInfo", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.AllConfigInfo
+			}
+		}
+	})
+
+	return
+}
+
+// GetConfigDiff gets the programs which would be added, changed and removed by ReloadConfig with their
+// changed keys, supervisord rereads the configuration file without applying it
+func (r *XMLRPCClient) GetConfigDiff(ctx context.Context) (reply []types.ProgramConfigChange, err error) {
+	ins := struct{}{}
+	result := struct{ Changes []types.ProgramConfigChange }{}
+	r.post(ctx, "supervisor.getConfigDiff", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err != nil {
+			return
+		}
+		var data []byte
+		if data, err = ioutil.ReadAll(body); err != nil {
+			return
+		}
+		err = xml.DecodeClientResponse(bytes.NewReader(data), &result)
+		if err != nil && bytes.Contains(data, []byte("<array><data></data></array>")) {
+			// the empty array can't be decoded to the slice, no program is changed
+			err = nil
+		}
+	})
+	reply = result.Changes
+	for i := range reply {
+		for j := range reply[i].Keys {
+			// empty string is decoded as raw xml
+			key := &reply[i].Keys[j]
+			key.Old = strings.TrimPrefix(key.Old, "<string></string>")
+			key.New = strings.TrimPrefix(key.New, "<string></string>")
+		}
+	}
+	return
+}
+
 // ChangeProcessState requests to change given process state
-func (r *XMLRPCClient) ChangeProcessState(change string, processName string) (reply StartStopReply, err error) {
+func (r *XMLRPCClient) ChangeProcessState(ctx context.Context, change string, processName string) (reply StartStopReply, err error) {
 	if !(change == "start" || change == "stop") {
 		err = fmt.Errorf("Incorrect required state")
 		return
 	}
 
 	ins := struct{ Value string }{processName}
-	r.post(fmt.Sprintf("supervisor.%sProcess", change), &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, fmt.Sprintf("supervisor.%sProcess", change), &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -235,13 +431,13 @@ func (r *XMLRPCClient) ChangeProcessState(change string, processName string) (re
 }
 
 // ChangeAllProcessState requests to change all supervised programs to same state( start/stop )
-func (r *XMLRPCClient) ChangeAllProcessState(change string) (reply AllProcessInfoReply, err error) {
+func (r *XMLRPCClient) ChangeAllProcessState(ctx context.Context, change string) (reply AllProcessInfoReply, err error) {
 	if !(change == "start" || change == "stop") {
 		err = fmt.Errorf("Incorrect required state")
 		return
 	}
 	ins := struct{ Wait bool }{true}
-	r.post(fmt.Sprintf("supervisor.%sAllProcesses", change), &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, fmt.Sprintf("supervisor.%sAllProcesses", change), &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -251,9 +447,9 @@ func (r *XMLRPCClient) ChangeAllProcessState(change string) (reply AllProcessInf
 }
 
 // Shutdown requests to shut down supervisord
-func (r *XMLRPCClient) Shutdown() (reply ShutdownReply, err error) {
+func (r *XMLRPCClient) Shutdown(ctx context.Context) (reply ShutdownReply, err error) {
 	ins := struct{}{}
-	r.post("supervisor.shutdown", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.shutdown", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -264,8 +460,34 @@ func (r *XMLRPCClient) Shutdown() (reply ShutdownReply, err error) {
 	return
 }
 
+// Restart requests to restart supervisord
+func (r *XMLRPCClient) Restart(ctx context.Context) (reply types.BooleanReply, err error) {
+	ins := struct{}{}
+	r.post(ctx, "supervisor.restart", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
+// ReopenLogs asks supervisord to reopen its log and the log files of programs
+func (r *XMLRPCClient) ReopenLogs(ctx context.Context) (reply types.BooleanReply, err error) {
+	ins := struct{}{}
+	r.post(ctx, "supervisor.reopenLogs", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
 // ReloadConfig requests supervisord to reload its configuration
-func (r *XMLRPCClient) ReloadConfig() (reply types.ReloadConfigResult, err error) {
+func (r *XMLRPCClient) ReloadConfig(ctx context.Context) (reply types.ReloadConfigResult, err error) {
 	ins := struct{}{}
 
 	xmlProcMgr := NewXMLProcessorManager()
@@ -286,7 +508,7 @@ func (r *XMLRPCClient) ReloadConfig() (reply types.ReloadConfigResult, err error
 			reply.RemovedGroup = append(reply.RemovedGroup, value)
 		}
 	})
-	r.post("supervisor.reloadConfig", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.reloadConfig", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			xmlProcMgr.ProcessXML(body)
@@ -296,9 +518,9 @@ func (r *XMLRPCClient) ReloadConfig() (reply types.ReloadConfigResult, err error
 }
 
 // SignalProcess requests to send signal to program
-func (r *XMLRPCClient) SignalProcess(signal string, name string) (reply types.BooleanReply, err error) {
+func (r *XMLRPCClient) SignalProcess(ctx context.Context, signal string, name string) (reply types.BooleanReply, err error) {
 	ins := types.ProcessSignal{Name: name, Signal: signal}
-	r.post("supervisor.signalProcess", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.signalProcess", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -308,9 +530,9 @@ func (r *XMLRPCClient) SignalProcess(signal string, name string) (reply types.Bo
 }
 
 // SignalAll requests to send signal to all the programs
-func (r *XMLRPCClient) SignalAll(signal string) (reply AllProcessInfoReply, err error) {
-	ins := struct{ Signal string }{signal}
-	r.post("supervisor.signalProcess", &ins, func(body io.ReadCloser, procError error) {
+func (r *XMLRPCClient) SignalAll(ctx context.Context, signal string) (reply AllProcessInfoReply, err error) {
+	ins := types.ProcessSignal{Name: "all", Signal: signal}
+	r.post(ctx, "supervisor.signalAllProcesses", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -321,10 +543,10 @@ func (r *XMLRPCClient) SignalAll(signal string) (reply AllProcessInfoReply, err
 }
 
 // GetProcessInfo requests given supervised process information
-func (r *XMLRPCClient) GetProcessInfo(process string) (reply types.ProcessInfo, err error) {
+func (r *XMLRPCClient) GetProcessInfo(ctx context.Context, process string) (reply types.ProcessInfo, err error) {
 	ins := struct{ Name string }{process}
 	result := struct{ Reply types.ProcessInfo }{}
-	r.post("supervisor.getProcessInfo", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.getProcessInfo", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &result)
@@ -339,8 +561,269 @@ func (r *XMLRPCClient) GetProcessInfo(process string) (reply types.ProcessInfo,
 	return
 }
 
+// WaitForState waits until the program reaches the state like RUNNING, the reply is false if it does
+// not reach the state in timeout seconds
+func (r *XMLRPCClient) WaitForState(ctx context.Context, process string, state string, timeout int) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name    string
+		State   string
+		Timeout int
+	}{process, state, timeout}
+	r.post(ctx, "supervisor.waitForState", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
+// GetProcessLogUsage get the disk usage of the log files with their rotated backups of a program
+func (r *XMLRPCClient) GetProcessLogUsage(ctx context.Context, process string) (reply types.ProcessLogUsage, err error) {
+	ins := struct{ Name string }{process}
+	result := struct{ Reply types.ProcessLogUsage }{}
+	r.post(ctx, "supervisor.getProcessLogUsage", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Reply
+			}
+		}
+	})
+
+	return
+}
+
+// GetAllProcessLogUsage get the disk usage of the log files with their rotated backups of all the programs
+func (r *XMLRPCClient) GetAllProcessLogUsage(ctx context.Context) (reply []types.ProcessLogUsage, err error) {
+	ins := struct{}{}
+	result := struct{ AllUsage []types.ProcessLogUsage }{}
+	r.post(ctx, "supervisor.getAllProcessLogUsage", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.AllUsage
+			}
+		}
+	})
+	return
+}
+
+// GetProcessResourceUsage get the latest CPU, memory, fd, thread and child process usage of the program
+func (r *XMLRPCClient) GetProcessResourceUsage(ctx context.Context, process string) (reply types.ProcessResourceUsage, err error) {
+	ins := struct{ Name string }{process}
+	result := struct{ Reply types.ProcessResourceUsage }{}
+	r.post(ctx, "supervisor.getProcessResourceUsage", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Reply
+			}
+		}
+	})
+
+	return
+}
+
+// GetProcessEnvironment get the environment variables the program is started with, the secrets are masked
+func (r *XMLRPCClient) GetProcessEnvironment(ctx context.Context, process string) (reply types.ProcessEnvironment, err error) {
+	ins := struct{ Name string }{process}
+	result := struct{ Reply types.ProcessEnvironment }{}
+	r.post(ctx, "supervisor.getProcessEnvironment", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Reply
+			}
+		}
+	})
+
+	return
+}
+
+// GetAllProcessResourceUsage get the latest resource usage of all the programs
+func (r *XMLRPCClient) GetAllProcessResourceUsage(ctx context.Context) (reply []types.ProcessResourceUsage, err error) {
+	ins := struct{}{}
+	result := struct{ Reply []types.ProcessResourceUsage }{}
+	r.post(ctx, "supervisor.getAllProcessResourceUsage", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Reply
+			}
+		}
+	})
+
+	return
+}
+
+// SearchProcessLog search the current and rotated logs of a program for the lines matching the pattern,
+// since and until are the time window in unix seconds, 0 for no bound
+func (r *XMLRPCClient) SearchProcessLog(ctx context.Context, process string, pattern string, maxResults int, since int, until int) (reply types.LogSearchResult, err error) {
+	ins := struct {
+		Name       string
+		Pattern    string
+		MaxResults int
+		Since      int
+		Until      int
+	}{process, pattern, maxResults, since, until}
+	result := struct{ Reply types.LogSearchResult }{}
+	r.post(ctx, "supervisor.searchProcessLog", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Reply
+			}
+		}
+	})
+
+	return
+}
+
+// AddProcessGroup adds the group in the configuration which is not added yet and starts its autostart programs
+func (r *XMLRPCClient) AddProcessGroup(ctx context.Context, group string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.addProcessGroup", group)
+}
+
+// RemoveProcessGroup stops the programs of the group and removes them
+func (r *XMLRPCClient) RemoveProcessGroup(ctx context.Context, group string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.removeProcessGroup", group)
+}
+
+// AddProgram adds the [program:name] section given as its "key=value" lines or a JSON object and starts its
+// autostart programs
+func (r *XMLRPCClient) AddProgram(ctx context.Context, name string, config string) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name   string
+		Config string
+	}{name, config}
+	r.post(ctx, "supervisor.addProgram", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
+// ClearProcessLogs clears the stdout and stderr logs of the program
+func (r *XMLRPCClient) ClearProcessLogs(ctx context.Context, name string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.clearProcessLogs", name)
+}
+
+// RemoveProgram stops the programs of the [program:name] section and removes them
+func (r *XMLRPCClient) RemoveProgram(ctx context.Context, name string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.removeProgram", name)
+}
+
+// call the method taking a name and returning a boolean
+func (r *XMLRPCClient) callByName(ctx context.Context, method string, name string) (reply types.BooleanReply, err error) {
+	ins := struct{ Name string }{name}
+	r.post(ctx, method, &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
+// GetEventHistory get the events kept by supervisord matching the filter like "PROCESS_STATE,processname:web",
+// since is in unix seconds, 0 for no bound
+func (r *XMLRPCClient) GetEventHistory(ctx context.Context, filter string, since int) (reply []types.EventInfo, err error) {
+	ins := struct {
+		Filter string
+		Since  int
+	}{filter, since}
+	result := struct{ Events []types.EventInfo }{}
+	r.post(ctx, "supervisor.getEventHistory", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Events
+			}
+		}
+	})
+
+	return
+}
+
+// CreateToken creates a bearer token which expires after ttl seconds, or never if ttl is 0
+func (r *XMLRPCClient) CreateToken(ctx context.Context, name string, ttl int) (token string, err error) {
+	ins := struct {
+		Name string
+		TTL  int
+	}{name, ttl}
+	result := struct{ Token string }{}
+	r.post(ctx, "supervisor.createToken", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				token = result.Token
+			}
+		}
+	})
+
+	return
+}
+
+// RevokeToken revokes the bearer token
+func (r *XMLRPCClient) RevokeToken(ctx context.Context, name string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.revokeToken", name)
+}
+
+// ListTokens lists the bearer tokens without the tokens themselves
+func (r *XMLRPCClient) ListTokens(ctx context.Context) (reply []types.APIToken, err error) {
+	ins := struct{}{}
+	result := struct{ Tokens []types.APIToken }{}
+	r.post(ctx, "supervisor.listTokens", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				reply = result.Tokens
+			}
+		}
+	})
+
+	return
+}
+
+// ExportState exports the state of supervisord and all its programs as JSON
+func (r *XMLRPCClient) ExportState(ctx context.Context) (state string, err error) {
+	ins := struct{}{}
+	result := struct{ State string }{}
+	r.post(ctx, "supervisor.exportState", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &result)
+			if err == nil {
+				state = result.State
+			}
+		}
+	})
+
+	return
+}
+
+// ImportState restores the state exported by ExportState
+func (r *XMLRPCClient) ImportState(ctx context.Context, state string) (reply types.BooleanReply, err error) {
+	return r.callByName(ctx, "supervisor.importState", state)
+}
+
 // StartProcess Start a process
-func (r *XMLRPCClient) StartProcess(process string, wait bool) (reply types.BooleanReply, err error) {
+func (r *XMLRPCClient) StartProcess(ctx context.Context, process string, wait bool) (reply types.BooleanReply, err error) {
 	ins := struct {
 		Name string
 		Wait bool
@@ -348,7 +831,7 @@ func (r *XMLRPCClient) StartProcess(process string, wait bool) (reply types.Bool
 		Name: process,
 		Wait: wait,
 	}
-	r.post("supervisor.startProcess", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.startProcess", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -367,8 +850,182 @@ func (r *XMLRPCClient) StartProcess(process string, wait bool) (reply types.Bool
 	return
 }
 
+// StartProcessWithEnv start a process with extra environment variables in KEY=VALUE format only for this run
+func (r *XMLRPCClient) StartProcessWithEnv(ctx context.Context, process string, env []string, wait bool) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name string
+		Wait bool
+		Env  []string
+	}{
+		Name: process,
+		Wait: wait,
+		Env:  env,
+	}
+	r.post(ctx, "supervisor.startProcess", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// RestartProcessWithEnv restart a process with environment overrides in KEY=VALUE format only for this run
+func (r *XMLRPCClient) RestartProcessWithEnv(ctx context.Context, process string, env []string, wait bool) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name string
+		Wait bool
+		Env  []string
+	}{
+		Name: process,
+		Wait: wait,
+		Env:  env,
+	}
+	r.post(ctx, "supervisor.restartProcessWithEnv", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// RestartProcess stop a process and wait for it to exit, then start it again on the server side
+func (r *XMLRPCClient) RestartProcess(ctx context.Context, process string, wait bool) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name string
+		Wait bool
+	}{
+		Name: process,
+		Wait: wait,
+	}
+	r.post(ctx, "supervisor.restartProcess", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// RestartProcessGroup stop all the processes in the group and wait for them to exit, then start them again
+func (r *XMLRPCClient) RestartProcessGroup(ctx context.Context, group string, wait bool) (reply AllProcessInfoReply, err error) {
+	ins := struct {
+		Name string
+		Wait bool
+	}{
+		Name: group,
+		Wait: wait,
+	}
+	r.post(ctx, "supervisor.restartProcessGroup", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// StartProcessGroup starts all the processes in the group, waits for them to be started if wait is true
+func (r *XMLRPCClient) StartProcessGroup(ctx context.Context, group string, wait bool) (reply AllProcessInfoReply, err error) {
+	return r.changeProcessGroupState(ctx, "supervisor.startProcessGroup", group, wait)
+}
+
+// StopProcessGroup stops all the processes in the group, waits for them to be stopped if wait is true
+func (r *XMLRPCClient) StopProcessGroup(ctx context.Context, group string, wait bool) (reply AllProcessInfoReply, err error) {
+	return r.changeProcessGroupState(ctx, "supervisor.stopProcessGroup", group, wait)
+}
+
+func (r *XMLRPCClient) changeProcessGroupState(ctx context.Context, method string, group string, wait bool) (reply AllProcessInfoReply, err error) {
+	ins := struct {
+		Name string
+		Wait bool
+	}{
+		Name: group,
+		Wait: wait,
+	}
+	r.post(ctx, method, &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// SignalProcessGroup requests to send signal to all the programs in the group
+func (r *XMLRPCClient) SignalProcessGroup(ctx context.Context, signal string, group string) (reply AllProcessInfoReply, err error) {
+	ins := types.ProcessSignal{Name: group, Signal: signal}
+	r.post(ctx, "supervisor.signalProcessGroup", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+
+	return
+}
+
+// CloseProcessStdin closes the stdin of a process to signal the end of input
+func (r *XMLRPCClient) CloseProcessStdin(ctx context.Context, process string) (reply types.BooleanReply, err error) {
+	ins := struct{ Name string }{process}
+	r.post(ctx, "supervisor.closeProcessStdin", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// SendProcessStdin sends the chars to the stdin of the running program
+func (r *XMLRPCClient) SendProcessStdin(ctx context.Context, process string, chars string) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name  string
+		Chars string
+	}{process, chars}
+	r.post(ctx, "supervisor.sendProcessStdin", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// SendProcessStdinBase64 sends the binary data to the stdin of the running program, the data is sent as
+// XML-RPC base64 so it is not limited to the valid XML chars
+func (r *XMLRPCClient) SendProcessStdinBase64(ctx context.Context, process string, data []byte) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Name string
+		Data []byte
+	}{process, data}
+	r.post(ctx, "supervisor.sendProcessStdinBase64", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
+// SendRemoteCommEvent emits the REMOTE_COMMUNICATION event with the type and data to the event listeners
+func (r *XMLRPCClient) SendRemoteCommEvent(ctx context.Context, eventType string, data string) (reply types.BooleanReply, err error) {
+	ins := struct {
+		Type string
+		Data string
+	}{eventType, data}
+	r.post(ctx, "supervisor.sendRemoteCommEvent", &ins, func(body io.ReadCloser, procError error) {
+		err = procError
+		if err == nil {
+			err = xml.DecodeClientResponse(body, &reply)
+		}
+	})
+	return
+}
+
 // StopProcess Stop a process named by name
-func (r *XMLRPCClient) StopProcess(process string, wait bool) (reply types.BooleanReply, err error) {
+func (r *XMLRPCClient) StopProcess(ctx context.Context, process string, wait bool) (reply types.BooleanReply, err error) {
 	ins := struct {
 		Name string
 		Wait bool
@@ -376,7 +1033,7 @@ func (r *XMLRPCClient) StopProcess(process string, wait bool) (reply types.Boole
 		Name: process,
 		Wait: wait,
 	}
-	r.post("supervisor.stopProcess", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.stopProcess", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -396,9 +1053,9 @@ func (r *XMLRPCClient) StopProcess(process string, wait bool) (reply types.Boole
 }
 
 // StartAllProcesses Start all processes listed in the configuration file
-func (r *XMLRPCClient) StartAllProcesses(wait bool) (reply AllProcStatusInfoReply, err error) {
+func (r *XMLRPCClient) StartAllProcesses(ctx context.Context, wait bool) (reply AllProcStatusInfoReply, err error) {
 	ins := struct{ Wait bool }{wait}
-	r.post("supervisor.startAllProcesses", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.startAllProcesses", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)
@@ -408,9 +1065,9 @@ func (r *XMLRPCClient) StartAllProcesses(wait bool) (reply AllProcStatusInfoRepl
 }
 
 // StopAllProcesses Stop all processes in the process list
-func (r *XMLRPCClient) StopAllProcesses(wait bool) (reply AllProcStatusInfoReply, err error) {
+func (r *XMLRPCClient) StopAllProcesses(ctx context.Context, wait bool) (reply AllProcStatusInfoReply, err error) {
 	ins := struct{ Wait bool }{wait}
-	r.post("supervisor.stopAllProcesses", &ins, func(body io.ReadCloser, procError error) {
+	r.post(ctx, "supervisor.stopAllProcesses", &ins, func(body io.ReadCloser, procError error) {
 		err = procError
 		if err == nil {
 			err = xml.DecodeClientResponse(body, &reply)